@@ -0,0 +1,427 @@
+package app
+
+import (
+	"7-solutions/database"
+	"7-solutions/federation"
+	"7-solutions/mailer"
+	"7-solutions/metrics"
+	"7-solutions/middleware"
+	"7-solutions/policy"
+	"7-solutions/repositories"
+	"7-solutions/router"
+	"7-solutions/services"
+	"7-solutions/tenant"
+	"7-solutions/tracing"
+	"7-solutions/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonlvhit/gocron"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Version is the build version reported by /health, set at build time with
+// -ldflags "-X 7-solutions/app.Version=...".
+var Version = "dev"
+
+type Config struct {
+	Port               string
+	ReadTimeout        time.Duration
+	ReadHeaderTimeout  time.Duration
+	WriteTimeout       time.Duration
+	IdleTimeout        time.Duration
+	ShutdownTimeout    time.Duration
+	ShutdownDelay      time.Duration
+	UserCountInterval  uint64
+	HealthCheckTimeout time.Duration
+	// OIDCIssuer is the public base URL of the API, used as the OIDC issuer
+	// and to build the discovery document.
+	OIDCIssuer         string
+	OIDCSigningKeyFile string
+	// Federation lists the external OpenID Providers users can sign in with.
+	Federation []federation.Config
+	Mailer     mailer.Config
+	Invitation router.InvitationConfig
+	Privacy    router.PrivacyConfig
+	Policy     policy.Config
+	// ImpersonationTTL is the lifetime of tokens from /admin/impersonate.
+	ImpersonationTTL time.Duration
+	RateLimit        RateLimitConfig
+	Security         SecurityConfig
+}
+
+func NewConfigFromEnv() (Config, error) {
+	config := Config{Port: utils.GetEnv("PORT", "8080")}
+	config.OIDCIssuer = strings.TrimSuffix(utils.GetEnv("OIDC_ISSUER", "http://localhost:"+config.Port), "/")
+	config.OIDCSigningKeyFile = utils.GetEnv("OIDC_SIGNING_KEY_FILE", "")
+	config.Mailer = mailer.NewConfigFromEnv()
+	config.Invitation.AcceptURL = utils.GetEnv("INVITATION_ACCEPT_URL", config.OIDCIssuer+"/invitations/accept")
+	// A known pseudonym key would let anyone map erased users back to
+	// their ids, so there is no default.
+	config.Privacy.PseudonymKey = utils.GetEnv("PSEUDONYM_KEY", "")
+	if config.Privacy.PseudonymKey == "" {
+		return config, errors.New("PSEUDONYM_KEY is required")
+	}
+
+	var err error
+	if config.ReadTimeout, err = utils.GetEnvDuration("SERVER_READ_TIMEOUT", 15*time.Second); err != nil {
+		return config, err
+	}
+	if config.ReadHeaderTimeout, err = utils.GetEnvDuration("SERVER_READ_HEADER_TIMEOUT", 5*time.Second); err != nil {
+		return config, err
+	}
+	if config.WriteTimeout, err = utils.GetEnvDuration("SERVER_WRITE_TIMEOUT", 15*time.Second); err != nil {
+		return config, err
+	}
+	if config.IdleTimeout, err = utils.GetEnvDuration("SERVER_IDLE_TIMEOUT", 60*time.Second); err != nil {
+		return config, err
+	}
+	if config.ShutdownTimeout, err = utils.GetEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second); err != nil {
+		return config, err
+	}
+	if config.ShutdownDelay, err = utils.GetEnvDuration("SHUTDOWN_DELAY", 0); err != nil {
+		return config, err
+	}
+	if config.UserCountInterval, err = utils.GetEnvUint("USER_COUNT_INTERVAL_SECONDS", 10); err != nil {
+		return config, err
+	}
+	if config.HealthCheckTimeout, err = utils.GetEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second); err != nil {
+		return config, err
+	}
+	if config.Invitation.TTL, err = utils.GetEnvDuration("INVITATION_TTL", services.DefaultInvitationTTL); err != nil {
+		return config, err
+	}
+	if config.Federation, err = federation.NewConfigsFromEnv(config.OIDCIssuer); err != nil {
+		return config, err
+	}
+	if config.ImpersonationTTL, err = utils.GetEnvDuration("IMPERSONATION_TTL", services.DefaultImpersonationTTL); err != nil {
+		return config, err
+	}
+	if config.Policy, err = policy.NewConfigFromEnv(); err != nil {
+		return config, err
+	}
+	if config.RateLimit, err = newRateLimitConfigFromEnv(); err != nil {
+		return config, err
+	}
+	if config.Security, err = newSecurityConfigFromEnv(); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// App owns the HTTP server, the background scheduler and the Mongo client so
+// they can be started and stopped together.
+type App struct {
+	config  Config
+	db      *mongo.Database
+	policy  *policy.Engine
+	imports services.UserImportService
+	exports services.UserExportService
+	privacy services.PrivacyService
+
+	Engine        *gin.Engine
+	HealthService services.HealthService
+	server        *http.Server
+	addr          net.Addr
+
+	scheduler     *gocron.Scheduler
+	stopScheduler chan bool
+
+	jobsMu   sync.Mutex
+	jobs     sync.WaitGroup
+	stopping bool
+
+	serveErr      chan error
+	shutdownHooks []func(context.Context) error
+	shutdownOnce  sync.Once
+	shutdownErr   error
+}
+
+func New(config Config, db *mongo.Database) *App {
+	r := gin.New()
+	// Only the configured proxies may set X-Forwarded-For, otherwise
+	// ClientIP() is the socket address. The list is validated in
+	// NewConfigFromEnv.
+	_ = r.SetTrustedProxies(config.Security.TrustedProxies)
+	r.Use(
+		middleware.RequestIDMiddleware(),
+		middleware.TracingMiddleware(),
+		middleware.LoggerMiddleware(),
+		middleware.RecoveryMiddleware(),
+		middleware.SecurityHeadersMiddleware(config.Security.Headers),
+		middleware.CORSMiddleware(config.Security.CORS),
+		middleware.MetricsMiddleware(),
+	)
+
+	healthService := services.NewHealthService(repositories.NewHealthRepository(db), Version, config.HealthCheckTimeout)
+
+	router.AddHealthRouter(r, db, healthService)
+	router.AddMetricsRouter(r)
+	// Database policies are loaded by Start and reloaded by a job.
+	policyEngine := policy.NewEngine(config.Policy.DryRun)
+	if config.Policy.Source != policy.SourceDatabase {
+		policies := config.Policy.Policies
+		if policies == nil {
+			policies = policy.DefaultPolicies()
+		}
+		if err := policyEngine.Load(policies); err != nil {
+			slog.Error("invalid policies, denying every policy check", "error", err)
+		}
+	}
+
+	rateLimitStore := newRateLimitStore(config.RateLimit, db)
+	router.AddAuthRouter(r, db, rateLimiter(config.RateLimit, rateLimitStore, "auth", config.RateLimit.Auth)...)
+	userImportService := router.NewUserImportService(db, policyEngine)
+	userExportService := router.NewUserExportService(db, policyEngine)
+	router.AddUserRouter(r, db, policyEngine, userImportService, userExportService, rateLimiter(config.RateLimit, rateLimitStore, "users", config.RateLimit.Users)...)
+	router.AddGroupRouter(r, db, rateLimiter(config.RateLimit, rateLimitStore, "groups", config.RateLimit.Users)...)
+	router.AddOIDCRouter(r, db)
+	router.AddFederationRouter(r, db, config.Federation, rateLimiter(config.RateLimit, rateLimitStore, "auth", config.RateLimit.Auth)...)
+	router.AddAPIKeyRouter(r, db, rateLimiter(config.RateLimit, rateLimitStore, "api_keys", config.RateLimit.Users)...)
+	router.AddOrganizationRouter(r, db, rateLimiter(config.RateLimit, rateLimitStore, "organizations", config.RateLimit.Users)...)
+	router.AddInvitationRouter(r, db, config.Invitation, mailer.New(config.Mailer),
+		rateLimiter(config.RateLimit, rateLimitStore, "invitations", config.RateLimit.Users),
+		rateLimiter(config.RateLimit, rateLimitStore, "auth", config.RateLimit.Auth))
+	router.AddAdminRouter(r, db, config.ImpersonationTTL, rateLimiter(config.RateLimit, rateLimitStore, "admin", config.RateLimit.Users)...)
+	router.AddSessionRouter(r, db, rateLimiter(config.RateLimit, rateLimitStore, "sessions", config.RateLimit.Users)...)
+	router.AddUserStatusRouter(r, db, policyEngine, rateLimiter(config.RateLimit, rateLimitStore, "admin", config.RateLimit.Users)...)
+	router.AddProfileRouter(r, db, policyEngine, rateLimiter(config.RateLimit, rateLimitStore, "users", config.RateLimit.Users)...)
+	router.AddAuthzRouter(r, db, policyEngine, rateLimiter(config.RateLimit, rateLimitStore, "authz", config.RateLimit.Users)...)
+	privacyService := router.NewPrivacyService(db, policyEngine, config.Privacy)
+	router.AddPrivacyRouter(r, db, privacyService, rateLimiter(config.RateLimit, rateLimitStore, "users", config.RateLimit.Users)...)
+
+	a := &App{
+		config:        config,
+		db:            db,
+		policy:        policyEngine,
+		imports:       userImportService,
+		exports:       userExportService,
+		privacy:       privacyService,
+		Engine:        r,
+		HealthService: healthService,
+		scheduler:     gocron.NewScheduler(),
+		serveErr:      make(chan error, 1),
+	}
+	a.server = &http.Server{
+		Addr:              ":" + config.Port,
+		Handler:           r,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+	}
+
+	if config.UserCountInterval > 0 {
+		a.scheduler.Every(config.UserCountInterval).Seconds().Do(a.runJob, "count_users", a.countUsers)
+	}
+	a.scheduler.Every(1).Hour().Do(a.runJob, "purge_exports", a.purgeExports)
+	a.scheduler.Every(10).Minutes().Do(a.runJob, "resume_erasures", a.resumeErasures)
+	if config.Policy.Source == policy.SourceDatabase && config.Policy.ReloadInterval > 0 {
+		a.scheduler.Every(config.Policy.ReloadInterval).Seconds().Do(a.runJob, "reload_policies", a.reloadPolicies)
+	}
+
+	return a
+}
+
+// OnShutdown registers a hook that runs after the server and scheduler have
+// stopped but before the Mongo client is disconnected, e.g. to flush buffers.
+func (a *App) OnShutdown(hook func(context.Context) error) {
+	a.shutdownHooks = append(a.shutdownHooks, hook)
+}
+
+// Start loads database policies, begins listening and starts the
+// scheduler. It does not block.
+func (a *App) Start() error {
+	if a.config.Policy.Source == policy.SourceDatabase {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := a.reloadPolicies(ctx)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+
+	listener, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", a.server.Addr, err)
+	}
+	a.addr = listener.Addr()
+
+	go func() {
+		if err := a.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.serveErr <- err
+		}
+	}()
+
+	a.stopScheduler = a.scheduler.Start()
+	slog.Info("server listening", "addr", a.addr.String())
+	return nil
+}
+
+// Addr returns the address the server is listening on once started.
+func (a *App) Addr() string {
+	if a.addr == nil {
+		return ""
+	}
+	return a.addr.String()
+}
+
+// Run starts the app and blocks until ctx is cancelled or the server fails,
+// then shuts down within the configured ShutdownTimeout.
+func (a *App) Run(ctx context.Context) error {
+	if err := a.Start(); err != nil {
+		return err
+	}
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		slog.Info("shutdown signal received")
+	case serveErr = <-a.serveErr:
+		slog.Error("server error", "error", serveErr)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.config.ShutdownTimeout)
+	defer cancel()
+	return errors.Join(serveErr, a.Shutdown(shutdownCtx))
+}
+
+// Shutdown marks the app as not ready, stops accepting connections, drains in-flight requests, stops the
+// scheduler and waits for running jobs and user imports and exports, runs the shutdown
+// hooks and finally disconnects Mongo. It is safe to call more than once.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.shutdownOnce.Do(func() {
+		var errs []error
+
+		// Fail readiness first and give load balancers ShutdownDelay to
+		// notice before the listener is closed.
+		a.HealthService.SetShuttingDown()
+		if a.config.ShutdownDelay > 0 {
+			select {
+			case <-time.After(a.config.ShutdownDelay):
+			case <-ctx.Done():
+			}
+		}
+
+		if err := a.server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shut down server: %w", err))
+		}
+
+		a.jobsMu.Lock()
+		a.stopping = true
+		a.jobsMu.Unlock()
+		if a.stopScheduler != nil {
+			a.stopScheduler <- true
+		}
+		a.scheduler.Clear()
+
+		if err := a.waitForJobs(ctx); err != nil {
+			errs = append(errs, err)
+		}
+		if err := a.imports.Wait(ctx); err != nil {
+			errs = append(errs, err)
+		}
+		if err := a.exports.Wait(ctx); err != nil {
+			errs = append(errs, err)
+		}
+
+		for _, hook := range a.shutdownHooks {
+			if err := hook(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		if err := database.Disconnect(ctx, a.db); err != nil {
+			errs = append(errs, fmt.Errorf("failed to disconnect database: %w", err))
+		}
+
+		a.shutdownErr = errors.Join(errs...)
+		slog.Info("shutdown complete")
+	})
+	return a.shutdownErr
+}
+
+func (a *App) waitForJobs(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		a.jobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for scheduled jobs: %w", ctx.Err())
+	}
+}
+
+// runJob runs a scheduled job unless shutdown has started, tracking it so
+// Shutdown can wait for it to finish.
+func (a *App) runJob(name string, job func(ctx context.Context) error) {
+	a.jobsMu.Lock()
+	if a.stopping {
+		a.jobsMu.Unlock()
+		return
+	}
+	a.jobs.Add(1)
+	a.jobsMu.Unlock()
+	defer a.jobs.Done()
+
+	ctx, span := tracing.Start(context.Background(), "job "+name)
+	defer span.End()
+
+	start := time.Now()
+	err := job(ctx)
+	tracing.RecordError(span, err)
+	duration := time.Since(start)
+	a.HealthService.RecordJobRun(name, start, duration, err)
+	metrics.ObserveJob(name, duration, err)
+	if err != nil {
+		slog.Error("job failed", "job", name, "error", err)
+	}
+}
+
+func (a *App) countUsers(ctx context.Context) error {
+	userRepository := repositories.WithUserMetrics(repositories.NewUserRepository(a.db))
+	count, err := userRepository.CountUsers(tenant.WithAllTenants(ctx))
+	if err != nil {
+		return err
+	}
+	metrics.UsersTotal.Set(float64(count))
+	return nil
+}
+
+// purgeExports deletes the files of user exports that are no longer kept.
+func (a *App) purgeExports(ctx context.Context) error {
+	_, err := a.exports.PurgeExpiredExports(ctx)
+	return err
+}
+
+// resumeErasures finishes user erasures that were interrupted, e.g. by a
+// restart.
+func (a *App) resumeErasures(ctx context.Context) error {
+	_, err := a.privacy.ResumeErasures(ctx)
+	return err
+}
+
+// reloadPolicies replaces the policies with the ones in the database. The
+// previous policies stay in effect when they cannot be loaded.
+func (a *App) reloadPolicies(ctx context.Context) error {
+	policies, err := repositories.WithPolicyMetrics(repositories.NewPolicyRepository(a.db)).GetPolicies(ctx)
+	if err != nil {
+		return err
+	}
+	if err := a.policy.Load(policies); err != nil {
+		return fmt.Errorf("failed to load policies: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"7-solutions/middleware"
+	"7-solutions/ratelimit"
+	"7-solutions/repositories"
+	"7-solutions/utils"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreMongo  = "mongo"
+)
+
+type RateLimitRule struct {
+	Limit ratelimit.Limit
+	Key   string
+}
+
+type RateLimitConfig struct {
+	Enabled bool
+	Store   string
+	Auth    RateLimitRule
+	Users   RateLimitRule
+}
+
+func newRateLimitConfigFromEnv() (RateLimitConfig, error) {
+	config := RateLimitConfig{Store: utils.GetEnv("RATE_LIMIT_STORE", RateLimitStoreMemory)}
+
+	var err error
+	if config.Enabled, err = utils.GetEnvBool("RATE_LIMIT_ENABLED", true); err != nil {
+		return config, err
+	}
+	if config.Store != RateLimitStoreMemory && config.Store != RateLimitStoreMongo {
+		return config, fmt.Errorf("invalid RATE_LIMIT_STORE %q", config.Store)
+	}
+	if config.Auth, err = rateLimitRuleFromEnv("AUTH", "10/m", "ip"); err != nil {
+		return config, err
+	}
+	if config.Users, err = rateLimitRuleFromEnv("USERS", "120/m", "user"); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+func rateLimitRuleFromEnv(group, limit, key string) (RateLimitRule, error) {
+	parsed, err := ratelimit.ParseLimit(utils.GetEnv("RATE_LIMIT_"+group, limit))
+	if err != nil {
+		return RateLimitRule{}, fmt.Errorf("invalid RATE_LIMIT_%s: %w", group, err)
+	}
+	rule := RateLimitRule{Limit: parsed, Key: utils.GetEnv("RATE_LIMIT_"+group+"_KEY", key)}
+	if _, err := middleware.RateLimitKeyFuncByName(rule.Key); err != nil {
+		return RateLimitRule{}, fmt.Errorf("invalid RATE_LIMIT_%s_KEY: %w", group, err)
+	}
+	return rule, nil
+}
+
+func newRateLimitStore(config RateLimitConfig, db *mongo.Database) ratelimit.Store {
+	if config.Store == RateLimitStoreMongo {
+		return repositories.NewRateLimitRepository(db)
+	}
+	return ratelimit.NewMemoryStore()
+}
+
+// rateLimiter returns the middleware for one route group, or nothing when
+// rate limiting is disabled.
+func rateLimiter(config RateLimitConfig, store ratelimit.Store, group string, rule RateLimitRule) []gin.HandlerFunc {
+	if !config.Enabled {
+		return nil
+	}
+	keyFunc, _ := middleware.RateLimitKeyFuncByName(rule.Key)
+	return []gin.HandlerFunc{middleware.RateLimitMiddleware(group, store, rule.Limit, keyFunc)}
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"7-solutions/middleware"
+	"7-solutions/utils"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+type SecurityConfig struct {
+	CORS           middleware.CORSConfig
+	Headers        middleware.SecurityHeadersConfig
+	TrustedProxies []string
+}
+
+func newSecurityConfigFromEnv() (SecurityConfig, error) {
+	config := SecurityConfig{
+		CORS: middleware.CORSConfig{
+			AllowedOrigins: utils.GetEnvList("CORS_ALLOWED_ORIGINS", nil),
+			AllowedMethods: utils.GetEnvList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+			AllowedHeaders: utils.GetEnvList("CORS_ALLOWED_HEADERS", []string{"Authorization", "Content-Type", "X-Request-ID"}),
+			ExposedHeaders: utils.GetEnvList("CORS_EXPOSED_HEADERS", []string{
+				"X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After",
+			}),
+		},
+		Headers: middleware.SecurityHeadersConfig{
+			FrameOptions:          utils.GetEnv("SECURITY_FRAME_OPTIONS", "DENY"),
+			ContentSecurityPolicy: utils.GetEnv("SECURITY_CSP", "default-src 'self'; frame-ancestors 'none'; base-uri 'none'; form-action 'self'"),
+			ReferrerPolicy:        utils.GetEnv("SECURITY_REFERRER_POLICY", "no-referrer"),
+		},
+		TrustedProxies: utils.GetEnvList("TRUSTED_PROXIES", nil),
+	}
+
+	var err error
+	if config.CORS.AllowCredentials, err = utils.GetEnvBool("CORS_ALLOW_CREDENTIALS", false); err != nil {
+		return config, err
+	}
+	if config.CORS.MaxAge, err = utils.GetEnvDuration("CORS_MAX_AGE", 10*time.Minute); err != nil {
+		return config, err
+	}
+	if config.Headers.HSTSMaxAge, err = utils.GetEnvDuration("SECURITY_HSTS_MAX_AGE", 365*24*time.Hour); err != nil {
+		return config, err
+	}
+	if config.Headers.HSTSIncludeSubdomains, err = utils.GetEnvBool("SECURITY_HSTS_INCLUDE_SUBDOMAINS", true); err != nil {
+		return config, err
+	}
+
+	for _, origin := range config.CORS.AllowedOrigins {
+		if origin == "*" && config.CORS.AllowCredentials {
+			return config, fmt.Errorf("CORS_ALLOWED_ORIGINS=* cannot be combined with CORS_ALLOW_CREDENTIALS")
+		}
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			return config, fmt.Errorf("invalid CORS origin %q, expected a scheme such as https://", origin)
+		}
+	}
+	for _, proxy := range config.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return config, fmt.Errorf("invalid TRUSTED_PROXIES entry %q", proxy)
+		}
+	}
+	return config, nil
+}
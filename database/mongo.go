@@ -1,23 +1,244 @@
 package database
 
 import (
+	"7-solutions/tracing"
+	"7-solutions/utils"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
 )
 
-func NewMongoDB(host, port, dbname string) *mongo.Database {
-	clientOptions := options.Client().ApplyURI("mongodb://" + host + ":" + port + "/" + dbname)
-	client, err := mongo.Connect(context.TODO(), clientOptions)
+// MongoConfig describes how to reach MongoDB. When URI is set it is used as
+// is (mongodb:// or mongodb+srv://) and the remaining fields only override
+// what the URI does not already specify; otherwise a URI is built from Host
+// and Port.
+type MongoConfig struct {
+	URI        string
+	Host       string
+	Port       string
+	Name       string
+	Username   string
+	Password   string
+	AuthSource string
+	ReplicaSet string
+
+	TLS                   bool
+	TLSCAFile             string
+	TLSInsecureSkipVerify bool
+
+	MaxPoolSize            uint64
+	MinPoolSize            uint64
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+	// OperationTimeout bounds every operation whose context has no earlier
+	// deadline of its own.
+	OperationTimeout time.Duration
+
+	ReadConcern  string
+	WriteConcern string
+
+	ConnectRetries int
+	RetryBackoff   time.Duration
+}
+
+// NewMongoConfigFromEnv reads the Mongo settings from the environment.
+// DB_URI takes precedence over DB_HOST/DB_PORT.
+func NewMongoConfigFromEnv() (MongoConfig, error) {
+	cfg := MongoConfig{
+		URI:          os.Getenv("DB_URI"),
+		Host:         os.Getenv("DB_HOST"),
+		Port:         os.Getenv("DB_PORT"),
+		Name:         os.Getenv("DB_NAME"),
+		Username:     os.Getenv("DB_USERNAME"),
+		Password:     os.Getenv("DB_PASSWORD"),
+		AuthSource:   os.Getenv("DB_AUTH_SOURCE"),
+		ReplicaSet:   os.Getenv("DB_REPLICA_SET"),
+		TLSCAFile:    os.Getenv("DB_TLS_CA_FILE"),
+		ReadConcern:  os.Getenv("DB_READ_CONCERN"),
+		WriteConcern: os.Getenv("DB_WRITE_CONCERN"),
+	}
+
+	var err error
+	if cfg.TLS, err = utils.GetEnvBool("DB_TLS", false); err != nil {
+		return cfg, err
+	}
+	if cfg.TLSInsecureSkipVerify, err = utils.GetEnvBool("DB_TLS_INSECURE", false); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxPoolSize, err = utils.GetEnvUint("DB_MAX_POOL_SIZE", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.MinPoolSize, err = utils.GetEnvUint("DB_MIN_POOL_SIZE", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.ConnectTimeout, err = utils.GetEnvDuration("DB_CONNECT_TIMEOUT", 10*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.ServerSelectionTimeout, err = utils.GetEnvDuration("DB_SERVER_SELECTION_TIMEOUT", 10*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.OperationTimeout, err = utils.GetEnvDuration("DB_OPERATION_TIMEOUT", 5*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.ConnectRetries, err = utils.GetEnvInt("DB_CONNECT_RETRIES", 5); err != nil {
+		return cfg, err
+	}
+	if cfg.RetryBackoff, err = utils.GetEnvDuration("DB_RETRY_BACKOFF", time.Second); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// ConnectionURI returns the URI handed to the driver.
+func (c MongoConfig) ConnectionURI() string {
+	if c.URI != "" {
+		return c.URI
+	}
+	u := url.URL{
+		Scheme: "mongodb",
+		Host:   c.Host + ":" + c.Port,
+		Path:   "/" + c.Name,
+	}
+	return u.String()
+}
+
+// DatabaseName returns Name, falling back to the database in the URI.
+func (c MongoConfig) DatabaseName() (string, error) {
+	if c.Name != "" {
+		return c.Name, nil
+	}
+	cs, err := connstring.ParseAndValidate(c.ConnectionURI())
+	if err != nil {
+		return "", fmt.Errorf("invalid mongo uri: %w", err)
+	}
+	if cs.Database == "" {
+		return "", errors.New("database name is required")
+	}
+	return cs.Database, nil
+}
+
+// ClientOptions builds the driver options for the configuration.
+func (c MongoConfig) ClientOptions() (*options.ClientOptions, error) {
+	clientOptions := options.Client().ApplyURI(c.ConnectionURI()).SetMonitor(tracing.NewMongoMonitor())
+	if err := clientOptions.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid mongo uri: %w", err)
+	}
+
+	// ApplyURI only sets the options present in the URI, so anything still
+	// nil below is free to be filled in from the remaining fields.
+	if c.Username != "" && clientOptions.Auth == nil {
+		clientOptions.SetAuth(options.Credential{
+			Username:   c.Username,
+			Password:   c.Password,
+			AuthSource: c.AuthSource,
+		})
+	}
+	if c.ReplicaSet != "" && clientOptions.ReplicaSet == nil {
+		clientOptions.SetReplicaSet(c.ReplicaSet)
+	}
+	if (c.TLS || c.TLSCAFile != "") && clientOptions.TLSConfig == nil {
+		tlsConfig := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: c.TLSInsecureSkipVerify,
+		}
+		if c.TLSCAFile != "" {
+			pem, err := os.ReadFile(c.TLSCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read TLS CA file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", c.TLSCAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		clientOptions.SetTLSConfig(tlsConfig)
+	}
+	if c.MaxPoolSize > 0 && clientOptions.MaxPoolSize == nil {
+		clientOptions.SetMaxPoolSize(c.MaxPoolSize)
+	}
+	if c.MinPoolSize > 0 && clientOptions.MinPoolSize == nil {
+		clientOptions.SetMinPoolSize(c.MinPoolSize)
+	}
+	if c.ConnectTimeout > 0 && clientOptions.ConnectTimeout == nil {
+		clientOptions.SetConnectTimeout(c.ConnectTimeout)
+	}
+	if c.ServerSelectionTimeout > 0 && clientOptions.ServerSelectionTimeout == nil {
+		clientOptions.SetServerSelectionTimeout(c.ServerSelectionTimeout)
+	}
+	if c.OperationTimeout > 0 && clientOptions.Timeout == nil {
+		clientOptions.SetTimeout(c.OperationTimeout)
+	}
+	if c.ReadConcern != "" && clientOptions.ReadConcern == nil {
+		clientOptions.SetReadConcern(&readconcern.ReadConcern{Level: c.ReadConcern})
+	}
+	if c.WriteConcern != "" && clientOptions.WriteConcern == nil {
+		wc := &writeconcern.WriteConcern{W: c.WriteConcern}
+		if n, err := strconv.Atoi(c.WriteConcern); err == nil {
+			wc.W = n
+		}
+		clientOptions.SetWriteConcern(wc)
+	}
+
+	return clientOptions, nil
+}
+
+// NewMongoDB connects to MongoDB and pings it, retrying with exponential
+// backoff up to ConnectRetries times before giving up.
+func NewMongoDB(ctx context.Context, cfg MongoConfig) (*mongo.Database, error) {
+	dbname, err := cfg.DatabaseName()
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	clientOptions, err := cfg.ClientOptions()
+	if err != nil {
+		return nil, err
 	}
 
-	err = client.Ping(context.TODO(), nil)
+	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to create mongo client: %w", err)
+	}
+
+	backoff := cfg.RetryBackoff
+	for attempt := 0; attempt <= cfg.ConnectRetries; attempt++ {
+		if attempt > 0 {
+			slog.Warn("mongo ping failed, retrying", "attempt", attempt, "attempts", cfg.ConnectRetries+1, "error", err, "backoff", backoff.String())
+			select {
+			case <-ctx.Done():
+				_ = client.Disconnect(context.Background())
+				return nil, fmt.Errorf("failed to connect to mongo: %w", ctx.Err())
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		if err = client.Ping(ctx, nil); err == nil {
+			return client.Database(dbname), nil
+		}
 	}
 
-	return client.Database(dbname)
+	_ = client.Disconnect(context.Background())
+	return nil, fmt.Errorf("failed to connect to mongo: %w", err)
+}
+
+// Disconnect closes the client behind db.
+func Disconnect(ctx context.Context, db *mongo.Database) error {
+	if db == nil {
+		return nil
+	}
+	return db.Client().Disconnect(ctx)
 }
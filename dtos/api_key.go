@@ -0,0 +1,27 @@
+package dtos
+
+import "time"
+
+type APIKeyCreate struct {
+	Name      string    `json:"name" binding:"required"`
+	Scopes    []string  `json:"scopes" binding:"required,min=1"`
+	ExpiresAt time.Time `json:"expiresAt" binding:"required"`
+}
+
+type APIKeyResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	TenantID  string     `json:"tenantId"`
+	CreatedBy string     `json:"createdBy"`
+	CreatedAt string     `json:"createdAt"`
+	ExpiresAt string     `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// APIKeyCreated carries the plaintext key, which is only ever returned once.
+type APIKeyCreated struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
@@ -0,0 +1,11 @@
+package dtos
+
+import "7-solutions/policy"
+
+type AuthzCheck struct {
+	// Subject defaults to the caller.
+	Subject  *policy.Subject `json:"subject"`
+	Action   string          `json:"action" binding:"required"`
+	Resource policy.Resource `json:"resource"`
+	Context  map[string]any  `json:"context"`
+}
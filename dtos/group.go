@@ -0,0 +1,55 @@
+package dtos
+
+type GroupCreate struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	ParentID    string `json:"parentId"`
+}
+
+type GroupUpdate struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	// ParentID moves the group; an empty string makes it a top-level group.
+	ParentID *string `json:"parentId"`
+}
+
+type GroupResponse struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ParentID    string `json:"parentId,omitempty"`
+	CreatedAt   string `json:"createdAt"`
+	UpdatedAt   string `json:"updatedAt"`
+}
+
+// UserGroupResponse is a group a user belongs to, either directly or
+// through one of its subgroups.
+type UserGroupResponse struct {
+	GroupResponse
+	Direct bool `json:"direct"`
+}
+
+type GroupMemberAdd struct {
+	UserID int `json:"userId" binding:"required"`
+}
+
+type GroupMemberResponse struct {
+	UserID  int    `json:"userId"`
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	AddedAt string `json:"addedAt"`
+}
+
+type GroupMemberPage struct {
+	Members []GroupMemberResponse `json:"members"`
+	Page    int                   `json:"page"`
+	Limit   int                   `json:"limit"`
+	Total   int64                 `json:"total"`
+}
+
+// PageQuery holds the page and limit query parameters of paginated
+// endpoints.
+type PageQuery struct {
+	Page  int `form:"page" binding:"omitempty,min=1"`
+	Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+}
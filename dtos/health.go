@@ -0,0 +1,33 @@
+package dtos
+
+type DependencyHealth struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type JobHealth struct {
+	Name         string  `json:"name"`
+	LastRun      string  `json:"lastRun"`
+	DurationMs   float64 `json:"durationMs"`
+	Success      bool    `json:"success"`
+	Error        string  `json:"error,omitempty"`
+	SuccessCount int64   `json:"successCount"`
+	FailureCount int64   `json:"failureCount"`
+}
+
+type ReadinessResponse struct {
+	Status string             `json:"status"`
+	Checks []DependencyHealth `json:"checks"`
+}
+
+type HealthResponse struct {
+	Status        string             `json:"status"`
+	Version       string             `json:"version"`
+	StartedAt     string             `json:"startedAt"`
+	UptimeSeconds float64            `json:"uptimeSeconds"`
+	ShuttingDown  bool               `json:"shuttingDown"`
+	Dependencies  []DependencyHealth `json:"dependencies"`
+	Jobs          []JobHealth        `json:"jobs"`
+}
@@ -0,0 +1,9 @@
+package dtos
+
+type IdentityResponse struct {
+	ID       string `json:"id"`
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"`
+	Email    string `json:"email"`
+	LinkedAt string `json:"linkedAt"`
+}
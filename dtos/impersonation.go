@@ -0,0 +1,8 @@
+package dtos
+
+type ImpersonationResponse struct {
+	Token     string       `json:"token"`
+	ExpiresAt string       `json:"expiresAt"`
+	ActorID   string       `json:"actorId"`
+	User      UserResponse `json:"user"`
+}
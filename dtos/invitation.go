@@ -0,0 +1,29 @@
+package dtos
+
+type InvitationCreate struct {
+	Email string `json:"email" binding:"required,email"`
+	// Role defaults to user.
+	Role    string `json:"role" binding:"omitempty,oneof=user admin"`
+	GroupID string `json:"groupId"`
+	// TenantID defaults to the caller's tenant; only super-admins may set
+	// another one.
+	TenantID string `json:"tenantId"`
+}
+
+type InvitationAccept struct {
+	Token    string `json:"token" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+type InvitationResponse struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	GroupID   string `json:"groupId,omitempty"`
+	TenantID  string `json:"tenantId"`
+	Status    string `json:"status"`
+	InvitedBy string `json:"invitedBy"`
+	CreatedAt string `json:"createdAt"`
+	ExpiresAt string `json:"expiresAt"`
+}
@@ -0,0 +1,84 @@
+package dtos
+
+type OAuthClientCreate struct {
+	Name         string   `json:"name" binding:"required"`
+	RedirectURIs []string `json:"redirectUris"`
+	GrantTypes   []string `json:"grantTypes" binding:"required,min=1"`
+	Scopes       []string `json:"scopes" binding:"required,min=1"`
+	Public       bool     `json:"public"`
+}
+
+type OAuthClientResponse struct {
+	ClientID     string   `json:"clientId"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirectUris"`
+	GrantTypes   []string `json:"grantTypes"`
+	Scopes       []string `json:"scopes"`
+	Public       bool     `json:"public"`
+	TenantID     string   `json:"tenantId"`
+	CreatedBy    string   `json:"createdBy"`
+	CreatedAt    string   `json:"createdAt"`
+}
+
+// OAuthClientCreated carries the client secret, which is only ever returned
+// once.
+type OAuthClientCreated struct {
+	OAuthClientResponse
+	ClientSecret string `json:"clientSecret,omitempty"`
+}
+
+// ClientCredentials authenticate a client at the token, introspection and
+// revocation endpoints, taken from HTTP Basic auth or the form body.
+type ClientCredentials struct {
+	ClientID     string
+	ClientSecret string
+}
+
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+	Nonce               string `form:"nonce"`
+}
+
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	Scope        string `form:"scope"`
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+	IDToken     string `json:"id_token,omitempty"`
+}
+
+// UserInfo holds the OIDC standard claims released for the granted scopes.
+type UserInfo struct {
+	Sub           string `json:"sub"`
+	Name          string `json:"name,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+}
+
+// IntrospectionResponse follows RFC 7662; inactive tokens only carry
+// "active": false.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+}
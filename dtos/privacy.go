@@ -0,0 +1,52 @@
+package dtos
+
+// UserDataExport is everything stored about a user, as handed to them on a
+// data subject access request.
+type UserDataExport struct {
+	ExportedAt    string               `json:"exportedAt"`
+	Profile       UserExportRow        `json:"profile"`
+	StatusHistory []UserStatusChange   `json:"statusHistory"`
+	Sessions      []UserDataSession    `json:"sessions"`
+	Identities    []IdentityResponse   `json:"identities"`
+	Memberships   []UserDataMembership `json:"memberships"`
+	AuditEvents   []UserDataAuditEvent `json:"auditEvents"`
+}
+
+// UserDataSession is a session of the user, including revoked and expired
+// ones.
+type UserDataSession struct {
+	ID         string `json:"id"`
+	UserAgent  string `json:"userAgent"`
+	IP         string `json:"ip"`
+	CreatedAt  string `json:"createdAt"`
+	LastSeenAt string `json:"lastSeenAt"`
+	ExpiresAt  string `json:"expiresAt"`
+	RevokedAt  string `json:"revokedAt,omitempty"`
+}
+
+type UserDataMembership struct {
+	GroupID   string `json:"groupId"`
+	GroupName string `json:"groupName"`
+	AddedAt   string `json:"addedAt"`
+}
+
+type UserDataAuditEvent struct {
+	Action       string         `json:"action"`
+	UserID       string         `json:"userId,omitempty"`
+	ActorID      string         `json:"actorId,omitempty"`
+	TargetUserID string         `json:"targetUserId,omitempty"`
+	RequestID    string         `json:"requestId,omitempty"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+	CreatedAt    string         `json:"createdAt"`
+}
+
+// ErasureResponse reports an erasure. Subject replaces the user id in the
+// records that are kept.
+type ErasureResponse struct {
+	Subject     string   `json:"subject"`
+	Status      string   `json:"status"`
+	Steps       []string `json:"steps"`
+	RequestedBy string   `json:"requestedBy"`
+	CreatedAt   string   `json:"createdAt"`
+	CompletedAt string   `json:"completedAt,omitempty"`
+}
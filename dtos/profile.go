@@ -0,0 +1,43 @@
+package dtos
+
+type AttributeDefinitionCreate struct {
+	Key      string   `json:"key" binding:"required"`
+	Type     string   `json:"type" binding:"required,oneof=string number boolean"`
+	Required bool     `json:"required"`
+	Pattern  string   `json:"pattern"`
+	Enum     []string `json:"enum"`
+	// Visibility defaults to private.
+	Visibility  string `json:"visibility" binding:"omitempty,oneof=public private admin"`
+	Description string `json:"description" binding:"max=500"`
+	// TenantID defaults to the caller's tenant; only super-admins may set
+	// another one.
+	TenantID string `json:"tenantId"`
+}
+
+// AttributeDefinitionUpdate replaces everything but the key and the type of
+// a definition.
+type AttributeDefinitionUpdate struct {
+	Required    bool     `json:"required"`
+	Pattern     string   `json:"pattern"`
+	Enum        []string `json:"enum"`
+	Visibility  string   `json:"visibility" binding:"omitempty,oneof=public private admin"`
+	Description string   `json:"description" binding:"max=500"`
+}
+
+type AttributeDefinitionResponse struct {
+	Key         string   `json:"key"`
+	Type        string   `json:"type"`
+	Required    bool     `json:"required"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	Visibility  string   `json:"visibility"`
+	Description string   `json:"description"`
+	CreatedAt   string   `json:"createdAt"`
+	UpdatedAt   string   `json:"updatedAt"`
+}
+
+// UserAttributesUpdate merges Attributes into the attributes of a user; a
+// null value removes the attribute.
+type UserAttributesUpdate struct {
+	Attributes map[string]any `json:"attributes" binding:"required"`
+}
@@ -0,0 +1,12 @@
+package dtos
+
+type SessionResponse struct {
+	ID         string `json:"id"`
+	UserAgent  string `json:"userAgent"`
+	IP         string `json:"ip"`
+	CreatedAt  string `json:"createdAt"`
+	LastSeenAt string `json:"lastSeenAt"`
+	ExpiresAt  string `json:"expiresAt"`
+	// Current marks the session of the token making the request.
+	Current bool `json:"current"`
+}
@@ -1,14 +1,26 @@
 package dtos
 
+import "time"
+
 type UserRegister struct {
 	Name     string `json:"name"`
 	Email    string `json:"email" gorm:"unique"`
 	Password string `json:"password"`
+	// TenantID defaults to the caller's tenant; only super-admins may set
+	// another one.
+	TenantID string `json:"tenantId"`
 }
 
 type UserAuthenticate struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// TenantID selects the organization to sign in to, the default tenant
+	// when empty.
+	TenantID string `json:"tenantId"`
+	// UserAgent and IP describe the device signing in and are filled in
+	// from the request.
+	UserAgent string `json:"-"`
+	IP        string `json:"-"`
 }
 
 type UserUpdate struct {
@@ -17,8 +29,27 @@ type UserUpdate struct {
 }
 
 type UserResponse struct {
-	ID        int    `json:"id"`
-	Name      string `json:"name"`
-	Email     string `json:"email"`
-	CreatedAt string `json:"createdAt"`
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"emailVerified"`
+	TenantID      string `json:"tenantId"`
+	Status        string `json:"status,omitempty"`
+	CreatedAt     string `json:"createdAt"`
+	// Attributes only holds the custom attributes the caller may see.
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// UserFilter narrows down the users that are listed or exported. Empty
+// fields match every user.
+type UserFilter struct {
+	Role          string     `form:"role" binding:"omitempty,oneof=user admin superadmin"`
+	EmailVerified *bool      `form:"emailVerified"`
+	Status        string     `form:"status" binding:"omitempty,oneof=pending active suspended locked deactivated"`
+	CreatedAfter  *time.Time `form:"createdAfter" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore *time.Time `form:"createdBefore" time_format:"2006-01-02T15:04:05Z07:00"`
+	// Attributes matches custom attribute values exactly. Handlers fill it
+	// from the attributes[key]=value query parameters, and services convert
+	// the values to the types of the attributes.
+	Attributes map[string]any `form:"-"`
 }
@@ -0,0 +1,49 @@
+package dtos
+
+// UserBulk is a list of operations on users. With Atomic set they are
+// applied in a transaction, all or none; otherwise each one that is valid
+// and allowed is applied on its own.
+type UserBulk struct {
+	Atomic     bool                `json:"atomic"`
+	Operations []UserBulkOperation `json:"operations" binding:"required,min=1,max=1000,dive"`
+}
+
+// UserBulkOperation updates, deletes or deactivates the user ID. Update is
+// required for updates and holds the fields to change. Reason is required
+// for deactivations.
+type UserBulkOperation struct {
+	Op     string          `json:"op" binding:"required,oneof=update delete deactivate"`
+	ID     int             `json:"id" binding:"required"`
+	Update *UserBulkUpdate `json:"update"`
+	Reason string          `json:"reason" binding:"max=500"`
+}
+
+// UserBulkUpdate changes the fields that are set.
+type UserBulkUpdate struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+	Role  *string `json:"role"`
+}
+
+type UserBulkSummary struct {
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+}
+
+// UserBulkResponse reports every operation in request order. Applied is
+// false when an atomic request was rolled back.
+type UserBulkResponse struct {
+	Atomic  bool                 `json:"atomic"`
+	Applied bool                 `json:"applied"`
+	Summary UserBulkSummary      `json:"summary"`
+	Results []UserBulkItemResult `json:"results"`
+}
+
+type UserBulkItemResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
@@ -0,0 +1,34 @@
+package dtos
+
+// UserExport holds the query parameters of an export.
+type UserExport struct {
+	// Format is csv, ndjson or xlsx.
+	Format string `form:"format"`
+	UserFilter
+}
+
+// UserExportRow is one exported user. It lists the exported fields
+// explicitly so secrets such as password hashes never leave the database.
+type UserExportRow struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"emailVerified"`
+	Role          string `json:"role"`
+	TenantID      string `json:"tenantId"`
+	CreatedAt     string `json:"createdAt"`
+	// Attributes holds every custom attribute of the user.
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+type UserExportResponse struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	Format      string `json:"format"`
+	Rows        int    `json:"rows"`
+	Size        int64  `json:"size"`
+	Error       string `json:"error,omitempty"`
+	CreatedAt   string `json:"createdAt"`
+	CompletedAt string `json:"completedAt,omitempty"`
+	ExpiresAt   string `json:"expiresAt"`
+}
@@ -0,0 +1,53 @@
+package dtos
+
+// UserImport holds the query parameters of an import; the file is the
+// request body.
+type UserImport struct {
+	// Format is csv or ndjson, taken from the Content-Type when empty.
+	Format string `form:"format"`
+	DryRun bool   `form:"dryRun"`
+	// TenantID defaults to the caller's tenant; only super-admins may set
+	// another one.
+	TenantID string `form:"tenantId"`
+}
+
+// UserImportRow is one user of an import file. Password is only used for
+// new users, and Role, user or admin, defaults to user for them and is left
+// alone for existing ones when empty.
+type UserImportRow struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+type UserImportSummary struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Invalid   int `json:"invalid"`
+	Conflicts int `json:"conflicts"`
+	Failed    int `json:"failed"`
+}
+
+// UserImportResponse reports an import. ID is only set for imports that
+// run in the background.
+type UserImportResponse struct {
+	ID          string                  `json:"id,omitempty"`
+	Status      string                  `json:"status"`
+	DryRun      bool                    `json:"dryRun"`
+	Total       int                     `json:"total"`
+	Processed   int                     `json:"processed"`
+	Summary     UserImportSummary       `json:"summary"`
+	Results     []UserImportRowResponse `json:"results"`
+	Error       string                  `json:"error,omitempty"`
+	CreatedAt   string                  `json:"createdAt"`
+	CompletedAt string                  `json:"completedAt,omitempty"`
+}
+
+type UserImportRowResponse struct {
+	Row    int    `json:"row"`
+	Email  string `json:"email,omitempty"`
+	UserID int    `json:"userId,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
@@ -0,0 +1,24 @@
+package dtos
+
+// UserStatusUpdate moves a user to Status. Reason is required for every
+// status but active.
+type UserStatusUpdate struct {
+	Status string `json:"status" binding:"required,oneof=pending active suspended locked deactivated"`
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+type UserStatusChange struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Reason  string `json:"reason,omitempty"`
+	ActorID string `json:"actorId"`
+	At      string `json:"at"`
+}
+
+// UserStatusResponse is the current status of a user and the changes that
+// led to it, oldest first.
+type UserStatusResponse struct {
+	UserID  int                `json:"userId"`
+	Status  string             `json:"status"`
+	History []UserStatusChange `json:"history"`
+}
@@ -0,0 +1,386 @@
+package federation
+
+import (
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	// ErrTokenExchange is returned when the provider rejects the
+	// authorization code, e.g. because it was already used or has expired.
+	ErrTokenExchange = errors.New("identity provider rejected the authorization code")
+	// ErrInvalidIDToken is returned for ID tokens that fail signature or
+	// claim validation.
+	ErrInvalidIDToken = errors.New("invalid id token")
+	// ErrProviderUnavailable is returned when the provider cannot be
+	// reached or answers with something other than what OIDC prescribes.
+	ErrProviderUnavailable = errors.New("identity provider unavailable")
+)
+
+// jwksRefreshInterval limits how often an unknown key id triggers a JWKS
+// download, so forged tokens cannot be used to hammer the provider.
+const jwksRefreshInterval = time.Minute
+
+var providerName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Config describes an external OpenID Provider this API signs users in with.
+type Config struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// RedirectURL is the callback registered at the provider.
+	RedirectURL string
+	// Tenant is the organization whose users sign in with this provider.
+	Tenant string
+}
+
+// NewConfigsFromEnv reads FEDERATION_PROVIDERS, a comma separated list of
+// provider names, and for each name the FEDERATION_<NAME>_ISSUER,
+// FEDERATION_<NAME>_CLIENT_ID, FEDERATION_<NAME>_CLIENT_SECRET,
+// FEDERATION_<NAME>_SCOPES and FEDERATION_<NAME>_TENANT variables. Dashes in names become underscores in
+// the variable names. Callbacks are served under baseURL.
+func NewConfigsFromEnv(baseURL string) ([]Config, error) {
+	var configs []Config
+	for _, name := range utils.GetEnvList("FEDERATION_PROVIDERS", nil) {
+		if !providerName.MatchString(name) {
+			return nil, fmt.Errorf("invalid FEDERATION_PROVIDERS entry %q, expected lowercase letters, digits and dashes", name)
+		}
+		if slices.ContainsFunc(configs, func(c Config) bool { return c.Name == name }) {
+			return nil, fmt.Errorf("duplicate FEDERATION_PROVIDERS entry %q", name)
+		}
+
+		prefix := "FEDERATION_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		config := Config{
+			Name:         name,
+			Issuer:       strings.TrimSuffix(os.Getenv(prefix+"ISSUER"), "/"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:       utils.GetEnvList(prefix+"SCOPES", []string{"openid", "profile", "email"}),
+			RedirectURL:  baseURL + "/auth/federation/" + name + "/callback",
+			Tenant:       utils.GetEnv(prefix+"TENANT", tenant.DefaultID),
+		}
+		if u, err := url.Parse(config.Issuer); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("invalid %sISSUER %q, expected an absolute URL", prefix, config.Issuer)
+		}
+		if config.ClientID == "" {
+			return nil, fmt.Errorf("%sCLIENT_ID is required", prefix)
+		}
+		if !slices.Contains(config.Scopes, "openid") {
+			return nil, fmt.Errorf("%sSCOPES must include openid", prefix)
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// Identity is the verified user information from a provider's ID token.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider is an OpenID Connect relying party for one provider. Discovery
+// metadata and signing keys are fetched on first use and cached.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu            sync.Mutex
+	metadata      *metadata
+	keys          map[string]*rsa.PublicKey
+	keysFetchedAt time.Time
+}
+
+// NewProvider returns a Provider that talks to the provider with client, or
+// with a client with a 10 second timeout when client is nil.
+func NewProvider(config Config, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{config: config, client: client}
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// Tenant returns the organization users of this provider belong to.
+func (p *Provider) Tenant() string {
+	if p.config.Tenant == "" {
+		return tenant.DefaultID
+	}
+	return p.config.Tenant
+}
+
+// AuthCodeURL returns the provider URL the user agent is sent to for an
+// authorization code flow with PKCE (S256).
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid authorization endpoint: %w", ErrProviderUnavailable, err)
+	}
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Exchange redeems an authorization code and returns the identity from the
+// verified ID token, whose nonce must match the one sent in AuthCodeURL.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to call token endpoint: %w", ErrProviderUnavailable, utils.ContextError(err))
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode token response (status %d): %w", ErrProviderUnavailable, resp.StatusCode, err)
+	}
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("%w: %s %s", ErrTokenExchange, body.Error, body.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: token endpoint returned status %d", ErrProviderUnavailable, resp.StatusCode)
+	}
+	if body.IDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrInvalidIDToken)
+	}
+	return p.verifyIDToken(ctx, body.IDToken, nonce)
+}
+
+func (p *Provider) verifyIDToken(ctx context.Context, idToken, nonce string) (*Identity, error) {
+	var keyErr error
+	token, err := jwt.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.signingKey(ctx, kid)
+		keyErr = err
+		return key, err
+	})
+	if err != nil {
+		// Failing to reach the JWKS is the provider's fault, not the token's.
+		if keyErr != nil && !errors.Is(keyErr, ErrInvalidIDToken) {
+			return nil, keyErr
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidIDToken
+	}
+
+	if iss, _ := claims["iss"].(string); iss != p.config.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, iss)
+	}
+	audience := audiences(claims["aud"])
+	if !slices.Contains(audience, p.config.ClientID) {
+		return nil, fmt.Errorf("%w: token is not intended for this client", ErrInvalidIDToken)
+	}
+	if azp, ok := claims["azp"].(string); (len(audience) > 1 || ok) && azp != p.config.ClientID {
+		return nil, fmt.Errorf("%w: unexpected authorized party %q", ErrInvalidIDToken, azp)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: missing exp", ErrInvalidIDToken)
+	}
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	identity := &Identity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	// Some providers send email_verified as a string.
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+	return identity, nil
+}
+
+func audiences(aud interface{}) []string {
+	switch aud := aud.(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		values := make([]string, 0, len(aud))
+		for _, v := range aud {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var meta metadata
+	if err := p.getJSON(ctx, p.config.Issuer+"/.well-known/openid-configuration", &meta); err != nil {
+		return nil, fmt.Errorf("%w: failed to discover %s: %w", ErrProviderUnavailable, p.config.Name, err)
+	}
+	if meta.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("%w: discovery document of %s has issuer %q, expected %q", ErrProviderUnavailable, p.config.Name, meta.Issuer, p.config.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: discovery document of %s is missing endpoints", ErrProviderUnavailable, p.config.Name)
+	}
+	p.metadata = &meta
+	return p.metadata, nil
+}
+
+// signingKey returns the key with the given id, downloading the JWKS again
+// when the id is unknown so key rotation at the provider is picked up.
+func (p *Provider) signingKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidIDToken, kid)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, meta.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("%w: failed to fetch signing keys of %s: %w", ErrProviderUnavailable, p.config.Name, err)
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidIDToken, kid)
+}
+
+// lookupKey must be called with p.mu locked. Tokens without a kid are only
+// accepted when the provider publishes a single key.
+func (p *Provider) lookupKey(kid string) *rsa.PublicKey {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return p.keys[kid]
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return utils.ContextError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"7-solutions/dtos"
+	services "7-solutions/services"
+	"7-solutions/utils"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	APIKeyService services.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		APIKeyService: apiKeyService,
+	}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var input dtos.APIKeyCreate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+
+	apiKey, err := h.APIKeyService.CreateAPIKey(c.Request.Context(), c.GetString("userID"), &input)
+	if err != nil {
+		status := 500
+		if errors.Is(err, services.ErrInvalidAPIKeyRequest) {
+			status = 400
+		}
+		c.JSON(errorStatus(err, status), utils.ErrorBody(c, "Failed to create API key: "+err.Error()))
+		return
+	}
+
+	c.JSON(201, gin.H{"apiKey": apiKey})
+}
+
+func (h *APIKeyHandler) GetAllAPIKeys(c *gin.Context) {
+	apiKeys, err := h.APIKeyService.GetAllAPIKeys(c.Request.Context())
+	if err != nil {
+		c.JSON(errorStatus(err, 500), utils.ErrorBody(c, "Failed to retrieve API keys: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"apiKeys": apiKeys})
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	err := h.APIKeyService.RevokeAPIKey(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, 404), utils.ErrorBody(c, "Failed to revoke API key: "+err.Error()))
+		return
+	}
+
+	c.JSON(204, gin.H{"message": "API key revoked successfully"})
+}
@@ -3,6 +3,8 @@ package handlers
 import (
 	"7-solutions/dtos"
 	services "7-solutions/services"
+	"7-solutions/utils"
+	"errors"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,13 +22,13 @@ func NewAuthHandler(authService services.AuthService) *AuthHandler {
 func (h *AuthHandler) RegisterUser(c *gin.Context) {
 	var userDto dtos.UserRegister
 	if err := c.ShouldBindJSON(&userDto); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid input: " + err.Error()})
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
 		return
 	}
 
-	err := h.AuthService.RegisterUser(&userDto)
+	err := h.AuthService.RegisterUser(c.Request.Context(), &userDto)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to register user: " + err.Error()})
+		c.JSON(errorStatus(err, userStatus(err)), utils.ErrorBody(c, "Failed to register user: "+err.Error()))
 		return
 	}
 
@@ -36,15 +38,26 @@ func (h *AuthHandler) RegisterUser(c *gin.Context) {
 func (h *AuthHandler) AuthenticateUser(c *gin.Context) {
 	var input dtos.UserAuthenticate
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid input: " + err.Error()})
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
 		return
 	}
+	input.UserAgent = c.Request.UserAgent()
+	input.IP = c.ClientIP()
 
-	token, err := h.AuthService.AuthenticateUser(&input)
+	token, err := h.AuthService.AuthenticateUser(c.Request.Context(), &input)
 	if err != nil {
-		c.JSON(401, gin.H{"error": "Authentication failed: " + err.Error()})
+		c.JSON(errorStatus(err, loginStatus(err)), utils.ErrorBody(c, "Authentication failed: "+err.Error()))
 		return
 	}
 
 	c.JSON(200, gin.H{"token": token})
 }
+
+// loginStatus answers 403 to users whose password matched but who may not
+// sign in, and 401 to every other failure.
+func loginStatus(err error) int {
+	if errors.Is(err, services.ErrAccountInactive) {
+		return 403
+	}
+	return 401
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"7-solutions/dtos"
+	services "7-solutions/services"
+	"7-solutions/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthzHandler struct {
+	AuthzService services.AuthzService
+}
+
+func NewAuthzHandler(authzService services.AuthzService) *AuthzHandler {
+	return &AuthzHandler{
+		AuthzService: authzService,
+	}
+}
+
+// Check reports the decision for a request, including every policy that
+// matched, without enforcing it. The subject defaults to the caller.
+func (h *AuthzHandler) Check(c *gin.Context) {
+	var input dtos.AuthzCheck
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+
+	decision, err := h.AuthzService.Check(c.Request.Context(), &input)
+	if err != nil {
+		c.JSON(errorStatus(err, 500), utils.ErrorBody(c, "Failed to check authorization: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"decision": decision})
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"7-solutions/policy"
+	"7-solutions/utils"
+	"errors"
+	"net/http"
+)
+
+// StatusClientClosedRequest is the non-standard status nginx uses when the
+// client went away before the response was ready.
+const StatusClientClosedRequest = 499
+
+// errorStatus maps cancellation and timeout errors to their own status codes,
+// policy denials to 403, and falls back to status for everything else.
+func errorStatus(err error, status int) int {
+	switch {
+	case errors.Is(err, utils.ErrCanceled):
+		return StatusClientClosedRequest
+	case errors.Is(err, utils.ErrTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, policy.ErrDenied):
+		return http.StatusForbidden
+	}
+	return status
+}
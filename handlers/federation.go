@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"7-solutions/federation"
+	services "7-solutions/services"
+	"7-solutions/utils"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// federationStateCookie binds a pending external login to the browser that
+// started it, so a callback URL from someone else's login is rejected.
+const federationStateCookie = "federation_state"
+
+type FederationHandler struct {
+	FederationService services.FederationService
+}
+
+func NewFederationHandler(federationService services.FederationService) *FederationHandler {
+	return &FederationHandler{
+		FederationService: federationService,
+	}
+}
+
+func (h *FederationHandler) GetProviders(c *gin.Context) {
+	c.JSON(200, gin.H{"providers": h.FederationService.Providers()})
+}
+
+// Login redirects the user agent to the provider's sign-in page.
+func (h *FederationHandler) Login(c *gin.Context) {
+	location, state, err := h.FederationService.BeginLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		c.JSON(errorStatus(err, federationStatus(err)), utils.ErrorBody(c, "Failed to start login: "+err.Error()))
+		return
+	}
+
+	setFederationStateCookie(c, c.Param("provider"), state, int(services.FederationStateTTL.Seconds()))
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, location)
+}
+
+// Callback is the redirect URI registered at the provider. It responds like
+// /auth/login with a token for the linked user.
+func (h *FederationHandler) Callback(c *gin.Context) {
+	provider := c.Param("provider")
+	state := c.Query("state")
+	cookie, _ := c.Cookie(federationStateCookie)
+	setFederationStateCookie(c, provider, "", -1)
+	c.Header("Cache-Control", "no-store")
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.JSON(401, utils.ErrorBody(c, "Identity provider returned an error: "+providerErr))
+		return
+	}
+	if state == "" || c.Query("code") == "" {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: state and code are required"))
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+		c.JSON(400, utils.ErrorBody(c, "Failed to complete login: "+services.ErrInvalidFederationState.Error()))
+		return
+	}
+
+	token, err := h.FederationService.CompleteLogin(c.Request.Context(), provider, state, c.Query("code"), c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(errorStatus(err, federationStatus(err)), utils.ErrorBody(c, "Failed to complete login: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"token": token})
+}
+
+func (h *FederationHandler) GetIdentities(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	identities, err := h.FederationService.GetIdentities(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(errorStatus(err, 500), utils.ErrorBody(c, "Failed to retrieve identities: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"identities": identities})
+}
+
+func (h *FederationHandler) UnlinkIdentity(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	err := h.FederationService.UnlinkIdentity(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, 404), utils.ErrorBody(c, "Failed to unlink identity: "+err.Error()))
+		return
+	}
+
+	c.JSON(204, gin.H{"message": "Identity unlinked successfully"})
+}
+
+// currentUserID returns the id of the signed-in user, or responds with 403
+// for API keys and client credentials tokens, which act for no user.
+func currentUserID(c *gin.Context) (int, bool) {
+	userID, err := strconv.Atoi(c.GetString("userID"))
+	if err != nil {
+		c.JSON(403, utils.ErrorBody(c, "This endpoint requires a user token"))
+		return 0, false
+	}
+	return userID, true
+}
+
+func federationStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUnknownProvider):
+		return 404
+	case errors.Is(err, services.ErrInvalidFederationState):
+		return 400
+	case errors.Is(err, services.ErrEmailNotVerified), errors.Is(err, services.ErrAccountInactive):
+		return 403
+	case errors.Is(err, federation.ErrTokenExchange), errors.Is(err, federation.ErrInvalidIDToken):
+		return 401
+	case errors.Is(err, federation.ErrProviderUnavailable):
+		return 502
+	}
+	return 500
+}
+
+// setFederationStateCookie scopes the cookie to the provider's callback.
+// SameSite=Lax still sends it on the top-level redirect back from the
+// provider.
+func setFederationStateCookie(c *gin.Context, provider, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	secure := c.Request.TLS != nil || strings.HasPrefix(utils.Issuer(), "https://")
+	c.SetCookie(federationStateCookie, value, maxAge, "/auth/federation/"+provider, "", secure, true)
+}
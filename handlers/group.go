@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GroupHandler struct {
+	GroupService services.GroupService
+}
+
+func NewGroupHandler(groupService services.GroupService) *GroupHandler {
+	return &GroupHandler{
+		GroupService: groupService,
+	}
+}
+
+func (h *GroupHandler) CreateGroup(c *gin.Context) {
+	var input dtos.GroupCreate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+
+	group, err := h.GroupService.CreateGroup(c.Request.Context(), &input)
+	if err != nil {
+		c.JSON(errorStatus(err, groupStatus(err)), utils.ErrorBody(c, "Failed to create group: "+err.Error()))
+		return
+	}
+
+	c.JSON(201, gin.H{"group": group})
+}
+
+func (h *GroupHandler) GetGroup(c *gin.Context) {
+	group, err := h.GroupService.GetGroup(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, groupStatus(err)), utils.ErrorBody(c, "Failed to retrieve group: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"group": group})
+}
+
+func (h *GroupHandler) GetAllGroups(c *gin.Context) {
+	groups, err := h.GroupService.GetAllGroups(c.Request.Context())
+	if err != nil {
+		c.JSON(errorStatus(err, 500), utils.ErrorBody(c, "Failed to retrieve groups: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"groups": groups})
+}
+
+func (h *GroupHandler) UpdateGroup(c *gin.Context) {
+	var input dtos.GroupUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+
+	group, err := h.GroupService.UpdateGroup(c.Request.Context(), c.Param("id"), &input)
+	if err != nil {
+		c.JSON(errorStatus(err, groupStatus(err)), utils.ErrorBody(c, "Failed to update group: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"group": group})
+}
+
+func (h *GroupHandler) DeleteGroup(c *gin.Context) {
+	err := h.GroupService.DeleteGroup(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, groupStatus(err)), utils.ErrorBody(c, "Failed to delete group: "+err.Error()))
+		return
+	}
+
+	c.JSON(204, gin.H{"message": "Group deleted successfully"})
+}
+
+func (h *GroupHandler) GetMembers(c *gin.Context) {
+	var query dtos.PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+
+	page, err := h.GroupService.GetMembers(c.Request.Context(), c.Param("id"), query.Page, query.Limit)
+	if err != nil {
+		c.JSON(errorStatus(err, groupStatus(err)), utils.ErrorBody(c, "Failed to retrieve group members: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, page)
+}
+
+func (h *GroupHandler) AddMember(c *gin.Context) {
+	var input dtos.GroupMemberAdd
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+
+	err := h.GroupService.AddMember(c.Request.Context(), c.Param("id"), input.UserID)
+	if err != nil {
+		c.JSON(errorStatus(err, groupStatus(err)), utils.ErrorBody(c, "Failed to add group member: "+err.Error()))
+		return
+	}
+
+	c.JSON(201, gin.H{"message": "Member added successfully"})
+}
+
+func (h *GroupHandler) RemoveMember(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid user ID: "+err.Error()))
+		return
+	}
+
+	err = h.GroupService.RemoveMember(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		c.JSON(errorStatus(err, groupStatus(err)), utils.ErrorBody(c, "Failed to remove group member: "+err.Error()))
+		return
+	}
+
+	c.JSON(204, gin.H{"message": "Member removed successfully"})
+}
+
+// GetUserGroups serves GET /users/:id/groups.
+func (h *GroupHandler) GetUserGroups(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid user ID: "+err.Error()))
+		return
+	}
+
+	groups, err := h.GroupService.GetUserGroups(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(errorStatus(err, groupStatus(err)), utils.ErrorBody(c, "Failed to retrieve groups: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"groups": groups})
+}
+
+func groupStatus(err error) int {
+	switch {
+	case errors.Is(err, repositories.ErrGroupNotFound),
+		errors.Is(err, repositories.ErrUserNotFound),
+		errors.Is(err, repositories.ErrMemberNotFound):
+		return 404
+	case errors.Is(err, services.ErrInvalidGroup):
+		return 400
+	case errors.Is(err, tenant.ErrCrossTenant):
+		return 403
+	case errors.Is(err, repositories.ErrGroupExists),
+		errors.Is(err, repositories.ErrAlreadyMember),
+		errors.Is(err, services.ErrGroupCycle),
+		errors.Is(err, services.ErrGroupTooDeep),
+		errors.Is(err, services.ErrGroupHasChildren):
+		return 409
+	}
+	return 500
+}
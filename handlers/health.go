@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	services "7-solutions/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	HealthService services.HealthService
+}
+
+func NewHealthHandler(healthService services.HealthService) *HealthHandler {
+	return &HealthHandler{
+		HealthService: healthService,
+	}
+}
+
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	if !h.HealthService.Live() {
+		c.JSON(503, gin.H{"status": services.StatusDown})
+		return
+	}
+	c.JSON(200, gin.H{"status": services.StatusUp})
+}
+
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	ready, checks := h.HealthService.Ready(c.Request.Context())
+	if !ready {
+		c.JSON(503, gin.H{"status": services.StatusDown, "checks": checks})
+		return
+	}
+	c.JSON(200, gin.H{"status": services.StatusUp, "checks": checks})
+}
+
+func (h *HealthHandler) Health(c *gin.Context) {
+	health := h.HealthService.Health(c.Request.Context())
+	if health.Status != services.StatusUp {
+		c.JSON(503, health)
+		return
+	}
+	c.JSON(200, health)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"7-solutions/repositories"
+	services "7-solutions/services"
+	"7-solutions/utils"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ImpersonationHandler struct {
+	ImpersonationService services.ImpersonationService
+}
+
+func NewImpersonationHandler(impersonationService services.ImpersonationService) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		ImpersonationService: impersonationService,
+	}
+}
+
+func (h *ImpersonationHandler) Impersonate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid user ID: "+err.Error()))
+		return
+	}
+
+	impersonation, err := h.ImpersonationService.Impersonate(c.Request.Context(), c.GetString("userID"), c.GetString("role"), id)
+	if err != nil {
+		c.JSON(errorStatus(err, impersonationStatus(err)), utils.ErrorBody(c, "Failed to impersonate user: "+err.Error()))
+		return
+	}
+
+	c.JSON(201, impersonation)
+}
+
+func impersonationStatus(err error) int {
+	switch {
+	case errors.Is(err, repositories.ErrUserNotFound):
+		return 404
+	case errors.Is(err, services.ErrImpersonationNotAllowed), errors.Is(err, services.ErrAccountInactive):
+		return 403
+	}
+	return 500
+}
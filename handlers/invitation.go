@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/mailer"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InvitationHandler struct {
+	InvitationService services.InvitationService
+}
+
+func NewInvitationHandler(invitationService services.InvitationService) *InvitationHandler {
+	return &InvitationHandler{
+		InvitationService: invitationService,
+	}
+}
+
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	var input dtos.InvitationCreate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+
+	invitation, err := h.InvitationService.CreateInvitation(c.Request.Context(), c.GetString("userID"), &input)
+	if err != nil {
+		c.JSON(errorStatus(err, invitationStatus(err)), utils.ErrorBody(c, "Failed to invite user: "+err.Error()))
+		return
+	}
+
+	c.JSON(201, gin.H{"invitation": invitation})
+}
+
+func (h *InvitationHandler) GetPendingInvitations(c *gin.Context) {
+	invitations, err := h.InvitationService.GetPendingInvitations(c.Request.Context())
+	if err != nil {
+		c.JSON(errorStatus(err, 500), utils.ErrorBody(c, "Failed to retrieve invitations: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"invitations": invitations})
+}
+
+func (h *InvitationHandler) ResendInvitation(c *gin.Context) {
+	invitation, err := h.InvitationService.ResendInvitation(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, invitationStatus(err)), utils.ErrorBody(c, "Failed to resend invitation: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"invitation": invitation})
+}
+
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	err := h.InvitationService.RevokeInvitation(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, invitationStatus(err)), utils.ErrorBody(c, "Failed to revoke invitation: "+err.Error()))
+		return
+	}
+
+	c.JSON(204, gin.H{"message": "Invitation revoked successfully"})
+}
+
+// AcceptInvitation is public: the token in the body is the credential.
+func (h *InvitationHandler) AcceptInvitation(c *gin.Context) {
+	var input dtos.InvitationAccept
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+
+	user, err := h.InvitationService.AcceptInvitation(c.Request.Context(), &input)
+	if err != nil {
+		c.JSON(errorStatus(err, invitationStatus(err)), utils.ErrorBody(c, "Failed to accept invitation: "+err.Error()))
+		return
+	}
+
+	c.JSON(201, gin.H{"user": user})
+}
+
+func invitationStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidInvitationRequest), errors.Is(err, services.ErrInvalidInvitation):
+		return 400
+	case errors.Is(err, tenant.ErrCrossTenant):
+		return 403
+	case errors.Is(err, repositories.ErrInvitationNotFound):
+		return 404
+	case errors.Is(err, repositories.ErrEmailTaken), errors.Is(err, services.ErrInvitationExists):
+		return 409
+	case errors.Is(err, mailer.ErrDelivery):
+		return 502
+	}
+	return 500
+}
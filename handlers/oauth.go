@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"7-solutions/dtos"
+	services "7-solutions/services"
+	"7-solutions/utils"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OAuthHandler struct {
+	OAuthService services.OAuthService
+}
+
+func NewOAuthHandler(oauthService services.OAuthService) *OAuthHandler {
+	return &OAuthHandler{
+		OAuthService: oauthService,
+	}
+}
+
+func (h *OAuthHandler) RegisterClient(c *gin.Context) {
+	var input dtos.OAuthClientCreate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+
+	client, err := h.OAuthService.RegisterClient(c.Request.Context(), c.GetString("userID"), &input)
+	if err != nil {
+		status := 500
+		if errors.Is(err, services.ErrInvalidOAuthClient) {
+			status = 400
+		}
+		c.JSON(errorStatus(err, status), utils.ErrorBody(c, "Failed to register client: "+err.Error()))
+		return
+	}
+
+	c.JSON(201, gin.H{"client": client})
+}
+
+func (h *OAuthHandler) GetAllClients(c *gin.Context) {
+	clients, err := h.OAuthService.GetAllClients(c.Request.Context())
+	if err != nil {
+		c.JSON(errorStatus(err, 500), utils.ErrorBody(c, "Failed to retrieve clients: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"clients": clients})
+}
+
+// AuthorizePage is the authorization endpoint. It shows the login/consent
+// page for a valid request.
+func (h *OAuthHandler) AuthorizePage(c *gin.Context) {
+	var req dtos.AuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		renderAuthorizePage(c, 400, authorizePageData{Error: "Invalid authorization request"})
+		return
+	}
+
+	client, err := h.OAuthService.ValidateAuthorizeRequest(c.Request.Context(), &req)
+	if err != nil {
+		h.authorizeError(c, err)
+		return
+	}
+
+	renderAuthorizePage(c, 200, authorizePageData{Client: client.Name, Request: &req})
+}
+
+// Authorize handles the submitted login/consent form and redirects back to
+// the client with a code or an error.
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	var req dtos.AuthorizeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		renderAuthorizePage(c, 400, authorizePageData{Error: "Invalid authorization request"})
+		return
+	}
+	login := &dtos.UserAuthenticate{
+		Email:     c.PostForm("email"),
+		Password:  c.PostForm("password"),
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+
+	location, err := h.OAuthService.Authorize(c.Request.Context(), &req, login, c.PostForm("action") == "approve")
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			client, _ := h.OAuthService.ValidateAuthorizeRequest(c.Request.Context(), &req)
+			data := authorizePageData{Error: "Invalid email or password", Request: &req}
+			if client != nil {
+				data.Client = client.Name
+			}
+			renderAuthorizePage(c, 401, data)
+			return
+		}
+		h.authorizeError(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, location)
+}
+
+func (h *OAuthHandler) authorizeError(c *gin.Context, err error) {
+	var oauthErr *services.OAuthError
+	if errors.As(err, &oauthErr) {
+		if oauthErr.RedirectURI != "" {
+			c.Redirect(http.StatusFound, oauthErr.ErrorRedirectURI())
+			return
+		}
+		renderAuthorizePage(c, oauthErr.Status, authorizePageData{Error: oauthErr.Description})
+		return
+	}
+	renderAuthorizePage(c, errorStatus(err, 500), authorizePageData{Error: "Authorization failed, please try again"})
+}
+
+// Token is the token endpoint (RFC 6749 section 3.2).
+func (h *OAuthHandler) Token(c *gin.Context) {
+	var req dtos.TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		oauthErrorResponse(c, &services.OAuthError{Code: "invalid_request", Description: err.Error(), Status: 400})
+		return
+	}
+
+	token, err := h.OAuthService.Token(c.Request.Context(), clientCredentials(c), &req)
+	if err != nil {
+		oauthErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.JSON(200, token)
+}
+
+// Introspect is the RFC 7662 introspection endpoint.
+func (h *OAuthHandler) Introspect(c *gin.Context) {
+	introspection, err := h.OAuthService.Introspect(c.Request.Context(), clientCredentials(c), c.PostForm("token"))
+	if err != nil {
+		oauthErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(200, introspection)
+}
+
+// Revoke is the RFC 7009 revocation endpoint.
+func (h *OAuthHandler) Revoke(c *gin.Context) {
+	err := h.OAuthService.Revoke(c.Request.Context(), clientCredentials(c), c.PostForm("token"))
+	if err != nil {
+		oauthErrorResponse(c, err)
+		return
+	}
+
+	c.Status(200)
+}
+
+// clientCredentials reads the client from HTTP Basic auth, whose parts are
+// form-encoded (RFC 6749 section 2.3.1), or from the request body.
+func clientCredentials(c *gin.Context) dtos.ClientCredentials {
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		creds := dtos.ClientCredentials{ClientID: id, ClientSecret: secret}
+		if decoded, err := url.QueryUnescape(id); err == nil {
+			creds.ClientID = decoded
+		}
+		if decoded, err := url.QueryUnescape(secret); err == nil {
+			creds.ClientSecret = decoded
+		}
+		return creds
+	}
+	return dtos.ClientCredentials{ClientID: c.PostForm("client_id"), ClientSecret: c.PostForm("client_secret")}
+}
+
+// oauthErrorResponse writes an RFC 6749 error body.
+func oauthErrorResponse(c *gin.Context, err error) {
+	var oauthErr *services.OAuthError
+	if !errors.As(err, &oauthErr) {
+		c.JSON(errorStatus(err, 500), gin.H{"error": "server_error", "error_description": err.Error()})
+		return
+	}
+	switch oauthErr.Code {
+	case "invalid_client":
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	case "invalid_token":
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(oauthErr.Status, gin.H{"error": oauthErr.Code, "error_description": oauthErr.Description})
+}
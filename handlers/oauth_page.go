@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"7-solutions/dtos"
+	"html/template"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var authorizePage = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Sign in</title>
+<style>
+body { font-family: sans-serif; max-width: 24rem; margin: 4rem auto; padding: 0 1rem; }
+label, input, button { display: block; width: 100%; box-sizing: border-box; }
+input { margin: .25rem 0 1rem; padding: .5rem; }
+button { padding: .5rem; margin-bottom: .5rem; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+{{if .Client}}
+<h1>Sign in to {{.Client}}</h1>
+<p>{{.Client}} is requesting access to:</p>
+<ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>
+{{end}}
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+{{if .Request}}
+<form method="post" action="/auth/authorize">
+<input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+<input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+<input type="hidden" name="scope" value="{{.Request.Scope}}">
+<input type="hidden" name="state" value="{{.Request.State}}">
+<input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+<input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+<label for="email">Email</label>
+<input id="email" name="email" type="email" autocomplete="username" required>
+<label for="password">Password</label>
+<input id="password" name="password" type="password" autocomplete="current-password" required>
+<button type="submit" name="action" value="approve">Allow</button>
+<button type="submit" name="action" value="deny" formnovalidate>Deny</button>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+type authorizePageData struct {
+	Client  string
+	Scopes  []string
+	Error   string
+	Request *dtos.AuthorizeRequest
+}
+
+// renderAuthorizePage writes the login/consent page. Its CSP only allows
+// inline styles and lets the form post here and follow the redirect back to
+// the client, which browsers check against form-action too.
+func renderAuthorizePage(c *gin.Context, status int, data authorizePageData) {
+	formAction := "'self'"
+	if data.Request != nil {
+		if u, err := url.Parse(data.Request.RedirectURI); err == nil && u.Scheme != "" && u.Host != "" {
+			formAction += " " + u.Scheme + "://" + u.Host
+		}
+	}
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action "+formAction+"; frame-ancestors 'none'; base-uri 'none'")
+	c.Header("Cache-Control", "no-store")
+	if data.Request != nil {
+		data.Scopes = strings.Fields(data.Request.Scope)
+	}
+
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	_ = authorizePage.Execute(c.Writer, data)
+}
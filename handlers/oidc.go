@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"7-solutions/middleware"
+	"7-solutions/models"
+	services "7-solutions/services"
+	"7-solutions/utils"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCHandler struct {
+	OAuthService services.OAuthService
+}
+
+func NewOIDCHandler(oauthService services.OAuthService) *OIDCHandler {
+	return &OIDCHandler{
+		OAuthService: oauthService,
+	}
+}
+
+// Discovery serves the OpenID Provider metadata.
+func (h *OIDCHandler) Discovery(c *gin.Context) {
+	issuer := utils.Issuer()
+	c.JSON(200, gin.H{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/auth/authorize",
+		"token_endpoint":                        issuer + "/auth/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"introspection_endpoint":                issuer + "/auth/introspect",
+		"revocation_endpoint":                   issuer + "/auth/revoke",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{models.GrantTypeAuthorizationCode, models.GrantTypeClientCredentials},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      slices.Concat(models.OIDCScopes, models.Scopes),
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported":                      []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "name", "email", "email_verified"},
+	})
+}
+
+func (h *OIDCHandler) JWKS(c *gin.Context) {
+	jwks, err := utils.JWKS()
+	if err != nil {
+		c.JSON(500, utils.ErrorBody(c, "Failed to load signing keys: "+err.Error()))
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(200, jwks)
+}
+
+// UserInfo is the OIDC UserInfo endpoint. Login JWTs see every claim;
+// OAuth access tokens only those their scopes allow.
+func (h *OIDCHandler) UserInfo(c *gin.Context) {
+	scopes := c.GetStringSlice("scopes")
+	if c.GetString("authMethod") == middleware.AuthMethodJWT {
+		scopes = models.OIDCScopes
+	}
+
+	info, err := h.OAuthService.UserInfo(c.Request.Context(), c.GetString("userID"), scopes)
+	if err != nil {
+		oauthErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(200, info)
+}
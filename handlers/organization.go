@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+	"7-solutions/utils"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrganizationHandler struct {
+	OrganizationService services.OrganizationService
+}
+
+func NewOrganizationHandler(organizationService services.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		OrganizationService: organizationService,
+	}
+}
+
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var input dtos.OrganizationCreate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+
+	organization, err := h.OrganizationService.CreateOrganization(c.Request.Context(), &input)
+	if err != nil {
+		status := 500
+		switch {
+		case errors.Is(err, services.ErrInvalidOrganization):
+			status = 400
+		case errors.Is(err, repositories.ErrOrganizationExists):
+			status = 409
+		}
+		c.JSON(errorStatus(err, status), utils.ErrorBody(c, "Failed to create organization: "+err.Error()))
+		return
+	}
+
+	c.JSON(201, gin.H{"organization": organization})
+}
+
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	organization, err := h.OrganizationService.GetOrganization(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, 404), utils.ErrorBody(c, "Failed to retrieve organization: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"organization": organization})
+}
+
+func (h *OrganizationHandler) GetAllOrganizations(c *gin.Context) {
+	organizations, err := h.OrganizationService.GetAllOrganizations(c.Request.Context())
+	if err != nil {
+		c.JSON(errorStatus(err, 500), utils.ErrorBody(c, "Failed to retrieve organizations: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"organizations": organizations})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"7-solutions/repositories"
+	services "7-solutions/services"
+	"7-solutions/utils"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PrivacyHandler struct {
+	PrivacyService services.PrivacyService
+}
+
+func NewPrivacyHandler(privacyService services.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{
+		PrivacyService: privacyService,
+	}
+}
+
+// ExportUserData answers with a JSON archive of everything stored about the
+// user, as a download.
+func (h *PrivacyHandler) ExportUserData(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid user ID: "+err.Error()))
+		return
+	}
+
+	export, err := h.PrivacyService.ExportUserData(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(errorStatus(err, privacyStatus(err)), utils.ErrorBody(c, "Failed to export user data: "+err.Error()))
+		return
+	}
+
+	c.Header("Content-Disposition", attachment("user-"+strconv.Itoa(id)+"-data.json"))
+	c.JSON(200, export)
+}
+
+func (h *PrivacyHandler) EraseUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid user ID: "+err.Error()))
+		return
+	}
+
+	erasure, err := h.PrivacyService.EraseUser(c.Request.Context(), c.GetString("userID"), id)
+	if err != nil {
+		c.JSON(errorStatus(err, privacyStatus(err)), utils.ErrorBody(c, "Failed to erase user: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"erasure": erasure})
+}
+
+func (h *PrivacyHandler) GetErasure(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid user ID: "+err.Error()))
+		return
+	}
+
+	erasure, err := h.PrivacyService.GetErasure(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(errorStatus(err, privacyStatus(err)), utils.ErrorBody(c, "Failed to retrieve erasure: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"erasure": erasure})
+}
+
+func privacyStatus(err error) int {
+	switch {
+	case errors.Is(err, repositories.ErrUserNotFound), errors.Is(err, repositories.ErrErasureNotFound):
+		return 404
+	case errors.Is(err, services.ErrErasureNotAllowed):
+		return 403
+	}
+	return 500
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProfileHandler struct {
+	ProfileService services.ProfileService
+}
+
+func NewProfileHandler(profileService services.ProfileService) *ProfileHandler {
+	return &ProfileHandler{
+		ProfileService: profileService,
+	}
+}
+
+func (h *ProfileHandler) CreateAttribute(c *gin.Context) {
+	var input dtos.AttributeDefinitionCreate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+
+	attribute, err := h.ProfileService.CreateAttribute(c.Request.Context(), &input)
+	if err != nil {
+		c.JSON(errorStatus(err, profileStatus(err)), utils.ErrorBody(c, "Failed to create attribute: "+err.Error()))
+		return
+	}
+
+	c.JSON(201, gin.H{"attribute": attribute})
+}
+
+func (h *ProfileHandler) GetAttributes(c *gin.Context) {
+	attributes, err := h.ProfileService.GetAttributes(c.Request.Context())
+	if err != nil {
+		c.JSON(errorStatus(err, 500), utils.ErrorBody(c, "Failed to retrieve attributes: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"attributes": attributes})
+}
+
+func (h *ProfileHandler) UpdateAttribute(c *gin.Context) {
+	var input dtos.AttributeDefinitionUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+
+	attribute, err := h.ProfileService.UpdateAttribute(c.Request.Context(), c.Param("key"), &input)
+	if err != nil {
+		c.JSON(errorStatus(err, profileStatus(err)), utils.ErrorBody(c, "Failed to update attribute: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"attribute": attribute})
+}
+
+func (h *ProfileHandler) DeleteAttribute(c *gin.Context) {
+	if err := h.ProfileService.DeleteAttribute(c.Request.Context(), c.Param("key")); err != nil {
+		c.JSON(errorStatus(err, profileStatus(err)), utils.ErrorBody(c, "Failed to delete attribute: "+err.Error()))
+		return
+	}
+
+	c.Status(204)
+}
+
+func (h *ProfileHandler) UpdateUserAttributes(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid user ID: "+err.Error()))
+		return
+	}
+
+	var input dtos.UserAttributesUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+
+	attributes, err := h.ProfileService.UpdateUserAttributes(c.Request.Context(), id, &input)
+	if err != nil {
+		c.JSON(errorStatus(err, profileStatus(err)), utils.ErrorBody(c, "Failed to update user attributes: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"attributes": attributes})
+}
+
+func profileStatus(err error) int {
+	switch {
+	case errors.Is(err, repositories.ErrAttributeNotFound),
+		errors.Is(err, repositories.ErrUserNotFound):
+		return 404
+	case errors.Is(err, services.ErrInvalidAttribute):
+		return 400
+	case errors.Is(err, tenant.ErrCrossTenant):
+		return 403
+	case errors.Is(err, repositories.ErrAttributeExists):
+		return 409
+	}
+	return 500
+}
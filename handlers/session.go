@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"7-solutions/repositories"
+	services "7-solutions/services"
+	"7-solutions/utils"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	SessionService services.SessionService
+}
+
+func NewSessionHandler(sessionService services.SessionService) *SessionHandler {
+	return &SessionHandler{
+		SessionService: sessionService,
+	}
+}
+
+func (h *SessionHandler) GetMySessions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.GetString("userID"))
+	if err != nil {
+		c.JSON(403, utils.ErrorBody(c, "Requires signing in as a user"))
+		return
+	}
+	h.getSessions(c, userID)
+}
+
+func (h *SessionHandler) RevokeMySession(c *gin.Context) {
+	userID, err := strconv.Atoi(c.GetString("userID"))
+	if err != nil {
+		c.JSON(403, utils.ErrorBody(c, "Requires signing in as a user"))
+		return
+	}
+	h.revokeSession(c, userID)
+}
+
+func (h *SessionHandler) GetUserSessions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid user ID: "+err.Error()))
+		return
+	}
+	h.getSessions(c, userID)
+}
+
+func (h *SessionHandler) RevokeUserSession(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid user ID: "+err.Error()))
+		return
+	}
+	h.revokeSession(c, userID)
+}
+
+func (h *SessionHandler) RevokeAllUserSessions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid user ID: "+err.Error()))
+		return
+	}
+
+	revoked, err := h.SessionService.RevokeAllSessions(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(errorStatus(err, sessionStatus(err)), utils.ErrorBody(c, "Failed to revoke sessions: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"revoked": revoked})
+}
+
+func (h *SessionHandler) getSessions(c *gin.Context, userID int) {
+	sessions, err := h.SessionService.GetSessions(c.Request.Context(), userID, c.GetString("sessionID"))
+	if err != nil {
+		c.JSON(errorStatus(err, sessionStatus(err)), utils.ErrorBody(c, "Failed to retrieve sessions: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"sessions": sessions})
+}
+
+func (h *SessionHandler) revokeSession(c *gin.Context, userID int) {
+	err := h.SessionService.RevokeSession(c.Request.Context(), userID, c.Param("sessionId"))
+	if err != nil {
+		c.JSON(errorStatus(err, sessionStatus(err)), utils.ErrorBody(c, "Failed to revoke session: "+err.Error()))
+		return
+	}
+
+	c.JSON(204, gin.H{"message": "Session revoked successfully"})
+}
+
+func sessionStatus(err error) int {
+	if errors.Is(err, repositories.ErrSessionNotFound) {
+		return 404
+	}
+	return 500
+}
@@ -2,7 +2,11 @@ package handlers
 
 import (
 	"7-solutions/dtos"
+	"7-solutions/repositories"
 	services "7-solutions/services"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -21,13 +25,13 @@ func NewUserHandler(userService services.UserService) *UserHandler {
 func (h *UserHandler) CreateUser(c *gin.Context) {
 	var userDto dtos.UserRegister
 	if err := c.ShouldBindJSON(&userDto); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid input: " + err.Error()})
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
 		return
 	}
 
-	user, err := h.UserService.CreateUser(&userDto)
+	user, err := h.UserService.CreateUser(c.Request.Context(), &userDto)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create user: " + err.Error()})
+		c.JSON(errorStatus(err, userStatus(err)), utils.ErrorBody(c, "Failed to create user: "+err.Error()))
 		return
 	}
 
@@ -36,19 +40,19 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 func (h *UserHandler) GetUserByID(c *gin.Context) {
 	idStr := c.Param("id")
 	if idStr == "" {
-		c.JSON(400, gin.H{"error": "User ID is required"})
+		c.JSON(400, utils.ErrorBody(c, "User ID is required"))
 		return
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(400, gin.H{"error": "Invalid user ID: " + err.Error()})
+		c.JSON(400, utils.ErrorBody(c, "Invalid user ID: "+err.Error()))
 		return
 	}
 
-	user, err := h.UserService.GetUserByID(id)
+	user, err := h.UserService.GetUserByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "User not found: " + err.Error()})
+		c.JSON(errorStatus(err, 404), utils.ErrorBody(c, "User not found: "+err.Error()))
 		return
 	}
 
@@ -56,9 +60,16 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 }
 
 func (h *UserHandler) GetAllUsers(c *gin.Context) {
-	users, err := h.UserService.GetAllUsers()
+	var filter dtos.UserFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+	bindAttributeFilter(c, &filter)
+
+	users, err := h.UserService.GetAllUsers(c.Request.Context(), &filter)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to retrieve users: " + err.Error()})
+		c.JSON(errorStatus(err, userStatus(err)), utils.ErrorBody(c, "Failed to retrieve users: "+err.Error()))
 		return
 	}
 
@@ -68,25 +79,25 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	idStr := c.Param("id")
 	if idStr == "" {
-		c.JSON(400, gin.H{"error": "User ID is required"})
+		c.JSON(400, utils.ErrorBody(c, "User ID is required"))
 		return
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(400, gin.H{"error": "Invalid user ID: " + err.Error()})
+		c.JSON(400, utils.ErrorBody(c, "Invalid user ID: "+err.Error()))
 		return
 	}
 
 	var userDto dtos.UserUpdate
 	if err := c.ShouldBindJSON(&userDto); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid input : " + err.Error()})
+		c.JSON(400, utils.ErrorBody(c, "Invalid input : "+err.Error()))
 		return
 	}
 
-	user, err := h.UserService.UpdateUser(id, &userDto)
+	user, err := h.UserService.UpdateUser(c.Request.Context(), id, &userDto)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to update user: " + err.Error()})
+		c.JSON(errorStatus(err, userStatus(err)), utils.ErrorBody(c, "Failed to update user: "+err.Error()))
 		return
 	}
 
@@ -96,21 +107,46 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	idStr := c.Param("id")
 	if idStr == "" {
-		c.JSON(400, gin.H{"error": "User ID is required"})
+		c.JSON(400, utils.ErrorBody(c, "User ID is required"))
 		return
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(400, gin.H{"error": "Invalid user ID: " + err.Error()})
+		c.JSON(400, utils.ErrorBody(c, "Invalid user ID: "+err.Error()))
 		return
 	}
 
-	err = h.UserService.DeleteUser(id)
+	err = h.UserService.DeleteUser(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to delete user: " + err.Error()})
+		c.JSON(errorStatus(err, userStatus(err)), utils.ErrorBody(c, "Failed to delete user: "+err.Error()))
 		return
 	}
 
 	c.JSON(204, gin.H{"message": "User deleted successfully"})
 }
+
+// bindAttributeFilter reads the attributes[key]=value query parameters
+// into filter.
+func bindAttributeFilter(c *gin.Context, filter *dtos.UserFilter) {
+	for key, value := range c.QueryMap("attributes") {
+		if filter.Attributes == nil {
+			filter.Attributes = map[string]any{}
+		}
+		filter.Attributes[key] = value
+	}
+}
+
+// userStatus reports users of other tenants as missing and refuses writes
+// into another tenant.
+func userStatus(err error) int {
+	switch {
+	case errors.Is(err, repositories.ErrUserNotFound):
+		return 404
+	case errors.Is(err, services.ErrInvalidAttribute):
+		return 400
+	case errors.Is(err, tenant.ErrCrossTenant):
+		return 403
+	}
+	return 500
+}
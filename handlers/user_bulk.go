@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+	"7-solutions/utils"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserBulkHandler struct {
+	UserBulkService services.UserBulkService
+}
+
+func NewUserBulkHandler(userBulkService services.UserBulkService) *UserBulkHandler {
+	return &UserBulkHandler{
+		UserBulkService: userBulkService,
+	}
+}
+
+// ApplyBulk answers 200 with the status of every operation, and 409 with
+// them when an atomic request was rolled back.
+func (h *UserBulkHandler) ApplyBulk(c *gin.Context) {
+	var input dtos.UserBulk
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+
+	result, err := h.UserBulkService.ApplyBulk(c.Request.Context(), c.GetString("userID"), &input)
+	if errors.Is(err, services.ErrBulkRolledBack) {
+		body := utils.ErrorBody(c, "Failed to apply bulk operations: "+err.Error())
+		body["bulk"] = result
+		c.JSON(409, body)
+		return
+	}
+	if err != nil {
+		c.JSON(errorStatus(err, bulkStatus(err)), utils.ErrorBody(c, "Failed to apply bulk operations: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"bulk": result})
+}
+
+func bulkStatus(err error) int {
+	if errors.Is(err, repositories.ErrTransactionsUnsupported) {
+		return 501
+	}
+	return 500
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/logging"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+	"7-solutions/utils"
+	"errors"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportStreamTimeout replaces the server's write timeout for streamed
+// exports, which take longer than other responses.
+const exportStreamTimeout = 10 * time.Minute
+
+type UserExportHandler struct {
+	UserExportService services.UserExportService
+}
+
+func NewUserExportHandler(userExportService services.UserExportService) *UserExportHandler {
+	return &UserExportHandler{
+		UserExportService: userExportService,
+	}
+}
+
+func bindUserExport(c *gin.Context) (*dtos.UserExport, bool) {
+	input := dtos.UserExport{Format: services.ExportFormatCSV}
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return nil, false
+	}
+	bindAttributeFilter(c, &input.UserFilter)
+	return &input, true
+}
+
+// ExportUsers streams the users as they are read from the database.
+func (h *UserExportHandler) ExportUsers(c *gin.Context) {
+	input, ok := bindUserExport(c)
+	if !ok {
+		return
+	}
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(exportStreamTimeout))
+
+	w := &exportWriter{c: c, format: input.Format, createdAt: time.Now()}
+	_, err := h.UserExportService.ExportUsers(c.Request.Context(), input, w)
+	if err != nil && !w.started {
+		c.JSON(errorStatus(err, exportStatus(err)), utils.ErrorBody(c, "Failed to export users: "+err.Error()))
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("user export interrupted", "error", err)
+		abortStream(c)
+	}
+}
+
+// StartExport answers 202 with the job of an export that runs in the
+// background.
+func (h *UserExportHandler) StartExport(c *gin.Context) {
+	input, ok := bindUserExport(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.UserExportService.StartExport(c.Request.Context(), c.GetString("userID"), input)
+	if err != nil {
+		c.JSON(errorStatus(err, exportStatus(err)), utils.ErrorBody(c, "Failed to export users: "+err.Error()))
+		return
+	}
+
+	c.Header("Location", "/users/export/"+job.ID)
+	c.JSON(202, gin.H{"export": job})
+}
+
+func (h *UserExportHandler) GetExportJob(c *gin.Context) {
+	job, err := h.UserExportService.GetExportJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, exportStatus(err)), utils.ErrorBody(c, "Failed to retrieve export: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"export": job})
+}
+
+func (h *UserExportHandler) DownloadExport(c *gin.Context) {
+	job, file, err := h.UserExportService.OpenExportFile(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, exportStatus(err)), utils.ErrorBody(c, "Failed to download export: "+err.Error()))
+		return
+	}
+	defer file.Close()
+
+	createdAt, _ := time.Parse(time.RFC3339, job.CreatedAt)
+	c.DataFromReader(200, job.Size, services.ExportContentType(job.Format), file, map[string]string{
+		"Content-Disposition": attachment(services.ExportFileName(job.Format, createdAt)),
+	})
+}
+
+// exportWriter sends the download headers along with the first bytes, so
+// errors before anything was exported can still be answered with JSON.
+type exportWriter struct {
+	c         *gin.Context
+	format    string
+	createdAt time.Time
+	started   bool
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", services.ExportContentType(w.format))
+		w.c.Header("Content-Disposition", attachment(services.ExportFileName(w.format, w.createdAt)))
+		w.c.Status(200)
+	}
+	return w.c.Writer.Write(p)
+}
+
+func attachment(name string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": name})
+}
+
+// abortStream drops the connection of a response that failed half-way, so
+// the client cannot mistake the partial export for a complete one.
+func abortStream(c *gin.Context) {
+	if unwrapper, ok := c.Writer.(interface{ Unwrap() http.ResponseWriter }); ok {
+		if hijacker, ok := unwrapper.Unwrap().(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				conn.Close()
+			}
+		}
+	}
+	c.Abort()
+}
+
+func exportStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidExport),
+		errors.Is(err, services.ErrInvalidAttribute):
+		return 400
+	case errors.Is(err, repositories.ErrExportJobNotFound):
+		return 404
+	case errors.Is(err, services.ErrExportNotReady):
+		return 409
+	}
+	return 500
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportBytes bounds the size of an import file.
+const maxImportBytes = 10 << 20
+
+type UserImportHandler struct {
+	UserImportService services.UserImportService
+}
+
+func NewUserImportHandler(userImportService services.UserImportService) *UserImportHandler {
+	return &UserImportHandler{
+		UserImportService: userImportService,
+	}
+}
+
+// ImportUsers answers 200 with the results of small imports and 202 with
+// the job of imports that continue in the background.
+func (h *UserImportHandler) ImportUsers(c *gin.Context) {
+	var input dtos.UserImport
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+	if input.Format == "" {
+		input.Format = importFormat(c.ContentType())
+		if input.Format == "" {
+			c.JSON(415, utils.ErrorBody(c, "Unsupported import format: send text/csv or application/x-ndjson"))
+			return
+		}
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	result, err := h.UserImportService.ImportUsers(c.Request.Context(), c.GetString("userID"), &input, body)
+	if err != nil {
+		c.JSON(errorStatus(err, importStatus(err)), utils.ErrorBody(c, "Failed to import users: "+err.Error()))
+		return
+	}
+
+	if result.ID != "" {
+		c.Header("Location", "/users/import/"+result.ID)
+		c.JSON(202, gin.H{"import": result})
+		return
+	}
+	c.JSON(200, gin.H{"import": result})
+}
+
+func (h *UserImportHandler) GetImportJob(c *gin.Context) {
+	result, err := h.UserImportService.GetImportJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, importStatus(err)), utils.ErrorBody(c, "Failed to retrieve import: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"import": result})
+}
+
+func importFormat(contentType string) string {
+	switch contentType {
+	case "text/csv", "application/csv":
+		return services.ImportFormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return services.ImportFormatNDJSON
+	}
+	return ""
+}
+
+func importStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, services.ErrInvalidImport):
+		return 400
+	case errors.Is(err, services.ErrImportTooLarge), errors.As(err, &maxBytesErr):
+		return 413
+	case errors.Is(err, repositories.ErrImportJobNotFound):
+		return 404
+	case errors.Is(err, tenant.ErrCrossTenant):
+		return 403
+	}
+	return 500
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+	"7-solutions/utils"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserStatusHandler struct {
+	UserStatusService services.UserStatusService
+}
+
+func NewUserStatusHandler(userStatusService services.UserStatusService) *UserStatusHandler {
+	return &UserStatusHandler{
+		UserStatusService: userStatusService,
+	}
+}
+
+func (h *UserStatusHandler) GetStatus(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid user ID: "+err.Error()))
+		return
+	}
+
+	status, err := h.UserStatusService.GetStatus(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(errorStatus(err, userStatusStatus(err)), utils.ErrorBody(c, "Failed to retrieve user status: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, status)
+}
+
+func (h *UserStatusHandler) ChangeStatus(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid user ID: "+err.Error()))
+		return
+	}
+
+	var input dtos.UserStatusUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, utils.ErrorBody(c, "Invalid input: "+err.Error()))
+		return
+	}
+
+	status, err := h.UserStatusService.ChangeStatus(c.Request.Context(), c.GetString("userID"), id, &input)
+	if err != nil {
+		c.JSON(errorStatus(err, userStatusStatus(err)), utils.ErrorBody(c, "Failed to change user status: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, status)
+}
+
+func userStatusStatus(err error) int {
+	switch {
+	case errors.Is(err, repositories.ErrUserNotFound):
+		return 404
+	case errors.Is(err, services.ErrStatusReasonRequired):
+		return 400
+	case errors.Is(err, services.ErrStatusChangeNotAllowed):
+		return 403
+	case errors.Is(err, services.ErrInvalidStatusTransition), errors.Is(err, repositories.ErrStatusChanged):
+		return 409
+	}
+	return 500
+}
@@ -0,0 +1,197 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+
+	redacted = "[REDACTED]"
+)
+
+type Config struct {
+	Level  slog.Level
+	Format string
+}
+
+func NewConfigFromEnv() (Config, error) {
+	config := Config{Format: FormatJSON}
+	if format := os.Getenv("LOG_FORMAT"); format != "" {
+		config.Format = format
+	}
+
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		if err := config.Level.UnmarshalText([]byte(level)); err != nil {
+			return config, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+		}
+	}
+	if config.Format != FormatJSON && config.Format != FormatText {
+		return config, fmt.Errorf("invalid LOG_FORMAT %q", config.Format)
+	}
+	return config, nil
+}
+
+// Setup builds a logger for config, installs it as the slog and log default
+// and returns it.
+func Setup(config Config) *slog.Logger {
+	logger := NewLogger(os.Stdout, config)
+	slog.SetDefault(logger)
+	return logger
+}
+
+func NewLogger(w io.Writer, config Config) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:       config.Level,
+		ReplaceAttr: redact,
+	}
+	if config.Format == FormatText {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+// sensitiveKeys are attribute keys whose values never reach the log output,
+// compared case-insensitively and ignoring "-" and "_".
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"newpassword":   true,
+	"token":         true,
+	"accesstoken":   true,
+	"refreshtoken":  true,
+	"idtoken":       true,
+	"authorization": true,
+	"cookie":        true,
+	"setcookie":     true,
+	"secret":        true,
+	"clientsecret":  true,
+	"apikey":        true,
+}
+
+func IsSensitiveKey(key string) bool {
+	key = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	return sensitiveKeys[key]
+}
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if IsSensitiveKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	return a
+}
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	userIDKey
+	actorIDKey
+)
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+func UserID(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey).(string)
+	return userID
+}
+
+// WithActorID marks ctx as impersonation: actorID acts as the user of ctx.
+func WithActorID(ctx context.Context, actorID string) context.Context {
+	return context.WithValue(ctx, actorIDKey, actorID)
+}
+
+func ActorID(ctx context.Context) string {
+	actorID, _ := ctx.Value(actorIDKey).(string)
+	return actorID
+}
+
+// FromContext returns the default logger annotated with the request, user
+// and impersonating actor ids carried by ctx.
+func FromContext(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if requestID := RequestID(ctx); requestID != "" {
+		logger = logger.With("request_id", requestID)
+	}
+	if userID := UserID(ctx); userID != "" {
+		logger = logger.With("user_id", userID)
+	}
+	if actorID := ActorID(ctx); actorID != "" {
+		logger = logger.With("actor_id", actorID)
+	}
+	return logger
+}
+
+// AuditEvent is an event recorded by Audit, as passed to the audit sink.
+type AuditEvent struct {
+	Time      time.Time
+	Action    string
+	RequestID string
+	UserID    string
+	ActorID   string
+	// Attrs holds the arguments of Audit, with sensitive values redacted
+	// and errors as their message.
+	Attrs map[string]any
+}
+
+var auditSink atomic.Pointer[func(context.Context, AuditEvent)]
+
+// SetAuditSink passes every audit event to sink as well as logging it, so
+// they can be stored; nil removes the sink. The sink runs synchronously and
+// must not call Audit.
+func SetAuditSink(sink func(ctx context.Context, event AuditEvent)) {
+	if sink == nil {
+		auditSink.Store(nil)
+		return
+	}
+	auditSink.Store(&sink)
+}
+
+// Audit records a security relevant event, e.g. a login or a user change.
+func Audit(ctx context.Context, action string, args ...any) {
+	FromContext(ctx).Info("audit", append([]any{"audit", true, "action", action}, args...)...)
+
+	sink := auditSink.Load()
+	if sink == nil {
+		return
+	}
+	event := AuditEvent{
+		Time:      time.Now(),
+		Action:    action,
+		RequestID: RequestID(ctx),
+		UserID:    UserID(ctx),
+		ActorID:   ActorID(ctx),
+		Attrs:     map[string]any{},
+	}
+	record := slog.NewRecord(event.Time, slog.LevelInfo, "audit", 0)
+	record.Add(args...)
+	record.Attrs(func(a slog.Attr) bool {
+		a = redact(nil, a)
+		switch value := a.Value.Resolve().Any().(type) {
+		case error:
+			event.Attrs[a.Key] = value.Error()
+		default:
+			event.Attrs[a.Key] = value
+		}
+		return true
+	})
+	(*sink)(ctx, event)
+}
@@ -0,0 +1,151 @@
+// Package mailer sends transactional email such as invitations. Mail goes
+// through SMTP when SMTP_HOST is set and is only logged otherwise, which is
+// meant for development.
+package mailer
+
+import (
+	"7-solutions/utils"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// ErrDelivery wraps every failure to hand a message over for delivery.
+var ErrDelivery = errors.New("failed to send email")
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends plain text email.
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+type Config struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func NewConfigFromEnv() Config {
+	return Config{
+		Host:     utils.GetEnv("SMTP_HOST", ""),
+		Port:     utils.GetEnv("SMTP_PORT", "587"),
+		Username: utils.GetEnv("SMTP_USERNAME", ""),
+		Password: utils.GetEnv("SMTP_PASSWORD", ""),
+		From:     utils.GetEnv("SMTP_FROM", "no-reply@localhost"),
+	}
+}
+
+// New returns an SMTP mailer, or a LogMailer when no host is configured.
+func New(config Config) Mailer {
+	if config.Host == "" {
+		return LogMailer{}
+	}
+	return &SMTPMailer{config: config}
+}
+
+// LogMailer writes messages to the log instead of sending them. Messages
+// may contain secrets such as invitation links, so it must not be used in
+// production.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, message Message) error {
+	slog.InfoContext(ctx, "email not sent, SMTP_HOST is not set",
+		"to", message.To, "subject", message.Subject, "body", message.Body)
+	return nil
+}
+
+// SMTPMailer sends mail through an SMTP relay, upgrading the connection
+// with STARTTLS when the server offers it. Credentials are only sent over
+// TLS, which net/smtp enforces for non-local servers.
+type SMTPMailer struct {
+	config Config
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message Message) error {
+	if err := m.send(ctx, message); err != nil {
+		return fmt.Errorf("%w: %w", ErrDelivery, utils.ContextError(err))
+	}
+	return nil
+}
+
+func (m *SMTPMailer) send(ctx context.Context, message Message) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.config.Host, m.config.Port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// net/smtp knows nothing about contexts, so the deadline bounds the
+	// whole conversation instead.
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+	}
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.config.Host}); err != nil {
+			return err
+		}
+	}
+	if m.config.Username != "" {
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(m.config.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(m.format(message)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// format renders the message with CRLF line endings. Header values are
+// stripped of line breaks so they cannot inject headers.
+func (m *SMTPMailer) format(message Message) []byte {
+	header := strings.NewReplacer("\r", "", "\n", "")
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", header.Replace(m.config.From))
+	fmt.Fprintf(&b, "To: %s\r\n", header.Replace(message.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", header.Replace(message.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	body := strings.ReplaceAll(message.Body, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
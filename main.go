@@ -1,27 +1,26 @@
 package main
 
 import (
+	"7-solutions/app"
 	"7-solutions/database"
-	"7-solutions/repositories"
+	"7-solutions/logging"
 	"7-solutions/router"
+	"7-solutions/tenant"
+	"7-solutions/tracing"
 	"7-solutions/utils"
+	"context"
 	"log"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/gin-gonic/gin"
-	"github.com/jasonlvhit/gocron"
 	"github.com/joho/godotenv"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func task(db *mongo.Database) {
-	userRepository := repositories.NewUserRepository(db)
-	count, err := userRepository.CountUsers()
-	if err != nil {
-		log.Printf("Error counting users: %v", err)
-		return
-	}
-	log.Printf("Number of users: %d", count)
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
 
 func main() {
@@ -30,29 +29,108 @@ func main() {
 		log.Fatalf("Error loading .env file: %v", err)
 	}
 
-	r := gin.Default()
+	logConfig, err := logging.NewConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Error loading log config: %v", err)
+	}
+	logging.Setup(logConfig)
 
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbName := os.Getenv("DB_NAME")
-	port := os.Getenv("PORT")
+	appConfig, err := app.NewConfigFromEnv()
+	if err != nil {
+		fatal("error loading server config", err)
+	}
 
-	db := database.NewMongoDB(dbHost, dbPort, dbName)
+	if err := utils.SetupIDTokenSigning(appConfig.OIDCIssuer, appConfig.OIDCSigningKeyFile); err != nil {
+		fatal("error loading OIDC signing key", err)
+	}
+	if appConfig.OIDCSigningKeyFile == "" {
+		slog.Warn("OIDC_SIGNING_KEY_FILE not set, ID tokens are signed with an ephemeral key")
+	}
 
-	if err := utils.EnsureEmailUniqueIndex(db); err != nil {
-		log.Fatalf("Error ensuring email unique index: %v", err)
+	mongoConfig, err := database.NewMongoConfigFromEnv()
+	if err != nil {
+		fatal("error loading database config", err)
+	}
+
+	tracingConfig, err := tracing.NewConfigFromEnv()
+	if err != nil {
+		fatal("error loading tracing config", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, tracingConfig)
+	if err != nil {
+		fatal("error setting up tracing", err)
 	}
 
-	router.AddAuthRouter(r, db)
-	router.AddUserRouter(r, db)
+	db, err := database.NewMongoDB(ctx, mongoConfig)
+	if err != nil {
+		fatal("error connecting to database", err)
+	}
+
+	if err := utils.EnsureDefaultOrganization(db, tenant.DefaultID); err != nil {
+		_ = database.Disconnect(context.Background(), db)
+		fatal("error migrating users to the default organization", err)
+	}
+	if err := utils.EnsureEmailUniqueIndex(db); err != nil {
+		_ = database.Disconnect(context.Background(), db)
+		fatal("error ensuring email unique index", err)
+	}
+	if err := utils.EnsureAPIKeyPrefixUniqueIndex(db); err != nil {
+		_ = database.Disconnect(context.Background(), db)
+		fatal("error ensuring api key prefix index", err)
+	}
+	if err := utils.EnsureOAuthIndexes(db); err != nil {
+		_ = database.Disconnect(context.Background(), db)
+		fatal("error ensuring oauth indexes", err)
+	}
+	if err := utils.EnsureFederationIndexes(db); err != nil {
+		_ = database.Disconnect(context.Background(), db)
+		fatal("error ensuring federation indexes", err)
+	}
+	if err := utils.EnsureGroupIndexes(db); err != nil {
+		_ = database.Disconnect(context.Background(), db)
+		fatal("error ensuring group indexes", err)
+	}
+	if err := utils.EnsureInvitationIndexes(db); err != nil {
+		_ = database.Disconnect(context.Background(), db)
+		fatal("error ensuring invitation indexes", err)
+	}
+	if err := utils.EnsureSessionIndexes(db); err != nil {
+		_ = database.Disconnect(context.Background(), db)
+		fatal("error ensuring session indexes", err)
+	}
+	if err := utils.EnsureImportIndexes(db); err != nil {
+		_ = database.Disconnect(context.Background(), db)
+		fatal("error ensuring import indexes", err)
+	}
+	if err := utils.EnsureExportIndexes(db); err != nil {
+		_ = database.Disconnect(context.Background(), db)
+		fatal("error ensuring export indexes", err)
+	}
+	if err := utils.EnsurePrivacyIndexes(db); err != nil {
+		_ = database.Disconnect(context.Background(), db)
+		fatal("error ensuring privacy indexes", err)
+	}
+	if err := utils.EnsureProfileIndexes(db); err != nil {
+		_ = database.Disconnect(context.Background(), db)
+		fatal("error ensuring profile indexes", err)
+	}
+	if appConfig.RateLimit.Store == app.RateLimitStoreMongo {
+		if err := utils.EnsureRateLimitTTLIndex(db); err != nil {
+			_ = database.Disconnect(context.Background(), db)
+			fatal("error ensuring rate limit index", err)
+		}
+	}
 
-	s := gocron.NewScheduler()
-	s.Every(10).Seconds().Do(task, db)
-	go func() {
-		<-s.Start()
-	}()
+	// Store audit events for data exports, besides logging them.
+	logging.SetAuditSink(router.NewAuditRecorder(db))
+	a := app.New(appConfig, db)
+	a.OnShutdown(shutdownTracing)
 
-	if err := r.Run(":" + port); err != nil {
-		log.Fatalf("Error running server: %v", err)
+	if err := a.Run(ctx); err != nil {
+		fatal("error running server", err)
 	}
 }
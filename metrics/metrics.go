@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "api"
+
+// Registry holds every collector exposed on /metrics. A dedicated registry
+// keeps tests independent of the global default one.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	HTTPRequestsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	LoginAttemptsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_login_attempts_total",
+		Help:      "Login attempts by result (success or failure).",
+	}, []string{"result"})
+
+	TokenVerificationFailuresTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_token_verification_failures_total",
+		Help:      "Rejected authorization tokens by reason.",
+	}, []string{"reason"})
+
+	RateLimitedRequestsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected with 429 by rate limit group.",
+	}, []string{"group"})
+
+	DBOperationDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_operation_duration_seconds",
+		Help:      "Mongo operation latency by repository method and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"repository", "method", "outcome"})
+
+	UsersTotal = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "users",
+		Help:      "Number of stored users, refreshed by the count_users job.",
+	})
+
+	JobDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Scheduled job duration by job name.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"job"})
+
+	JobFailuresTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_failures_total",
+		Help:      "Failed scheduled job runs by job name.",
+	}, []string{"job"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// ObserveDBOperation records the latency of a repository call started at
+// start, labelled with its outcome.
+func ObserveDBOperation(repository, method string, start time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	DBOperationDuration.WithLabelValues(repository, method, outcome).Observe(time.Since(start).Seconds())
+}
+
+// ObserveJob records the duration of a scheduled job run and counts failures.
+func ObserveJob(job string, duration time.Duration, err error) {
+	JobDuration.WithLabelValues(job).Observe(duration.Seconds())
+	if err != nil {
+		JobFailuresTotal.WithLabelValues(job).Inc()
+	}
+}
@@ -1,54 +1,386 @@
 package middleware
 
 import (
+	"7-solutions/logging"
+	"7-solutions/metrics"
+	"7-solutions/models"
+	"7-solutions/policy"
+	"7-solutions/services"
+	"7-solutions/tenant"
 	"7-solutions/utils"
+	"context"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
 )
 
-func AuthenticationMiddleware() gin.HandlerFunc {
+const (
+	AuthMethodJWT    = "jwt"
+	AuthMethodOAuth  = "oauth"
+	AuthMethodAPIKey = "apikey"
+)
+
+// APIKeyAuthenticator resolves the plaintext of an "Authorization: ApiKey"
+// header to the stored key.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+// TokenRevocationChecker reports whether the JWT with the given jti was
+// revoked.
+type TokenRevocationChecker interface {
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// SessionValidator returns services.ErrSessionRevoked unless the login
+// session with the given id is still active.
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, sessionID string) error
+}
+
+// AccountStatusChecker returns services.ErrAccountInactive unless the user
+// with the given id is active.
+type AccountStatusChecker interface {
+	CheckAccount(ctx context.Context, userID string) error
+}
+
+// AuthenticationMiddleware accepts "Authorization: Bearer <jwt>" and, when
+// apiKeys is not nil, "Authorization: ApiKey <key>". When revocations is not
+// nil, revoked JWTs are rejected, and when sessions is not nil, so are
+// login JWTs whose session was revoked. When accounts is not nil, tokens of
+// users who are not active are rejected too, as are impersonation tokens of
+// admins who are not.
+//
+// Login JWTs get email, name, role and userID in the context, and
+// sessionID when they belong to a session. Impersonation
+// tokens also get actorID, the admin acting as the user, and every request
+// made with one is audited. OAuth access tokens get clientID and scopes,
+// plus email, name and userID when issued on behalf of a user. API key
+// requests get apiKeyID and scopes. Every request gets tenantID, and the
+// request context is scoped to that tenant; super admins signed in with a
+// login JWT are scoped to all tenants.
+func AuthenticationMiddleware(apiKeys APIKeyAuthenticator, revocations TokenRevocationChecker, sessions SessionValidator, accounts AccountStatusChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authorization token"})
-			c.Abort()
+			rejectToken(c, "missing_token", "Missing authorization token")
 			return
 		}
 
 		tokenParts := strings.Split(tokenString, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization token format"})
+		if len(tokenParts) != 2 {
+			rejectToken(c, "invalid_format", "Invalid authorization token format")
+			return
+		}
+
+		switch {
+		case tokenParts[0] == "Bearer":
+			authenticateJWT(c, revocations, sessions, accounts, tokenParts[1])
+		case tokenParts[0] == "ApiKey" && apiKeys != nil:
+			authenticateAPIKey(c, apiKeys, tokenParts[1])
+		default:
+			rejectToken(c, "invalid_format", "Invalid authorization token format")
+		}
+	}
+}
+
+func authenticateJWT(c *gin.Context, revocations TokenRevocationChecker, sessions SessionValidator, accounts AccountStatusChecker, tokenString string) {
+	claims, err := utils.VerifyToken(tokenString)
+	if err != nil {
+		rejectToken(c, tokenFailureReason(err), "Invalid authorization token")
+		return
+	}
+
+	if jti, ok := claims["jti"].(string); ok && revocations != nil {
+		revoked, err := revocations.IsTokenRevoked(c.Request.Context(), jti)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, utils.ErrorBody(c, "Failed to verify authorization token: "+err.Error()))
 			c.Abort()
 			return
 		}
+		if revoked {
+			rejectToken(c, "revoked", "Authorization token revoked")
+			return
+		}
+	}
 
-		tokenString = tokenParts[1]
+	tenantID, _ := claims["tenantId"].(string)
+	setTenant(c, tenantID)
 
-		claims, err := utils.VerifyToken(tokenString)
+	if clientID, ok := claims["client_id"].(string); ok {
+		c.Set("authMethod", AuthMethodOAuth)
+		c.Set("clientID", clientID)
+		scope, _ := claims["scope"].(string)
+		c.Set("scopes", strings.Fields(scope))
+		if email, ok := claims["email"]; ok {
+			c.Set("email", email)
+			c.Set("name", claims["name"])
+			setUserID(c, claims)
+		}
+		if !checkAccount(c, accounts) {
+			return
+		}
+		setSubject(c)
+		c.Next()
+		return
+	}
+
+	email, ok := claims["email"]
+	if !ok {
+		rejectToken(c, "missing_claim", "email not found in token")
+		return
+	}
+
+	name, ok := claims["name"]
+	if !ok {
+		rejectToken(c, "missing_claim", "name not found in token")
+		return
+	}
+
+	if sessionID, ok := claims["sid"].(string); ok {
+		if sessions != nil {
+			err := sessions.ValidateSession(c.Request.Context(), sessionID)
+			if errors.Is(err, services.ErrSessionRevoked) {
+				rejectToken(c, "revoked", "Session revoked")
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusServiceUnavailable, utils.ErrorBody(c, "Failed to verify session: "+err.Error()))
+				c.Abort()
+				return
+			}
+		}
+		c.Set("sessionID", sessionID)
+	}
+
+	c.Set("authMethod", AuthMethodJWT)
+	c.Set("email", email)
+	c.Set("name", name)
+	if role, ok := claims["role"].(string); ok {
+		c.Set("role", role)
+		if role == models.RoleSuperAdmin {
+			c.Request = c.Request.WithContext(tenant.WithAllTenants(c.Request.Context()))
+		}
+	}
+	setUserID(c, claims)
+	actorID, ok := actor(claims)
+	if !ok {
+		rejectToken(c, "invalid_token", "Invalid authorization token")
+		return
+	}
+	if actorID != "" {
+		c.Set("actorID", actorID)
+		c.Request = c.Request.WithContext(logging.WithActorID(c.Request.Context(), actorID))
+	}
+	if !checkAccount(c, accounts) {
+		return
+	}
+	setSubject(c)
+	c.Next()
+
+	if actorID != "" {
+		logging.Audit(c.Request.Context(), "impersonation.request",
+			"method", c.Request.Method, "path", c.Request.URL.Path, "status", c.Writer.Status())
+	}
+}
+
+// actor returns the subject of the RFC 8693 act claim of an impersonation
+// token, or "" for regular tokens. ok is false when the claim is malformed.
+func actor(claims map[string]interface{}) (actorID string, ok bool) {
+	act, present := claims["act"]
+	if !present {
+		return "", true
+	}
+	actClaims, _ := act.(map[string]interface{})
+	actorID, _ = actClaims["sub"].(string)
+	return actorID, actorID != ""
+}
+
+// checkAccount rejects the request unless the user it is made for and the
+// admin impersonating them, if any, are active, and reports whether it may
+// go on.
+func checkAccount(c *gin.Context, accounts AccountStatusChecker) bool {
+	if accounts == nil {
+		return true
+	}
+	for _, userID := range []string{c.GetString("userID"), c.GetString("actorID")} {
+		if userID == "" {
+			continue
+		}
+		err := accounts.CheckAccount(c.Request.Context(), userID)
+		if errors.Is(err, services.ErrAccountInactive) {
+			rejectToken(c, "account_inactive", "Account not active")
+			return false
+		}
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization token"})
+			c.JSON(http.StatusServiceUnavailable, utils.ErrorBody(c, "Failed to verify account: "+err.Error()))
+			c.Abort()
+			return false
+		}
+	}
+	return true
+}
+
+func setUserID(c *gin.Context, claims map[string]interface{}) {
+	if sub, ok := claims["sub"].(string); ok {
+		c.Set("userID", sub)
+		c.Request = c.Request.WithContext(logging.WithUserID(c.Request.Context(), sub))
+	}
+}
+
+// setTenant scopes the request to tenantID. Tokens and keys issued before
+// organizations existed carry none and belong to the default tenant.
+func setTenant(c *gin.Context, tenantID string) {
+	if tenantID == "" {
+		tenantID = tenant.DefaultID
+	}
+	c.Set("tenantID", tenantID)
+	c.Request = c.Request.WithContext(tenant.WithID(c.Request.Context(), tenantID))
+}
+
+// setSubject makes the caller available to policy checks in services. Its
+// id is the user id, or apikey:<id> or client:<id> for callers acting on
+// their own.
+func setSubject(c *gin.Context) {
+	subject := policy.Subject{
+		ID:       c.GetString("userID"),
+		Role:     c.GetString("role"),
+		TenantID: c.GetString("tenantID"),
+		Scopes:   c.GetStringSlice("scopes"),
+		ActorID:  c.GetString("actorID"),
+	}
+	switch {
+	case subject.ID != "":
+	case c.GetString("apiKeyID") != "":
+		subject.ID = "apikey:" + c.GetString("apiKeyID")
+	case c.GetString("clientID") != "":
+		subject.ID = "client:" + c.GetString("clientID")
+	}
+	c.Request = c.Request.WithContext(policy.WithSubject(c.Request.Context(), subject))
+}
+
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyAuthenticator, key string) {
+	apiKey, err := apiKeys.AuthenticateAPIKey(c.Request.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAPIKeyExpired):
+			rejectToken(c, "expired", "API key expired")
+		case errors.Is(err, services.ErrAPIKeyRevoked):
+			rejectToken(c, "revoked", "API key revoked")
+		case errors.Is(err, services.ErrInvalidAPIKey):
+			rejectToken(c, "invalid_api_key", "Invalid API key")
+		default:
+			c.JSON(http.StatusServiceUnavailable, utils.ErrorBody(c, "Failed to verify API key: "+err.Error()))
 			c.Abort()
+		}
+		return
+	}
+
+	apiKeyID := apiKey.ID.Hex()
+	c.Set("authMethod", AuthMethodAPIKey)
+	c.Set("apiKeyID", apiKeyID)
+	c.Set("scopes", apiKey.Scopes)
+	setTenant(c, apiKey.TenantID)
+	c.Request = c.Request.WithContext(logging.WithUserID(c.Request.Context(), "apikey:"+apiKeyID))
+	setSubject(c)
+	c.Next()
+}
+
+// RequireScope restricts API keys and OAuth access tokens; users signed in
+// with a login JWT act with their own permissions and are let through.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("authMethod") == AuthMethodJWT {
+			c.Next()
 			return
 		}
+		for _, granted := range c.GetStringSlice("scopes") {
+			if granted == scope {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, utils.ErrorBody(c, "Missing required scope: "+scope))
+		c.Abort()
+	}
+}
 
-		email, ok := claims["email"]
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "email not found in token"})
+// RequireRole admits users signed in with a login JWT that carries the
+// given role, and super admins. API keys and OAuth tokens carry no role and
+// are rejected.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := c.GetString("role")
+		if c.GetString("authMethod") != AuthMethodJWT || (granted != role && granted != models.RoleSuperAdmin) {
+			c.JSON(http.StatusForbidden, utils.ErrorBody(c, "Insufficient permissions"))
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
-		name, ok := claims["name"]
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "name not found in token"})
+// RequireLogin admits users signed in with a login JWT, whatever their role.
+// API keys and OAuth tokens are rejected.
+func RequireLogin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("authMethod") != AuthMethodJWT || c.GetString("userID") == "" {
+			c.JSON(http.StatusForbidden, utils.ErrorBody(c, "Requires signing in as a user"))
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
-		c.Set("email", email)
-		c.Set("name", name)
+// RequireSelfOrRole admits users signed in with a login JWT who either are
+// the user of the :id route parameter or have role.
+func RequireSelfOrRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := c.GetString("role")
+		self := c.GetString("userID") != "" && c.GetString("userID") == c.Param("id")
+		if c.GetString("authMethod") != AuthMethodJWT || !(self || granted == role || granted == models.RoleSuperAdmin) {
+			c.JSON(http.StatusForbidden, utils.ErrorBody(c, "Insufficient permissions"))
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
+
+// RejectImpersonation blocks actions only the account owner may take, such
+// as changing credentials, while an admin impersonates them.
+func RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("actorID") != "" {
+			c.JSON(http.StatusForbidden, utils.ErrorBody(c, "Not allowed while impersonating a user"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func rejectToken(c *gin.Context, reason, message string) {
+	metrics.TokenVerificationFailuresTotal.WithLabelValues(reason).Inc()
+	c.JSON(http.StatusUnauthorized, utils.ErrorBody(c, message))
+	c.Abort()
+}
+
+func tokenFailureReason(err error) string {
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) {
+		switch {
+		case validationErr.Errors&jwt.ValidationErrorExpired != 0:
+			return "expired"
+		case validationErr.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+			return "invalid_signature"
+		case validationErr.Errors&jwt.ValidationErrorMalformed != 0:
+			return "malformed"
+		}
+	}
+	return "invalid_token"
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"7-solutions/logging"
+	"7-solutions/utils"
+	"io"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecoveryMiddleware turns panics into a 500 response and a structured log
+// line, carrying the stack trace, instead of gin's plain text stack dump.
+func RecoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		logging.FromContext(c.Request.Context()).Error("panic recovered",
+			"panic", recovered, "path", c.Request.URL.Path, "stack", string(debug.Stack()))
+		c.AbortWithStatusJSON(http.StatusInternalServerError, utils.ErrorBody(c, "Internal server error"))
+	})
+}
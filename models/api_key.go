@@ -0,0 +1,45 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+
+	ScopeGroupsRead  = "groups:read"
+	ScopeGroupsWrite = "groups:write"
+
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// Scopes lists every scope an API key can be granted.
+var Scopes = []string{ScopeUsersRead, ScopeUsersWrite, ScopeGroupsRead, ScopeGroupsWrite}
+
+// OIDCScopes are only meaningful to OAuth clients acting for a user.
+var OIDCScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+
+// APIKey is a machine credential. Only the prefix, used to look the key up,
+// and a SHA-256 hash of the full key are stored.
+type APIKey struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name      string             `json:"name" bson:"name"`
+	Prefix    string             `json:"prefix" bson:"prefix"`
+	Hash      string             `json:"-" bson:"hash"`
+	Scopes    []string           `json:"scopes" bson:"scopes"`
+	TenantID  string             `json:"tenantId" bson:"tenantId,omitempty"`
+	CreatedBy string             `json:"createdBy" bson:"createdBy"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	ExpiresAt time.Time          `json:"expiresAt" bson:"expiresAt"`
+	RevokedAt *time.Time         `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
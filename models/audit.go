@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditEvent is a stored audit event. UserID is the user who acted,
+// ActorID the admin impersonating them, if any, and TargetUserID the user
+// the event is about.
+type AuditEvent struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID     string             `json:"tenantId,omitempty" bson:"tenantId,omitempty"`
+	Action       string             `json:"action" bson:"action"`
+	UserID       string             `json:"userId,omitempty" bson:"userId,omitempty"`
+	ActorID      string             `json:"actorId,omitempty" bson:"actorId,omitempty"`
+	TargetUserID string             `json:"targetUserId,omitempty" bson:"targetUserId,omitempty"`
+	RequestID    string             `json:"requestId,omitempty" bson:"requestId,omitempty"`
+	Attributes   map[string]any     `json:"attributes,omitempty" bson:"attributes,omitempty"`
+	CreatedAt    time.Time          `json:"createdAt" bson:"createdAt"`
+}
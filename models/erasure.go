@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ErasureRunning   = "running"
+	ErasureCompleted = "completed"
+)
+
+// Erasure tracks the erasure of a user's data and remains as its record.
+// Subject is a keyed hash of the user id that replaces the id wherever
+// records about the user are kept. UserID and Email are only stored while
+// the erasure runs, so an interrupted erasure can resume; Steps lists the
+// steps that are done.
+type Erasure struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID    string             `json:"tenantId" bson:"tenantId,omitempty"`
+	Subject     string             `json:"subject" bson:"subject"`
+	UserID      int                `json:"-" bson:"userId,omitempty"`
+	Email       string             `json:"-" bson:"email,omitempty"`
+	Status      string             `json:"status" bson:"status"`
+	Steps       []string           `json:"steps" bson:"steps"`
+	RequestedBy string             `json:"requestedBy" bson:"requestedBy"`
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   time.Time          `json:"updatedAt" bson:"updatedAt"`
+	CompletedAt *time.Time         `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+}
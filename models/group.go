@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Group is a team of users. Groups nest through ParentID, and members of a
+// group are also considered members of its ancestors.
+type Group struct {
+	ID          primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	TenantID    string              `json:"tenantId" bson:"tenantId,omitempty"`
+	Name        string              `json:"name" bson:"name"`
+	Description string              `json:"description" bson:"description"`
+	ParentID    *primitive.ObjectID `json:"parentId,omitempty" bson:"parentId"`
+	CreatedAt   time.Time           `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   time.Time           `json:"updatedAt" bson:"updatedAt"`
+}
+
+// GroupMember records that a user belongs directly to a group.
+type GroupMember struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID string             `json:"tenantId" bson:"tenantId,omitempty"`
+	GroupID  primitive.ObjectID `json:"groupId" bson:"groupId"`
+	UserID   int                `json:"userId" bson:"userId"`
+	AddedAt  time.Time          `json:"addedAt" bson:"addedAt"`
+}
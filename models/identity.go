@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Identity links a user to their account at an external identity provider.
+// Provider and Subject together are unique.
+type Identity struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID   int                `json:"userId" bson:"userId"`
+	Provider string             `json:"provider" bson:"provider"`
+	Subject  string             `json:"subject" bson:"subject"`
+	Email    string             `json:"email" bson:"email"`
+	LinkedAt time.Time          `json:"linkedAt" bson:"linkedAt"`
+}
+
+// FederationState is a pending login at an external provider, keyed by a
+// hash of the state parameter. It holds the nonce and PKCE verifier the
+// callback must match.
+type FederationState struct {
+	StateHash    string    `bson:"_id"`
+	Provider     string    `bson:"provider"`
+	Nonce        string    `bson:"nonce"`
+	CodeVerifier string    `bson:"codeVerifier"`
+	ExpiresAt    time.Time `bson:"expiresAt"`
+}
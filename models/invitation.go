@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// Invitation lets someone create their own account with a role chosen by
+// an admin. Only a SHA-256 hash of the emailed token is stored.
+type Invitation struct {
+	ID         primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	TenantID   string              `json:"tenantId" bson:"tenantId,omitempty"`
+	Email      string              `json:"email" bson:"email"`
+	Role       string              `json:"role" bson:"role"`
+	GroupID    *primitive.ObjectID `json:"groupId,omitempty" bson:"groupId,omitempty"`
+	TokenHash  string              `json:"-" bson:"tokenHash"`
+	InvitedBy  string              `json:"invitedBy" bson:"invitedBy"`
+	CreatedAt  time.Time           `json:"createdAt" bson:"createdAt"`
+	ExpiresAt  time.Time           `json:"expiresAt" bson:"expiresAt"`
+	AcceptedAt *time.Time          `json:"acceptedAt,omitempty" bson:"acceptedAt,omitempty"`
+	RevokedAt  *time.Time          `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+}
+
+func (i *Invitation) Status(now time.Time) string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case !now.Before(i.ExpiresAt):
+		return InvitationExpired
+	}
+	return InvitationPending
+}
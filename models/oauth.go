@@ -0,0 +1,62 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// OAuthClient is an application registered with the authorization server.
+// Public clients (SPAs, native apps) have no secret and must use PKCE.
+type OAuthClient struct {
+	ID           primitive.ObjectID `json:"-" bson:"_id,omitempty"`
+	ClientID     string             `json:"clientId" bson:"clientId"`
+	SecretHash   string             `json:"-" bson:"secretHash,omitempty"`
+	Name         string             `json:"name" bson:"name"`
+	RedirectURIs []string           `json:"redirectUris" bson:"redirectUris"`
+	GrantTypes   []string           `json:"grantTypes" bson:"grantTypes"`
+	Scopes       []string           `json:"scopes" bson:"scopes"`
+	Public       bool               `json:"public" bson:"public"`
+	TenantID     string             `json:"tenantId" bson:"tenantId,omitempty"`
+	CreatedBy    string             `json:"createdBy" bson:"createdBy"`
+	CreatedAt    time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+func (c *OAuthClient) HasGrantType(grantType string) bool {
+	return slices.Contains(c.GrantTypes, grantType)
+}
+
+func (c *OAuthClient) HasRedirectURI(uri string) bool {
+	return slices.Contains(c.RedirectURIs, uri)
+}
+
+// AuthorizationCode is a single-use code handed to the client after the
+// user consents. Only a hash of the code is stored.
+type AuthorizationCode struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	CodeHash      string             `bson:"codeHash"`
+	ClientID      string             `bson:"clientId"`
+	RedirectURI   string             `bson:"redirectUri"`
+	Scope         string             `bson:"scope"`
+	CodeChallenge string             `bson:"codeChallenge"`
+	Nonce         string             `bson:"nonce,omitempty"`
+	Subject       string             `bson:"subject"`
+	Name          string             `bson:"name"`
+	Email         string             `bson:"email"`
+	CreatedAt     time.Time          `bson:"createdAt"`
+	ExpiresAt     time.Time          `bson:"expiresAt"`
+	UsedAt        *time.Time         `bson:"usedAt,omitempty"`
+}
+
+// RevokedToken records the jti of a revoked JWT until it would have expired
+// anyway.
+type RevokedToken struct {
+	JTI       string    `bson:"_id"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Organization is a tenant. Its ID is a short slug stored as tenantId on
+// the users, API keys and OAuth clients it owns.
+type Organization struct {
+	ID        string    `json:"id" bson:"_id"`
+	Name      string    `json:"name" bson:"name"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
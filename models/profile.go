@@ -0,0 +1,50 @@
+package models
+
+import (
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Types of custom attribute values. Numbers are stored as float64.
+const (
+	AttributeString  = "string"
+	AttributeNumber  = "number"
+	AttributeBoolean = "boolean"
+)
+
+// Visibilities of custom attributes.
+const (
+	// VisibilityPublic attributes are seen by everyone who may read the
+	// user.
+	VisibilityPublic = "public"
+	// VisibilityPrivate attributes are seen by the user and by admins.
+	VisibilityPrivate = "private"
+	// VisibilityAdmin attributes are only seen and set by admins.
+	VisibilityAdmin = "admin"
+)
+
+// AttributeKeyPattern is what keys of custom attributes look like, so they
+// can be used as field names and export column names.
+var AttributeKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+// AttributeDefinition is the schema of a custom profile attribute of the
+// users of a tenant. Key and Type cannot change once defined.
+type AttributeDefinition struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID string             `json:"tenantId" bson:"tenantId,omitempty"`
+	Key      string             `json:"key" bson:"key"`
+	Type     string             `json:"type" bson:"type"`
+	// Required attributes must have a value once a user's attributes are
+	// updated by someone allowed to set them.
+	Required bool `json:"required" bson:"required"`
+	// Pattern is a regular expression string values must match in full.
+	Pattern string `json:"pattern,omitempty" bson:"pattern,omitempty"`
+	// Enum lists the allowed string values; any value is allowed when empty.
+	Enum        []string  `json:"enum,omitempty" bson:"enum,omitempty"`
+	Visibility  string    `json:"visibility" bson:"visibility"`
+	Description string    `json:"description" bson:"description"`
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt" bson:"updatedAt"`
+}
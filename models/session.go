@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is a sign-in from one device. Login tokens carry the session id
+// in their sid claim, so revoking the session revokes the token.
+type Session struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID   string             `json:"tenantId" bson:"tenantId,omitempty"`
+	UserID     int                `json:"userId" bson:"userId"`
+	UserAgent  string             `json:"userAgent" bson:"userAgent"`
+	IP         string             `json:"ip" bson:"ip"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+	LastSeenAt time.Time          `json:"lastSeenAt" bson:"lastSeenAt"`
+	ExpiresAt  time.Time          `json:"expiresAt" bson:"expiresAt"`
+	RevokedAt  *time.Time         `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+}
+
+// Active reports whether the session can still be used at now.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
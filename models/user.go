@@ -1,16 +1,77 @@
 package models
 
 import (
+	"slices"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+	// RoleSuperAdmin works across every tenant and passes every role check.
+	RoleSuperAdmin = "superadmin"
+)
+
+// Account statuses. Only active users may sign in or use their tokens.
+// Users stored before statuses existed have none and are active.
+const (
+	// StatusPending accounts await verification by an admin.
+	StatusPending     = "pending"
+	StatusActive      = "active"
+	StatusSuspended   = "suspended"
+	StatusLocked      = "locked"
+	StatusDeactivated = "deactivated"
+)
+
+// StatusTransitions lists the statuses each status may change to.
+var StatusTransitions = map[string][]string{
+	StatusPending:     {StatusActive, StatusDeactivated},
+	StatusActive:      {StatusPending, StatusSuspended, StatusLocked, StatusDeactivated},
+	StatusSuspended:   {StatusActive, StatusDeactivated},
+	StatusLocked:      {StatusActive, StatusDeactivated},
+	StatusDeactivated: {StatusActive},
+}
+
+// CanTransitionStatus reports whether an account may change from status
+// from to status to.
+func CanTransitionStatus(from, to string) bool {
+	return slices.Contains(StatusTransitions[from], to)
+}
+
+// StatusChange is an entry of the status history of a user. ActorID is the
+// admin who made the change.
+type StatusChange struct {
+	From    string    `json:"from" bson:"from"`
+	To      string    `json:"to" bson:"to"`
+	Reason  string    `json:"reason,omitempty" bson:"reason,omitempty"`
+	ActorID string    `json:"actorId" bson:"actorId"`
+	At      time.Time `json:"at" bson:"at"`
+}
+
 type User struct {
-	ID        int                `json:"id" bson:"id"`
-	ObjectID  primitive.ObjectID `json:"-" bson:"_id,omitempty"`
-	Name      string             `json:"name" bson:"name" validate:"required"`
-	Email     string             `json:"email" bson:"email" validate:"required,email"`
-	Password  string             `json:"password" bson:"password" validate:"required,min=6"`
-	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	ID            int                `json:"id" bson:"id"`
+	ObjectID      primitive.ObjectID `json:"-" bson:"_id,omitempty"`
+	Name          string             `json:"name" bson:"name" validate:"required"`
+	Email         string             `json:"email" bson:"email" validate:"required,email"`
+	Password      string             `json:"password" bson:"password" validate:"required,min=6"`
+	Role          string             `json:"role" bson:"role,omitempty"`
+	TenantID      string             `json:"tenantId" bson:"tenantId,omitempty"`
+	EmailVerified bool               `json:"emailVerified" bson:"emailVerified,omitempty"`
+	CreatedAt     time.Time          `json:"createdAt" bson:"createdAt"`
+	Status        string             `json:"status" bson:"status,omitempty"`
+	StatusHistory []StatusChange     `json:"statusHistory,omitempty" bson:"statusHistory,omitempty"`
+	// Attributes holds the values of the custom attributes defined for the
+	// tenant, keyed by AttributeDefinition.Key. They only change through
+	// profile updates.
+	Attributes map[string]any `json:"attributes,omitempty" bson:"attributes,omitempty"`
+}
+
+// AccountStatus returns the status of the user, active when none is stored.
+func (u *User) AccountStatus() string {
+	if u.Status == "" {
+		return StatusActive
+	}
+	return u.Status
 }
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+)
+
+// ExportJob tracks a user export that runs in the background. The file
+// it writes is stored in the exports GridFS bucket.
+type ExportJob struct {
+	ID          primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	TenantID    string              `json:"tenantId" bson:"tenantId,omitempty"`
+	Status      string              `json:"status" bson:"status"`
+	Format      string              `json:"format" bson:"format"`
+	StartedBy   string              `json:"startedBy" bson:"startedBy"`
+	Rows        int                 `json:"rows" bson:"rows"`
+	FileID      *primitive.ObjectID `json:"-" bson:"fileId,omitempty"`
+	Size        int64               `json:"size" bson:"size"`
+	Error       string              `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt   time.Time           `json:"createdAt" bson:"createdAt"`
+	CompletedAt *time.Time          `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+	// ExpiresAt is when the job and its file are purged.
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
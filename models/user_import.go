@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// Outcomes of an import row. In a dry run, created and updated say what
+// would happen.
+const (
+	ImportRowCreated  = "created"
+	ImportRowUpdated  = "updated"
+	ImportRowInvalid  = "invalid"
+	ImportRowConflict = "conflict"
+	ImportRowFailed   = "failed"
+)
+
+// ImportJob tracks a user import that runs in the background.
+type ImportJob struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID    string             `json:"tenantId" bson:"tenantId,omitempty"`
+	Status      string             `json:"status" bson:"status"`
+	DryRun      bool               `json:"dryRun" bson:"dryRun"`
+	StartedBy   string             `json:"startedBy" bson:"startedBy"`
+	Total       int                `json:"total" bson:"total"`
+	Processed   int                `json:"processed" bson:"processed"`
+	Results     []ImportRowResult  `json:"results" bson:"results"`
+	Error       string             `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+	CompletedAt *time.Time         `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+	// ExpiresAt is when Mongo drops the finished job.
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
+
+// ImportRowResult is the outcome of one row; Row counts data rows from 1.
+type ImportRowResult struct {
+	Row    int    `json:"row" bson:"row"`
+	Email  string `json:"email,omitempty" bson:"email,omitempty"`
+	UserID int    `json:"userId,omitempty" bson:"userId,omitempty"`
+	Status string `json:"status" bson:"status"`
+	Error  string `json:"error,omitempty" bson:"error,omitempty"`
+}
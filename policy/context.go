@@ -0,0 +1,16 @@
+package policy
+
+import "context"
+
+type contextKey struct{}
+
+// WithSubject stores the authenticated subject in ctx.
+func WithSubject(ctx context.Context, subject Subject) context.Context {
+	return context.WithValue(ctx, contextKey{}, subject)
+}
+
+// SubjectFromContext returns the subject stored by WithSubject, if any.
+func SubjectFromContext(ctx context.Context) (Subject, bool) {
+	subject, ok := ctx.Value(contextKey{}).(Subject)
+	return subject, ok
+}
@@ -0,0 +1,299 @@
+package policy
+
+import (
+	"7-solutions/logging"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrDenied is returned when no policy allows a request or one denies it.
+var ErrDenied = errors.New("permission denied")
+
+// Subject is who acts: a user, or an API key or OAuth client.
+type Subject struct {
+	ID       string   `json:"id"`
+	Role     string   `json:"role,omitempty"`
+	TenantID string   `json:"tenantId,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+	// Groups are the names of the groups the subject belongs to directly.
+	Groups []string `json:"groups,omitempty"`
+	// ActorID is the admin impersonating the subject, if any.
+	ActorID    string         `json:"actorId,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// Resource is what is acted on. Field is set when authorizing a change to
+// a single field.
+type Resource struct {
+	Type       string         `json:"type"`
+	ID         string         `json:"id,omitempty"`
+	TenantID   string         `json:"tenantId,omitempty"`
+	Field      string         `json:"field,omitempty"`
+	Groups     []string       `json:"groups,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+type Request struct {
+	Subject  Subject        `json:"subject"`
+	Action   string         `json:"action"`
+	Resource Resource       `json:"resource"`
+	Context  map[string]any `json:"context,omitempty"`
+}
+
+// Decision explains the outcome of a request. PolicyID is the deciding
+// policy, empty when nothing matched.
+type Decision struct {
+	Allowed  bool     `json:"allowed"`
+	PolicyID string   `json:"policyId,omitempty"`
+	Reason   string   `json:"reason"`
+	Matched  []string `json:"matched"`
+	DryRun   bool     `json:"dryRun"`
+}
+
+// Engine evaluates requests against a set of policies that can be replaced
+// at runtime. Deny policies win over allow policies, and requests no policy
+// allows are denied.
+type Engine struct {
+	mu       sync.RWMutex
+	policies []Policy
+	dryRun   bool
+}
+
+// NewEngine returns an engine without policies, which denies everything
+// until Load is called. In dry-run mode Authorize logs denials but lets
+// the requests through.
+func NewEngine(dryRun bool) *Engine {
+	return &Engine{dryRun: dryRun}
+}
+
+// Load validates policies and replaces the current ones with them.
+func (e *Engine) Load(policies []Policy) error {
+	if err := Validate(policies); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.policies = slices.Clone(policies)
+	e.mu.Unlock()
+	return nil
+}
+
+// Uses reports whether any policy refers to the attribute, so callers can
+// skip loading attributes nobody looks at.
+func (e *Engine) Uses(attr string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, p := range e.policies {
+		for _, c := range p.Conditions {
+			if c.Attr == attr || c.Ref == attr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Evaluate decides req without logging or enforcing anything.
+func (e *Engine) Evaluate(req Request) Decision {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	decision := Decision{Matched: []string{}, DryRun: e.dryRun}
+	var allow, deny string
+	for _, p := range e.policies {
+		if !p.matches(req) {
+			continue
+		}
+		decision.Matched = append(decision.Matched, p.ID)
+		if p.Effect == EffectDeny && deny == "" {
+			deny = p.ID
+		}
+		if p.Effect == EffectAllow && allow == "" {
+			allow = p.ID
+		}
+	}
+
+	switch {
+	case deny != "":
+		decision.PolicyID = deny
+		decision.Reason = "denied by policy " + deny
+	case allow != "":
+		decision.Allowed = true
+		decision.PolicyID = allow
+		decision.Reason = "allowed by policy " + allow
+	default:
+		decision.Reason = "no policy allows the request"
+	}
+	return decision
+}
+
+// Authorize returns an error wrapping ErrDenied unless req is allowed. In
+// dry-run mode the denial is only logged.
+func (e *Engine) Authorize(ctx context.Context, req Request) error {
+	decision := e.Evaluate(req)
+	if decision.Allowed {
+		return nil
+	}
+
+	args := []any{
+		"subject_id", req.Subject.ID,
+		"authz_action", req.Action,
+		"resource_type", req.Resource.Type,
+		"resource_id", req.Resource.ID,
+		"field", req.Resource.Field,
+		"reason", decision.Reason,
+	}
+	if decision.DryRun {
+		logging.FromContext(ctx).Warn("policy would deny request", args...)
+		return nil
+	}
+	logging.Audit(ctx, "authz.deny", args...)
+
+	target := req.Action
+	if req.Resource.Field != "" {
+		target += " on field " + req.Resource.Field
+	}
+	return fmt.Errorf("%w: %s", ErrDenied, target)
+}
+
+func (p *Policy) matches(req Request) bool {
+	if p.TenantID != "" && p.TenantID != req.Subject.TenantID {
+		return false
+	}
+	if len(p.Roles) > 0 && !slices.Contains(p.Roles, req.Subject.Role) {
+		return false
+	}
+	if !matchAny(p.Actions, req.Action) {
+		return false
+	}
+	if len(p.Resources) > 0 && !matchAny(p.Resources, req.Resource.Type) {
+		return false
+	}
+	if len(p.Fields) > 0 && !slices.Contains(p.Fields, req.Resource.Field) {
+		return false
+	}
+	for _, c := range p.Conditions {
+		if !c.holds(req) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAny matches value against glob patterns such as users:*.
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Condition) holds(req Request) bool {
+	left, ok := req.lookup(c.Attr)
+	if !ok {
+		return false
+	}
+	right := c.Value
+	if c.Ref != "" {
+		if right, ok = req.lookup(c.Ref); !ok {
+			return false
+		}
+	}
+
+	switch c.Op {
+	case OpEq:
+		return scalar(left) == scalar(right)
+	case OpNe:
+		return scalar(left) != scalar(right)
+	case OpIn:
+		return slices.Contains(list(right), scalar(left))
+	case OpContains:
+		return slices.Contains(list(left), scalar(right))
+	case OpIntersects:
+		values := list(right)
+		return slices.ContainsFunc(list(left), func(v string) bool { return slices.Contains(values, v) })
+	}
+	return false
+}
+
+// lookup resolves attributes like subject.role, resource.groups or
+// context.ip. Unknown names fall back to the Attributes maps.
+func (req Request) lookup(attr string) (any, bool) {
+	scope, name, _ := strings.Cut(attr, ".")
+	switch scope {
+	case "subject":
+		s := req.Subject
+		switch name {
+		case "id":
+			return s.ID, s.ID != ""
+		case "role":
+			return s.Role, s.Role != ""
+		case "tenantId":
+			return s.TenantID, s.TenantID != ""
+		case "scopes":
+			return s.Scopes, true
+		case "groups":
+			return s.Groups, true
+		case "actorId":
+			return s.ActorID, s.ActorID != ""
+		}
+		value, ok := s.Attributes[name]
+		return value, ok
+	case "resource":
+		r := req.Resource
+		switch name {
+		case "type":
+			return r.Type, r.Type != ""
+		case "id":
+			return r.ID, r.ID != ""
+		case "tenantId":
+			return r.TenantID, r.TenantID != ""
+		case "field":
+			return r.Field, r.Field != ""
+		case "groups":
+			return r.Groups, true
+		}
+		value, ok := r.Attributes[name]
+		return value, ok
+	case "context":
+		value, ok := req.Context[name]
+		return value, ok
+	}
+	return nil, false
+}
+
+// scalar compares values by their text, so the number 7 from JSON equals
+// the id "7".
+func scalar(value any) string {
+	if f, ok := value.(float64); ok && f == float64(int64(f)) {
+		return fmt.Sprint(int64(f))
+	}
+	return fmt.Sprint(value)
+}
+
+// list turns slices, including the ones decoded from BSON, into strings;
+// any other value is a list of one.
+func list(value any) []string {
+	if values, ok := value.([]string); ok {
+		return values
+	}
+	if value == nil {
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []string{scalar(value)}
+	}
+	values := make([]string, v.Len())
+	for i := range values {
+		values[i] = scalar(v.Index(i).Interface())
+	}
+	return values
+}
@@ -0,0 +1,192 @@
+// Package policy decides whether a subject may perform an action on a
+// resource, based on declarative allow and deny rules loaded from a JSON
+// file or the policies collection.
+package policy
+
+import (
+	"7-solutions/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+const (
+	SourceDefault  = "default"
+	SourceFile     = "file"
+	SourceDatabase = "database"
+)
+
+// ErrInvalidPolicy is returned for policies that cannot be evaluated.
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+// Policy allows or denies the actions it lists. Empty Roles, Resources and
+// Fields match anything, and every condition must hold. A policy with
+// Fields only applies to requests for one of those fields.
+type Policy struct {
+	ID          string `json:"id" bson:"_id"`
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+	Effect      string `json:"effect" bson:"effect"`
+	// TenantID limits the policy to subjects of one organization.
+	TenantID   string      `json:"tenantId,omitempty" bson:"tenantId,omitempty"`
+	Roles      []string    `json:"roles,omitempty" bson:"roles,omitempty"`
+	Actions    []string    `json:"actions" bson:"actions"`
+	Resources  []string    `json:"resources,omitempty" bson:"resources,omitempty"`
+	Fields     []string    `json:"fields,omitempty" bson:"fields,omitempty"`
+	Conditions []Condition `json:"conditions,omitempty" bson:"conditions,omitempty"`
+}
+
+// Condition compares the attribute Attr, e.g. subject.groups, with either
+// the literal Value or the attribute Ref.
+type Condition struct {
+	Attr  string `json:"attr" bson:"attr"`
+	Op    string `json:"op" bson:"op"`
+	Value any    `json:"value,omitempty" bson:"value,omitempty"`
+	Ref   string `json:"ref,omitempty" bson:"ref,omitempty"`
+}
+
+// Condition operators. A condition on a missing attribute never holds.
+const (
+	OpEq         = "eq"
+	OpNe         = "ne"
+	OpIn         = "in"
+	OpContains   = "contains"
+	OpIntersects = "intersects"
+)
+
+var operators = []string{OpEq, OpNe, OpIn, OpContains, OpIntersects}
+
+// DefaultPolicies allow everything, which keeps the behaviour from before
+// policies existed: routes are still guarded by roles and scopes.
+func DefaultPolicies() []Policy {
+	return []Policy{{ID: "allow-all", Effect: EffectAllow, Actions: []string{"*"}}}
+}
+
+type Config struct {
+	// Source is default, file or database.
+	Source string
+	File   string
+	// DryRun logs denials instead of enforcing them.
+	DryRun bool
+	// ReloadInterval is how often, in seconds, database policies are
+	// reloaded.
+	ReloadInterval uint64
+	// Policies holds the default or file policies.
+	Policies []Policy
+}
+
+func NewConfigFromEnv() (Config, error) {
+	config := Config{
+		Source: utils.GetEnv("POLICY_SOURCE", SourceDefault),
+		File:   utils.GetEnv("POLICY_FILE", ""),
+	}
+
+	var err error
+	if config.DryRun, err = utils.GetEnvBool("POLICY_DRY_RUN", false); err != nil {
+		return config, err
+	}
+	if config.ReloadInterval, err = utils.GetEnvUint("POLICY_RELOAD_INTERVAL_SECONDS", 30); err != nil {
+		return config, err
+	}
+
+	switch config.Source {
+	case SourceDefault:
+		config.Policies = DefaultPolicies()
+	case SourceFile:
+		if config.File == "" {
+			return config, errors.New("POLICY_FILE is required when POLICY_SOURCE is file")
+		}
+		if config.Policies, err = LoadFile(config.File); err != nil {
+			return config, err
+		}
+	case SourceDatabase:
+	default:
+		return config, fmt.Errorf("invalid POLICY_SOURCE %q, expected default, file or database", config.Source)
+	}
+	return config, nil
+}
+
+// LoadFile reads policies from a JSON document of the form
+// {"policies": [...]}.
+func LoadFile(name string) ([]Policy, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	var document struct {
+		Policies []Policy `json:"policies"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", name, err)
+	}
+	if len(document.Policies) == 0 {
+		return nil, fmt.Errorf("policy file %s has no policies", name)
+	}
+	if err := Validate(document.Policies); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return document.Policies, nil
+}
+
+// Validate checks that policies have unique ids, a known effect, at least
+// one action and well-formed conditions.
+func Validate(policies []Policy) error {
+	ids := map[string]bool{}
+	for _, p := range policies {
+		if p.ID == "" {
+			return fmt.Errorf("%w: missing id", ErrInvalidPolicy)
+		}
+		if ids[p.ID] {
+			return fmt.Errorf("%w %s: duplicate id", ErrInvalidPolicy, p.ID)
+		}
+		ids[p.ID] = true
+
+		if p.Effect != EffectAllow && p.Effect != EffectDeny {
+			return fmt.Errorf("%w %s: effect must be allow or deny", ErrInvalidPolicy, p.ID)
+		}
+		if len(p.Actions) == 0 {
+			return fmt.Errorf("%w %s: no actions", ErrInvalidPolicy, p.ID)
+		}
+		for _, pattern := range append(append([]string{}, p.Actions...), p.Resources...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%w %s: bad pattern %q", ErrInvalidPolicy, p.ID, pattern)
+			}
+		}
+		for _, c := range p.Conditions {
+			if err := c.validate(); err != nil {
+				return fmt.Errorf("%w %s: %w", ErrInvalidPolicy, p.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c Condition) validate() error {
+	if !validAttr(c.Attr) {
+		return fmt.Errorf("bad attr %q", c.Attr)
+	}
+	if !slices.Contains(operators, c.Op) {
+		return fmt.Errorf("unknown op %q", c.Op)
+	}
+	if c.Ref != "" && !validAttr(c.Ref) {
+		return fmt.Errorf("bad ref %q", c.Ref)
+	}
+	if c.Ref != "" && c.Value != nil {
+		return errors.New("condition has both value and ref")
+	}
+	return nil
+}
+
+// validAttr accepts subject.*, resource.* and context.* attribute names.
+func validAttr(attr string) bool {
+	scope, name, ok := strings.Cut(attr, ".")
+	return ok && name != "" && (scope == "subject" || scope == "resource" || scope == "context")
+}
@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"7-solutions/models"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIKeyRepository scopes management to the tenant in the context. Lookups
+// by prefix are not scoped because they authenticate the request that
+// establishes the tenant.
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	GetAllAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *mongo.Database
+}
+
+func NewAPIKeyRepository(db *mongo.Database) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	tenantID, err := tenant.ForWrite(ctx, key.TenantID)
+	if err != nil {
+		return err
+	}
+	key.TenantID = tenantID
+
+	collection := r.db.Collection("api_keys")
+	result, err := collection.InsertOne(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", utils.ContextError(err))
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		key.ID = id
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	collection := r.db.Collection("api_keys")
+	err := collection.FindOne(ctx, bson.M{"prefix": prefix}).Decode(&key)
+	if err != nil {
+		return nil, fmt.Errorf("api key not found: %w", utils.ContextError(err))
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) GetAllAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	filter, err := scopeToTenant(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	collection := r.db.Collection("api_keys")
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve api keys: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	keys := []models.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode api keys: %w", utils.ContextError(err))
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey marks the key as revoked. Revoking an already revoked key
+// keeps the original revocation time.
+func (r *apiKeyRepository) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid api key id: %w", err)
+	}
+
+	filter, err := scopeToTenant(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return err
+	}
+
+	collection := r.db.Collection("api_keys")
+	result, err := collection.UpdateOne(ctx,
+		filter,
+		bson.A{bson.M{"$set": bson.M{"revokedAt": bson.M{"$ifNull": bson.A{"$revokedAt", revokedAt}}}}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", utils.ContextError(err))
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("api key not found")
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"7-solutions/models"
+	"7-solutions/utils"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditRepository stores the audit events recorded by logging.Audit.
+type AuditRepository interface {
+	InsertAuditEvent(ctx context.Context, event *models.AuditEvent) error
+	GetUserAuditEvents(ctx context.Context, userID, email string) ([]models.AuditEvent, error)
+}
+
+type auditRepository struct {
+	db *mongo.Database
+}
+
+func NewAuditRepository(db *mongo.Database) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// InsertAuditEvent stores event in the tenant it names; events recorded
+// before a tenant is known, such as failed logins, have none.
+func (r *auditRepository) InsertAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	if _, err := r.db.Collection("audit_events").InsertOne(ctx, event); err != nil {
+		return fmt.Errorf("failed to insert audit event: %w", utils.ContextError(err))
+	}
+	return nil
+}
+
+// GetUserAuditEvents returns, oldest first, the events userID took part in
+// or that are about them, and those of the tenant naming email, such as
+// logins. User ids are unique across tenants, so they are not scoped.
+func (r *auditRepository) GetUserAuditEvents(ctx context.Context, userID, email string) ([]models.AuditEvent, error) {
+	byEmail, err := scopeToTenant(ctx, bson.M{"attributes.email": email})
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"$or": bson.A{
+		bson.M{"userId": userID},
+		bson.M{"actorId": userID},
+		bson.M{"targetUserId": userID},
+		byEmail,
+	}}
+
+	cursor, err := r.db.Collection("audit_events").Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve audit events: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.AuditEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode audit events: %w", utils.ContextError(err))
+	}
+	return events, nil
+}
@@ -3,19 +3,22 @@ package repositories
 import (
 	"7-solutions/dtos"
 	"7-solutions/models"
+	"7-solutions/tenant"
 	"7-solutions/utils"
 
 	"context"
 	"fmt"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthRepository interface {
-	RegisterUser(userDto *dtos.UserRegister) error
-	AuthenticateUser(input *dtos.UserAuthenticate) (*string, error)
+	RegisterUser(ctx context.Context, userDto *dtos.UserRegister) error
+	AuthenticateUser(ctx context.Context, input *dtos.UserAuthenticate) (*models.User, error)
 }
 
 type authRepository struct {
@@ -28,49 +31,56 @@ func NewAuthRepository(db *mongo.Database) AuthRepository {
 	}
 }
 
-func (r *authRepository) RegisterUser(userDto *dtos.UserRegister) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(userDto.Password), bcrypt.DefaultCost)
+// RegisterUser stores userDto as is; the password must already be hashed.
+// The user joins the tenant of ctx.
+func (r *authRepository) RegisterUser(ctx context.Context, userDto *dtos.UserRegister) error {
+	tenantID, err := tenant.ForWrite(ctx, userDto.TenantID)
 	if err != nil {
-		return fmt.Errorf("failed to hash password: %w", err)
+		return err
 	}
-	userDto.Password = string(hashedPassword)
-	newID, err := GetNextSequence(r.db, "users")
+
+	newID, err := GetNextSequence(ctx, r.db, "users")
 	if err != nil {
-		return fmt.Errorf("failed to get new user ID: %w", err)
+		return fmt.Errorf("failed to get new user ID: %w", utils.ContextError(err))
 	}
 	user := &models.User{
 		ID:        newID,
 		Name:      userDto.Name,
 		Email:     userDto.Email,
 		Password:  userDto.Password,
+		Role:      models.RoleUser,
+		TenantID:  tenantID,
 		CreatedAt: time.Now(),
 	}
 	collection := r.db.Collection("users")
-	_, err = collection.InsertOne(context.Background(), user)
+	_, err = collection.InsertOne(ctx, user)
 	if err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
+		return fmt.Errorf("failed to create user: %w", utils.ContextError(err))
 	}
 	return nil
 }
 
-func (r *authRepository) AuthenticateUser(input *dtos.UserAuthenticate) (*string, error) {
-	var user models.User
+// AuthenticateUser returns the user whose email and password match input.
+// It looks the email up case-insensitively, like the unique email index, in
+// the tenant of ctx only, since the same address may be registered in
+// several organizations.
+func (r *authRepository) AuthenticateUser(ctx context.Context, input *dtos.UserAuthenticate) (*models.User, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"email": input.Email})
+	if err != nil {
+		return nil, err
+	}
 
+	var user models.User
 	collection := r.db.Collection("users")
-	filter := map[string]interface{}{"email": input.Email}
-	err := collection.FindOne(nil, filter).Decode(&user)
+	opts := options.FindOne().SetCollation(emailCollation)
+	err = collection.FindOne(ctx, filter, opts).Decode(&user)
 	if err != nil {
-		return nil, fmt.Errorf("user not found: %w", err)
+		return nil, fmt.Errorf("user not found: %w", utils.ContextError(err))
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
 		return nil, fmt.Errorf("authentication failed: %w", err)
 	}
 
-	token, err := utils.GenerateToken(user.Name, user.Email)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
-	}
-
-	return &token, nil
+	return &user, nil
 }
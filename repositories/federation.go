@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"7-solutions/models"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FederationRepository stores pending external logins and linked identities.
+// Like AuthRepository it reads and writes users directly, because federated
+// users are found by email and created without a password. User queries are
+// scoped to the tenant in the context.
+type FederationRepository interface {
+	CreateState(ctx context.Context, state *models.FederationState) error
+	ConsumeState(ctx context.Context, stateHash string) (*models.FederationState, error)
+	FindIdentity(ctx context.Context, provider, subject string) (*models.Identity, error)
+	GetIdentitiesByUserID(ctx context.Context, userID int) ([]models.Identity, error)
+	CreateIdentity(ctx context.Context, identity *models.Identity) error
+	DeleteIdentity(ctx context.Context, userID int, id string) error
+	GetUserByID(ctx context.Context, id int) (*models.User, error)
+	FindUserByEmail(ctx context.Context, email string) (*models.User, error)
+	CreateUser(ctx context.Context, user *models.User) error
+	VerifyUserEmail(ctx context.Context, id int, clearPassword bool) error
+}
+
+type federationRepository struct {
+	db *mongo.Database
+}
+
+func NewFederationRepository(db *mongo.Database) FederationRepository {
+	return &federationRepository{db: db}
+}
+
+func (r *federationRepository) CreateState(ctx context.Context, state *models.FederationState) error {
+	collection := r.db.Collection("federation_states")
+	_, err := collection.InsertOne(ctx, state)
+	if err != nil {
+		return fmt.Errorf("failed to create federation state: %w", utils.ContextError(err))
+	}
+	return nil
+}
+
+// ConsumeState deletes and returns the state so each one is used once.
+func (r *federationRepository) ConsumeState(ctx context.Context, stateHash string) (*models.FederationState, error) {
+	var state models.FederationState
+	collection := r.db.Collection("federation_states")
+	err := collection.FindOneAndDelete(ctx, bson.M{"_id": stateHash}).Decode(&state)
+	if err != nil {
+		return nil, fmt.Errorf("federation state not found: %w", utils.ContextError(err))
+	}
+	return &state, nil
+}
+
+// FindIdentity returns nil without an error when no identity is linked.
+func (r *federationRepository) FindIdentity(ctx context.Context, provider, subject string) (*models.Identity, error) {
+	var identity models.Identity
+	collection := r.db.Collection("identities")
+	err := collection.FindOne(ctx, bson.M{"provider": provider, "subject": subject}).Decode(&identity)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identity: %w", utils.ContextError(err))
+	}
+	return &identity, nil
+}
+
+func (r *federationRepository) GetIdentitiesByUserID(ctx context.Context, userID int) ([]models.Identity, error) {
+	collection := r.db.Collection("identities")
+	cursor, err := collection.Find(ctx, bson.M{"userId": userID}, options.Find().SetSort(bson.M{"linkedAt": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve identities: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	identities := []models.Identity{}
+	if err := cursor.All(ctx, &identities); err != nil {
+		return nil, fmt.Errorf("failed to decode identities: %w", utils.ContextError(err))
+	}
+	return identities, nil
+}
+
+func (r *federationRepository) CreateIdentity(ctx context.Context, identity *models.Identity) error {
+	collection := r.db.Collection("identities")
+	result, err := collection.InsertOne(ctx, identity)
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", utils.ContextError(err))
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		identity.ID = id
+	}
+	return nil
+}
+
+// DeleteIdentity only deletes identities linked to userID.
+func (r *federationRepository) DeleteIdentity(ctx context.Context, userID int, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid identity id: %w", err)
+	}
+
+	collection := r.db.Collection("identities")
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": objectID, "userId": userID})
+	if err != nil {
+		return fmt.Errorf("failed to unlink identity: %w", utils.ContextError(err))
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("identity not found")
+	}
+	return nil
+}
+
+func (r *federationRepository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"id": id})
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	collection := r.db.Collection("users")
+	err = collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", utils.ContextError(err))
+	}
+	return &user, nil
+}
+
+// FindUserByEmail matches email case-insensitively and returns nil without
+// an error when there is no such user.
+func (r *federationRepository) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"email": email})
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	collection := r.db.Collection("users")
+	opts := options.FindOne().SetCollation(emailCollation)
+	err = collection.FindOne(ctx, filter, opts).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", utils.ContextError(err))
+	}
+	return &user, nil
+}
+
+// CreateUser assigns the next user id and the tenant of ctx, then stores
+// user.
+func (r *federationRepository) CreateUser(ctx context.Context, user *models.User) error {
+	tenantID, err := tenant.ForWrite(ctx, user.TenantID)
+	if err != nil {
+		return err
+	}
+	newID, err := GetNextSequence(ctx, r.db, "users")
+	if err != nil {
+		return fmt.Errorf("failed to get new user ID: %w", utils.ContextError(err))
+	}
+	user.ID = newID
+	user.TenantID = tenantID
+
+	collection := r.db.Collection("users")
+	_, err = collection.InsertOne(ctx, user)
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", utils.ContextError(err))
+	}
+	return nil
+}
+
+// VerifyUserEmail marks the user's email as verified and, with
+// clearPassword, removes their password so it can no longer be used to sign
+// in.
+func (r *federationRepository) VerifyUserEmail(ctx context.Context, id int, clearPassword bool) error {
+	filter, err := scopeToTenant(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	set := bson.M{"emailVerified": true}
+	if clearPassword {
+		set["password"] = ""
+	}
+
+	collection := r.db.Collection("users")
+	result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return fmt.Errorf("failed to verify user email: %w", utils.ContextError(err))
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package repositories
+
+import (
+	"7-solutions/models"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrGroupNotFound  = errors.New("group not found")
+	ErrGroupExists    = errors.New("a group with this name already exists")
+	ErrAlreadyMember  = errors.New("user is already a member of the group")
+	ErrMemberNotFound = errors.New("user is not a member of the group")
+)
+
+// GroupRepository stores groups and their direct members. Like
+// UserRepository it scopes every query to the tenant in the context.
+type GroupRepository interface {
+	CreateGroup(ctx context.Context, group *models.Group) error
+	GetGroup(ctx context.Context, id string) (*models.Group, error)
+	GetGroupsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Group, error)
+	GetAllGroups(ctx context.Context) ([]models.Group, error)
+	UpdateGroup(ctx context.Context, group *models.Group) error
+	DeleteGroup(ctx context.Context, id primitive.ObjectID) error
+	CountChildGroups(ctx context.Context, id primitive.ObjectID) (int64, error)
+	GetChildGroupIDs(ctx context.Context, parentIDs []primitive.ObjectID) ([]primitive.ObjectID, error)
+	AddMember(ctx context.Context, member *models.GroupMember) error
+	RemoveMember(ctx context.Context, groupID primitive.ObjectID, userID int) error
+	GetMembers(ctx context.Context, groupID primitive.ObjectID, skip, limit int64) ([]models.GroupMember, int64, error)
+	GetMembershipsByUserID(ctx context.Context, userID int) ([]models.GroupMember, error)
+	GetUsersByIDs(ctx context.Context, ids []int) ([]models.User, error)
+}
+
+type groupRepository struct {
+	db *mongo.Database
+}
+
+func NewGroupRepository(db *mongo.Database) GroupRepository {
+	return &groupRepository{db: db}
+}
+
+func (r *groupRepository) CreateGroup(ctx context.Context, group *models.Group) error {
+	tenantID, err := tenant.ForWrite(ctx, group.TenantID)
+	if err != nil {
+		return err
+	}
+	group.TenantID = tenantID
+
+	collection := r.db.Collection("groups")
+	result, err := collection.InsertOne(ctx, group)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrGroupExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create group: %w", utils.ContextError(err))
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		group.ID = id
+	}
+	return nil
+}
+
+// GetGroup returns ErrGroupNotFound for malformed ids too, so callers need
+// not tell them apart.
+func (r *groupRepository) GetGroup(ctx context.Context, id string) (*models.Group, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrGroupNotFound
+	}
+	filter, err := scopeToTenant(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return nil, err
+	}
+
+	var group models.Group
+	collection := r.db.Collection("groups")
+	err = collection.FindOne(ctx, filter).Decode(&group)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve group: %w", utils.ContextError(err))
+	}
+	return &group, nil
+}
+
+func (r *groupRepository) GetGroupsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Group, error) {
+	return r.findGroups(ctx, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+func (r *groupRepository) GetAllGroups(ctx context.Context) ([]models.Group, error) {
+	return r.findGroups(ctx, bson.M{})
+}
+
+func (r *groupRepository) findGroups(ctx context.Context, filter bson.M) ([]models.Group, error) {
+	filter, err := scopeToTenant(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := r.db.Collection("groups")
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve groups: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	groups := []models.Group{}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("failed to decode groups: %w", utils.ContextError(err))
+	}
+	return groups, nil
+}
+
+// UpdateGroup saves the name, description and parent of group.
+func (r *groupRepository) UpdateGroup(ctx context.Context, group *models.Group) error {
+	filter, err := scopeToTenant(ctx, bson.M{"_id": group.ID})
+	if err != nil {
+		return err
+	}
+
+	collection := r.db.Collection("groups")
+	result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+		"name":        group.Name,
+		"description": group.Description,
+		"parentId":    group.ParentID,
+		"updatedAt":   group.UpdatedAt,
+	}})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrGroupExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update group: %w", utils.ContextError(err))
+	}
+	if result.MatchedCount == 0 {
+		return ErrGroupNotFound
+	}
+	return nil
+}
+
+// DeleteGroup deletes the group and its memberships.
+func (r *groupRepository) DeleteGroup(ctx context.Context, id primitive.ObjectID) error {
+	filter, err := scopeToTenant(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Collection("groups").DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to delete group: %w", utils.ContextError(err))
+	}
+	if result.DeletedCount == 0 {
+		return ErrGroupNotFound
+	}
+
+	members, err := scopeToTenant(ctx, bson.M{"groupId": id})
+	if err != nil {
+		return err
+	}
+	if _, err := r.db.Collection("group_members").DeleteMany(ctx, members); err != nil {
+		return fmt.Errorf("failed to delete group members: %w", utils.ContextError(err))
+	}
+	return nil
+}
+
+func (r *groupRepository) CountChildGroups(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"parentId": id})
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := r.db.Collection("groups").CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count child groups: %w", utils.ContextError(err))
+	}
+	return count, nil
+}
+
+// GetChildGroupIDs returns the ids of the groups directly below any of
+// parentIDs.
+func (r *groupRepository) GetChildGroupIDs(ctx context.Context, parentIDs []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"parentId": bson.M{"$in": parentIDs}})
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.db.Collection("groups").Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve child groups: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	groups := []models.Group{}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("failed to decode child groups: %w", utils.ContextError(err))
+	}
+	ids := make([]primitive.ObjectID, len(groups))
+	for i, group := range groups {
+		ids[i] = group.ID
+	}
+	return ids, nil
+}
+
+func (r *groupRepository) AddMember(ctx context.Context, member *models.GroupMember) error {
+	tenantID, err := tenant.ForWrite(ctx, member.TenantID)
+	if err != nil {
+		return err
+	}
+	member.TenantID = tenantID
+
+	result, err := r.db.Collection("group_members").InsertOne(ctx, member)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAlreadyMember
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add group member: %w", utils.ContextError(err))
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		member.ID = id
+	}
+	return nil
+}
+
+func (r *groupRepository) RemoveMember(ctx context.Context, groupID primitive.ObjectID, userID int) error {
+	filter, err := scopeToTenant(ctx, bson.M{"groupId": groupID, "userId": userID})
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Collection("group_members").DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to remove group member: %w", utils.ContextError(err))
+	}
+	if result.DeletedCount == 0 {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+// GetMembers returns one page of the group's direct members, oldest first,
+// and the total number of members.
+func (r *groupRepository) GetMembers(ctx context.Context, groupID primitive.ObjectID, skip, limit int64) ([]models.GroupMember, int64, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"groupId": groupID})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	collection := r.db.Collection("group_members")
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count group members: %w", utils.ContextError(err))
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "addedAt", Value: 1}, {Key: "_id", Value: 1}}).SetSkip(skip).SetLimit(limit)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve group members: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	members := []models.GroupMember{}
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode group members: %w", utils.ContextError(err))
+	}
+	return members, total, nil
+}
+
+func (r *groupRepository) GetMembershipsByUserID(ctx context.Context, userID int) ([]models.GroupMember, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.db.Collection("group_members").Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve memberships: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	members := []models.GroupMember{}
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, fmt.Errorf("failed to decode memberships: %w", utils.ContextError(err))
+	}
+	return members, nil
+}
+
+// GetUsersByIDs returns the users of the tenant with the given ids, in no
+// particular order. Missing users are skipped.
+func (r *groupRepository) GetUsersByIDs(ctx context.Context, ids []int) ([]models.User, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetProjection(bson.M{"password": 0})
+	cursor, err := r.db.Collection("users").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve users: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	users := []models.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %w", utils.ContextError(err))
+	}
+	return users, nil
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"7-solutions/utils"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	HasIndex(ctx context.Context, collectionName, indexName string) (bool, error)
+}
+
+type healthRepository struct {
+	db *mongo.Database
+}
+
+func NewHealthRepository(db *mongo.Database) HealthRepository {
+	return &healthRepository{db: db}
+}
+
+func (r *healthRepository) Ping(ctx context.Context) error {
+	err := r.db.Client().Ping(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to ping database: %w", utils.ContextError(err))
+	}
+	return nil
+}
+
+func (r *healthRepository) HasIndex(ctx context.Context, collectionName, indexName string) (bool, error) {
+	cursor, err := r.db.Collection(collectionName).Indexes().List(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to list indexes: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var index bson.M
+		if err := cursor.Decode(&index); err != nil {
+			return false, fmt.Errorf("failed to decode index: %w", utils.ContextError(err))
+		}
+		if index["name"] == indexName {
+			return true, nil
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return false, fmt.Errorf("cursor error: %w", utils.ContextError(err))
+	}
+	return false, nil
+}
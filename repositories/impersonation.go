@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"7-solutions/models"
+	"7-solutions/utils"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ImpersonationRepository looks up the users admins may act as, within the
+// tenant of the context.
+type ImpersonationRepository interface {
+	GetUserByID(ctx context.Context, id int) (*models.User, error)
+}
+
+type impersonationRepository struct {
+	db *mongo.Database
+}
+
+func NewImpersonationRepository(db *mongo.Database) ImpersonationRepository {
+	return &impersonationRepository{db: db}
+}
+
+func (r *impersonationRepository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"id": id})
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	err = r.db.Collection("users").FindOne(ctx, filter).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user: %w", utils.ContextError(err))
+	}
+	return &user, nil
+}
@@ -0,0 +1,261 @@
+package repositories
+
+import (
+	"7-solutions/models"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrInvitationNotFound is also returned for invitations that can no
+	// longer change because they were accepted, revoked or have expired.
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrEmailTaken         = errors.New("a user with this email already exists")
+)
+
+// InvitationRepository stores invitations and, like FederationRepository,
+// creates the users that accept them. Queries are scoped to the tenant in
+// the context, except ConsumeInvitation: the token establishes the tenant.
+type InvitationRepository interface {
+	CreateInvitation(ctx context.Context, invitation *models.Invitation) error
+	GetInvitation(ctx context.Context, id string) (*models.Invitation, error)
+	FindPendingInvitation(ctx context.Context, email string, now time.Time) (*models.Invitation, error)
+	GetPendingInvitations(ctx context.Context, now time.Time) ([]models.Invitation, error)
+	RenewInvitation(ctx context.Context, id primitive.ObjectID, tokenHash string, expiresAt time.Time) error
+	RevokeInvitation(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) error
+	ConsumeInvitation(ctx context.Context, tokenHash string, now time.Time) (*models.Invitation, error)
+	ReleaseInvitation(ctx context.Context, id primitive.ObjectID) error
+	FindUserByEmail(ctx context.Context, email string) (*models.User, error)
+	CreateUser(ctx context.Context, user *models.User) error
+}
+
+type invitationRepository struct {
+	db *mongo.Database
+}
+
+func NewInvitationRepository(db *mongo.Database) InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+// emailCollation compares emails case-insensitively, like the email indexes.
+var emailCollation = utils.EmailCollation
+
+// pendingFilter matches invitations that can still be accepted.
+func pendingFilter(now time.Time) bson.M {
+	return bson.M{
+		"acceptedAt": bson.M{"$exists": false},
+		"revokedAt":  bson.M{"$exists": false},
+		"expiresAt":  bson.M{"$gt": now},
+	}
+}
+
+func (r *invitationRepository) CreateInvitation(ctx context.Context, invitation *models.Invitation) error {
+	tenantID, err := tenant.ForWrite(ctx, invitation.TenantID)
+	if err != nil {
+		return err
+	}
+	invitation.TenantID = tenantID
+
+	result, err := r.db.Collection("invitations").InsertOne(ctx, invitation)
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", utils.ContextError(err))
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		invitation.ID = id
+	}
+	return nil
+}
+
+func (r *invitationRepository) GetInvitation(ctx context.Context, id string) (*models.Invitation, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvitationNotFound
+	}
+	filter, err := scopeToTenant(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return nil, err
+	}
+
+	var invitation models.Invitation
+	err = r.db.Collection("invitations").FindOne(ctx, filter).Decode(&invitation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve invitation: %w", utils.ContextError(err))
+	}
+	return &invitation, nil
+}
+
+// FindPendingInvitation returns nil without an error when email has no
+// pending invitation.
+func (r *invitationRepository) FindPendingInvitation(ctx context.Context, email string, now time.Time) (*models.Invitation, error) {
+	query := pendingFilter(now)
+	query["email"] = email
+	filter, err := scopeToTenant(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var invitation models.Invitation
+	opts := options.FindOne().SetCollation(emailCollation)
+	err = r.db.Collection("invitations").FindOne(ctx, filter, opts).Decode(&invitation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find invitation: %w", utils.ContextError(err))
+	}
+	return &invitation, nil
+}
+
+func (r *invitationRepository) GetPendingInvitations(ctx context.Context, now time.Time) ([]models.Invitation, error) {
+	filter, err := scopeToTenant(ctx, pendingFilter(now))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"createdAt": -1})
+	cursor, err := r.db.Collection("invitations").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve invitations: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	invitations := []models.Invitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, fmt.Errorf("failed to decode invitations: %w", utils.ContextError(err))
+	}
+	return invitations, nil
+}
+
+// RenewInvitation replaces the token of an invitation that was neither
+// accepted nor revoked, which also revives it if it had expired.
+func (r *invitationRepository) RenewInvitation(ctx context.Context, id primitive.ObjectID, tokenHash string, expiresAt time.Time) error {
+	filter, err := scopeToTenant(ctx, bson.M{
+		"_id":        id,
+		"acceptedAt": bson.M{"$exists": false},
+		"revokedAt":  bson.M{"$exists": false},
+	})
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{"tokenHash": tokenHash, "expiresAt": expiresAt}}
+	result, err := r.db.Collection("invitations").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to renew invitation: %w", utils.ContextError(err))
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+func (r *invitationRepository) RevokeInvitation(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) error {
+	filter, err := scopeToTenant(ctx, bson.M{
+		"_id":        id,
+		"acceptedAt": bson.M{"$exists": false},
+		"revokedAt":  bson.M{"$exists": false},
+	})
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{"revokedAt": revokedAt}}
+	result, err := r.db.Collection("invitations").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", utils.ContextError(err))
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// ConsumeInvitation marks the pending invitation with tokenHash accepted
+// and returns it, so each token is used once.
+func (r *invitationRepository) ConsumeInvitation(ctx context.Context, tokenHash string, now time.Time) (*models.Invitation, error) {
+	filter := pendingFilter(now)
+	filter["tokenHash"] = tokenHash
+
+	var invitation models.Invitation
+	update := bson.M{"$set": bson.M{"acceptedAt": now}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.db.Collection("invitations").FindOneAndUpdate(ctx, filter, update, opts).Decode(&invitation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept invitation: %w", utils.ContextError(err))
+	}
+	return &invitation, nil
+}
+
+// ReleaseInvitation makes a consumed invitation pending again after the
+// account could not be created.
+func (r *invitationRepository) ReleaseInvitation(ctx context.Context, id primitive.ObjectID) error {
+	filter, err := scopeToTenant(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Collection("invitations").UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"acceptedAt": ""}})
+	if err != nil {
+		return fmt.Errorf("failed to release invitation: %w", utils.ContextError(err))
+	}
+	return nil
+}
+
+// FindUserByEmail matches email case-insensitively and returns nil without
+// an error when there is no such user.
+func (r *invitationRepository) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"email": email})
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	opts := options.FindOne().SetCollation(emailCollation)
+	err = r.db.Collection("users").FindOne(ctx, filter, opts).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", utils.ContextError(err))
+	}
+	return &user, nil
+}
+
+// CreateUser assigns the next user id and the tenant of ctx, then stores
+// user.
+func (r *invitationRepository) CreateUser(ctx context.Context, user *models.User) error {
+	tenantID, err := tenant.ForWrite(ctx, user.TenantID)
+	if err != nil {
+		return err
+	}
+	newID, err := GetNextSequence(ctx, r.db, "users")
+	if err != nil {
+		return fmt.Errorf("failed to get new user ID: %w", utils.ContextError(err))
+	}
+	user.ID = newID
+	user.TenantID = tenantID
+
+	_, err = r.db.Collection("users").InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", utils.ContextError(err))
+	}
+	return nil
+}
@@ -0,0 +1,886 @@
+package repositories
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/metrics"
+	"7-solutions/models"
+	"7-solutions/policy"
+	"context"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WithUserMetrics wraps a UserRepository so every call is recorded in the
+// db_operation_duration_seconds histogram.
+func WithUserMetrics(next UserRepository) UserRepository {
+	return &userRepositoryMetrics{next: next}
+}
+
+type userRepositoryMetrics struct {
+	next UserRepository
+}
+
+func (r *userRepositoryMetrics) CreateUser(ctx context.Context, userDto *dtos.UserRegister) (*dtos.UserResponse, error) {
+	start := time.Now()
+	user, err := r.next.CreateUser(ctx, userDto)
+	metrics.ObserveDBOperation("user", "CreateUser", start, err)
+	return user, err
+}
+
+func (r *userRepositoryMetrics) GetUserByID(ctx context.Context, id int) (*dtos.UserResponse, error) {
+	start := time.Now()
+	user, err := r.next.GetUserByID(ctx, id)
+	metrics.ObserveDBOperation("user", "GetUserByID", start, err)
+	return user, err
+}
+
+func (r *userRepositoryMetrics) GetAllUsers(ctx context.Context, filter *dtos.UserFilter) ([]dtos.UserResponse, error) {
+	start := time.Now()
+	users, err := r.next.GetAllUsers(ctx, filter)
+	metrics.ObserveDBOperation("user", "GetAllUsers", start, err)
+	return users, err
+}
+
+func (r *userRepositoryMetrics) UpdateUser(ctx context.Context, id int, userDto *dtos.UserUpdate) (*dtos.UserResponse, error) {
+	start := time.Now()
+	user, err := r.next.UpdateUser(ctx, id, userDto)
+	metrics.ObserveDBOperation("user", "UpdateUser", start, err)
+	return user, err
+}
+
+func (r *userRepositoryMetrics) DeleteUser(ctx context.Context, id int) error {
+	start := time.Now()
+	err := r.next.DeleteUser(ctx, id)
+	metrics.ObserveDBOperation("user", "DeleteUser", start, err)
+	return err
+}
+
+func (r *userRepositoryMetrics) CountUsers(ctx context.Context) (int64, error) {
+	start := time.Now()
+	count, err := r.next.CountUsers(ctx)
+	metrics.ObserveDBOperation("user", "CountUsers", start, err)
+	return count, err
+}
+
+// WithAuthMetrics wraps an AuthRepository so every call is recorded in the
+// db_operation_duration_seconds histogram.
+func WithAuthMetrics(next AuthRepository) AuthRepository {
+	return &authRepositoryMetrics{next: next}
+}
+
+type authRepositoryMetrics struct {
+	next AuthRepository
+}
+
+func (r *authRepositoryMetrics) RegisterUser(ctx context.Context, userDto *dtos.UserRegister) error {
+	start := time.Now()
+	err := r.next.RegisterUser(ctx, userDto)
+	metrics.ObserveDBOperation("auth", "RegisterUser", start, err)
+	return err
+}
+
+func (r *authRepositoryMetrics) AuthenticateUser(ctx context.Context, input *dtos.UserAuthenticate) (*models.User, error) {
+	start := time.Now()
+	user, err := r.next.AuthenticateUser(ctx, input)
+	metrics.ObserveDBOperation("auth", "AuthenticateUser", start, err)
+	return user, err
+}
+
+// WithAPIKeyMetrics wraps an APIKeyRepository so every call is recorded in
+// the db_operation_duration_seconds histogram.
+func WithAPIKeyMetrics(next APIKeyRepository) APIKeyRepository {
+	return &apiKeyRepositoryMetrics{next: next}
+}
+
+type apiKeyRepositoryMetrics struct {
+	next APIKeyRepository
+}
+
+func (r *apiKeyRepositoryMetrics) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	start := time.Now()
+	err := r.next.CreateAPIKey(ctx, key)
+	metrics.ObserveDBOperation("api_key", "CreateAPIKey", start, err)
+	return err
+}
+
+func (r *apiKeyRepositoryMetrics) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	start := time.Now()
+	key, err := r.next.GetAPIKeyByPrefix(ctx, prefix)
+	metrics.ObserveDBOperation("api_key", "GetAPIKeyByPrefix", start, err)
+	return key, err
+}
+
+func (r *apiKeyRepositoryMetrics) GetAllAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	start := time.Now()
+	keys, err := r.next.GetAllAPIKeys(ctx)
+	metrics.ObserveDBOperation("api_key", "GetAllAPIKeys", start, err)
+	return keys, err
+}
+
+func (r *apiKeyRepositoryMetrics) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error {
+	start := time.Now()
+	err := r.next.RevokeAPIKey(ctx, id, revokedAt)
+	metrics.ObserveDBOperation("api_key", "RevokeAPIKey", start, err)
+	return err
+}
+
+// WithOAuthMetrics wraps an OAuthRepository so every call is recorded in the
+// db_operation_duration_seconds histogram.
+func WithOAuthMetrics(next OAuthRepository) OAuthRepository {
+	return &oauthRepositoryMetrics{next: next}
+}
+
+type oauthRepositoryMetrics struct {
+	next OAuthRepository
+}
+
+func (r *oauthRepositoryMetrics) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	start := time.Now()
+	err := r.next.CreateClient(ctx, client)
+	metrics.ObserveDBOperation("oauth", "CreateClient", start, err)
+	return err
+}
+
+func (r *oauthRepositoryMetrics) GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	start := time.Now()
+	client, err := r.next.GetClientByClientID(ctx, clientID)
+	metrics.ObserveDBOperation("oauth", "GetClientByClientID", start, err)
+	return client, err
+}
+
+func (r *oauthRepositoryMetrics) GetAllClients(ctx context.Context) ([]models.OAuthClient, error) {
+	start := time.Now()
+	clients, err := r.next.GetAllClients(ctx)
+	metrics.ObserveDBOperation("oauth", "GetAllClients", start, err)
+	return clients, err
+}
+
+func (r *oauthRepositoryMetrics) CreateAuthorizationCode(ctx context.Context, code *models.AuthorizationCode) error {
+	start := time.Now()
+	err := r.next.CreateAuthorizationCode(ctx, code)
+	metrics.ObserveDBOperation("oauth", "CreateAuthorizationCode", start, err)
+	return err
+}
+
+func (r *oauthRepositoryMetrics) ConsumeAuthorizationCode(ctx context.Context, codeHash string, usedAt time.Time) (*models.AuthorizationCode, error) {
+	start := time.Now()
+	code, err := r.next.ConsumeAuthorizationCode(ctx, codeHash, usedAt)
+	metrics.ObserveDBOperation("oauth", "ConsumeAuthorizationCode", start, err)
+	return code, err
+}
+
+func (r *oauthRepositoryMetrics) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	start := time.Now()
+	err := r.next.RevokeToken(ctx, jti, expiresAt)
+	metrics.ObserveDBOperation("oauth", "RevokeToken", start, err)
+	return err
+}
+
+func (r *oauthRepositoryMetrics) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	start := time.Now()
+	revoked, err := r.next.IsTokenRevoked(ctx, jti)
+	metrics.ObserveDBOperation("oauth", "IsTokenRevoked", start, err)
+	return revoked, err
+}
+
+// WithFederationMetrics wraps a FederationRepository so every call is
+// recorded in the db_operation_duration_seconds histogram.
+func WithFederationMetrics(next FederationRepository) FederationRepository {
+	return &federationRepositoryMetrics{next: next}
+}
+
+type federationRepositoryMetrics struct {
+	next FederationRepository
+}
+
+func (r *federationRepositoryMetrics) CreateState(ctx context.Context, state *models.FederationState) error {
+	start := time.Now()
+	err := r.next.CreateState(ctx, state)
+	metrics.ObserveDBOperation("federation", "CreateState", start, err)
+	return err
+}
+
+func (r *federationRepositoryMetrics) ConsumeState(ctx context.Context, stateHash string) (*models.FederationState, error) {
+	start := time.Now()
+	state, err := r.next.ConsumeState(ctx, stateHash)
+	metrics.ObserveDBOperation("federation", "ConsumeState", start, err)
+	return state, err
+}
+
+func (r *federationRepositoryMetrics) FindIdentity(ctx context.Context, provider, subject string) (*models.Identity, error) {
+	start := time.Now()
+	identity, err := r.next.FindIdentity(ctx, provider, subject)
+	metrics.ObserveDBOperation("federation", "FindIdentity", start, err)
+	return identity, err
+}
+
+func (r *federationRepositoryMetrics) GetIdentitiesByUserID(ctx context.Context, userID int) ([]models.Identity, error) {
+	start := time.Now()
+	identities, err := r.next.GetIdentitiesByUserID(ctx, userID)
+	metrics.ObserveDBOperation("federation", "GetIdentitiesByUserID", start, err)
+	return identities, err
+}
+
+func (r *federationRepositoryMetrics) CreateIdentity(ctx context.Context, identity *models.Identity) error {
+	start := time.Now()
+	err := r.next.CreateIdentity(ctx, identity)
+	metrics.ObserveDBOperation("federation", "CreateIdentity", start, err)
+	return err
+}
+
+func (r *federationRepositoryMetrics) DeleteIdentity(ctx context.Context, userID int, id string) error {
+	start := time.Now()
+	err := r.next.DeleteIdentity(ctx, userID, id)
+	metrics.ObserveDBOperation("federation", "DeleteIdentity", start, err)
+	return err
+}
+
+func (r *federationRepositoryMetrics) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	start := time.Now()
+	user, err := r.next.GetUserByID(ctx, id)
+	metrics.ObserveDBOperation("federation", "GetUserByID", start, err)
+	return user, err
+}
+
+func (r *federationRepositoryMetrics) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	start := time.Now()
+	user, err := r.next.FindUserByEmail(ctx, email)
+	metrics.ObserveDBOperation("federation", "FindUserByEmail", start, err)
+	return user, err
+}
+
+func (r *federationRepositoryMetrics) CreateUser(ctx context.Context, user *models.User) error {
+	start := time.Now()
+	err := r.next.CreateUser(ctx, user)
+	metrics.ObserveDBOperation("federation", "CreateUser", start, err)
+	return err
+}
+
+func (r *federationRepositoryMetrics) VerifyUserEmail(ctx context.Context, id int, clearPassword bool) error {
+	start := time.Now()
+	err := r.next.VerifyUserEmail(ctx, id, clearPassword)
+	metrics.ObserveDBOperation("federation", "VerifyUserEmail", start, err)
+	return err
+}
+
+// WithOrganizationMetrics wraps an OrganizationRepository so every call is
+// recorded in the db_operation_duration_seconds histogram.
+func WithOrganizationMetrics(next OrganizationRepository) OrganizationRepository {
+	return &organizationRepositoryMetrics{next: next}
+}
+
+type organizationRepositoryMetrics struct {
+	next OrganizationRepository
+}
+
+func (r *organizationRepositoryMetrics) CreateOrganization(ctx context.Context, organization *models.Organization) error {
+	start := time.Now()
+	err := r.next.CreateOrganization(ctx, organization)
+	metrics.ObserveDBOperation("organization", "CreateOrganization", start, err)
+	return err
+}
+
+func (r *organizationRepositoryMetrics) GetOrganization(ctx context.Context, id string) (*models.Organization, error) {
+	start := time.Now()
+	organization, err := r.next.GetOrganization(ctx, id)
+	metrics.ObserveDBOperation("organization", "GetOrganization", start, err)
+	return organization, err
+}
+
+func (r *organizationRepositoryMetrics) GetAllOrganizations(ctx context.Context) ([]models.Organization, error) {
+	start := time.Now()
+	organizations, err := r.next.GetAllOrganizations(ctx)
+	metrics.ObserveDBOperation("organization", "GetAllOrganizations", start, err)
+	return organizations, err
+}
+
+// WithGroupMetrics wraps a GroupRepository so every call is recorded in the
+// db_operation_duration_seconds histogram.
+func WithGroupMetrics(next GroupRepository) GroupRepository {
+	return &groupRepositoryMetrics{next: next}
+}
+
+type groupRepositoryMetrics struct {
+	next GroupRepository
+}
+
+func (r *groupRepositoryMetrics) CreateGroup(ctx context.Context, group *models.Group) error {
+	start := time.Now()
+	err := r.next.CreateGroup(ctx, group)
+	metrics.ObserveDBOperation("group", "CreateGroup", start, err)
+	return err
+}
+
+func (r *groupRepositoryMetrics) GetGroup(ctx context.Context, id string) (*models.Group, error) {
+	start := time.Now()
+	group, err := r.next.GetGroup(ctx, id)
+	metrics.ObserveDBOperation("group", "GetGroup", start, err)
+	return group, err
+}
+
+func (r *groupRepositoryMetrics) GetGroupsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Group, error) {
+	start := time.Now()
+	groups, err := r.next.GetGroupsByIDs(ctx, ids)
+	metrics.ObserveDBOperation("group", "GetGroupsByIDs", start, err)
+	return groups, err
+}
+
+func (r *groupRepositoryMetrics) GetAllGroups(ctx context.Context) ([]models.Group, error) {
+	start := time.Now()
+	groups, err := r.next.GetAllGroups(ctx)
+	metrics.ObserveDBOperation("group", "GetAllGroups", start, err)
+	return groups, err
+}
+
+func (r *groupRepositoryMetrics) UpdateGroup(ctx context.Context, group *models.Group) error {
+	start := time.Now()
+	err := r.next.UpdateGroup(ctx, group)
+	metrics.ObserveDBOperation("group", "UpdateGroup", start, err)
+	return err
+}
+
+func (r *groupRepositoryMetrics) DeleteGroup(ctx context.Context, id primitive.ObjectID) error {
+	start := time.Now()
+	err := r.next.DeleteGroup(ctx, id)
+	metrics.ObserveDBOperation("group", "DeleteGroup", start, err)
+	return err
+}
+
+func (r *groupRepositoryMetrics) CountChildGroups(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	start := time.Now()
+	count, err := r.next.CountChildGroups(ctx, id)
+	metrics.ObserveDBOperation("group", "CountChildGroups", start, err)
+	return count, err
+}
+
+func (r *groupRepositoryMetrics) GetChildGroupIDs(ctx context.Context, parentIDs []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	start := time.Now()
+	ids, err := r.next.GetChildGroupIDs(ctx, parentIDs)
+	metrics.ObserveDBOperation("group", "GetChildGroupIDs", start, err)
+	return ids, err
+}
+
+func (r *groupRepositoryMetrics) AddMember(ctx context.Context, member *models.GroupMember) error {
+	start := time.Now()
+	err := r.next.AddMember(ctx, member)
+	metrics.ObserveDBOperation("group", "AddMember", start, err)
+	return err
+}
+
+func (r *groupRepositoryMetrics) RemoveMember(ctx context.Context, groupID primitive.ObjectID, userID int) error {
+	start := time.Now()
+	err := r.next.RemoveMember(ctx, groupID, userID)
+	metrics.ObserveDBOperation("group", "RemoveMember", start, err)
+	return err
+}
+
+func (r *groupRepositoryMetrics) GetMembers(ctx context.Context, groupID primitive.ObjectID, skip, limit int64) ([]models.GroupMember, int64, error) {
+	start := time.Now()
+	members, total, err := r.next.GetMembers(ctx, groupID, skip, limit)
+	metrics.ObserveDBOperation("group", "GetMembers", start, err)
+	return members, total, err
+}
+
+func (r *groupRepositoryMetrics) GetMembershipsByUserID(ctx context.Context, userID int) ([]models.GroupMember, error) {
+	start := time.Now()
+	members, err := r.next.GetMembershipsByUserID(ctx, userID)
+	metrics.ObserveDBOperation("group", "GetMembershipsByUserID", start, err)
+	return members, err
+}
+
+func (r *groupRepositoryMetrics) GetUsersByIDs(ctx context.Context, ids []int) ([]models.User, error) {
+	start := time.Now()
+	users, err := r.next.GetUsersByIDs(ctx, ids)
+	metrics.ObserveDBOperation("group", "GetUsersByIDs", start, err)
+	return users, err
+}
+
+// WithInvitationMetrics wraps an InvitationRepository so every call is
+// recorded in the db_operation_duration_seconds histogram.
+func WithInvitationMetrics(next InvitationRepository) InvitationRepository {
+	return &invitationRepositoryMetrics{next: next}
+}
+
+type invitationRepositoryMetrics struct {
+	next InvitationRepository
+}
+
+func (r *invitationRepositoryMetrics) CreateInvitation(ctx context.Context, invitation *models.Invitation) error {
+	start := time.Now()
+	err := r.next.CreateInvitation(ctx, invitation)
+	metrics.ObserveDBOperation("invitation", "CreateInvitation", start, err)
+	return err
+}
+
+func (r *invitationRepositoryMetrics) GetInvitation(ctx context.Context, id string) (*models.Invitation, error) {
+	start := time.Now()
+	invitation, err := r.next.GetInvitation(ctx, id)
+	metrics.ObserveDBOperation("invitation", "GetInvitation", start, err)
+	return invitation, err
+}
+
+func (r *invitationRepositoryMetrics) FindPendingInvitation(ctx context.Context, email string, now time.Time) (*models.Invitation, error) {
+	start := time.Now()
+	invitation, err := r.next.FindPendingInvitation(ctx, email, now)
+	metrics.ObserveDBOperation("invitation", "FindPendingInvitation", start, err)
+	return invitation, err
+}
+
+func (r *invitationRepositoryMetrics) GetPendingInvitations(ctx context.Context, now time.Time) ([]models.Invitation, error) {
+	start := time.Now()
+	invitations, err := r.next.GetPendingInvitations(ctx, now)
+	metrics.ObserveDBOperation("invitation", "GetPendingInvitations", start, err)
+	return invitations, err
+}
+
+func (r *invitationRepositoryMetrics) RenewInvitation(ctx context.Context, id primitive.ObjectID, tokenHash string, expiresAt time.Time) error {
+	start := time.Now()
+	err := r.next.RenewInvitation(ctx, id, tokenHash, expiresAt)
+	metrics.ObserveDBOperation("invitation", "RenewInvitation", start, err)
+	return err
+}
+
+func (r *invitationRepositoryMetrics) RevokeInvitation(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) error {
+	start := time.Now()
+	err := r.next.RevokeInvitation(ctx, id, revokedAt)
+	metrics.ObserveDBOperation("invitation", "RevokeInvitation", start, err)
+	return err
+}
+
+func (r *invitationRepositoryMetrics) ConsumeInvitation(ctx context.Context, tokenHash string, now time.Time) (*models.Invitation, error) {
+	start := time.Now()
+	invitation, err := r.next.ConsumeInvitation(ctx, tokenHash, now)
+	metrics.ObserveDBOperation("invitation", "ConsumeInvitation", start, err)
+	return invitation, err
+}
+
+func (r *invitationRepositoryMetrics) ReleaseInvitation(ctx context.Context, id primitive.ObjectID) error {
+	start := time.Now()
+	err := r.next.ReleaseInvitation(ctx, id)
+	metrics.ObserveDBOperation("invitation", "ReleaseInvitation", start, err)
+	return err
+}
+
+func (r *invitationRepositoryMetrics) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	start := time.Now()
+	user, err := r.next.FindUserByEmail(ctx, email)
+	metrics.ObserveDBOperation("invitation", "FindUserByEmail", start, err)
+	return user, err
+}
+
+func (r *invitationRepositoryMetrics) CreateUser(ctx context.Context, user *models.User) error {
+	start := time.Now()
+	err := r.next.CreateUser(ctx, user)
+	metrics.ObserveDBOperation("invitation", "CreateUser", start, err)
+	return err
+}
+
+// WithPolicyMetrics wraps a PolicyRepository so every call is recorded in
+// the db_operation_duration_seconds histogram.
+func WithPolicyMetrics(next PolicyRepository) PolicyRepository {
+	return &policyRepositoryMetrics{next: next}
+}
+
+type policyRepositoryMetrics struct {
+	next PolicyRepository
+}
+
+func (r *policyRepositoryMetrics) GetPolicies(ctx context.Context) ([]policy.Policy, error) {
+	start := time.Now()
+	policies, err := r.next.GetPolicies(ctx)
+	metrics.ObserveDBOperation("policy", "GetPolicies", start, err)
+	return policies, err
+}
+
+// WithImpersonationMetrics wraps an ImpersonationRepository so every call
+// is recorded in the db_operation_duration_seconds histogram.
+func WithImpersonationMetrics(next ImpersonationRepository) ImpersonationRepository {
+	return &impersonationRepositoryMetrics{next: next}
+}
+
+type impersonationRepositoryMetrics struct {
+	next ImpersonationRepository
+}
+
+func (r *impersonationRepositoryMetrics) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	start := time.Now()
+	user, err := r.next.GetUserByID(ctx, id)
+	metrics.ObserveDBOperation("impersonation", "GetUserByID", start, err)
+	return user, err
+}
+
+// WithSessionMetrics wraps a SessionRepository so every call is recorded in
+// the db_operation_duration_seconds histogram.
+func WithSessionMetrics(next SessionRepository) SessionRepository {
+	return &sessionRepositoryMetrics{next: next}
+}
+
+type sessionRepositoryMetrics struct {
+	next SessionRepository
+}
+
+func (r *sessionRepositoryMetrics) CreateSession(ctx context.Context, session *models.Session) error {
+	start := time.Now()
+	err := r.next.CreateSession(ctx, session)
+	metrics.ObserveDBOperation("session", "CreateSession", start, err)
+	return err
+}
+
+func (r *sessionRepositoryMetrics) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	start := time.Now()
+	session, err := r.next.GetSession(ctx, id)
+	metrics.ObserveDBOperation("session", "GetSession", start, err)
+	return session, err
+}
+
+func (r *sessionRepositoryMetrics) GetActiveSessions(ctx context.Context, userID int, now time.Time) ([]models.Session, error) {
+	start := time.Now()
+	sessions, err := r.next.GetActiveSessions(ctx, userID, now)
+	metrics.ObserveDBOperation("session", "GetActiveSessions", start, err)
+	return sessions, err
+}
+
+func (r *sessionRepositoryMetrics) TouchSession(ctx context.Context, id primitive.ObjectID, lastSeenAt time.Time) error {
+	start := time.Now()
+	err := r.next.TouchSession(ctx, id, lastSeenAt)
+	metrics.ObserveDBOperation("session", "TouchSession", start, err)
+	return err
+}
+
+func (r *sessionRepositoryMetrics) RevokeSession(ctx context.Context, userID int, id string, revokedAt time.Time) error {
+	start := time.Now()
+	err := r.next.RevokeSession(ctx, userID, id, revokedAt)
+	metrics.ObserveDBOperation("session", "RevokeSession", start, err)
+	return err
+}
+
+func (r *sessionRepositoryMetrics) RevokeUserSessions(ctx context.Context, userID int, revokedAt time.Time) (int64, error) {
+	start := time.Now()
+	revoked, err := r.next.RevokeUserSessions(ctx, userID, revokedAt)
+	metrics.ObserveDBOperation("session", "RevokeUserSessions", start, err)
+	return revoked, err
+}
+
+// WithImportMetrics wraps an ImportRepository so every call is recorded in
+// the db_operation_duration_seconds histogram.
+func WithImportMetrics(next ImportRepository) ImportRepository {
+	return &importRepositoryMetrics{next: next}
+}
+
+type importRepositoryMetrics struct {
+	next ImportRepository
+}
+
+func (r *importRepositoryMetrics) FindUsersByEmail(ctx context.Context, emails []string) ([]models.User, error) {
+	start := time.Now()
+	users, err := r.next.FindUsersByEmail(ctx, emails)
+	metrics.ObserveDBOperation("import", "FindUsersByEmail", start, err)
+	return users, err
+}
+
+func (r *importRepositoryMetrics) InsertUsers(ctx context.Context, users []models.User) (map[int]error, error) {
+	start := time.Now()
+	failed, err := r.next.InsertUsers(ctx, users)
+	metrics.ObserveDBOperation("import", "InsertUsers", start, err)
+	return failed, err
+}
+
+func (r *importRepositoryMetrics) UpdateUsers(ctx context.Context, users []models.User) (map[int]error, error) {
+	start := time.Now()
+	failed, err := r.next.UpdateUsers(ctx, users)
+	metrics.ObserveDBOperation("import", "UpdateUsers", start, err)
+	return failed, err
+}
+
+func (r *importRepositoryMetrics) CreateImportJob(ctx context.Context, job *models.ImportJob) error {
+	start := time.Now()
+	err := r.next.CreateImportJob(ctx, job)
+	metrics.ObserveDBOperation("import", "CreateImportJob", start, err)
+	return err
+}
+
+func (r *importRepositoryMetrics) UpdateImportJob(ctx context.Context, job *models.ImportJob) error {
+	start := time.Now()
+	err := r.next.UpdateImportJob(ctx, job)
+	metrics.ObserveDBOperation("import", "UpdateImportJob", start, err)
+	return err
+}
+
+func (r *importRepositoryMetrics) GetImportJob(ctx context.Context, id string) (*models.ImportJob, error) {
+	start := time.Now()
+	job, err := r.next.GetImportJob(ctx, id)
+	metrics.ObserveDBOperation("import", "GetImportJob", start, err)
+	return job, err
+}
+
+// WithExportMetrics wraps an ExportRepository so every call is recorded in
+// the db_operation_duration_seconds histogram.
+func WithExportMetrics(next ExportRepository) ExportRepository {
+	return &exportRepositoryMetrics{next: next}
+}
+
+type exportRepositoryMetrics struct {
+	next ExportRepository
+}
+
+func (r *exportRepositoryMetrics) StreamUsers(ctx context.Context, filter *dtos.UserFilter, fn func(*models.User) error) error {
+	start := time.Now()
+	err := r.next.StreamUsers(ctx, filter, fn)
+	metrics.ObserveDBOperation("export", "StreamUsers", start, err)
+	return err
+}
+
+func (r *exportRepositoryMetrics) CreateExportJob(ctx context.Context, job *models.ExportJob) error {
+	start := time.Now()
+	err := r.next.CreateExportJob(ctx, job)
+	metrics.ObserveDBOperation("export", "CreateExportJob", start, err)
+	return err
+}
+
+func (r *exportRepositoryMetrics) UpdateExportJob(ctx context.Context, job *models.ExportJob) error {
+	start := time.Now()
+	err := r.next.UpdateExportJob(ctx, job)
+	metrics.ObserveDBOperation("export", "UpdateExportJob", start, err)
+	return err
+}
+
+func (r *exportRepositoryMetrics) GetExportJob(ctx context.Context, id string) (*models.ExportJob, error) {
+	start := time.Now()
+	job, err := r.next.GetExportJob(ctx, id)
+	metrics.ObserveDBOperation("export", "GetExportJob", start, err)
+	return job, err
+}
+
+func (r *exportRepositoryMetrics) SaveExportFile(ctx context.Context, fileID primitive.ObjectID, name string, write func(io.Writer) error) (int64, error) {
+	start := time.Now()
+	size, err := r.next.SaveExportFile(ctx, fileID, name, write)
+	metrics.ObserveDBOperation("export", "SaveExportFile", start, err)
+	return size, err
+}
+
+func (r *exportRepositoryMetrics) OpenExportFile(ctx context.Context, fileID primitive.ObjectID) (io.ReadCloser, error) {
+	start := time.Now()
+	file, err := r.next.OpenExportFile(ctx, fileID)
+	metrics.ObserveDBOperation("export", "OpenExportFile", start, err)
+	return file, err
+}
+
+func (r *exportRepositoryMetrics) DeleteExpiredExports(ctx context.Context, now time.Time) (int64, error) {
+	start := time.Now()
+	deleted, err := r.next.DeleteExpiredExports(ctx, now)
+	metrics.ObserveDBOperation("export", "DeleteExpiredExports", start, err)
+	return deleted, err
+}
+
+// WithBulkMetrics wraps a BulkRepository so every call is recorded in the
+// db_operation_duration_seconds histogram.
+func WithBulkMetrics(next BulkRepository) BulkRepository {
+	return &bulkRepositoryMetrics{next: next}
+}
+
+type bulkRepositoryMetrics struct {
+	next BulkRepository
+}
+
+func (r *bulkRepositoryMetrics) FindUsersByID(ctx context.Context, ids []int) ([]models.User, error) {
+	start := time.Now()
+	users, err := r.next.FindUsersByID(ctx, ids)
+	metrics.ObserveDBOperation("bulk", "FindUsersByID", start, err)
+	return users, err
+}
+
+func (r *bulkRepositoryMetrics) WriteUsers(ctx context.Context, writes []UserWrite, atomic bool) (map[int]error, error) {
+	start := time.Now()
+	failed, err := r.next.WriteUsers(ctx, writes, atomic)
+	metrics.ObserveDBOperation("bulk", "WriteUsers", start, err)
+	return failed, err
+}
+
+// WithAuditMetrics wraps an AuditRepository so every call is recorded in
+// the db_operation_duration_seconds histogram.
+func WithAuditMetrics(next AuditRepository) AuditRepository {
+	return &auditRepositoryMetrics{next: next}
+}
+
+type auditRepositoryMetrics struct {
+	next AuditRepository
+}
+
+func (r *auditRepositoryMetrics) InsertAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	start := time.Now()
+	err := r.next.InsertAuditEvent(ctx, event)
+	metrics.ObserveDBOperation("audit", "InsertAuditEvent", start, err)
+	return err
+}
+
+func (r *auditRepositoryMetrics) GetUserAuditEvents(ctx context.Context, userID, email string) ([]models.AuditEvent, error) {
+	start := time.Now()
+	events, err := r.next.GetUserAuditEvents(ctx, userID, email)
+	metrics.ObserveDBOperation("audit", "GetUserAuditEvents", start, err)
+	return events, err
+}
+
+// WithPrivacyMetrics wraps a PrivacyRepository so every call is recorded in
+// the db_operation_duration_seconds histogram.
+func WithPrivacyMetrics(next PrivacyRepository) PrivacyRepository {
+	return &privacyRepositoryMetrics{next: next}
+}
+
+type privacyRepositoryMetrics struct {
+	next PrivacyRepository
+}
+
+func (r *privacyRepositoryMetrics) GetUser(ctx context.Context, id int) (*models.User, error) {
+	start := time.Now()
+	user, err := r.next.GetUser(ctx, id)
+	metrics.ObserveDBOperation("privacy", "GetUser", start, err)
+	return user, err
+}
+
+func (r *privacyRepositoryMetrics) GetUserSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	start := time.Now()
+	sessions, err := r.next.GetUserSessions(ctx, userID)
+	metrics.ObserveDBOperation("privacy", "GetUserSessions", start, err)
+	return sessions, err
+}
+
+func (r *privacyRepositoryMetrics) GetUserIdentities(ctx context.Context, userID int) ([]models.Identity, error) {
+	start := time.Now()
+	identities, err := r.next.GetUserIdentities(ctx, userID)
+	metrics.ObserveDBOperation("privacy", "GetUserIdentities", start, err)
+	return identities, err
+}
+
+func (r *privacyRepositoryMetrics) StartErasure(ctx context.Context, erasure *models.Erasure) (*models.Erasure, error) {
+	start := time.Now()
+	stored, err := r.next.StartErasure(ctx, erasure)
+	metrics.ObserveDBOperation("privacy", "StartErasure", start, err)
+	return stored, err
+}
+
+func (r *privacyRepositoryMetrics) GetErasure(ctx context.Context, subject string) (*models.Erasure, error) {
+	start := time.Now()
+	erasure, err := r.next.GetErasure(ctx, subject)
+	metrics.ObserveDBOperation("privacy", "GetErasure", start, err)
+	return erasure, err
+}
+
+func (r *privacyRepositoryMetrics) GetStaleErasures(ctx context.Context, updatedBefore time.Time) ([]models.Erasure, error) {
+	start := time.Now()
+	erasures, err := r.next.GetStaleErasures(ctx, updatedBefore)
+	metrics.ObserveDBOperation("privacy", "GetStaleErasures", start, err)
+	return erasures, err
+}
+
+func (r *privacyRepositoryMetrics) EraseUserData(ctx context.Context, step string, erasure *models.Erasure) error {
+	start := time.Now()
+	err := r.next.EraseUserData(ctx, step, erasure)
+	metrics.ObserveDBOperation("privacy", "EraseUserData", start, err)
+	return err
+}
+
+func (r *privacyRepositoryMetrics) CompleteErasureStep(ctx context.Context, id primitive.ObjectID, step string, now time.Time) error {
+	start := time.Now()
+	err := r.next.CompleteErasureStep(ctx, id, step, now)
+	metrics.ObserveDBOperation("privacy", "CompleteErasureStep", start, err)
+	return err
+}
+
+func (r *privacyRepositoryMetrics) CompleteErasure(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	start := time.Now()
+	err := r.next.CompleteErasure(ctx, id, now)
+	metrics.ObserveDBOperation("privacy", "CompleteErasure", start, err)
+	return err
+}
+
+// WithStatusMetrics wraps a StatusRepository so every call is recorded in
+// the db_operation_duration_seconds histogram.
+func WithStatusMetrics(next StatusRepository) StatusRepository {
+	return &statusRepositoryMetrics{next: next}
+}
+
+type statusRepositoryMetrics struct {
+	next StatusRepository
+}
+
+func (r *statusRepositoryMetrics) GetUser(ctx context.Context, id int) (*models.User, error) {
+	start := time.Now()
+	user, err := r.next.GetUser(ctx, id)
+	metrics.ObserveDBOperation("status", "GetUser", start, err)
+	return user, err
+}
+
+func (r *statusRepositoryMetrics) GetUserStatus(ctx context.Context, id int) (string, error) {
+	start := time.Now()
+	status, err := r.next.GetUserStatus(ctx, id)
+	metrics.ObserveDBOperation("status", "GetUserStatus", start, err)
+	return status, err
+}
+
+func (r *statusRepositoryMetrics) ChangeUserStatus(ctx context.Context, id int, change *models.StatusChange) error {
+	start := time.Now()
+	err := r.next.ChangeUserStatus(ctx, id, change)
+	metrics.ObserveDBOperation("status", "ChangeUserStatus", start, err)
+	return err
+}
+
+// WithProfileMetrics wraps a ProfileRepository so every call is recorded in
+// the db_operation_duration_seconds histogram.
+func WithProfileMetrics(next ProfileRepository) ProfileRepository {
+	return &profileRepositoryMetrics{next: next}
+}
+
+type profileRepositoryMetrics struct {
+	next ProfileRepository
+}
+
+func (r *profileRepositoryMetrics) CreateAttribute(ctx context.Context, definition *models.AttributeDefinition) error {
+	start := time.Now()
+	err := r.next.CreateAttribute(ctx, definition)
+	metrics.ObserveDBOperation("profile", "CreateAttribute", start, err)
+	return err
+}
+
+func (r *profileRepositoryMetrics) GetAttributes(ctx context.Context) ([]models.AttributeDefinition, error) {
+	start := time.Now()
+	definitions, err := r.next.GetAttributes(ctx)
+	metrics.ObserveDBOperation("profile", "GetAttributes", start, err)
+	return definitions, err
+}
+
+func (r *profileRepositoryMetrics) GetAttribute(ctx context.Context, key string) (*models.AttributeDefinition, error) {
+	start := time.Now()
+	definition, err := r.next.GetAttribute(ctx, key)
+	metrics.ObserveDBOperation("profile", "GetAttribute", start, err)
+	return definition, err
+}
+
+func (r *profileRepositoryMetrics) UpdateAttribute(ctx context.Context, definition *models.AttributeDefinition) error {
+	start := time.Now()
+	err := r.next.UpdateAttribute(ctx, definition)
+	metrics.ObserveDBOperation("profile", "UpdateAttribute", start, err)
+	return err
+}
+
+func (r *profileRepositoryMetrics) DeleteAttribute(ctx context.Context, definition *models.AttributeDefinition) error {
+	start := time.Now()
+	err := r.next.DeleteAttribute(ctx, definition)
+	metrics.ObserveDBOperation("profile", "DeleteAttribute", start, err)
+	return err
+}
+
+func (r *profileRepositoryMetrics) GetUser(ctx context.Context, id int) (*models.User, error) {
+	start := time.Now()
+	user, err := r.next.GetUser(ctx, id)
+	metrics.ObserveDBOperation("profile", "GetUser", start, err)
+	return user, err
+}
+
+func (r *profileRepositoryMetrics) SetUserAttributes(ctx context.Context, id int, set map[string]any, unset []string) error {
+	start := time.Now()
+	err := r.next.SetUserAttributes(ctx, id, set, unset)
+	metrics.ObserveDBOperation("profile", "SetUserAttributes", start, err)
+	return err
+}
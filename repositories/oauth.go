@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"7-solutions/models"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OAuthRepository scopes client registration and listing to the tenant in
+// the context. Client lookups by id serve the OAuth endpoints themselves and
+// are not scoped.
+type OAuthRepository interface {
+	CreateClient(ctx context.Context, client *models.OAuthClient) error
+	GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error)
+	GetAllClients(ctx context.Context) ([]models.OAuthClient, error)
+	CreateAuthorizationCode(ctx context.Context, code *models.AuthorizationCode) error
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string, usedAt time.Time) (*models.AuthorizationCode, error)
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type oauthRepository struct {
+	db *mongo.Database
+}
+
+func NewOAuthRepository(db *mongo.Database) OAuthRepository {
+	return &oauthRepository{db: db}
+}
+
+func (r *oauthRepository) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	tenantID, err := tenant.ForWrite(ctx, client.TenantID)
+	if err != nil {
+		return err
+	}
+	client.TenantID = tenantID
+
+	collection := r.db.Collection("oauth_clients")
+	_, err = collection.InsertOne(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to create oauth client: %w", utils.ContextError(err))
+	}
+	return nil
+}
+
+func (r *oauthRepository) GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	collection := r.db.Collection("oauth_clients")
+	err := collection.FindOne(ctx, bson.M{"clientId": clientID}).Decode(&client)
+	if err != nil {
+		return nil, fmt.Errorf("oauth client not found: %w", utils.ContextError(err))
+	}
+	return &client, nil
+}
+
+func (r *oauthRepository) GetAllClients(ctx context.Context) ([]models.OAuthClient, error) {
+	filter, err := scopeToTenant(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	collection := r.db.Collection("oauth_clients")
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve oauth clients: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	clients := []models.OAuthClient{}
+	if err := cursor.All(ctx, &clients); err != nil {
+		return nil, fmt.Errorf("failed to decode oauth clients: %w", utils.ContextError(err))
+	}
+	return clients, nil
+}
+
+func (r *oauthRepository) CreateAuthorizationCode(ctx context.Context, code *models.AuthorizationCode) error {
+	collection := r.db.Collection("oauth_codes")
+	_, err := collection.InsertOne(ctx, code)
+	if err != nil {
+		return fmt.Errorf("failed to create authorization code: %w", utils.ContextError(err))
+	}
+	return nil
+}
+
+// ConsumeAuthorizationCode marks an unused code as used and returns it, so
+// concurrent exchanges of the same code cannot both succeed.
+func (r *oauthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string, usedAt time.Time) (*models.AuthorizationCode, error) {
+	var code models.AuthorizationCode
+	collection := r.db.Collection("oauth_codes")
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"codeHash": codeHash, "usedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"usedAt": usedAt}},
+	).Decode(&code)
+	if err != nil {
+		return nil, fmt.Errorf("authorization code not found: %w", utils.ContextError(err))
+	}
+	return &code, nil
+}
+
+func (r *oauthRepository) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	collection := r.db.Collection("revoked_tokens")
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"_id": jti},
+		bson.M{"$set": bson.M{"expiresAt": expiresAt}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", utils.ContextError(err))
+	}
+	return nil
+}
+
+func (r *oauthRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	collection := r.db.Collection("revoked_tokens")
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": jti}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", utils.ContextError(err))
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"7-solutions/models"
+	"7-solutions/utils"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrOrganizationExists is returned when an organization id is taken.
+var ErrOrganizationExists = errors.New("organization already exists")
+
+// OrganizationRepository manages the tenants themselves and is therefore not
+// tenant-scoped; only super admins reach it.
+type OrganizationRepository interface {
+	CreateOrganization(ctx context.Context, organization *models.Organization) error
+	GetOrganization(ctx context.Context, id string) (*models.Organization, error)
+	GetAllOrganizations(ctx context.Context) ([]models.Organization, error)
+}
+
+type organizationRepository struct {
+	db *mongo.Database
+}
+
+func NewOrganizationRepository(db *mongo.Database) OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+func (r *organizationRepository) CreateOrganization(ctx context.Context, organization *models.Organization) error {
+	collection := r.db.Collection("organizations")
+	_, err := collection.InsertOne(ctx, organization)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrOrganizationExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create organization: %w", utils.ContextError(err))
+	}
+	return nil
+}
+
+func (r *organizationRepository) GetOrganization(ctx context.Context, id string) (*models.Organization, error) {
+	var organization models.Organization
+	collection := r.db.Collection("organizations")
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&organization)
+	if err != nil {
+		return nil, fmt.Errorf("organization not found: %w", utils.ContextError(err))
+	}
+	return &organization, nil
+}
+
+func (r *organizationRepository) GetAllOrganizations(ctx context.Context) ([]models.Organization, error) {
+	collection := r.db.Collection("organizations")
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve organizations: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	organizations := []models.Organization{}
+	if err := cursor.All(ctx, &organizations); err != nil {
+		return nil, fmt.Errorf("failed to decode organizations: %w", utils.ContextError(err))
+	}
+	return organizations, nil
+}
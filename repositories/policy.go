@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"7-solutions/policy"
+	"7-solutions/utils"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PolicyRepository reads the policies collection. Policies are global
+// configuration, so it is not scoped to a tenant; a policy's own tenantId
+// limits whom it applies to.
+type PolicyRepository interface {
+	GetPolicies(ctx context.Context) ([]policy.Policy, error)
+}
+
+type policyRepository struct {
+	db *mongo.Database
+}
+
+func NewPolicyRepository(db *mongo.Database) PolicyRepository {
+	return &policyRepository{db: db}
+}
+
+func (r *policyRepository) GetPolicies(ctx context.Context) ([]policy.Policy, error) {
+	cursor, err := r.db.Collection("policies").Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve policies: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	policies := []policy.Policy{}
+	if err := cursor.All(ctx, &policies); err != nil {
+		return nil, fmt.Errorf("failed to decode policies: %w", utils.ContextError(err))
+	}
+	return policies, nil
+}
@@ -0,0 +1,260 @@
+package repositories
+
+import (
+	"7-solutions/models"
+	"7-solutions/utils"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrErasureNotFound = errors.New("erasure not found")
+
+// ErasureSteps are the steps of an erasure in the order they run. The user
+// is deleted last, so an interrupted erasure can still be found from them.
+var ErasureSteps = []string{
+	"sessions",
+	"identities",
+	"oauth_codes",
+	"group_members",
+	"invitations",
+	"api_keys",
+	"import_jobs",
+	"export_jobs",
+	"audit_events",
+	"erasures",
+	"status_history",
+	"user",
+}
+
+// PrivacyRepository reads everything stored about a user and erases it.
+// Queries are scoped to the tenant in the context, except on ids: user ids
+// are unique across tenants.
+type PrivacyRepository interface {
+	GetUser(ctx context.Context, id int) (*models.User, error)
+	GetUserSessions(ctx context.Context, userID int) ([]models.Session, error)
+	GetUserIdentities(ctx context.Context, userID int) ([]models.Identity, error)
+	StartErasure(ctx context.Context, erasure *models.Erasure) (*models.Erasure, error)
+	GetErasure(ctx context.Context, subject string) (*models.Erasure, error)
+	GetStaleErasures(ctx context.Context, updatedBefore time.Time) ([]models.Erasure, error)
+	EraseUserData(ctx context.Context, step string, erasure *models.Erasure) error
+	CompleteErasureStep(ctx context.Context, id primitive.ObjectID, step string, now time.Time) error
+	CompleteErasure(ctx context.Context, id primitive.ObjectID, now time.Time) error
+}
+
+type privacyRepository struct {
+	db *mongo.Database
+}
+
+func NewPrivacyRepository(db *mongo.Database) PrivacyRepository {
+	return &privacyRepository{db: db}
+}
+
+// GetUser returns the user without their password hash.
+func (r *privacyRepository) GetUser(ctx context.Context, id int) (*models.User, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"id": id})
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	err = r.db.Collection("users").FindOne(ctx, filter, options.FindOne().SetProjection(bson.M{"password": 0})).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user: %w", utils.ContextError(err))
+	}
+	return &user, nil
+}
+
+// GetUserSessions returns every session of the user, including revoked
+// and expired ones.
+func (r *privacyRepository) GetUserSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []models.Session{}
+	if err := r.findAll(ctx, "sessions", filter, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to retrieve sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+func (r *privacyRepository) GetUserIdentities(ctx context.Context, userID int) ([]models.Identity, error) {
+	identities := []models.Identity{}
+	if err := r.findAll(ctx, "identities", bson.M{"userId": userID}, &identities); err != nil {
+		return nil, fmt.Errorf("failed to retrieve identities: %w", err)
+	}
+	return identities, nil
+}
+
+func (r *privacyRepository) findAll(ctx context.Context, collection string, filter bson.M, results any) error {
+	cursor, err := r.db.Collection(collection).Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return utils.ContextError(err)
+	}
+	defer cursor.Close(ctx)
+	return utils.ContextError(cursor.All(ctx, results))
+}
+
+// StartErasure stores erasure unless an erasure of the same subject exists,
+// and returns the stored one.
+func (r *privacyRepository) StartErasure(ctx context.Context, erasure *models.Erasure) (*models.Erasure, error) {
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var stored models.Erasure
+	err := r.db.Collection("erasures").
+		FindOneAndUpdate(ctx, bson.M{"subject": erasure.Subject}, bson.M{"$setOnInsert": erasure}, opts).
+		Decode(&stored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start erasure: %w", utils.ContextError(err))
+	}
+	return &stored, nil
+}
+
+func (r *privacyRepository) GetErasure(ctx context.Context, subject string) (*models.Erasure, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"subject": subject})
+	if err != nil {
+		return nil, err
+	}
+
+	var erasure models.Erasure
+	err = r.db.Collection("erasures").FindOne(ctx, filter).Decode(&erasure)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrErasureNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve erasure: %w", utils.ContextError(err))
+	}
+	return &erasure, nil
+}
+
+// GetStaleErasures returns the running erasures that made no progress
+// since updatedBefore, i.e. were interrupted.
+func (r *privacyRepository) GetStaleErasures(ctx context.Context, updatedBefore time.Time) ([]models.Erasure, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"status": models.ErasureRunning, "updatedAt": bson.M{"$lt": updatedBefore}})
+	if err != nil {
+		return nil, err
+	}
+
+	erasures := []models.Erasure{}
+	if err := r.findAll(ctx, "erasures", filter, &erasures); err != nil {
+		return nil, fmt.Errorf("failed to retrieve erasures: %w", err)
+	}
+	return erasures, nil
+}
+
+// EraseUserData runs one of ErasureSteps for the user of erasure. Records
+// that only concern the user are deleted; in the ones kept for others,
+// such as audit events and jobs, the user id and email are replaced by the
+// subject of erasure. Every step can safely run again.
+func (r *privacyRepository) EraseUserData(ctx context.Context, step string, erasure *models.Erasure) error {
+	id := strconv.Itoa(erasure.UserID)
+	pseudonymize := func(field string) bson.M {
+		return bson.M{"$set": bson.M{field: erasure.Subject}}
+	}
+
+	var err error
+	switch step {
+	case "sessions":
+		err = r.deleteMany(ctx, "sessions", bson.M{"userId": erasure.UserID}, true)
+	case "identities":
+		err = r.deleteMany(ctx, "identities", bson.M{"userId": erasure.UserID}, false)
+	case "oauth_codes":
+		err = r.deleteMany(ctx, "oauth_codes", bson.M{"subject": id}, false)
+	case "group_members":
+		err = r.deleteMany(ctx, "group_members", bson.M{"userId": erasure.UserID}, true)
+	case "invitations":
+		err = errors.Join(
+			r.deleteMany(ctx, "invitations", bson.M{"email": erasure.Email}, true),
+			r.updateMany(ctx, "invitations", bson.M{"invitedBy": id}, pseudonymize("invitedBy"), true),
+		)
+	case "api_keys":
+		err = r.updateMany(ctx, "api_keys", bson.M{"createdBy": id}, pseudonymize("createdBy"), true)
+	case "import_jobs":
+		rows := options.Update().SetArrayFilters(options.ArrayFilters{Filters: bson.A{bson.M{"row.email": erasure.Email}}})
+		err = errors.Join(
+			r.updateMany(ctx, "import_jobs", bson.M{"startedBy": id}, pseudonymize("startedBy"), true),
+			r.updateMany(ctx, "import_jobs", bson.M{"results.email": erasure.Email}, bson.M{
+				"$set":   bson.M{"results.$[row].email": erasure.Subject},
+				"$unset": bson.M{"results.$[row].userId": ""},
+			}, true, rows),
+		)
+	case "export_jobs":
+		err = r.updateMany(ctx, "export_jobs", bson.M{"startedBy": id}, pseudonymize("startedBy"), true)
+	case "audit_events":
+		err = errors.Join(
+			r.updateMany(ctx, "audit_events", bson.M{"userId": id}, pseudonymize("userId"), false),
+			r.updateMany(ctx, "audit_events", bson.M{"actorId": id}, pseudonymize("actorId"), false),
+			r.updateMany(ctx, "audit_events", bson.M{"targetUserId": id}, pseudonymize("targetUserId"), false),
+			r.updateMany(ctx, "audit_events", bson.M{"attributes.email": erasure.Email}, pseudonymize("attributes.email"), true),
+		)
+	case "erasures":
+		err = r.updateMany(ctx, "erasures", bson.M{"requestedBy": id}, pseudonymize("requestedBy"), true)
+	case "status_history":
+		changes := options.Update().SetArrayFilters(options.ArrayFilters{Filters: bson.A{bson.M{"change.actorId": id}}})
+		err = r.updateMany(ctx, "users", bson.M{"statusHistory.actorId": id}, bson.M{
+			"$set": bson.M{"statusHistory.$[change].actorId": erasure.Subject},
+		}, true, changes)
+	case "user":
+		err = r.deleteMany(ctx, "users", bson.M{"id": erasure.UserID}, true)
+	default:
+		return fmt.Errorf("unknown erasure step %q", step)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to erase %s: %w", step, err)
+	}
+	return nil
+}
+
+func (r *privacyRepository) deleteMany(ctx context.Context, collection string, filter bson.M, scoped bool) error {
+	if scoped {
+		var err error
+		if filter, err = scopeToTenant(ctx, filter); err != nil {
+			return err
+		}
+	}
+	_, err := r.db.Collection(collection).DeleteMany(ctx, filter)
+	return utils.ContextError(err)
+}
+
+func (r *privacyRepository) updateMany(ctx context.Context, collection string, filter, update bson.M, scoped bool, opts ...*options.UpdateOptions) error {
+	if scoped {
+		var err error
+		if filter, err = scopeToTenant(ctx, filter); err != nil {
+			return err
+		}
+	}
+	_, err := r.db.Collection(collection).UpdateMany(ctx, filter, update, opts...)
+	return utils.ContextError(err)
+}
+
+func (r *privacyRepository) CompleteErasureStep(ctx context.Context, id primitive.ObjectID, step string, now time.Time) error {
+	update := bson.M{"$addToSet": bson.M{"steps": step}, "$set": bson.M{"updatedAt": now}}
+	if _, err := r.db.Collection("erasures").UpdateByID(ctx, id, update); err != nil {
+		return fmt.Errorf("failed to update erasure: %w", utils.ContextError(err))
+	}
+	return nil
+}
+
+// CompleteErasure marks the erasure completed and drops the user id and
+// email it kept to resume.
+func (r *privacyRepository) CompleteErasure(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	update := bson.M{
+		"$set":   bson.M{"status": models.ErasureCompleted, "completedAt": now, "updatedAt": now},
+		"$unset": bson.M{"userId": "", "email": ""},
+	}
+	if _, err := r.db.Collection("erasures").UpdateByID(ctx, id, update); err != nil {
+		return fmt.Errorf("failed to complete erasure: %w", utils.ContextError(err))
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"7-solutions/models"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrAttributeNotFound = errors.New("attribute not found")
+	ErrAttributeExists   = errors.New("an attribute with this key already exists")
+)
+
+// ProfileRepository stores the custom attribute definitions of tenants and
+// the attribute values of users, scoped to the tenant in the context.
+type ProfileRepository interface {
+	CreateAttribute(ctx context.Context, definition *models.AttributeDefinition) error
+	// GetAttributes returns the definitions sorted by key.
+	GetAttributes(ctx context.Context) ([]models.AttributeDefinition, error)
+	GetAttribute(ctx context.Context, key string) (*models.AttributeDefinition, error)
+	UpdateAttribute(ctx context.Context, definition *models.AttributeDefinition) error
+	// DeleteAttribute deletes the definition and the values users of its
+	// tenant have for it.
+	DeleteAttribute(ctx context.Context, definition *models.AttributeDefinition) error
+	// GetUser returns the user with only their id, tenant, role and
+	// attributes.
+	GetUser(ctx context.Context, id int) (*models.User, error)
+	// SetUserAttributes sets the attributes in set and removes the ones in
+	// unset.
+	SetUserAttributes(ctx context.Context, id int, set map[string]any, unset []string) error
+}
+
+type profileRepository struct {
+	db *mongo.Database
+}
+
+func NewProfileRepository(db *mongo.Database) ProfileRepository {
+	return &profileRepository{db: db}
+}
+
+func (r *profileRepository) CreateAttribute(ctx context.Context, definition *models.AttributeDefinition) error {
+	tenantID, err := tenant.ForWrite(ctx, definition.TenantID)
+	if err != nil {
+		return err
+	}
+	definition.TenantID = tenantID
+
+	result, err := r.db.Collection("attribute_definitions").InsertOne(ctx, definition)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAttributeExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create attribute: %w", utils.ContextError(err))
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		definition.ID = id
+	}
+	return nil
+}
+
+func (r *profileRepository) GetAttributes(ctx context.Context) ([]models.AttributeDefinition, error) {
+	filter, err := scopeToTenant(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "key", Value: 1}, {Key: "tenantId", Value: 1}})
+	cursor, err := r.db.Collection("attribute_definitions").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve attributes: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	definitions := []models.AttributeDefinition{}
+	if err := cursor.All(ctx, &definitions); err != nil {
+		return nil, fmt.Errorf("failed to decode attributes: %w", utils.ContextError(err))
+	}
+	return definitions, nil
+}
+
+func (r *profileRepository) GetAttribute(ctx context.Context, key string) (*models.AttributeDefinition, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"key": key})
+	if err != nil {
+		return nil, err
+	}
+
+	var definition models.AttributeDefinition
+	err = r.db.Collection("attribute_definitions").FindOne(ctx, filter).Decode(&definition)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrAttributeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve attribute: %w", utils.ContextError(err))
+	}
+	return &definition, nil
+}
+
+// UpdateAttribute saves everything but the key and the type of definition.
+func (r *profileRepository) UpdateAttribute(ctx context.Context, definition *models.AttributeDefinition) error {
+	filter, err := scopeToTenant(ctx, bson.M{"_id": definition.ID})
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Collection("attribute_definitions").UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+		"required":    definition.Required,
+		"pattern":     definition.Pattern,
+		"enum":        definition.Enum,
+		"visibility":  definition.Visibility,
+		"description": definition.Description,
+		"updatedAt":   definition.UpdatedAt,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to update attribute: %w", utils.ContextError(err))
+	}
+	if result.MatchedCount == 0 {
+		return ErrAttributeNotFound
+	}
+	return nil
+}
+
+func (r *profileRepository) DeleteAttribute(ctx context.Context, definition *models.AttributeDefinition) error {
+	filter, err := scopeToTenant(ctx, bson.M{"_id": definition.ID})
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Collection("attribute_definitions").DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to delete attribute: %w", utils.ContextError(err))
+	}
+	if result.DeletedCount == 0 {
+		return ErrAttributeNotFound
+	}
+
+	field := "attributes." + definition.Key
+	users := bson.M{"tenantId": definition.TenantID, field: bson.M{"$exists": true}}
+	if _, err := r.db.Collection("users").UpdateMany(ctx, users, bson.M{"$unset": bson.M{field: ""}}); err != nil {
+		return fmt.Errorf("failed to delete attribute values: %w", utils.ContextError(err))
+	}
+	return nil
+}
+
+func (r *profileRepository) GetUser(ctx context.Context, id int) (*models.User, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"id": id})
+	if err != nil {
+		return nil, err
+	}
+
+	projection := bson.M{"id": 1, "tenantId": 1, "role": 1, "attributes": 1}
+	var user models.User
+	err = r.db.Collection("users").FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user: %w", utils.ContextError(err))
+	}
+	return &user, nil
+}
+
+func (r *profileRepository) SetUserAttributes(ctx context.Context, id int, set map[string]any, unset []string) error {
+	filter, err := scopeToTenant(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		fields := bson.M{}
+		for key, value := range set {
+			fields["attributes."+key] = value
+		}
+		update["$set"] = fields
+	}
+	if len(unset) > 0 {
+		fields := bson.M{}
+		for _, key := range unset {
+			fields["attributes."+key] = ""
+		}
+		update["$unset"] = fields
+	}
+	if len(update) == 0 {
+		return nil
+	}
+
+	result, err := r.db.Collection("users").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update user attributes: %w", utils.ContextError(err))
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
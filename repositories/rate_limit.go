@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"7-solutions/ratelimit"
+	"7-solutions/utils"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const rateLimitCollection = "rate_limits"
+
+// rateLimitRepository is a ratelimit.Store shared by every replica. Each
+// bucket is refilled and drawn from in a single findAndModify using the
+// server clock, so concurrent requests cannot overspend it.
+type rateLimitRepository struct {
+	db *mongo.Database
+}
+
+func NewRateLimitRepository(db *mongo.Database) ratelimit.Store {
+	return &rateLimitRepository{db: db}
+}
+
+func (r *rateLimitRepository) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	burst := float64(limit.Burst)
+	elapsedSeconds := bson.M{"$divide": bson.A{
+		bson.M{"$subtract": bson.A{"$$NOW", bson.M{"$ifNull": bson.A{"$updatedAt", "$$NOW"}}}},
+		1000,
+	}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$min": bson.A{burst, bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$tokens", burst}},
+				bson.M{"$multiply": bson.A{elapsedSeconds, limit.Rate}},
+			}}}},
+			"updatedAt": "$$NOW",
+			"expiresAt": bson.M{"$add": bson.A{"$$NOW", limit.Window().Milliseconds()}},
+		}}},
+		{{Key: "$set", Value: bson.M{"allowed": bson.M{"$gte": bson.A{"$tokens", 1}}}}},
+		{{Key: "$set", Value: bson.M{"tokens": bson.M{"$cond": bson.A{
+			"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens",
+		}}}}},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)
+
+	var bucket struct {
+		Tokens  float64 `bson:"tokens"`
+		Allowed bool    `bson:"allowed"`
+	}
+	err := r.db.Collection(rateLimitCollection).
+		FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).
+		Decode(&bucket)
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("failed to take rate limit token: %w", utils.ContextError(err))
+	}
+
+	return ratelimit.NewResult(limit, bucket.Tokens, bucket.Allowed), nil
+}
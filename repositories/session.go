@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"7-solutions/models"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrSessionNotFound is also returned for sessions that were already
+// revoked or have expired, when they are about to change.
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionRepository stores login sessions, scoped to the tenant in the
+// context.
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSession(ctx context.Context, id string) (*models.Session, error)
+	GetActiveSessions(ctx context.Context, userID int, now time.Time) ([]models.Session, error)
+	TouchSession(ctx context.Context, id primitive.ObjectID, lastSeenAt time.Time) error
+	RevokeSession(ctx context.Context, userID int, id string, revokedAt time.Time) error
+	RevokeUserSessions(ctx context.Context, userID int, revokedAt time.Time) (int64, error)
+}
+
+type sessionRepository struct {
+	db *mongo.Database
+}
+
+func NewSessionRepository(db *mongo.Database) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+// activeSessionFilter matches the sessions of userID that can still be
+// used.
+func activeSessionFilter(userID int, now time.Time) bson.M {
+	return bson.M{
+		"userId":    userID,
+		"revokedAt": bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": now},
+	}
+}
+
+func (r *sessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	tenantID, err := tenant.ForWrite(ctx, session.TenantID)
+	if err != nil {
+		return err
+	}
+	session.TenantID = tenantID
+
+	result, err := r.db.Collection("sessions").InsertOne(ctx, session)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", utils.ContextError(err))
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		session.ID = id
+	}
+	return nil
+}
+
+func (r *sessionRepository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrSessionNotFound
+	}
+	filter, err := scopeToTenant(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return nil, err
+	}
+
+	var session models.Session
+	err = r.db.Collection("sessions").FindOne(ctx, filter).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve session: %w", utils.ContextError(err))
+	}
+	return &session, nil
+}
+
+// GetActiveSessions returns the usable sessions of userID, most recently
+// used first.
+func (r *sessionRepository) GetActiveSessions(ctx context.Context, userID int, now time.Time) ([]models.Session, error) {
+	filter, err := scopeToTenant(ctx, activeSessionFilter(userID, now))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"lastSeenAt": -1})
+	cursor, err := r.db.Collection("sessions").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve sessions: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []models.Session{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to decode sessions: %w", utils.ContextError(err))
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) TouchSession(ctx context.Context, id primitive.ObjectID, lastSeenAt time.Time) error {
+	filter, err := scopeToTenant(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Collection("sessions").UpdateOne(ctx, filter, bson.M{"$set": bson.M{"lastSeenAt": lastSeenAt}})
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", utils.ContextError(err))
+	}
+	return nil
+}
+
+// RevokeSession revokes the active session id of userID.
+func (r *sessionRepository) RevokeSession(ctx context.Context, userID int, id string, revokedAt time.Time) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+	query := activeSessionFilter(userID, revokedAt)
+	query["_id"] = objectID
+	filter, err := scopeToTenant(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Collection("sessions").UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revokedAt": revokedAt}})
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", utils.ContextError(err))
+	}
+	if result.MatchedCount == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every active session of userID and returns
+// how many there were.
+func (r *sessionRepository) RevokeUserSessions(ctx context.Context, userID int, revokedAt time.Time) (int64, error) {
+	filter, err := scopeToTenant(ctx, activeSessionFilter(userID, revokedAt))
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.db.Collection("sessions").UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revokedAt": revokedAt}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", utils.ContextError(err))
+	}
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"7-solutions/tenant"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// scopeToTenant adds the tenant of ctx to filter unless ctx may access every
+// tenant. Without a tenant it fails instead of matching across tenants.
+func scopeToTenant(ctx context.Context, filter bson.M) (bson.M, error) {
+	id, all := tenant.FromContext(ctx)
+	if all {
+		return filter, nil
+	}
+	if id == "" {
+		return nil, tenant.ErrMissing
+	}
+	filter["tenantId"] = id
+	return filter, nil
+}
@@ -3,23 +3,31 @@ package repositories
 import (
 	"7-solutions/dtos"
 	"7-solutions/models"
+	"7-solutions/tenant"
+	"7-solutions/utils"
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrUserNotFound is returned by updates and deletes that match no user in
+// the caller's tenant.
+var ErrUserNotFound = errors.New("user not found")
+
+// UserRepository scopes every query to the tenant in the context, see
+// scopeToTenant.
 type UserRepository interface {
-	CreateUser(userDto *dtos.UserRegister) (*dtos.UserResponse, error)
-	GetUserByID(id int) (*dtos.UserResponse, error)
-	GetAllUsers() ([]dtos.UserResponse, error)
-	UpdateUser(id int, userDto *dtos.UserUpdate) (*dtos.UserResponse, error)
-	DeleteUser(id int) error
-	CountUsers() (int64, error)
+	CreateUser(ctx context.Context, userDto *dtos.UserRegister) (*dtos.UserResponse, error)
+	GetUserByID(ctx context.Context, id int) (*dtos.UserResponse, error)
+	GetAllUsers(ctx context.Context, filter *dtos.UserFilter) ([]dtos.UserResponse, error)
+	UpdateUser(ctx context.Context, id int, userDto *dtos.UserUpdate) (*dtos.UserResponse, error)
+	DeleteUser(ctx context.Context, id int) error
+	CountUsers(ctx context.Context) (int64, error)
 }
 
 type userRepository struct {
@@ -30,87 +38,110 @@ func NewUserRepository(db *mongo.Database) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) CreateUser(userDto *dtos.UserRegister) (*dtos.UserResponse, error) {
-	newID, err := GetNextSequence(r.db, "users")
+// CreateUser stores userDto as is; the password must already be hashed. The
+// user joins the caller's tenant unless a super-admin picked another one.
+func (r *userRepository) CreateUser(ctx context.Context, userDto *dtos.UserRegister) (*dtos.UserResponse, error) {
+	tenantID, err := tenant.ForWrite(ctx, userDto.TenantID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get new user ID: %w", err)
+		return nil, err
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(userDto.Password), bcrypt.DefaultCost)
+	newID, err := GetNextSequence(ctx, r.db, "users")
 	if err != nil {
-		return nil, fmt.Errorf("failed to hash password: %w", err)
+		return nil, fmt.Errorf("failed to get new user ID: %w", utils.ContextError(err))
 	}
 
 	user := &models.User{
 		ID:        newID,
 		Name:      userDto.Name,
 		Email:     userDto.Email,
-		Password:  string(hashedPassword),
+		Password:  userDto.Password,
+		Role:      models.RoleUser,
+		TenantID:  tenantID,
 		CreatedAt: time.Now(),
 	}
 
 	collection := r.db.Collection("users")
-	_, err = collection.InsertOne(context.Background(), user)
+	_, err = collection.InsertOne(ctx, user)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		return nil, fmt.Errorf("failed to create user: %w", utils.ContextError(err))
 	}
 
 	userResponse := &dtos.UserResponse{
 		ID:        user.ID,
 		Name:      user.Name,
 		Email:     user.Email,
+		TenantID:  user.TenantID,
+		Status:    user.AccountStatus(),
 		CreatedAt: user.CreatedAt.Format(time.RFC3339),
 	}
 
 	return userResponse, nil
 }
 
-func (r *userRepository) GetUserByID(id int) (*dtos.UserResponse, error) {
+func (r *userRepository) GetUserByID(ctx context.Context, id int) (*dtos.UserResponse, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"id": id})
+	if err != nil {
+		return nil, err
+	}
+
 	var user models.User
 	collection := r.db.Collection("users")
-	filter := map[string]interface{}{"id": id}
-	err := collection.FindOne(nil, filter).Decode(&user)
+	err = collection.FindOne(ctx, filter).Decode(&user)
 	if err != nil {
-		return nil, fmt.Errorf("user not found: %w", err)
+		return nil, fmt.Errorf("user not found: %w", utils.ContextError(err))
 	}
 
 	userResponse := &dtos.UserResponse{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.Format(time.RFC3339),
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		TenantID:      user.TenantID,
+		Status:        user.AccountStatus(),
+		CreatedAt:     user.CreatedAt.Format(time.RFC3339),
+		Attributes:    user.Attributes,
 	}
 	return userResponse, nil
 }
 
-func (r *userRepository) GetAllUsers() ([]dtos.UserResponse, error) {
+func (r *userRepository) GetAllUsers(ctx context.Context, userFilter *dtos.UserFilter) ([]dtos.UserResponse, error) {
+	filter, err := scopeToTenant(ctx, matchUsers(userFilter))
+	if err != nil {
+		return nil, err
+	}
+
 	var users []models.User
 	collection := r.db.Collection("users")
-	cursor, err := collection.Find(nil, map[string]interface{}{})
+	cursor, err := collection.Find(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve users: %w", err)
+		return nil, fmt.Errorf("failed to retrieve users: %w", utils.ContextError(err))
 	}
-	defer cursor.Close(nil)
+	defer cursor.Close(ctx)
 
-	for cursor.Next(nil) {
+	for cursor.Next(ctx) {
 		var user models.User
 		if err := cursor.Decode(&user); err != nil {
-			return nil, fmt.Errorf("failed to decode user: %w", err)
+			return nil, fmt.Errorf("failed to decode user: %w", utils.ContextError(err))
 		}
 		users = append(users, user)
 	}
 
 	if err := cursor.Err(); err != nil {
-		return nil, fmt.Errorf("cursor error: %w", err)
+		return nil, fmt.Errorf("cursor error: %w", utils.ContextError(err))
 	}
 
 	var userResponses []dtos.UserResponse
 	for _, user := range users {
 		userResponse := dtos.UserResponse{
-			ID:        user.ID,
-			Name:      user.Name,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt.Format(time.RFC3339),
+			ID:            user.ID,
+			Name:          user.Name,
+			Email:         user.Email,
+			EmailVerified: user.EmailVerified,
+			TenantID:      user.TenantID,
+			Status:        user.AccountStatus(),
+			CreatedAt:     user.CreatedAt.Format(time.RFC3339),
+			Attributes:    user.Attributes,
 		}
 		userResponses = append(userResponses, userResponse)
 	}
@@ -118,59 +149,145 @@ func (r *userRepository) GetAllUsers() ([]dtos.UserResponse, error) {
 	return userResponses, nil
 }
 
-func (r *userRepository) UpdateUser(id int, userDto *dtos.UserUpdate) (*dtos.UserResponse, error) {
-	user := &models.User{
-		ID:    id,
-		Name:  userDto.Name,
-		Email: userDto.Email,
+// matchUsers turns filter into a query on the users collection.
+func matchUsers(filter *dtos.UserFilter) bson.M {
+	query := bson.M{}
+	if filter == nil {
+		return query
+	}
+	if filter.Role != "" {
+		query["role"] = filter.Role
 	}
+	if filter.EmailVerified != nil {
+		// emailVerified is only stored when true.
+		query["emailVerified"] = bson.M{"$eq": true}
+		if !*filter.EmailVerified {
+			query["emailVerified"] = bson.M{"$ne": true}
+		}
+	}
+	if filter.Status != "" {
+		query["status"] = matchStatus(filter.Status)
+	}
+	createdAt := bson.M{}
+	if filter.CreatedAfter != nil {
+		createdAt["$gte"] = *filter.CreatedAfter
+	}
+	if filter.CreatedBefore != nil {
+		createdAt["$lt"] = *filter.CreatedBefore
+	}
+	if len(createdAt) > 0 {
+		query["createdAt"] = createdAt
+	}
+	for key, value := range filter.Attributes {
+		query["attributes."+key] = value
+	}
+	return query
+}
 
-	collection := r.db.Collection("users")
-	filter := map[string]interface{}{"id": id}
-	update := map[string]interface{}{
-		"$set": user,
+// matchStatus matches users with status; users without one are active.
+func matchStatus(status string) any {
+	if status == models.StatusActive {
+		return bson.M{"$in": bson.A{nil, models.StatusActive}}
 	}
+	return status
+}
 
-	_, err := collection.UpdateOne(nil, filter, update)
+func (r *userRepository) UpdateUser(ctx context.Context, id int, userDto *dtos.UserUpdate) (*dtos.UserResponse, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"id": id})
 	if err != nil {
-		return nil, fmt.Errorf("failed to update user: %w", err)
+		return nil, err
+	}
+
+	collection := r.db.Collection("users")
+	update := setUserFields(bson.M{"name": userDto.Name, "email": userDto.Email})
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", utils.ContextError(err))
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrUserNotFound
 	}
 
 	userResponse := &dtos.UserResponse{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
+		ID:        id,
+		Name:      userDto.Name,
+		Email:     userDto.Email,
 		CreatedAt: time.Now().Format(time.RFC3339),
 	}
 
 	return userResponse, nil
 }
 
-func (r *userRepository) DeleteUser(id int) error {
-	collection := r.db.Collection("users")
-	filter := map[string]interface{}{"id": id}
+// setUserFields returns an update that sets the fields in set. When set
+// holds an email address that differs from the stored one, emailVerified is
+// cleared as well; the update is then a pipeline so the addresses are
+// compared on the server, in the same write.
+func setUserFields(set bson.M) interface{} {
+	email, ok := set["email"]
+	if !ok {
+		return bson.M{"$set": set}
+	}
+	stage := bson.M{}
+	for field, value := range set {
+		stage[field] = bson.M{"$literal": value}
+	}
+	stage["emailVerified"] = bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{"$email", bson.M{"$literal": email}}},
+		"$emailVerified",
+		"$$REMOVE",
+	}}
+	return bson.A{bson.M{"$set": stage}}
+}
 
-	_, err := collection.DeleteOne(nil, filter)
+// DeleteUser deletes the user and removes them from every group.
+func (r *userRepository) DeleteUser(ctx context.Context, id int) error {
+	filter, err := scopeToTenant(ctx, bson.M{"id": id})
 	if err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
+		return err
+	}
+
+	collection := r.db.Collection("users")
+	result, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", utils.ContextError(err))
+	}
+	if result.DeletedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	memberships, _ := scopeToTenant(ctx, bson.M{"userId": id})
+	if _, err := r.db.Collection("group_members").DeleteMany(ctx, memberships); err != nil {
+		return fmt.Errorf("failed to delete group memberships: %w", utils.ContextError(err))
 	}
 	return nil
 }
 
-func (r *userRepository) CountUsers() (int64, error) {
+func (r *userRepository) CountUsers(ctx context.Context) (int64, error) {
+	filter, err := scopeToTenant(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+
 	collection := r.db.Collection("users")
-	count, err := collection.CountDocuments(nil, map[string]interface{}{})
+	count, err := collection.CountDocuments(ctx, filter)
 	if err != nil {
-		return 0, fmt.Errorf("failed to count users: %w", err)
+		return 0, fmt.Errorf("failed to count users: %w", utils.ContextError(err))
 	}
 	return count, nil
 }
 
-func GetNextSequence(db *mongo.Database, name string) (int, error) {
+func GetNextSequence(ctx context.Context, db *mongo.Database, name string) (int, error) {
+	return GetNextSequences(ctx, db, name, 1)
+}
+
+// GetNextSequences reserves n consecutive values of the sequence name and
+// returns the last of them.
+func GetNextSequences(ctx context.Context, db *mongo.Database, name string, n int) (int, error) {
 	collection := db.Collection("counters")
 
 	filter := bson.M{"_id": name}
-	update := bson.M{"$inc": bson.M{"seq": 1}}
+	update := bson.M{"$inc": bson.M{"seq": n}}
 
 	opts := options.FindOneAndUpdate().
 		SetUpsert(true).
@@ -180,7 +297,7 @@ func GetNextSequence(db *mongo.Database, name string) (int, error) {
 		Seq int `bson:"seq"`
 	}
 
-	err := collection.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&result)
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&result)
 	if err != nil {
 		return 0, err
 	}
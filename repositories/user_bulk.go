@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"7-solutions/models"
+	"7-solutions/utils"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrTransactionsUnsupported is returned for atomic bulk writes when Mongo
+// is a standalone server rather than a replica set or sharded cluster.
+var ErrTransactionsUnsupported = errors.New("transactions require a MongoDB replica set")
+
+// illegalOperation is the code of the error a standalone server returns
+// for transactions.
+const illegalOperation = 20
+
+// UserWrite updates or deletes the user UserID. An update sets only the
+// fields that are not nil. Status, when set, moves the user to Status.To
+// and records it in the history, provided they still have Status.From.
+type UserWrite struct {
+	UserID int
+	Delete bool
+	Name   *string
+	Email  *string
+	Role   *string
+	Status *models.StatusChange
+}
+
+// BulkRepository changes many users with a single BulkWrite. Queries are
+// scoped to the tenant in the context.
+type BulkRepository interface {
+	FindUsersByID(ctx context.Context, ids []int) ([]models.User, error)
+	WriteUsers(ctx context.Context, writes []UserWrite, atomic bool) (map[int]error, error)
+}
+
+type bulkRepository struct {
+	db *mongo.Database
+}
+
+func NewBulkRepository(db *mongo.Database) BulkRepository {
+	return &bulkRepository{db: db}
+}
+
+func (r *bulkRepository) FindUsersByID(ctx context.Context, ids []int) ([]models.User, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.db.Collection("users").Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	users := []models.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %w", utils.ContextError(err))
+	}
+	return users, nil
+}
+
+// WriteUsers applies writes and removes deleted users from their groups.
+// The map holds the errors of the writes that failed by index,
+// ErrEmailTaken for duplicate emails. Without atomic the writes are
+// unordered and independent. With atomic they run in order in a
+// transaction, which is aborted at the first failing write.
+func (r *bulkRepository) WriteUsers(ctx context.Context, writes []UserWrite, atomic bool) (map[int]error, error) {
+	writeModels := make([]mongo.WriteModel, len(writes))
+	for i, write := range writes {
+		filter, err := scopeToTenant(ctx, bson.M{"id": write.UserID})
+		if err != nil {
+			return nil, err
+		}
+		if write.Delete {
+			writeModels[i] = mongo.NewDeleteOneModel().SetFilter(filter)
+			continue
+		}
+		if write.Status != nil {
+			filter["status"] = matchStatus(write.Status.From)
+			writeModels[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{
+				"$set":  bson.M{"status": write.Status.To},
+				"$push": bson.M{"statusHistory": write.Status},
+			})
+			continue
+		}
+		set := bson.M{}
+		if write.Name != nil {
+			set["name"] = *write.Name
+		}
+		if write.Email != nil {
+			set["email"] = *write.Email
+		}
+		if write.Role != nil {
+			set["role"] = *write.Role
+		}
+		writeModels[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(setUserFields(set))
+	}
+
+	if !atomic {
+		_, err := r.db.Collection("users").BulkWrite(ctx, writeModels, options.BulkWrite().SetOrdered(false))
+		failed, err := writeErrors(err, "failed to write users")
+		if err != nil {
+			return nil, err
+		}
+		return failed, r.deleteMemberships(ctx, writes, failed)
+	}
+
+	session, err := r.db.Client().StartSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", utils.ContextError(err))
+	}
+	defer session.EndSession(ctx)
+
+	var failed map[int]error
+	_, err = session.WithTransaction(ctx, func(ctx mongo.SessionContext) (interface{}, error) {
+		_, err := r.db.Collection("users").BulkWrite(ctx, writeModels)
+		if failed, err = writeErrors(err, "failed to write users"); err != nil {
+			return nil, err
+		}
+		if len(failed) > 0 {
+			return nil, errBulkAborted
+		}
+		return nil, r.deleteMemberships(ctx, writes, failed)
+	})
+	var serverErr mongo.ServerError
+	switch {
+	case errors.Is(err, errBulkAborted):
+		return failed, nil
+	case errors.As(err, &serverErr) && serverErr.HasErrorCode(illegalOperation):
+		return nil, ErrTransactionsUnsupported
+	case err != nil:
+		return nil, fmt.Errorf("failed to write users: %w", utils.ContextError(err))
+	}
+	return failed, nil
+}
+
+// errBulkAborted aborts the transaction of an atomic bulk write.
+var errBulkAborted = errors.New("bulk write aborted")
+
+// deleteMemberships removes the users deleted by writes from every group.
+func (r *bulkRepository) deleteMemberships(ctx context.Context, writes []UserWrite, failed map[int]error) error {
+	var ids []int
+	for i, write := range writes {
+		if _, ok := failed[i]; write.Delete && !ok {
+			ids = append(ids, write.UserID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	memberships, err := scopeToTenant(ctx, bson.M{"userId": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+	if _, err := r.db.Collection("group_members").DeleteMany(ctx, memberships); err != nil {
+		return fmt.Errorf("failed to delete group memberships: %w", utils.ContextError(err))
+	}
+	return nil
+}
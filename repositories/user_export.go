@@ -0,0 +1,247 @@
+package repositories
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/models"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrExportJobNotFound = errors.New("export job not found")
+
+// exportBatchSize is how many users each round trip of an export reads.
+const exportBatchSize = 500
+
+// ExportRepository reads users for exports and stores the exports that run
+// in the background, with their files in the exports GridFS bucket.
+// Queries are scoped to the tenant in the context.
+type ExportRepository interface {
+	StreamUsers(ctx context.Context, filter *dtos.UserFilter, fn func(*models.User) error) error
+	CreateExportJob(ctx context.Context, job *models.ExportJob) error
+	UpdateExportJob(ctx context.Context, job *models.ExportJob) error
+	GetExportJob(ctx context.Context, id string) (*models.ExportJob, error)
+	SaveExportFile(ctx context.Context, fileID primitive.ObjectID, name string, write func(io.Writer) error) (int64, error)
+	OpenExportFile(ctx context.Context, fileID primitive.ObjectID) (io.ReadCloser, error)
+	DeleteExpiredExports(ctx context.Context, now time.Time) (int64, error)
+}
+
+type exportRepository struct {
+	db *mongo.Database
+}
+
+func NewExportRepository(db *mongo.Database) ExportRepository {
+	return &exportRepository{db: db}
+}
+
+func (r *exportRepository) bucket() (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(r.db, options.GridFSBucket().SetName("exports"))
+}
+
+// StreamUsers calls fn for each user matching filter, reading them from a
+// cursor in batches rather than all at once. Users are ordered by tenant
+// and email, which the email index covers, and never include the password.
+func (r *exportRepository) StreamUsers(ctx context.Context, userFilter *dtos.UserFilter, fn func(*models.User) error) error {
+	filter, err := scopeToTenant(ctx, matchUsers(userFilter))
+	if err != nil {
+		return err
+	}
+
+	opts := options.Find().
+		SetProjection(bson.M{"password": 0}).
+		SetSort(bson.D{{Key: "tenantId", Value: 1}, {Key: "email", Value: 1}}).
+		SetBatchSize(exportBatchSize)
+	cursor, err := r.db.Collection("users").Find(ctx, filter, opts)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve users: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			return fmt.Errorf("failed to decode user: %w", utils.ContextError(err))
+		}
+		if err := fn(&user); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor error: %w", utils.ContextError(err))
+	}
+	return nil
+}
+
+func (r *exportRepository) CreateExportJob(ctx context.Context, job *models.ExportJob) error {
+	tenantID, err := tenant.ForWrite(ctx, job.TenantID)
+	if err != nil {
+		return err
+	}
+	job.TenantID = tenantID
+
+	result, err := r.db.Collection("export_jobs").InsertOne(ctx, job)
+	if err != nil {
+		return fmt.Errorf("failed to create export job: %w", utils.ContextError(err))
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		job.ID = id
+	}
+	return nil
+}
+
+// UpdateExportJob saves the progress and outcome of job.
+func (r *exportRepository) UpdateExportJob(ctx context.Context, job *models.ExportJob) error {
+	filter, err := scopeToTenant(ctx, bson.M{"_id": job.ID})
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{
+		"status":    job.Status,
+		"rows":      job.Rows,
+		"size":      job.Size,
+		"error":     job.Error,
+		"expiresAt": job.ExpiresAt,
+	}
+	if job.CompletedAt != nil {
+		set["completedAt"] = job.CompletedAt
+	}
+	result, err := r.db.Collection("export_jobs").UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return fmt.Errorf("failed to update export job: %w", utils.ContextError(err))
+	}
+	if result.MatchedCount == 0 {
+		return ErrExportJobNotFound
+	}
+	return nil
+}
+
+func (r *exportRepository) GetExportJob(ctx context.Context, id string) (*models.ExportJob, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrExportJobNotFound
+	}
+	filter, err := scopeToTenant(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return nil, err
+	}
+
+	var job models.ExportJob
+	err = r.db.Collection("export_jobs").FindOne(ctx, filter).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrExportJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve export job: %w", utils.ContextError(err))
+	}
+	return &job, nil
+}
+
+// SaveExportFile stores what write writes as the file fileID and returns
+// its size. The file is discarded when write fails.
+func (r *exportRepository) SaveExportFile(ctx context.Context, fileID primitive.ObjectID, name string, write func(io.Writer) error) (int64, error) {
+	bucket, err := r.bucket()
+	if err != nil {
+		return 0, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = bucket.SetWriteDeadline(deadline)
+	}
+	stream, err := bucket.OpenUploadStreamWithID(fileID, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create export file: %w", utils.ContextError(err))
+	}
+
+	counter := &countingWriter{w: stream}
+	if err := write(counter); err != nil {
+		_ = stream.Abort()
+		return 0, err
+	}
+	if err := stream.Close(); err != nil {
+		return 0, fmt.Errorf("failed to save export file: %w", utils.ContextError(err))
+	}
+	return counter.n, nil
+}
+
+func (r *exportRepository) OpenExportFile(ctx context.Context, fileID primitive.ObjectID) (io.ReadCloser, error) {
+	bucket, err := r.bucket()
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = bucket.SetReadDeadline(deadline)
+	}
+	stream, err := bucket.OpenDownloadStream(fileID)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, ErrExportJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export file: %w", utils.ContextError(err))
+	}
+	return stream, nil
+}
+
+// DeleteExpiredExports deletes the jobs that expired by now together with
+// their files, and returns how many jobs were deleted.
+func (r *exportRepository) DeleteExpiredExports(ctx context.Context, now time.Time) (int64, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"expiresAt": bson.M{"$lte": now}})
+	if err != nil {
+		return 0, err
+	}
+
+	cursor, err := r.db.Collection("export_jobs").Find(ctx, filter, options.Find().SetProjection(bson.M{"fileId": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find expired exports: %w", utils.ContextError(err))
+	}
+	var jobs []models.ExportJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return 0, fmt.Errorf("failed to decode expired exports: %w", utils.ContextError(err))
+	}
+	if len(jobs) == 0 {
+		return 0, nil
+	}
+
+	bucket, err := r.bucket()
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(jobs))
+	for _, job := range jobs {
+		// Files of failed or interrupted jobs may be partial or missing;
+		// deleting them still removes their chunks.
+		if job.FileID != nil {
+			err := bucket.DeleteContext(ctx, *job.FileID)
+			if err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+				return 0, fmt.Errorf("failed to delete export file: %w", utils.ContextError(err))
+			}
+		}
+		ids = append(ids, job.ID)
+	}
+
+	result, err := r.db.Collection("export_jobs").DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired exports: %w", utils.ContextError(err))
+	}
+	return result.DeletedCount, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
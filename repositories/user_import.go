@@ -0,0 +1,189 @@
+package repositories
+
+import (
+	"7-solutions/models"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrImportJobNotFound = errors.New("import job not found")
+
+// ImportRepository writes imported users in bulk and tracks the imports
+// that run in the background. Queries are scoped to the tenant in the
+// context.
+type ImportRepository interface {
+	FindUsersByEmail(ctx context.Context, emails []string) ([]models.User, error)
+	InsertUsers(ctx context.Context, users []models.User) (map[int]error, error)
+	UpdateUsers(ctx context.Context, users []models.User) (map[int]error, error)
+	CreateImportJob(ctx context.Context, job *models.ImportJob) error
+	UpdateImportJob(ctx context.Context, job *models.ImportJob) error
+	GetImportJob(ctx context.Context, id string) (*models.ImportJob, error)
+}
+
+type importRepository struct {
+	db *mongo.Database
+}
+
+func NewImportRepository(db *mongo.Database) ImportRepository {
+	return &importRepository{db: db}
+}
+
+// FindUsersByEmail matches emails case-insensitively.
+func (r *importRepository) FindUsersByEmail(ctx context.Context, emails []string) ([]models.User, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"email": bson.M{"$in": emails}})
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetCollation(emailCollation)
+	cursor, err := r.db.Collection("users").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users: %w", utils.ContextError(err))
+	}
+	defer cursor.Close(ctx)
+
+	users := []models.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %w", utils.ContextError(err))
+	}
+	return users, nil
+}
+
+// InsertUsers assigns ids and the tenant of ctx to users and inserts them
+// with a single unordered InsertMany, so one bad user does not stop the
+// others. The map holds the errors of the users that were not inserted by
+// index, ErrEmailTaken for duplicate emails.
+func (r *importRepository) InsertUsers(ctx context.Context, users []models.User) (map[int]error, error) {
+	tenantID, err := tenant.ForWrite(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	lastID, err := GetNextSequences(ctx, r.db, "users", len(users))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get new user IDs: %w", utils.ContextError(err))
+	}
+
+	documents := make([]interface{}, len(users))
+	for i := range users {
+		users[i].ID = lastID - len(users) + 1 + i
+		users[i].TenantID = tenantID
+		documents[i] = users[i]
+	}
+
+	_, err = r.db.Collection("users").InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	return writeErrors(err, "failed to insert users")
+}
+
+// UpdateUsers sets the name and, when not empty, the role of the user with
+// the id of each of users, in a single unordered BulkWrite.
+func (r *importRepository) UpdateUsers(ctx context.Context, users []models.User) (map[int]error, error) {
+	writes := make([]mongo.WriteModel, len(users))
+	for i, user := range users {
+		filter, err := scopeToTenant(ctx, bson.M{"id": user.ID})
+		if err != nil {
+			return nil, err
+		}
+		set := bson.M{"name": user.Name}
+		if user.Role != "" {
+			set["role"] = user.Role
+		}
+		writes[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{"$set": set})
+	}
+
+	_, err := r.db.Collection("users").BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return writeErrors(err, "failed to update users")
+}
+
+// writeErrors splits the error of an unordered bulk write into the errors
+// of single documents, by index, and an error that failed the whole write.
+func writeErrors(err error, message string) (map[int]error, error) {
+	failed := map[int]error{}
+	if err == nil {
+		return failed, nil
+	}
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil || len(bulkErr.WriteErrors) == 0 {
+		return nil, fmt.Errorf("%s: %w", message, utils.ContextError(err))
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if mongo.IsDuplicateKeyError(writeErr) {
+			failed[writeErr.Index] = ErrEmailTaken
+		} else {
+			failed[writeErr.Index] = errors.New(writeErr.Message)
+		}
+	}
+	return failed, nil
+}
+
+func (r *importRepository) CreateImportJob(ctx context.Context, job *models.ImportJob) error {
+	tenantID, err := tenant.ForWrite(ctx, job.TenantID)
+	if err != nil {
+		return err
+	}
+	job.TenantID = tenantID
+
+	result, err := r.db.Collection("import_jobs").InsertOne(ctx, job)
+	if err != nil {
+		return fmt.Errorf("failed to create import job: %w", utils.ContextError(err))
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		job.ID = id
+	}
+	return nil
+}
+
+// UpdateImportJob saves the progress and outcome of job.
+func (r *importRepository) UpdateImportJob(ctx context.Context, job *models.ImportJob) error {
+	filter, err := scopeToTenant(ctx, bson.M{"_id": job.ID})
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{
+		"status":    job.Status,
+		"processed": job.Processed,
+		"results":   job.Results,
+		"error":     job.Error,
+		"expiresAt": job.ExpiresAt,
+	}
+	if job.CompletedAt != nil {
+		set["completedAt"] = job.CompletedAt
+	}
+	result, err := r.db.Collection("import_jobs").UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return fmt.Errorf("failed to update import job: %w", utils.ContextError(err))
+	}
+	if result.MatchedCount == 0 {
+		return ErrImportJobNotFound
+	}
+	return nil
+}
+
+func (r *importRepository) GetImportJob(ctx context.Context, id string) (*models.ImportJob, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrImportJobNotFound
+	}
+	filter, err := scopeToTenant(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return nil, err
+	}
+
+	var job models.ImportJob
+	err = r.db.Collection("import_jobs").FindOne(ctx, filter).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrImportJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve import job: %w", utils.ContextError(err))
+	}
+	return &job, nil
+}
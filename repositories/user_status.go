@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"7-solutions/models"
+	"7-solutions/utils"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrStatusChanged is returned when the status of a user changed, or the
+// user was deleted, between reading and changing it.
+var ErrStatusChanged = errors.New("user status changed concurrently")
+
+// StatusRepository reads and changes account statuses, scoped to the tenant
+// in the context.
+type StatusRepository interface {
+	GetUser(ctx context.Context, id int) (*models.User, error)
+	// GetUserStatus returns the status of the user, active when none is
+	// stored.
+	GetUserStatus(ctx context.Context, id int) (string, error)
+	// ChangeUserStatus moves the user from change.From to change.To and
+	// appends change to their history.
+	ChangeUserStatus(ctx context.Context, id int, change *models.StatusChange) error
+}
+
+type statusRepository struct {
+	db *mongo.Database
+}
+
+func NewStatusRepository(db *mongo.Database) StatusRepository {
+	return &statusRepository{db: db}
+}
+
+// GetUser returns the user without their password hash.
+func (r *statusRepository) GetUser(ctx context.Context, id int) (*models.User, error) {
+	return r.findUser(ctx, id, bson.M{"password": 0})
+}
+
+func (r *statusRepository) GetUserStatus(ctx context.Context, id int) (string, error) {
+	user, err := r.findUser(ctx, id, bson.M{"status": 1})
+	if err != nil {
+		return "", err
+	}
+	return user.AccountStatus(), nil
+}
+
+func (r *statusRepository) findUser(ctx context.Context, id int, projection bson.M) (*models.User, error) {
+	filter, err := scopeToTenant(ctx, bson.M{"id": id})
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	err = r.db.Collection("users").FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user: %w", utils.ContextError(err))
+	}
+	return &user, nil
+}
+
+// ChangeUserStatus only matches the user while they still have the status
+// change.From, so concurrent changes cannot both apply.
+func (r *statusRepository) ChangeUserStatus(ctx context.Context, id int, change *models.StatusChange) error {
+	filter, err := scopeToTenant(ctx, bson.M{"id": id, "status": matchStatus(change.From)})
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{
+		"$set":  bson.M{"status": change.To},
+		"$push": bson.M{"statusHistory": change},
+	}
+	result, err := r.db.Collection("users").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to change user status: %w", utils.ContextError(err))
+	}
+	if result.MatchedCount == 0 {
+		return ErrStatusChanged
+	}
+	return nil
+}
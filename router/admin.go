@@ -0,0 +1,29 @@
+package router
+
+import (
+	handlers "7-solutions/handlers"
+	"7-solutions/middleware"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AddAdminRouter serves support tools. Impersonation tokens expire after
+// impersonationTTL and cannot be used to impersonate again.
+func AddAdminRouter(r *gin.Engine, db *mongo.Database, impersonationTTL time.Duration, middlewares ...gin.HandlerFunc) {
+	impersonationHandler := handlers.NewImpersonationHandler(services.NewImpersonationService(
+		repositories.WithImpersonationMetrics(repositories.NewImpersonationRepository(db)),
+		impersonationTTL,
+	))
+
+	adminGroup := r.Group("/admin")
+
+	adminGroup.Use(authentication(db), middleware.RequireRole(models.RoleAdmin), middleware.RejectImpersonation())
+	adminGroup.Use(middlewares...)
+
+	adminGroup.POST("/impersonate/:id", impersonationHandler.Impersonate)
+}
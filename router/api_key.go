@@ -0,0 +1,35 @@
+package router
+
+import (
+	handlers "7-solutions/handlers"
+	"7-solutions/middleware"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func AddAPIKeyRouter(r *gin.Engine, db *mongo.Database, middlewares ...gin.HandlerFunc) {
+	apiKeyHandler := handlers.NewAPIKeyHandler(newAPIKeyService(db))
+
+	apiKeyGroup := r.Group("/api-keys")
+
+	apiKeyGroup.Use(authentication(db), middleware.RequireRole(models.RoleAdmin), middleware.RejectImpersonation())
+	apiKeyGroup.Use(middlewares...)
+
+	apiKeyGroup.POST("/", apiKeyHandler.CreateAPIKey)
+	apiKeyGroup.GET("/", apiKeyHandler.GetAllAPIKeys)
+	apiKeyGroup.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
+}
+
+func newAPIKeyService(db *mongo.Database) services.APIKeyService {
+	return services.NewAPIKeyService(repositories.WithAPIKeyMetrics(repositories.NewAPIKeyRepository(db)))
+}
+
+// authentication accepts JWTs of active users that have not been revoked,
+// nor had their session revoked, and API keys.
+func authentication(db *mongo.Database) gin.HandlerFunc {
+	return middleware.AuthenticationMiddleware(newAPIKeyService(db), newOAuthService(db), newSessionService(db), newAccountChecker(db))
+}
@@ -2,6 +2,8 @@ package router
 
 import (
 	handlers "7-solutions/handlers"
+	"7-solutions/middleware"
+	"7-solutions/models"
 	"7-solutions/repositories"
 	services "7-solutions/services"
 
@@ -12,13 +14,40 @@ import (
 func AddAuthRouter(
 	r *gin.Engine,
 	db *mongo.Database,
+	middlewares ...gin.HandlerFunc,
 ) {
-	authRepository := repositories.NewAuthRepository(db)
-	authService := services.NewAuthService(authRepository)
-	authHandler := handlers.NewAuthHandler(authService)
+	authHandler := handlers.NewAuthHandler(newAuthService(db))
+	oauthHandler := handlers.NewOAuthHandler(newOAuthService(db))
 
-	authGroup := r.Group("/auth")
+	authGroup := r.Group("/auth", middlewares...)
 
 	authGroup.POST("/register", authHandler.RegisterUser)
 	authGroup.POST("/login", authHandler.AuthenticateUser)
+
+	authGroup.GET("/authorize", oauthHandler.AuthorizePage)
+	authGroup.POST("/authorize", oauthHandler.Authorize)
+	authGroup.POST("/token", oauthHandler.Token)
+	authGroup.POST("/introspect", oauthHandler.Introspect)
+	authGroup.POST("/revoke", oauthHandler.Revoke)
+
+	clientGroup := authGroup.Group("/clients", authentication(db), middleware.RequireRole(models.RoleAdmin), middleware.RejectImpersonation())
+	clientGroup.POST("/", oauthHandler.RegisterClient)
+	clientGroup.GET("/", oauthHandler.GetAllClients)
+}
+
+func newAuthService(db *mongo.Database) services.AuthService {
+	return services.NewAuthService(
+		repositories.WithAuthMetrics(repositories.NewAuthRepository(db)),
+		repositories.WithSessionMetrics(repositories.NewSessionRepository(db)),
+	)
+}
+
+func newOAuthService(db *mongo.Database) services.OAuthService {
+	return services.NewOAuthService(
+		repositories.WithOAuthMetrics(repositories.NewOAuthRepository(db)),
+		repositories.WithUserMetrics(repositories.NewUserRepository(db)),
+		newAuthService(db),
+		newSessionService(db),
+		newAccountChecker(db),
+	)
 }
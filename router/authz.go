@@ -0,0 +1,23 @@
+package router
+
+import (
+	handlers "7-solutions/handlers"
+	"7-solutions/middleware"
+	"7-solutions/models"
+	"7-solutions/policy"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func AddAuthzRouter(r *gin.Engine, db *mongo.Database, engine *policy.Engine, middlewares ...gin.HandlerFunc) {
+	authzService := newAuthzService(db, engine)
+	authzHandler := handlers.NewAuthzHandler(authzService)
+
+	authzGroup := r.Group("/authz")
+
+	authzGroup.Use(authentication(db), middleware.RequireRole(models.RoleAdmin))
+	authzGroup.Use(middlewares...)
+
+	authzGroup.POST("/check", authzHandler.Check)
+}
@@ -0,0 +1,35 @@
+package router
+
+import (
+	"7-solutions/federation"
+	handlers "7-solutions/handlers"
+	"7-solutions/middleware"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func AddFederationRouter(r *gin.Engine, db *mongo.Database, configs []federation.Config, middlewares ...gin.HandlerFunc) {
+	providers := make([]*federation.Provider, 0, len(configs))
+	for _, config := range configs {
+		providers = append(providers, federation.NewProvider(config, nil))
+	}
+	federationHandler := handlers.NewFederationHandler(services.NewFederationService(
+		repositories.WithFederationMetrics(repositories.NewFederationRepository(db)),
+		repositories.WithSessionMetrics(repositories.NewSessionRepository(db)),
+		providers,
+	))
+
+	federationGroup := r.Group("/auth/federation", middlewares...)
+	federationGroup.GET("/", federationHandler.GetProviders)
+	federationGroup.GET("/:provider/login", federationHandler.Login)
+	federationGroup.GET("/:provider/callback", federationHandler.Callback)
+
+	identityGroup := r.Group("/identities")
+	identityGroup.Use(authentication(db))
+	identityGroup.Use(middlewares...)
+	identityGroup.GET("/", federationHandler.GetIdentities)
+	identityGroup.DELETE("/:id", middleware.RejectImpersonation(), federationHandler.UnlinkIdentity)
+}
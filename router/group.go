@@ -0,0 +1,40 @@
+package router
+
+import (
+	handlers "7-solutions/handlers"
+	"7-solutions/middleware"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func AddGroupRouter(r *gin.Engine, db *mongo.Database, middlewares ...gin.HandlerFunc) {
+	groupRepository := repositories.WithGroupMetrics(repositories.NewGroupRepository(db))
+	groupHandler := handlers.NewGroupHandler(services.NewGroupService(groupRepository))
+
+	groupGroup := r.Group("/groups")
+
+	groupGroup.Use(authentication(db))
+	groupGroup.Use(middlewares...)
+
+	canRead := middleware.RequireScope(models.ScopeGroupsRead)
+	// Group writes change who inherits which policies, so only admins make
+	// them.
+	canWrite := middleware.RequireRole(models.RoleAdmin)
+
+	groupGroup.POST("/", canWrite, groupHandler.CreateGroup)
+	groupGroup.GET("/", canRead, groupHandler.GetAllGroups)
+	groupGroup.GET("/:id", canRead, groupHandler.GetGroup)
+	groupGroup.PATCH("/:id", canWrite, groupHandler.UpdateGroup)
+	groupGroup.DELETE("/:id", canWrite, groupHandler.DeleteGroup)
+	groupGroup.GET("/:id/members", canRead, groupHandler.GetMembers)
+	groupGroup.POST("/:id/members", canWrite, groupHandler.AddMember)
+	groupGroup.DELETE("/:id/members/:userId", canWrite, groupHandler.RemoveMember)
+
+	userGroups := append([]gin.HandlerFunc{authentication(db)}, middlewares...)
+	userGroups = append(userGroups, canRead, groupHandler.GetUserGroups)
+	r.GET("/users/:id/groups", userGroups...)
+}
@@ -0,0 +1,23 @@
+package router
+
+import (
+	handlers "7-solutions/handlers"
+	"7-solutions/middleware"
+	"7-solutions/models"
+	services "7-solutions/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func AddHealthRouter(r *gin.Engine, db *mongo.Database, healthService services.HealthService) {
+	healthHandler := handlers.NewHealthHandler(healthService)
+
+	r.GET("/healthz", healthHandler.Liveness)
+	r.GET("/readyz", healthHandler.Readiness)
+	r.GET("/health",
+		middleware.AuthenticationMiddleware(nil, newOAuthService(db), newSessionService(db), newAccountChecker(db)),
+		middleware.RequireRole(models.RoleAdmin),
+		healthHandler.Health,
+	)
+}
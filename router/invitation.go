@@ -0,0 +1,54 @@
+package router
+
+import (
+	handlers "7-solutions/handlers"
+	"7-solutions/mailer"
+	"7-solutions/middleware"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// InvitationConfig configures the links sent in invitation emails.
+type InvitationConfig struct {
+	// AcceptURL is the page invitees open; it receives the token in the
+	// token query parameter and posts it to /invitations/accept.
+	AcceptURL string
+	TTL       time.Duration
+}
+
+// AddInvitationRouter serves the admin endpoints behind adminMiddlewares
+// and the public accept endpoint behind acceptMiddlewares.
+func AddInvitationRouter(
+	r *gin.Engine,
+	db *mongo.Database,
+	config InvitationConfig,
+	mail mailer.Mailer,
+	adminMiddlewares []gin.HandlerFunc,
+	acceptMiddlewares []gin.HandlerFunc,
+) {
+	invitationService := services.NewInvitationService(
+		repositories.WithInvitationMetrics(repositories.NewInvitationRepository(db)),
+		repositories.WithGroupMetrics(repositories.NewGroupRepository(db)),
+		mail,
+		config.AcceptURL,
+		config.TTL,
+	)
+	invitationHandler := handlers.NewInvitationHandler(invitationService)
+
+	r.POST("/invitations/accept", append(acceptMiddlewares, invitationHandler.AcceptInvitation)...)
+
+	invitationGroup := r.Group("/invitations")
+
+	invitationGroup.Use(authentication(db), middleware.RequireRole(models.RoleAdmin))
+	invitationGroup.Use(adminMiddlewares...)
+
+	invitationGroup.POST("/", invitationHandler.CreateInvitation)
+	invitationGroup.GET("/", invitationHandler.GetPendingInvitations)
+	invitationGroup.POST("/:id/resend", invitationHandler.ResendInvitation)
+	invitationGroup.DELETE("/:id", invitationHandler.RevokeInvitation)
+}
@@ -0,0 +1,12 @@
+package router
+
+import (
+	"7-solutions/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func AddMetricsRouter(r *gin.Engine) {
+	r.GET("/metrics", gin.WrapH(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})))
+}
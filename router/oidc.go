@@ -0,0 +1,21 @@
+package router
+
+import (
+	handlers "7-solutions/handlers"
+	"7-solutions/middleware"
+	"7-solutions/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func AddOIDCRouter(r *gin.Engine, db *mongo.Database) {
+	oidcHandler := handlers.NewOIDCHandler(newOAuthService(db))
+
+	r.GET("/.well-known/openid-configuration", oidcHandler.Discovery)
+	r.GET("/.well-known/jwks.json", oidcHandler.JWKS)
+
+	userInfo := []gin.HandlerFunc{authentication(db), middleware.RequireScope(models.ScopeOpenID), oidcHandler.UserInfo}
+	r.GET("/userinfo", userInfo...)
+	r.POST("/userinfo", userInfo...)
+}
@@ -0,0 +1,26 @@
+package router
+
+import (
+	handlers "7-solutions/handlers"
+	"7-solutions/middleware"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func AddOrganizationRouter(r *gin.Engine, db *mongo.Database, middlewares ...gin.HandlerFunc) {
+	organizationRepository := repositories.WithOrganizationMetrics(repositories.NewOrganizationRepository(db))
+	organizationHandler := handlers.NewOrganizationHandler(services.NewOrganizationService(organizationRepository))
+
+	organizationGroup := r.Group("/organizations")
+
+	organizationGroup.Use(authentication(db), middleware.RequireRole(models.RoleSuperAdmin))
+	organizationGroup.Use(middlewares...)
+
+	organizationGroup.POST("/", organizationHandler.CreateOrganization)
+	organizationGroup.GET("/", organizationHandler.GetAllOrganizations)
+	organizationGroup.GET("/:id", organizationHandler.GetOrganization)
+}
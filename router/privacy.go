@@ -0,0 +1,57 @@
+package router
+
+import (
+	handlers "7-solutions/handlers"
+	"7-solutions/logging"
+	"7-solutions/middleware"
+	"7-solutions/models"
+	"7-solutions/policy"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PrivacyConfig configures data subject requests.
+type PrivacyConfig struct {
+	// PseudonymKey keys the pseudonyms that replace the ids of erased
+	// users. Changing it loses track of past erasures.
+	PseudonymKey string
+}
+
+// AddPrivacyRouter lets users download their data and admins download the
+// data of any user of their tenant and erase them; privacyService is owned
+// by the caller so a job can resume interrupted erasures.
+func AddPrivacyRouter(r *gin.Engine, db *mongo.Database, privacyService services.PrivacyService, middlewares ...gin.HandlerFunc) {
+	privacyHandler := handlers.NewPrivacyHandler(privacyService)
+
+	userGroup := r.Group("/users/:id")
+
+	userGroup.Use(authentication(db))
+	userGroup.Use(middlewares...)
+
+	isAdmin := middleware.RequireRole(models.RoleAdmin)
+	userGroup.GET("/data-export", middleware.RequireSelfOrRole(models.RoleAdmin), privacyHandler.ExportUserData)
+	userGroup.POST("/erasure", isAdmin, middleware.RejectImpersonation(), privacyHandler.EraseUser)
+	userGroup.GET("/erasure", isAdmin, privacyHandler.GetErasure)
+}
+
+// NewPrivacyService exports and erases the data of users in db, subject to
+// the policies of engine.
+func NewPrivacyService(db *mongo.Database, engine *policy.Engine, config PrivacyConfig) services.PrivacyService {
+	return services.NewPrivacyService(
+		repositories.WithPrivacyMetrics(repositories.NewPrivacyRepository(db)),
+		repositories.WithGroupMetrics(repositories.NewGroupRepository(db)),
+		repositories.WithAuditMetrics(repositories.NewAuditRepository(db)),
+		newAuthzService(db, engine),
+		config.PseudonymKey,
+	)
+}
+
+// NewAuditRecorder stores audit events in db; install it with
+// logging.SetAuditSink.
+func NewAuditRecorder(db *mongo.Database) func(context.Context, logging.AuditEvent) {
+	return services.NewAuditRecorder(repositories.WithAuditMetrics(repositories.NewAuditRepository(db)))
+}
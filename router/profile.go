@@ -0,0 +1,42 @@
+package router
+
+import (
+	handlers "7-solutions/handlers"
+	"7-solutions/middleware"
+	"7-solutions/models"
+	"7-solutions/policy"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AddProfileRouter lets admins define the custom attributes of the users of
+// their tenant under /attributes, and users and admins set their values
+// under /users/:id/attributes.
+func AddProfileRouter(r *gin.Engine, db *mongo.Database, engine *policy.Engine, middlewares ...gin.HandlerFunc) {
+	profileHandler := handlers.NewProfileHandler(services.NewProfileService(
+		newProfileRepository(db),
+		newAuthzService(db, engine),
+	))
+
+	attributeGroup := r.Group("/attributes")
+
+	attributeGroup.Use(authentication(db))
+	attributeGroup.Use(middlewares...)
+
+	isAdmin := middleware.RequireRole(models.RoleAdmin)
+	attributeGroup.POST("/", isAdmin, profileHandler.CreateAttribute)
+	attributeGroup.GET("/", middleware.RequireScope(models.ScopeUsersRead), profileHandler.GetAttributes)
+	attributeGroup.PUT("/:key", isAdmin, profileHandler.UpdateAttribute)
+	attributeGroup.DELETE("/:key", isAdmin, profileHandler.DeleteAttribute)
+
+	userAttributes := append([]gin.HandlerFunc{authentication(db)}, middlewares...)
+	userAttributes = append(userAttributes, middleware.RequireSelfOrRole(models.RoleAdmin), profileHandler.UpdateUserAttributes)
+	r.PUT("/users/:id/attributes", userAttributes...)
+}
+
+func newProfileRepository(db *mongo.Database) repositories.ProfileRepository {
+	return repositories.WithProfileMetrics(repositories.NewProfileRepository(db))
+}
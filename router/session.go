@@ -0,0 +1,40 @@
+package router
+
+import (
+	handlers "7-solutions/handlers"
+	"7-solutions/middleware"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AddSessionRouter lets users see and revoke their own sessions under
+// /users/me/sessions, and admins those of any user of their tenant under
+// /admin/users/:id/sessions.
+func AddSessionRouter(r *gin.Engine, db *mongo.Database, middlewares ...gin.HandlerFunc) {
+	sessionHandler := handlers.NewSessionHandler(newSessionService(db))
+
+	mySessionGroup := r.Group("/users/me/sessions")
+
+	mySessionGroup.Use(authentication(db), middleware.RequireLogin())
+	mySessionGroup.Use(middlewares...)
+
+	mySessionGroup.GET("/", sessionHandler.GetMySessions)
+	mySessionGroup.DELETE("/:sessionId", middleware.RejectImpersonation(), sessionHandler.RevokeMySession)
+
+	userSessionGroup := r.Group("/admin/users/:id/sessions")
+
+	userSessionGroup.Use(authentication(db), middleware.RequireRole(models.RoleAdmin), middleware.RejectImpersonation())
+	userSessionGroup.Use(middlewares...)
+
+	userSessionGroup.GET("/", sessionHandler.GetUserSessions)
+	userSessionGroup.DELETE("/", sessionHandler.RevokeAllUserSessions)
+	userSessionGroup.DELETE("/:sessionId", sessionHandler.RevokeUserSession)
+}
+
+func newSessionService(db *mongo.Database) services.SessionService {
+	return services.NewSessionService(repositories.WithSessionMetrics(repositories.NewSessionRepository(db)))
+}
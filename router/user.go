@@ -3,6 +3,8 @@ package router
 import (
 	handlers "7-solutions/handlers"
 	"7-solutions/middleware"
+	"7-solutions/models"
+	"7-solutions/policy"
 	"7-solutions/repositories"
 	services "7-solutions/services"
 
@@ -10,18 +12,66 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func AddUserRouter(r *gin.Engine, db *mongo.Database) {
-	userRepository := repositories.NewUserRepository(db)
-	userService := services.NewUserService(userRepository)
+// AddUserRouter serves users, bulk changes to them and their import and
+// export; importService and exportService are owned by the caller so
+// shutdown can wait for background jobs.
+func AddUserRouter(r *gin.Engine, db *mongo.Database, engine *policy.Engine, importService services.UserImportService, exportService services.UserExportService, middlewares ...gin.HandlerFunc) {
+	userRepository := repositories.WithUserMetrics(repositories.NewUserRepository(db))
+	userService := services.NewUserService(userRepository, newProfileRepository(db), newAuthzService(db, engine))
 	userHandler := handlers.NewUserHandler(userService)
+	userImportHandler := handlers.NewUserImportHandler(importService)
+	userExportHandler := handlers.NewUserExportHandler(exportService)
+	userBulkHandler := handlers.NewUserBulkHandler(services.NewUserBulkService(
+		repositories.WithBulkMetrics(repositories.NewBulkRepository(db)),
+		repositories.WithSessionMetrics(repositories.NewSessionRepository(db)),
+		newAuthzService(db, engine),
+	))
 
 	userGroup := r.Group("/users")
 
-	userGroup.Use(middleware.AuthenticationMiddleware())
+	userGroup.Use(authentication(db))
+	userGroup.Use(middlewares...)
 
-	userGroup.POST("/", userHandler.CreateUser)
-	userGroup.GET("/:id", userHandler.GetUserByID)
-	userGroup.GET("/", userHandler.GetAllUsers)
-	userGroup.PUT("/:id", userHandler.UpdateUser)
-	userGroup.DELETE("/:id", userHandler.DeleteUser)
+	canRead := middleware.RequireScope(models.ScopeUsersRead)
+	canWrite := middleware.RequireScope(models.ScopeUsersWrite)
+
+	userGroup.POST("/", canWrite, userHandler.CreateUser)
+	userGroup.GET("/:id", canRead, userHandler.GetUserByID)
+	userGroup.GET("/", canRead, userHandler.GetAllUsers)
+	userGroup.PUT("/:id", canWrite, userHandler.UpdateUser)
+	userGroup.DELETE("/:id", canWrite, userHandler.DeleteUser)
+
+	isAdmin := middleware.RequireRole(models.RoleAdmin)
+	userGroup.POST("/bulk", isAdmin, userBulkHandler.ApplyBulk)
+	userGroup.POST("/import", isAdmin, userImportHandler.ImportUsers)
+	userGroup.GET("/import/:id", isAdmin, userImportHandler.GetImportJob)
+	userGroup.GET("/export", isAdmin, userExportHandler.ExportUsers)
+	userGroup.POST("/export", isAdmin, userExportHandler.StartExport)
+	userGroup.GET("/export/:id", isAdmin, userExportHandler.GetExportJob)
+	userGroup.GET("/export/:id/download", isAdmin, userExportHandler.DownloadExport)
+}
+
+// newAuthzService checks policies with engine, looking group names up in
+// db.
+func newAuthzService(db *mongo.Database, engine *policy.Engine) services.AuthzService {
+	return services.NewAuthzService(engine, repositories.WithGroupMetrics(repositories.NewGroupRepository(db)))
+}
+
+// NewUserImportService imports users into db, checking each row against
+// the policies of engine.
+func NewUserImportService(db *mongo.Database, engine *policy.Engine) services.UserImportService {
+	return services.NewUserImportService(
+		repositories.WithImportMetrics(repositories.NewImportRepository(db)),
+		newAuthzService(db, engine),
+	)
+}
+
+// NewUserExportService exports users from db, subject to the policies of
+// engine.
+func NewUserExportService(db *mongo.Database, engine *policy.Engine) services.UserExportService {
+	return services.NewUserExportService(
+		repositories.WithExportMetrics(repositories.NewExportRepository(db)),
+		newProfileRepository(db),
+		newAuthzService(db, engine),
+	)
 }
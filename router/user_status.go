@@ -0,0 +1,35 @@
+package router
+
+import (
+	handlers "7-solutions/handlers"
+	"7-solutions/middleware"
+	"7-solutions/models"
+	"7-solutions/policy"
+	"7-solutions/repositories"
+	services "7-solutions/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AddUserStatusRouter lets admins see and change the account status of the
+// users of their tenant under /admin/users/:id/status.
+func AddUserStatusRouter(r *gin.Engine, db *mongo.Database, engine *policy.Engine, middlewares ...gin.HandlerFunc) {
+	userStatusHandler := handlers.NewUserStatusHandler(services.NewUserStatusService(
+		repositories.WithStatusMetrics(repositories.NewStatusRepository(db)),
+		repositories.WithSessionMetrics(repositories.NewSessionRepository(db)),
+		newAuthzService(db, engine),
+	))
+
+	userStatusGroup := r.Group("/admin/users/:id/status")
+
+	userStatusGroup.Use(authentication(db), middleware.RequireRole(models.RoleAdmin))
+	userStatusGroup.Use(middlewares...)
+
+	userStatusGroup.GET("/", userStatusHandler.GetStatus)
+	userStatusGroup.PUT("/", middleware.RejectImpersonation(), userStatusHandler.ChangeStatus)
+}
+
+func newAccountChecker(db *mongo.Database) services.AccountChecker {
+	return services.NewAccountChecker(repositories.WithStatusMetrics(repositories.NewStatusRepository(db)))
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/logging"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/tracing"
+	"7-solutions/utils"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix starts every key so it is easy to spot in logs and secret
+// scanners.
+const APIKeyPrefix = "7s"
+
+var (
+	ErrInvalidAPIKeyRequest = errors.New("invalid api key request")
+	ErrInvalidAPIKey        = errors.New("invalid api key")
+	ErrAPIKeyExpired        = errors.New("api key expired")
+	ErrAPIKeyRevoked        = errors.New("api key revoked")
+)
+
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, createdBy string, input *dtos.APIKeyCreate) (*dtos.APIKeyCreated, error)
+	GetAllAPIKeys(ctx context.Context) ([]dtos.APIKeyResponse, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+type apiKeyService struct {
+	apiKeyRepository repositories.APIKeyRepository
+	now              func() time.Time
+}
+
+func NewAPIKeyService(apiKeyRepository repositories.APIKeyRepository) APIKeyService {
+	return &apiKeyService{
+		apiKeyRepository: apiKeyRepository,
+		now:              time.Now,
+	}
+}
+
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, createdBy string, input *dtos.APIKeyCreate) (*dtos.APIKeyCreated, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.CreateAPIKey")
+	defer span.End()
+
+	now := s.now()
+	if !input.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expiresAt must be in the future", ErrInvalidAPIKeyRequest)
+	}
+	for _, scope := range input.Scopes {
+		if !slices.Contains(models.Scopes, scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKeyRequest, scope)
+		}
+	}
+
+	plaintext, prefix, err := generateAPIKey()
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	key := &models.APIKey{
+		Name:      input.Name,
+		Prefix:    prefix,
+		Hash:      sha256Hex(plaintext),
+		Scopes:    input.Scopes,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := s.apiKeyRepository.CreateAPIKey(ctx, key); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	logging.Audit(ctx, "api_key.create", "api_key_id", key.ID.Hex(), "scopes", key.Scopes)
+	return &dtos.APIKeyCreated{APIKeyResponse: toAPIKeyResponse(key), Key: plaintext}, nil
+}
+
+func (s *apiKeyService) GetAllAPIKeys(ctx context.Context) ([]dtos.APIKeyResponse, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.GetAllAPIKeys")
+	defer span.End()
+
+	keys, err := s.apiKeyRepository.GetAllAPIKeys(ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	responses := make([]dtos.APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		responses = append(responses, toAPIKeyResponse(&keys[i]))
+	}
+	return responses, nil
+}
+
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "APIKeyService.RevokeAPIKey")
+	defer span.End()
+
+	if err := s.apiKeyRepository.RevokeAPIKey(ctx, id, s.now()); err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	logging.Audit(ctx, "api_key.revoke", "api_key_id", id)
+	return nil
+}
+
+// AuthenticateAPIKey looks the key up by its prefix and compares hashes in
+// constant time. Lookup failures are reported as ErrInvalidAPIKey unless
+// they were caused by a cancellation or timeout.
+func (s *apiKeyService) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.AuthenticateAPIKey")
+	defer span.End()
+
+	prefix, ok := parseAPIKey(key)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	apiKey, err := s.apiKeyRepository.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, utils.ErrCanceled) || errors.Is(err, utils.ErrTimeout) {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		return nil, ErrInvalidAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(apiKey.Hash), []byte(sha256Hex(key))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+	if apiKey.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+	if !apiKey.ExpiresAt.After(s.now()) {
+		return nil, ErrAPIKeyExpired
+	}
+	return apiKey, nil
+}
+
+// generateAPIKey returns a key of the form 7s_<prefix>_<secret>. The prefix
+// is stored in clear for lookups; the secret carries 256 bits of entropy,
+// which is why a plain SHA-256 is enough to store it.
+func generateAPIKey() (key, prefix string, err error) {
+	buf := make([]byte, 6+32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	prefix = hex.EncodeToString(buf[:6])
+	return APIKeyPrefix + "_" + prefix + "_" + hex.EncodeToString(buf[6:]), prefix, nil
+}
+
+func parseAPIKey(key string) (prefix string, ok bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != APIKeyPrefix || len(parts[1]) != 12 || len(parts[2]) != 64 {
+		return "", false
+	}
+	return parts[1], true
+}
+
+func toAPIKeyResponse(key *models.APIKey) dtos.APIKeyResponse {
+	return dtos.APIKeyResponse{
+		ID:        key.ID.Hex(),
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		TenantID:  key.TenantID,
+		CreatedBy: key.CreatedBy,
+		CreatedAt: key.CreatedAt.Format(time.RFC3339),
+		ExpiresAt: key.ExpiresAt.Format(time.RFC3339),
+		RevokedAt: key.RevokedAt,
+	}
+}
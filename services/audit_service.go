@@ -0,0 +1,48 @@
+package services
+
+import (
+	"7-solutions/logging"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/tenant"
+	"context"
+	"fmt"
+	"time"
+)
+
+// auditWriteTimeout bounds how long storing an audit event may delay the
+// request that recorded it.
+const auditWriteTimeout = 2 * time.Second
+
+// NewAuditRecorder returns a sink for logging.SetAuditSink that stores audit
+// events in auditRepository, in the tenant of their context. The user an
+// event is about is taken from its target_user_id or user_id argument.
+// Events that cannot be stored are still logged.
+func NewAuditRecorder(auditRepository repositories.AuditRepository) func(context.Context, logging.AuditEvent) {
+	return func(ctx context.Context, event logging.AuditEvent) {
+		record := &models.AuditEvent{
+			Action:    event.Action,
+			UserID:    event.UserID,
+			ActorID:   event.ActorID,
+			RequestID: event.RequestID,
+			CreatedAt: event.Time,
+		}
+		record.TenantID, _ = tenant.FromContext(ctx)
+		for _, key := range []string{"target_user_id", "user_id"} {
+			if value, ok := event.Attrs[key]; ok {
+				record.TargetUserID = fmt.Sprint(value)
+				delete(event.Attrs, key)
+				break
+			}
+		}
+		if len(event.Attrs) > 0 {
+			record.Attributes = event.Attrs
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditWriteTimeout)
+		defer cancel()
+		if err := auditRepository.InsertAuditEvent(ctx, record); err != nil {
+			logging.FromContext(ctx).Error("failed to store audit event", "action", event.Action, "error", err)
+		}
+	}
+}
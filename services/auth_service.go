@@ -2,45 +2,140 @@ package services
 
 import (
 	"7-solutions/dtos"
+	"7-solutions/logging"
+	"7-solutions/metrics"
+	"7-solutions/models"
 	"7-solutions/repositories"
-
-	"golang.org/x/crypto/bcrypt"
+	"7-solutions/tenant"
+	"7-solutions/tracing"
+	"7-solutions/utils"
+	"context"
+	"time"
 )
 
+// maxUserAgentLength bounds the user agent stored with a session.
+const maxUserAgentLength = 512
+
 type AuthService interface {
-	RegisterUser(userDto *dtos.UserRegister) error
-	AuthenticateUser(input *dtos.UserAuthenticate) (*string, error)
+	RegisterUser(ctx context.Context, userDto *dtos.UserRegister) error
+	AuthenticateUser(ctx context.Context, input *dtos.UserAuthenticate) (*string, error)
+	VerifyCredentials(ctx context.Context, input *dtos.UserAuthenticate) (*models.User, error)
 }
 
 type authService struct {
-	authRepository repositories.AuthRepository
+	authRepository    repositories.AuthRepository
+	sessionRepository repositories.SessionRepository
 }
 
-func NewAuthService(authRepository repositories.AuthRepository) AuthService {
+func NewAuthService(authRepository repositories.AuthRepository, sessionRepository repositories.SessionRepository) AuthService {
 	return &authService{
-		authRepository: authRepository,
+		authRepository:    authRepository,
+		sessionRepository: sessionRepository,
 	}
 }
 
-func (s *authService) RegisterUser(userDto *dtos.UserRegister) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(userDto.Password), bcrypt.DefaultCost)
+// RegisterUser signs a user up in the default tenant. Users of other
+// organizations are created by their admins.
+func (s *authService) RegisterUser(ctx context.Context, userDto *dtos.UserRegister) error {
+	ctx, span := tracing.Start(ctx, "AuthService.RegisterUser")
+	defer span.End()
+	ctx = tenant.WithID(ctx, tenant.DefaultID)
+
+	hashedPassword, err := hashPassword(ctx, userDto.Password)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return err
 	}
 	userDto.Password = string(hashedPassword)
 
-	err = s.authRepository.RegisterUser(userDto)
+	err = s.authRepository.RegisterUser(ctx, userDto)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return err
 	}
+	logging.Audit(ctx, "user.register", "email", userDto.Email)
 	return nil
 }
 
-func (s *authService) AuthenticateUser(input *dtos.UserAuthenticate) (*string, error) {
-	token, err := s.authRepository.AuthenticateUser(input)
+// AuthenticateUser signs a user in to the organization in input, or to the
+// default tenant. Only active users may sign in. Every sign-in starts a
+// session for the device in input, and the token stops working once the
+// session is revoked.
+func (s *authService) AuthenticateUser(ctx context.Context, input *dtos.UserAuthenticate) (*string, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.AuthenticateUser")
+	defer span.End()
+
+	user, err := s.VerifyCredentials(ctx, input)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
-	return token, nil
+	ctx = tenant.WithID(ctx, loginTenant(input))
+
+	token, session, err := startSession(ctx, s.sessionRepository, user, input.UserAgent, input.IP)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	metrics.LoginAttemptsTotal.WithLabelValues("success").Inc()
+	logging.Audit(ctx, "auth.login", "email", input.Email, "session_id", session.ID.Hex())
+	return &token, nil
 }
 
+// startSession records a session of user on the device described by
+// userAgent and ip, and issues a login token tied to it.
+func startSession(ctx context.Context, sessions repositories.SessionRepository, user *models.User, userAgent, ip string) (string, *models.Session, error) {
+	now := time.Now()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	session := &models.Session{
+		TenantID:   user.TenantID,
+		UserID:     user.ID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(utils.LoginTokenTTL),
+	}
+	if err := sessions.CreateSession(ctx, session); err != nil {
+		return "", nil, err
+	}
+	token, err := utils.GenerateSessionToken(user.ID, user.Name, user.Email, user.Role, user.TenantID, session.ID.Hex())
+	if err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// VerifyCredentials returns the user whose email and password are in input,
+// in the organization in input or the default tenant, without starting a
+// session. Only active users are returned.
+func (s *authService) VerifyCredentials(ctx context.Context, input *dtos.UserAuthenticate) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.VerifyCredentials")
+	defer span.End()
+	ctx = tenant.WithID(ctx, loginTenant(input))
+
+	user, err := s.authRepository.AuthenticateUser(ctx, input)
+	if err != nil {
+		tracing.RecordError(span, err)
+		metrics.LoginAttemptsTotal.WithLabelValues("failure").Inc()
+		logging.Audit(ctx, "auth.login_failed", "email", input.Email, "error", err)
+		return nil, err
+	}
+	// The status is only revealed to callers who know the password.
+	if err := checkAccountStatus(user.AccountStatus()); err != nil {
+		metrics.LoginAttemptsTotal.WithLabelValues("failure").Inc()
+		logging.Audit(ctx, "auth.login_failed", "email", input.Email, "error", err)
+		return nil, err
+	}
+	return user, nil
+}
+
+// loginTenant returns the organization input signs in to.
+func loginTenant(input *dtos.UserAuthenticate) string {
+	if input.TenantID == "" {
+		return tenant.DefaultID
+	}
+	return input.TenantID
+}
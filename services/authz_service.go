@@ -0,0 +1,129 @@
+package services
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/policy"
+	"7-solutions/repositories"
+	"7-solutions/tenant"
+	"7-solutions/tracing"
+	"context"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Authorizer checks the caller in ctx against the policies. Services use
+// it for decisions that depend on the data, such as which fields of a user
+// may change.
+type Authorizer interface {
+	// Authorize checks action on resource, or on each of fields when given.
+	Authorize(ctx context.Context, action string, resource policy.Resource, fields ...string) error
+}
+
+type AuthzService interface {
+	Authorizer
+	// Check explains the decision for a request without enforcing it.
+	Check(ctx context.Context, input *dtos.AuthzCheck) (*policy.Decision, error)
+}
+
+type authzService struct {
+	engine          *policy.Engine
+	groupRepository repositories.GroupRepository
+}
+
+// NewAuthzService evaluates requests with engine. Group names of users are
+// looked up with groupRepository, but only while a policy refers to them.
+func NewAuthzService(engine *policy.Engine, groupRepository repositories.GroupRepository) AuthzService {
+	return &authzService{
+		engine:          engine,
+		groupRepository: groupRepository,
+	}
+}
+
+func (s *authzService) Authorize(ctx context.Context, action string, resource policy.Resource, fields ...string) error {
+	ctx, span := tracing.Start(ctx, "AuthzService.Authorize")
+	defer span.End()
+
+	subject, _ := policy.SubjectFromContext(ctx)
+	req, err := s.request(ctx, subject, action, resource, nil)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+
+	if len(fields) == 0 {
+		return s.engine.Authorize(ctx, req)
+	}
+	for _, field := range fields {
+		req.Resource.Field = field
+		if err := s.engine.Authorize(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *authzService) Check(ctx context.Context, input *dtos.AuthzCheck) (*policy.Decision, error) {
+	ctx, span := tracing.Start(ctx, "AuthzService.Check")
+	defer span.End()
+
+	subject, _ := policy.SubjectFromContext(ctx)
+	if input.Subject != nil {
+		subject = *input.Subject
+	}
+	req, err := s.request(ctx, subject, input.Action, input.Resource, input.Context)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	decision := s.engine.Evaluate(req)
+	return &decision, nil
+}
+
+// request completes the subject's and resource's tenant and groups when
+// they were not given.
+func (s *authzService) request(ctx context.Context, subject policy.Subject, action string, resource policy.Resource, attributes map[string]any) (policy.Request, error) {
+	if resource.TenantID == "" {
+		resource.TenantID, _ = tenant.FromContext(ctx)
+	}
+
+	var err error
+	if subject.Groups == nil && s.engine.Uses("subject.groups") {
+		if subject.Groups, err = s.groupNames(ctx, subject.ID); err != nil {
+			return policy.Request{}, err
+		}
+	}
+	if resource.Groups == nil && resource.Type == "user" && s.engine.Uses("resource.groups") {
+		if resource.Groups, err = s.groupNames(ctx, resource.ID); err != nil {
+			return policy.Request{}, err
+		}
+	}
+
+	return policy.Request{Subject: subject, Action: action, Resource: resource, Context: attributes}, nil
+}
+
+// groupNames returns the names of the groups the user with id is a direct
+// member of, and none for ids that are not user ids such as API keys.
+func (s *authzService) groupNames(ctx context.Context, id string) ([]string, error) {
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		return []string{}, nil
+	}
+	memberships, err := s.groupRepository.GetMembershipsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(memberships))
+	for _, membership := range memberships {
+		ids = append(ids, membership.GroupID)
+	}
+	groups, err := s.groupRepository.GetGroupsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	return names, nil
+}
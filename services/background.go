@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// backgroundJobs runs work that outlives the request starting it, such as
+// large imports and exports, and lets shutdown wait for it.
+type backgroundJobs struct {
+	wg sync.WaitGroup
+	// stop interrupts the jobs on shutdown.
+	stop   context.Context
+	cancel context.CancelFunc
+}
+
+func newBackgroundJobs() *backgroundJobs {
+	stop, cancel := context.WithCancel(context.Background())
+	return &backgroundJobs{stop: stop, cancel: cancel}
+}
+
+// Go runs job in a goroutine. Its context keeps the tenant, caller and log
+// attributes of ctx but not its cancellation.
+func (b *backgroundJobs) Go(ctx context.Context, job func(ctx context.Context)) {
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(b.stop, cancel)
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer cancel()
+		defer stop()
+		job(jobCtx)
+	}()
+}
+
+// Wait blocks until the jobs have finished, and interrupts them when ctx
+// is done first.
+func (b *backgroundJobs) Wait(ctx context.Context, name string) error {
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return fmt.Errorf("timed out waiting for %s: %w", name, ctx.Err())
+	}
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/federation"
+	"7-solutions/logging"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/tenant"
+	"7-solutions/tracing"
+	"context"
+	"errors"
+	"time"
+)
+
+// FederationStateTTL is how long a user has to sign in at the provider.
+const FederationStateTTL = 10 * time.Minute
+
+var (
+	ErrUnknownProvider        = errors.New("unknown identity provider")
+	ErrInvalidFederationState = errors.New("invalid or expired login state")
+	// ErrEmailNotVerified is returned when an unlinked identity has no
+	// email the provider vouches for, so it can neither be linked to an
+	// existing user nor create one.
+	ErrEmailNotVerified = errors.New("identity provider did not verify the email address")
+)
+
+type FederationService interface {
+	Providers() []string
+	BeginLogin(ctx context.Context, provider string) (location, state string, err error)
+	CompleteLogin(ctx context.Context, provider, state, code, userAgent, ip string) (*string, error)
+	GetIdentities(ctx context.Context, userID int) ([]dtos.IdentityResponse, error)
+	UnlinkIdentity(ctx context.Context, userID int, id string) error
+}
+
+type federationService struct {
+	federationRepository repositories.FederationRepository
+	sessionRepository    repositories.SessionRepository
+	providers            map[string]*federation.Provider
+	names                []string
+	now                  func() time.Time
+}
+
+func NewFederationService(federationRepository repositories.FederationRepository, sessionRepository repositories.SessionRepository, providers []*federation.Provider) FederationService {
+	s := &federationService{
+		federationRepository: federationRepository,
+		sessionRepository:    sessionRepository,
+		providers:            make(map[string]*federation.Provider, len(providers)),
+		names:                []string{},
+		now:                  time.Now,
+	}
+	for _, provider := range providers {
+		s.providers[provider.Name()] = provider
+		s.names = append(s.names, provider.Name())
+	}
+	return s
+}
+
+func (s *federationService) Providers() []string {
+	return s.names
+}
+
+// BeginLogin stores a new state with its nonce and PKCE verifier and returns
+// the provider URL to send the user agent to.
+func (s *federationService) BeginLogin(ctx context.Context, providerName string) (string, string, error) {
+	ctx, span := tracing.Start(ctx, "FederationService.BeginLogin")
+	defer span.End()
+
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	var values [3]string
+	for i := range values {
+		value, err := randomHex(32)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return "", "", err
+		}
+		values[i] = value
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	location, err := provider.AuthCodeURL(ctx, state, nonce, pkceChallenge(verifier))
+	if err != nil {
+		tracing.RecordError(span, err)
+		return "", "", err
+	}
+
+	err = s.federationRepository.CreateState(ctx, &models.FederationState{
+		StateHash:    sha256Hex(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    s.now().Add(FederationStateTTL),
+	})
+	if err != nil {
+		tracing.RecordError(span, err)
+		return "", "", err
+	}
+	return location, state, nil
+}
+
+// CompleteLogin handles the provider callback: it consumes the state,
+// redeems the code and signs the linked user in, linking or creating the
+// user by verified email on their first login. Like password logins, it
+// starts a session for the device described by userAgent and ip.
+func (s *federationService) CompleteLogin(ctx context.Context, providerName, state, code, userAgent, ip string) (*string, error) {
+	ctx, span := tracing.Start(ctx, "FederationService.CompleteLogin")
+	defer span.End()
+
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	pending, err := s.federationRepository.ConsumeState(ctx, sha256Hex(state))
+	if err != nil {
+		if isContextError(err) {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		return nil, ErrInvalidFederationState
+	}
+	if pending.Provider != providerName || !s.now().Before(pending.ExpiresAt) {
+		return nil, ErrInvalidFederationState
+	}
+
+	identity, err := provider.Exchange(ctx, code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Audit(ctx, "federation.login_failed", "provider", providerName, "error", err)
+		return nil, err
+	}
+
+	// Users are looked up and created in the provider's organization.
+	ctx = tenant.WithID(ctx, provider.Tenant())
+	user, err := s.resolveUser(ctx, providerName, identity)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Audit(ctx, "federation.login_failed", "provider", providerName, "subject", identity.Subject, "error", err)
+		return nil, err
+	}
+	if err := checkAccountStatus(user.AccountStatus()); err != nil {
+		logging.Audit(ctx, "federation.login_failed", "provider", providerName, "user_id", user.ID, "error", err)
+		return nil, err
+	}
+
+	token, session, err := startSession(ctx, s.sessionRepository, user, userAgent, ip)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	logging.Audit(ctx, "federation.login", "provider", providerName, "user_id", user.ID, "session_id", session.ID.Hex())
+	return &token, nil
+}
+
+// resolveUser returns the user linked to identity. An unlinked identity
+// with a verified email is linked to the user with that email, or to a new
+// passwordless user when there is none.
+func (s *federationService) resolveUser(ctx context.Context, providerName string, identity *federation.Identity) (*models.User, error) {
+	linked, err := s.federationRepository.FindIdentity(ctx, providerName, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		return s.federationRepository.GetUserByID(ctx, linked.UserID)
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	user, err := s.federationRepository.FindUserByEmail(ctx, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		user = &models.User{
+			Name:          identity.Name,
+			Email:         identity.Email,
+			Role:          models.RoleUser,
+			EmailVerified: true,
+			CreatedAt:     s.now(),
+		}
+		if user.Name == "" {
+			user.Name = identity.Email
+		}
+		if err := s.federationRepository.CreateUser(ctx, user); err != nil {
+			return nil, err
+		}
+		logging.Audit(ctx, "user.register", "email", user.Email, "provider", providerName)
+	} else if !user.EmailVerified {
+		// Anyone could have registered this email before its owner showed
+		// up, so a password set on the unverified account stops working.
+		if err := s.federationRepository.VerifyUserEmail(ctx, user.ID, true); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.federationRepository.CreateIdentity(ctx, &models.Identity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+		LinkedAt: s.now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	logging.Audit(ctx, "federation.link", "provider", providerName, "subject", identity.Subject, "user_id", user.ID)
+	return user, nil
+}
+
+func (s *federationService) GetIdentities(ctx context.Context, userID int) ([]dtos.IdentityResponse, error) {
+	ctx, span := tracing.Start(ctx, "FederationService.GetIdentities")
+	defer span.End()
+
+	identities, err := s.federationRepository.GetIdentitiesByUserID(ctx, userID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	responses := make([]dtos.IdentityResponse, 0, len(identities))
+	for i := range identities {
+		responses = append(responses, toIdentityResponse(&identities[i]))
+	}
+	return responses, nil
+}
+
+func (s *federationService) UnlinkIdentity(ctx context.Context, userID int, id string) error {
+	ctx, span := tracing.Start(ctx, "FederationService.UnlinkIdentity")
+	defer span.End()
+
+	if err := s.federationRepository.DeleteIdentity(ctx, userID, id); err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	logging.Audit(ctx, "federation.unlink", "identity_id", id)
+	return nil
+}
+
+func toIdentityResponse(identity *models.Identity) dtos.IdentityResponse {
+	return dtos.IdentityResponse{
+		ID:       identity.ID.Hex(),
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+		LinkedAt: identity.LinkedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,424 @@
+package services
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/logging"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/tenant"
+	"7-solutions/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MaxGroupDepth bounds how deeply groups nest, which also bounds the
+	// walks up the hierarchy.
+	MaxGroupDepth = 10
+
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+var (
+	ErrInvalidGroup     = errors.New("invalid group")
+	ErrGroupCycle       = errors.New("a group cannot be nested inside itself or its subgroups")
+	ErrGroupTooDeep     = fmt.Errorf("groups cannot be nested more than %d levels deep", MaxGroupDepth)
+	ErrGroupHasChildren = errors.New("group has subgroups")
+)
+
+type GroupService interface {
+	CreateGroup(ctx context.Context, input *dtos.GroupCreate) (*dtos.GroupResponse, error)
+	GetGroup(ctx context.Context, id string) (*dtos.GroupResponse, error)
+	GetAllGroups(ctx context.Context) ([]dtos.GroupResponse, error)
+	UpdateGroup(ctx context.Context, id string, input *dtos.GroupUpdate) (*dtos.GroupResponse, error)
+	DeleteGroup(ctx context.Context, id string) error
+	AddMember(ctx context.Context, groupID string, userID int) error
+	RemoveMember(ctx context.Context, groupID string, userID int) error
+	GetMembers(ctx context.Context, groupID string, page, limit int) (*dtos.GroupMemberPage, error)
+	GetUserGroups(ctx context.Context, userID int) ([]dtos.UserGroupResponse, error)
+}
+
+type groupService struct {
+	groupRepository repositories.GroupRepository
+	now             func() time.Time
+}
+
+func NewGroupService(groupRepository repositories.GroupRepository) GroupService {
+	return &groupService{
+		groupRepository: groupRepository,
+		now:             time.Now,
+	}
+}
+
+func (s *groupService) CreateGroup(ctx context.Context, input *dtos.GroupCreate) (*dtos.GroupResponse, error) {
+	ctx, span := tracing.Start(ctx, "GroupService.CreateGroup")
+	defer span.End()
+
+	now := s.now()
+	group := &models.Group{
+		Name:        input.Name,
+		Description: input.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if input.ParentID != "" {
+		parent, err := s.checkParent(ctx, primitive.NilObjectID, input.ParentID)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		// A subgroup lives in its parent's tenant, also when a super-admin
+		// creates it.
+		ctx = tenant.Restrict(ctx, parent.TenantID)
+		group.ParentID, group.TenantID = &parent.ID, parent.TenantID
+	}
+
+	if err := s.groupRepository.CreateGroup(ctx, group); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	logging.Audit(ctx, "group.create", "group_id", group.ID.Hex())
+	response := toGroupResponse(group)
+	return &response, nil
+}
+
+func (s *groupService) GetGroup(ctx context.Context, id string) (*dtos.GroupResponse, error) {
+	ctx, span := tracing.Start(ctx, "GroupService.GetGroup")
+	defer span.End()
+
+	group, err := s.groupRepository.GetGroup(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	response := toGroupResponse(group)
+	return &response, nil
+}
+
+func (s *groupService) GetAllGroups(ctx context.Context) ([]dtos.GroupResponse, error) {
+	ctx, span := tracing.Start(ctx, "GroupService.GetAllGroups")
+	defer span.End()
+
+	groups, err := s.groupRepository.GetAllGroups(ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	responses := make([]dtos.GroupResponse, 0, len(groups))
+	for i := range groups {
+		responses = append(responses, toGroupResponse(&groups[i]))
+	}
+	return responses, nil
+}
+
+func (s *groupService) UpdateGroup(ctx context.Context, id string, input *dtos.GroupUpdate) (*dtos.GroupResponse, error) {
+	ctx, span := tracing.Start(ctx, "GroupService.UpdateGroup")
+	defer span.End()
+
+	group, err := s.groupRepository.GetGroup(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	ctx = tenant.Restrict(ctx, group.TenantID)
+
+	if input.Name != nil {
+		if *input.Name == "" {
+			return nil, fmt.Errorf("%w: name must not be empty", ErrInvalidGroup)
+		}
+		group.Name = *input.Name
+	}
+	if input.Description != nil {
+		group.Description = *input.Description
+	}
+	if input.ParentID != nil {
+		group.ParentID = nil
+		if *input.ParentID != "" {
+			parent, err := s.checkParent(ctx, group.ID, *input.ParentID)
+			if err != nil {
+				tracing.RecordError(span, err)
+				return nil, err
+			}
+			group.ParentID = &parent.ID
+		}
+	}
+	group.UpdatedAt = s.now()
+
+	if err := s.groupRepository.UpdateGroup(ctx, group); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	logging.Audit(ctx, "group.update", "group_id", id)
+	response := toGroupResponse(group)
+	return &response, nil
+}
+
+// checkParent returns the group parentID refers to, provided that nesting
+// the group id under it creates neither a cycle nor a hierarchy deeper than
+// MaxGroupDepth. A zero id stands for a group that does not exist yet.
+func (s *groupService) checkParent(ctx context.Context, id primitive.ObjectID, parentID string) (*models.Group, error) {
+	parent, err := s.groupRepository.GetGroup(ctx, parentID)
+	if errors.Is(err, repositories.ErrGroupNotFound) {
+		return nil, fmt.Errorf("%w: parent group not found", ErrInvalidGroup)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	depth := 1
+	for ancestor := parent; ; depth++ {
+		if ancestor.ID == id {
+			return nil, ErrGroupCycle
+		}
+		if depth >= MaxGroupDepth {
+			return nil, ErrGroupTooDeep
+		}
+		if ancestor.ParentID == nil {
+			break
+		}
+		ancestor, err = s.groupRepository.GetGroup(ctx, ancestor.ParentID.Hex())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if id.IsZero() {
+		return parent, nil
+	}
+	// The group moves with its subgroups, which must still fit.
+	height, err := s.subtreeHeight(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if depth+1+height > MaxGroupDepth {
+		return nil, ErrGroupTooDeep
+	}
+	return parent, nil
+}
+
+// subtreeHeight returns how many levels of subgroups hang below id,
+// walking down one level per query.
+func (s *groupService) subtreeHeight(ctx context.Context, id primitive.ObjectID) (int, error) {
+	height := 0
+	level := []primitive.ObjectID{id}
+	for height <= MaxGroupDepth {
+		children, err := s.groupRepository.GetChildGroupIDs(ctx, level)
+		if err != nil {
+			return 0, err
+		}
+		if len(children) == 0 {
+			break
+		}
+		height++
+		level = children
+	}
+	return height, nil
+}
+
+// DeleteGroup refuses to delete groups that still have subgroups, which
+// would otherwise be orphaned.
+func (s *groupService) DeleteGroup(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "GroupService.DeleteGroup")
+	defer span.End()
+
+	group, err := s.groupRepository.GetGroup(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	children, err := s.groupRepository.CountChildGroups(ctx, group.ID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	if children > 0 {
+		return ErrGroupHasChildren
+	}
+
+	if err := s.groupRepository.DeleteGroup(ctx, group.ID); err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	logging.Audit(ctx, "group.delete", "group_id", id)
+	return nil
+}
+
+func (s *groupService) AddMember(ctx context.Context, groupID string, userID int) error {
+	ctx, span := tracing.Start(ctx, "GroupService.AddMember")
+	defer span.End()
+
+	group, err := s.groupRepository.GetGroup(ctx, groupID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	// Members come from the group's tenant, and so does the membership, so
+	// that the tenant's admins can see and remove it.
+	ctx = tenant.Restrict(ctx, group.TenantID)
+	users, err := s.groupRepository.GetUsersByIDs(ctx, []int{userID})
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	if len(users) == 0 {
+		return repositories.ErrUserNotFound
+	}
+
+	err = s.groupRepository.AddMember(ctx, &models.GroupMember{
+		GroupID:  group.ID,
+		UserID:   userID,
+		TenantID: group.TenantID,
+		AddedAt:  s.now(),
+	})
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	logging.Audit(ctx, "group.add_member", "group_id", groupID, "target_user_id", userID)
+	return nil
+}
+
+func (s *groupService) RemoveMember(ctx context.Context, groupID string, userID int) error {
+	ctx, span := tracing.Start(ctx, "GroupService.RemoveMember")
+	defer span.End()
+
+	group, err := s.groupRepository.GetGroup(ctx, groupID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	if err := s.groupRepository.RemoveMember(ctx, group.ID, userID); err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	logging.Audit(ctx, "group.remove_member", "group_id", groupID, "target_user_id", userID)
+	return nil
+}
+
+// GetMembers returns one page of the group's direct members. page starts
+// at 1; zero values select the first page and DefaultPageLimit, and limit
+// is capped at MaxPageLimit.
+func (s *groupService) GetMembers(ctx context.Context, groupID string, page, limit int) (*dtos.GroupMemberPage, error) {
+	ctx, span := tracing.Start(ctx, "GroupService.GetMembers")
+	defer span.End()
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+
+	group, err := s.groupRepository.GetGroup(ctx, groupID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	members, total, err := s.groupRepository.GetMembers(ctx, group.ID, int64((page-1)*limit), int64(limit))
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(members))
+	for _, member := range members {
+		ids = append(ids, member.UserID)
+	}
+	users, err := s.groupRepository.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	byID := make(map[int]*models.User, len(users))
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+
+	result := &dtos.GroupMemberPage{
+		Members: make([]dtos.GroupMemberResponse, 0, len(members)),
+		Page:    page,
+		Limit:   limit,
+		Total:   total,
+	}
+	for _, member := range members {
+		response := dtos.GroupMemberResponse{
+			UserID:  member.UserID,
+			AddedAt: member.AddedAt.Format(time.RFC3339),
+		}
+		if user, ok := byID[member.UserID]; ok {
+			response.Name = user.Name
+			response.Email = user.Email
+		}
+		result.Members = append(result.Members, response)
+	}
+	return result, nil
+}
+
+// GetUserGroups returns the groups the user is a direct member of, followed
+// by the ancestors of those groups, which the user belongs to indirectly.
+func (s *groupService) GetUserGroups(ctx context.Context, userID int) ([]dtos.UserGroupResponse, error) {
+	ctx, span := tracing.Start(ctx, "GroupService.GetUserGroups")
+	defer span.End()
+
+	users, err := s.groupRepository.GetUsersByIDs(ctx, []int{userID})
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, repositories.ErrUserNotFound
+	}
+
+	memberships, err := s.groupRepository.GetMembershipsByUserID(ctx, userID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	responses := []dtos.UserGroupResponse{}
+	seen := map[primitive.ObjectID]bool{}
+	ids := make([]primitive.ObjectID, 0, len(memberships))
+	for _, membership := range memberships {
+		ids = append(ids, membership.GroupID)
+	}
+	for depth, direct := 0, true; len(ids) > 0 && depth < MaxGroupDepth; depth, direct = depth+1, false {
+		groups, err := s.groupRepository.GetGroupsByIDs(ctx, ids)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		ids = ids[:0:0]
+		for i := range groups {
+			if seen[groups[i].ID] {
+				continue
+			}
+			seen[groups[i].ID] = true
+			responses = append(responses, dtos.UserGroupResponse{GroupResponse: toGroupResponse(&groups[i]), Direct: direct})
+			if parent := groups[i].ParentID; parent != nil && !seen[*parent] {
+				ids = append(ids, *parent)
+			}
+		}
+	}
+	return responses, nil
+}
+
+func toGroupResponse(group *models.Group) dtos.GroupResponse {
+	response := dtos.GroupResponse{
+		ID:          group.ID.Hex(),
+		Name:        group.Name,
+		Description: group.Description,
+		CreatedAt:   group.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   group.UpdatedAt.Format(time.RFC3339),
+	}
+	if group.ParentID != nil {
+		response.ParentID = group.ParentID.Hex()
+	}
+	return response
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/repositories"
+	"7-solutions/utils"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+type HealthService interface {
+	Live() bool
+	Ready(ctx context.Context) (bool, []dtos.DependencyHealth)
+	Health(ctx context.Context) dtos.HealthResponse
+	SetShuttingDown()
+	RecordJobRun(name string, startedAt time.Time, duration time.Duration, err error)
+}
+
+type healthService struct {
+	healthRepository repositories.HealthRepository
+	version          string
+	startedAt        time.Time
+	checkTimeout     time.Duration
+	shuttingDown     atomic.Bool
+
+	jobsMu sync.RWMutex
+	jobs   map[string]*dtos.JobHealth
+}
+
+func NewHealthService(healthRepository repositories.HealthRepository, version string, checkTimeout time.Duration) HealthService {
+	return &healthService{
+		healthRepository: healthRepository,
+		version:          version,
+		startedAt:        time.Now(),
+		checkTimeout:     checkTimeout,
+		jobs:             map[string]*dtos.JobHealth{},
+	}
+}
+
+func (s *healthService) Live() bool {
+	return true
+}
+
+func (s *healthService) Ready(ctx context.Context) (bool, []dtos.DependencyHealth) {
+	checks := s.checkDependencies(ctx)
+	if s.shuttingDown.Load() {
+		return false, checks
+	}
+	for _, check := range checks {
+		if check.Status != StatusUp {
+			return false, checks
+		}
+	}
+	return true, checks
+}
+
+func (s *healthService) Health(ctx context.Context) dtos.HealthResponse {
+	ready, checks := s.Ready(ctx)
+
+	status := StatusUp
+	if !ready {
+		status = StatusDown
+	}
+
+	s.jobsMu.RLock()
+	jobs := make([]dtos.JobHealth, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+	s.jobsMu.RUnlock()
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+
+	return dtos.HealthResponse{
+		Status:        status,
+		Version:       s.version,
+		StartedAt:     s.startedAt.Format(time.RFC3339),
+		UptimeSeconds: time.Since(s.startedAt).Seconds(),
+		ShuttingDown:  s.shuttingDown.Load(),
+		Dependencies:  checks,
+		Jobs:          jobs,
+	}
+}
+
+func (s *healthService) SetShuttingDown() {
+	s.shuttingDown.Store(true)
+}
+
+func (s *healthService) RecordJobRun(name string, startedAt time.Time, duration time.Duration, err error) {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	job, ok := s.jobs[name]
+	if !ok {
+		job = &dtos.JobHealth{Name: name}
+		s.jobs[name] = job
+	}
+	job.LastRun = startedAt.Format(time.RFC3339)
+	job.DurationMs = float64(duration.Microseconds()) / 1000
+	job.Success = err == nil
+	job.Error = ""
+	if err != nil {
+		job.Error = err.Error()
+		job.FailureCount++
+	} else {
+		job.SuccessCount++
+	}
+}
+
+func (s *healthService) checkDependencies(ctx context.Context) []dtos.DependencyHealth {
+	ctx, cancel := context.WithTimeout(ctx, s.checkTimeout)
+	defer cancel()
+
+	checks := []dtos.DependencyHealth{
+		s.check("mongo", func() error { return s.healthRepository.Ping(ctx) }),
+	}
+
+	collections := make([]string, 0, len(utils.RequiredIndexes))
+	for collection := range utils.RequiredIndexes {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+
+	for _, collection := range collections {
+		for _, index := range utils.RequiredIndexes[collection] {
+			checks = append(checks, s.check("index:"+collection+"."+index, func() error {
+				ok, err := s.healthRepository.HasIndex(ctx, collection, index)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return fmt.Errorf("index %s missing on %s", index, collection)
+				}
+				return nil
+			}))
+		}
+	}
+
+	return checks
+}
+
+func (s *healthService) check(name string, fn func() error) dtos.DependencyHealth {
+	start := time.Now()
+	err := fn()
+	result := dtos.DependencyHealth{
+		Name:      name,
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/logging"
+	"7-solutions/mailer"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/tenant"
+	"7-solutions/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// DefaultInvitationTTL is how long an invitation link stays valid unless
+// configured otherwise.
+const DefaultInvitationTTL = 72 * time.Hour
+
+var (
+	ErrInvalidInvitationRequest = errors.New("invalid invitation request")
+	ErrInvitationExists         = errors.New("email already has a pending invitation")
+	// ErrInvalidInvitation is returned for unknown, used, revoked and
+	// expired tokens alike.
+	ErrInvalidInvitation = errors.New("invalid or expired invitation")
+)
+
+type InvitationService interface {
+	CreateInvitation(ctx context.Context, invitedBy string, input *dtos.InvitationCreate) (*dtos.InvitationResponse, error)
+	GetPendingInvitations(ctx context.Context) ([]dtos.InvitationResponse, error)
+	ResendInvitation(ctx context.Context, id string) (*dtos.InvitationResponse, error)
+	RevokeInvitation(ctx context.Context, id string) error
+	AcceptInvitation(ctx context.Context, input *dtos.InvitationAccept) (*dtos.UserResponse, error)
+}
+
+type invitationService struct {
+	invitationRepository repositories.InvitationRepository
+	groupRepository      repositories.GroupRepository
+	mailer               mailer.Mailer
+	acceptURL            string
+	ttl                  time.Duration
+	now                  func() time.Time
+}
+
+// NewInvitationService sends invitation links to acceptURL with the token
+// in the token query parameter. They expire after ttl.
+func NewInvitationService(
+	invitationRepository repositories.InvitationRepository,
+	groupRepository repositories.GroupRepository,
+	mailer mailer.Mailer,
+	acceptURL string,
+	ttl time.Duration,
+) InvitationService {
+	return &invitationService{
+		invitationRepository: invitationRepository,
+		groupRepository:      groupRepository,
+		mailer:               mailer,
+		acceptURL:            acceptURL,
+		ttl:                  ttl,
+		now:                  time.Now,
+	}
+}
+
+// CreateInvitation stores an invitation and emails its link. When the
+// email cannot be sent the invitation is kept, so it can be resent.
+func (s *invitationService) CreateInvitation(ctx context.Context, invitedBy string, input *dtos.InvitationCreate) (*dtos.InvitationResponse, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.CreateInvitation")
+	defer span.End()
+
+	tenantID, err := tenant.ForWrite(ctx, input.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = tenant.Restrict(ctx, tenantID)
+
+	now := s.now()
+	invitation := &models.Invitation{
+		TenantID:  tenantID,
+		Email:     input.Email,
+		Role:      input.Role,
+		InvitedBy: invitedBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+	if invitation.Role == "" {
+		invitation.Role = models.RoleUser
+	}
+	if input.GroupID != "" {
+		group, err := s.groupRepository.GetGroup(ctx, input.GroupID)
+		if errors.Is(err, repositories.ErrGroupNotFound) {
+			return nil, fmt.Errorf("%w: group not found", ErrInvalidInvitationRequest)
+		}
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		invitation.GroupID = &group.ID
+	}
+
+	user, err := s.invitationRepository.FindUserByEmail(ctx, input.Email)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	if user != nil {
+		return nil, repositories.ErrEmailTaken
+	}
+	pending, err := s.invitationRepository.FindPendingInvitation(ctx, input.Email, now)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	if pending != nil {
+		return nil, ErrInvitationExists
+	}
+
+	token, err := randomHex(32)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	invitation.TokenHash = sha256Hex(token)
+	if err := s.invitationRepository.CreateInvitation(ctx, invitation); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	logging.Audit(ctx, "invitation.create", "invitation_id", invitation.ID.Hex(), "email", invitation.Email, "role", invitation.Role)
+
+	response := s.toInvitationResponse(invitation)
+	if err := s.send(ctx, invitation, token); err != nil {
+		tracing.RecordError(span, err)
+		return &response, err
+	}
+	return &response, nil
+}
+
+func (s *invitationService) GetPendingInvitations(ctx context.Context) ([]dtos.InvitationResponse, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.GetPendingInvitations")
+	defer span.End()
+
+	invitations, err := s.invitationRepository.GetPendingInvitations(ctx, s.now())
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	responses := make([]dtos.InvitationResponse, 0, len(invitations))
+	for i := range invitations {
+		responses = append(responses, s.toInvitationResponse(&invitations[i]))
+	}
+	return responses, nil
+}
+
+// ResendInvitation emails a new link, which invalidates the previous one
+// and restarts the expiry. Expired invitations can be resent too.
+func (s *invitationService) ResendInvitation(ctx context.Context, id string) (*dtos.InvitationResponse, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.ResendInvitation")
+	defer span.End()
+
+	invitation, err := s.invitationRepository.GetInvitation(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	token, err := randomHex(32)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	invitation.TokenHash = sha256Hex(token)
+	invitation.ExpiresAt = s.now().Add(s.ttl)
+	err = s.invitationRepository.RenewInvitation(ctx, invitation.ID, invitation.TokenHash, invitation.ExpiresAt)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	logging.Audit(ctx, "invitation.resend", "invitation_id", id)
+
+	response := s.toInvitationResponse(invitation)
+	if err := s.send(ctx, invitation, token); err != nil {
+		tracing.RecordError(span, err)
+		return &response, err
+	}
+	return &response, nil
+}
+
+func (s *invitationService) RevokeInvitation(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "InvitationService.RevokeInvitation")
+	defer span.End()
+
+	invitation, err := s.invitationRepository.GetInvitation(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	if err := s.invitationRepository.RevokeInvitation(ctx, invitation.ID, s.now()); err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	logging.Audit(ctx, "invitation.revoke", "invitation_id", id)
+	return nil
+}
+
+// AcceptInvitation uses up the token and creates the invited user in the
+// invitation's tenant with the chosen password. The email counts as
+// verified since the token was delivered to it.
+func (s *invitationService) AcceptInvitation(ctx context.Context, input *dtos.InvitationAccept) (*dtos.UserResponse, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.AcceptInvitation")
+	defer span.End()
+
+	hashedPassword, err := hashPassword(ctx, input.Password)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	now := s.now()
+	invitation, err := s.invitationRepository.ConsumeInvitation(ctx, sha256Hex(input.Token), now)
+	if errors.Is(err, repositories.ErrInvitationNotFound) {
+		return nil, ErrInvalidInvitation
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	ctx = tenant.Restrict(ctx, invitation.TenantID)
+
+	user := &models.User{
+		Name:          input.Name,
+		Email:         invitation.Email,
+		Password:      string(hashedPassword),
+		Role:          invitation.Role,
+		EmailVerified: true,
+		CreatedAt:     now,
+	}
+	if err := s.invitationRepository.CreateUser(ctx, user); err != nil {
+		tracing.RecordError(span, err)
+		// Someone else took the email; the invitation is useless anyway.
+		if !errors.Is(err, repositories.ErrEmailTaken) {
+			if releaseErr := s.invitationRepository.ReleaseInvitation(ctx, invitation.ID); releaseErr != nil {
+				tracing.RecordError(span, releaseErr)
+			}
+		}
+		return nil, err
+	}
+	logging.Audit(ctx, "invitation.accept", "invitation_id", invitation.ID.Hex(), "target_user_id", user.ID)
+
+	if invitation.GroupID != nil {
+		s.joinGroup(ctx, invitation.GroupID.Hex(), user.ID)
+	}
+
+	return &dtos.UserResponse{
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		TenantID:      user.TenantID,
+		CreatedAt:     user.CreatedAt.Format(time.RFC3339),
+	}, nil
+}
+
+// joinGroup adds a newly accepted user to the group they were invited to.
+// The account already exists at this point, so failures are only logged;
+// the group may also have been deleted in the meantime.
+func (s *invitationService) joinGroup(ctx context.Context, groupID string, userID int) {
+	group, err := s.groupRepository.GetGroup(ctx, groupID)
+	if err == nil {
+		err = s.groupRepository.AddMember(ctx, &models.GroupMember{GroupID: group.ID, UserID: userID, AddedAt: s.now()})
+	}
+	if err != nil && !errors.Is(err, repositories.ErrGroupNotFound) {
+		logging.FromContext(ctx).Error("failed to add invited user to group", "group_id", groupID, "error", err)
+		return
+	}
+	if err == nil {
+		logging.Audit(ctx, "group.add_member", "group_id", groupID, "target_user_id", userID)
+	}
+}
+
+func (s *invitationService) send(ctx context.Context, invitation *models.Invitation, token string) error {
+	link := s.acceptURL + "?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      invitation.Email,
+		Subject: "You have been invited",
+		Body: fmt.Sprintf("You have been invited to create an account with the %s role.\n\n"+
+			"Open this link to choose your password:\n%s\n\n"+
+			"The link can be used once and expires on %s.\n",
+			invitation.Role, link, invitation.ExpiresAt.UTC().Format(time.RFC1123)),
+	})
+}
+
+func (s *invitationService) toInvitationResponse(invitation *models.Invitation) dtos.InvitationResponse {
+	response := dtos.InvitationResponse{
+		ID:        invitation.ID.Hex(),
+		Email:     invitation.Email,
+		Role:      invitation.Role,
+		TenantID:  invitation.TenantID,
+		Status:    invitation.Status(s.now()),
+		InvitedBy: invitation.InvitedBy,
+		CreatedAt: invitation.CreatedAt.Format(time.RFC3339),
+		ExpiresAt: invitation.ExpiresAt.Format(time.RFC3339),
+	}
+	if invitation.GroupID != nil {
+		response.GroupID = invitation.GroupID.Hex()
+	}
+	return response
+}
@@ -0,0 +1,651 @@
+package services
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/logging"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/tenant"
+	"7-solutions/tracing"
+	"7-solutions/utils"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	AccessTokenTTL       = time.Hour
+	AuthorizationCodeTTL = 5 * time.Minute
+)
+
+var (
+	// ErrInvalidCredentials is returned when the resource owner's email or
+	// password is wrong on the login page.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrInvalidOAuthClient is returned for client registrations that fail
+	// validation.
+	ErrInvalidOAuthClient = errors.New("invalid oauth client")
+)
+
+// OAuthError is an RFC 6749 error response. When RedirectURI is set the
+// error is reported to the client by redirecting the user agent to it,
+// otherwise it is returned to the caller directly with Status.
+type OAuthError struct {
+	Code        string
+	Description string
+	Status      int
+	RedirectURI string
+	State       string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// ErrorRedirectURI returns where the user agent is sent for a redirectable
+// OAuthError.
+func (e *OAuthError) ErrorRedirectURI() string {
+	return redirectURI(e.RedirectURI, url.Values{"error": {e.Code}, "error_description": {e.Description}, "state": {e.State}})
+}
+
+func oauthError(code, description string, status int) *OAuthError {
+	return &OAuthError{Code: code, Description: description, Status: status}
+}
+
+type OAuthService interface {
+	RegisterClient(ctx context.Context, createdBy string, input *dtos.OAuthClientCreate) (*dtos.OAuthClientCreated, error)
+	GetAllClients(ctx context.Context) ([]dtos.OAuthClientResponse, error)
+	ValidateAuthorizeRequest(ctx context.Context, req *dtos.AuthorizeRequest) (*models.OAuthClient, error)
+	Authorize(ctx context.Context, req *dtos.AuthorizeRequest, login *dtos.UserAuthenticate, approved bool) (string, error)
+	Token(ctx context.Context, creds dtos.ClientCredentials, req *dtos.TokenRequest) (*dtos.TokenResponse, error)
+	Introspect(ctx context.Context, creds dtos.ClientCredentials, token string) (*dtos.IntrospectionResponse, error)
+	Revoke(ctx context.Context, creds dtos.ClientCredentials, token string) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	UserInfo(ctx context.Context, subject string, scopes []string) (*dtos.UserInfo, error)
+}
+
+type oauthService struct {
+	oauthRepository repositories.OAuthRepository
+	userRepository  repositories.UserRepository
+	authService     AuthService
+	sessionService  SessionService
+	accountChecker  AccountChecker
+	now             func() time.Time
+}
+
+func NewOAuthService(oauthRepository repositories.OAuthRepository, userRepository repositories.UserRepository, authService AuthService, sessionService SessionService, accountChecker AccountChecker) OAuthService {
+	return &oauthService{
+		oauthRepository: oauthRepository,
+		userRepository:  userRepository,
+		authService:     authService,
+		sessionService:  sessionService,
+		accountChecker:  accountChecker,
+		now:             time.Now,
+	}
+}
+
+func (s *oauthService) RegisterClient(ctx context.Context, createdBy string, input *dtos.OAuthClientCreate) (*dtos.OAuthClientCreated, error) {
+	ctx, span := tracing.Start(ctx, "OAuthService.RegisterClient")
+	defer span.End()
+
+	if err := validateClient(input); err != nil {
+		return nil, err
+	}
+
+	clientID, err := randomHex(16)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	client := &models.OAuthClient{
+		ClientID:     clientID,
+		Name:         input.Name,
+		RedirectURIs: input.RedirectURIs,
+		GrantTypes:   input.GrantTypes,
+		Scopes:       input.Scopes,
+		Public:       input.Public,
+		CreatedBy:    createdBy,
+		CreatedAt:    s.now(),
+	}
+	var secret string
+	if !client.Public {
+		if secret, err = randomHex(32); err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		client.SecretHash = sha256Hex(secret)
+	}
+
+	if err := s.oauthRepository.CreateClient(ctx, client); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	logging.Audit(ctx, "oauth.client_register", "client_id", client.ClientID)
+	return &dtos.OAuthClientCreated{OAuthClientResponse: toOAuthClientResponse(client), ClientSecret: secret}, nil
+}
+
+func (s *oauthService) GetAllClients(ctx context.Context) ([]dtos.OAuthClientResponse, error) {
+	ctx, span := tracing.Start(ctx, "OAuthService.GetAllClients")
+	defer span.End()
+
+	clients, err := s.oauthRepository.GetAllClients(ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	responses := make([]dtos.OAuthClientResponse, 0, len(clients))
+	for i := range clients {
+		responses = append(responses, toOAuthClientResponse(&clients[i]))
+	}
+	return responses, nil
+}
+
+// ValidateAuthorizeRequest checks an authorization request before the login
+// page is shown. Problems with the client or redirect URI are returned
+// directly, since redirecting to an unverified URI would make this an open
+// redirector; everything else is reported through the redirect.
+func (s *oauthService) ValidateAuthorizeRequest(ctx context.Context, req *dtos.AuthorizeRequest) (*models.OAuthClient, error) {
+	client, err := s.oauthRepository.GetClientByClientID(ctx, req.ClientID)
+	if err != nil {
+		if isContextError(err) {
+			return nil, err
+		}
+		return nil, oauthError("invalid_client", "unknown client", http.StatusBadRequest)
+	}
+	if !client.HasRedirectURI(req.RedirectURI) {
+		return nil, oauthError("invalid_request", "redirect_uri is not registered for this client", http.StatusBadRequest)
+	}
+
+	redirectError := func(code, description string) error {
+		return &OAuthError{Code: code, Description: description, Status: http.StatusFound, RedirectURI: req.RedirectURI, State: req.State}
+	}
+	if req.ResponseType != "code" {
+		return nil, redirectError("unsupported_response_type", "response_type must be code")
+	}
+	if !client.HasGrantType(models.GrantTypeAuthorizationCode) {
+		return nil, redirectError("unauthorized_client", "client may not use the authorization code grant")
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return nil, redirectError("invalid_request", "PKCE with code_challenge_method S256 is required")
+	}
+	scope, ok := grantedScope(client, req.Scope)
+	if !ok {
+		return nil, redirectError("invalid_scope", "requested scope is not allowed for this client")
+	}
+	req.Scope = scope
+	return client, nil
+}
+
+// Authorize completes the login/consent step and returns the URI the user
+// agent is redirected to, carrying either a code or an error.
+func (s *oauthService) Authorize(ctx context.Context, req *dtos.AuthorizeRequest, login *dtos.UserAuthenticate, approved bool) (string, error) {
+	ctx, span := tracing.Start(ctx, "OAuthService.Authorize")
+	defer span.End()
+
+	client, err := s.ValidateAuthorizeRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if !approved {
+		logging.Audit(ctx, "oauth.consent_denied", "client_id", req.ClientID)
+		return redirectURI(req.RedirectURI, url.Values{"error": {"access_denied"}, "state": {req.State}}), nil
+	}
+
+	// Reuse the regular credential check so password checks, metrics and
+	// audit logs stay in one place, without starting a login session.
+	// Only users of the client's own organization can sign in.
+	login.TenantID = clientTenant(client)
+	user, err := s.authService.VerifyCredentials(ctx, login)
+	if err != nil {
+		if isContextError(err) {
+			tracing.RecordError(span, err)
+			return "", err
+		}
+		return "", ErrInvalidCredentials
+	}
+
+	code, err := randomHex(32)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return "", err
+	}
+	now := s.now()
+	subject := strconv.Itoa(user.ID)
+	err = s.oauthRepository.CreateAuthorizationCode(ctx, &models.AuthorizationCode{
+		CodeHash:      sha256Hex(code),
+		ClientID:      req.ClientID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         req.Scope,
+		CodeChallenge: req.CodeChallenge,
+		Nonce:         req.Nonce,
+		Subject:       subject,
+		Name:          user.Name,
+		Email:         user.Email,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(AuthorizationCodeTTL),
+	})
+	if err != nil {
+		tracing.RecordError(span, err)
+		return "", err
+	}
+	logging.Audit(ctx, "oauth.consent_granted", "client_id", req.ClientID, "target_user_id", subject, "scope", req.Scope)
+	return redirectURI(req.RedirectURI, url.Values{"code": {code}, "state": {req.State}}), nil
+}
+
+func (s *oauthService) Token(ctx context.Context, creds dtos.ClientCredentials, req *dtos.TokenRequest) (*dtos.TokenResponse, error) {
+	ctx, span := tracing.Start(ctx, "OAuthService.Token")
+	defer span.End()
+
+	client, err := s.authenticateClient(ctx, creds)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	if !client.HasGrantType(req.GrantType) {
+		if req.GrantType != models.GrantTypeAuthorizationCode && req.GrantType != models.GrantTypeClientCredentials {
+			return nil, oauthError("unsupported_grant_type", "grant_type is not supported", http.StatusBadRequest)
+		}
+		return nil, oauthError("unauthorized_client", "client may not use this grant type", http.StatusBadRequest)
+	}
+
+	var response *dtos.TokenResponse
+	switch req.GrantType {
+	case models.GrantTypeAuthorizationCode:
+		response, err = s.exchangeCode(ctx, client, req)
+	case models.GrantTypeClientCredentials:
+		response, err = s.clientCredentials(ctx, client, req)
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	logging.Audit(ctx, "oauth.token", "client_id", client.ClientID, "grant_type", req.GrantType, "scope", response.Scope)
+	return response, nil
+}
+
+func (s *oauthService) exchangeCode(ctx context.Context, client *models.OAuthClient, req *dtos.TokenRequest) (*dtos.TokenResponse, error) {
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, oauthError("invalid_request", "code and code_verifier are required", http.StatusBadRequest)
+	}
+
+	now := s.now()
+	code, err := s.oauthRepository.ConsumeAuthorizationCode(ctx, sha256Hex(req.Code), now)
+	if err != nil {
+		if isContextError(err) {
+			return nil, err
+		}
+		return nil, oauthError("invalid_grant", "authorization code is invalid or already used", http.StatusBadRequest)
+	}
+	if code.ClientID != client.ClientID || code.RedirectURI != req.RedirectURI || !code.ExpiresAt.After(now) {
+		return nil, oauthError("invalid_grant", "authorization code is invalid or expired", http.StatusBadRequest)
+	}
+	if subtle.ConstantTimeCompare([]byte(pkceChallenge(req.CodeVerifier)), []byte(code.CodeChallenge)) != 1 {
+		return nil, oauthError("invalid_grant", "code_verifier does not match code_challenge", http.StatusBadRequest)
+	}
+
+	owner := map[string]interface{}{"sub": code.Subject, "name": code.Name, "email": code.Email}
+	response, err := issueToken(client, code.Scope, owner)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(strings.Fields(code.Scope), models.ScopeOpenID) {
+		// The token endpoint is called by the client, so the user is looked
+		// up in the client's tenant.
+		if response.IDToken, err = s.idToken(tenant.WithID(ctx, clientTenant(client)), client.ClientID, code); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// idToken builds the OIDC ID token for an exchanged code. Claims come from
+// the stored user record so they reflect the account, not the login form.
+func (s *oauthService) idToken(ctx context.Context, clientID string, code *models.AuthorizationCode) (string, error) {
+	info, err := s.userInfo(ctx, code.Subject, strings.Fields(code.Scope))
+	if err != nil {
+		return "", err
+	}
+	claims := jwt.MapClaims{"sub": info.Sub, "auth_time": code.CreatedAt.Unix()}
+	if code.Nonce != "" {
+		claims["nonce"] = code.Nonce
+	}
+	if info.Name != "" {
+		claims["name"] = info.Name
+	}
+	if info.Email != "" {
+		claims["email"] = info.Email
+		claims["email_verified"] = *info.EmailVerified
+	}
+	return utils.GenerateIDToken(clientID, claims, AccessTokenTTL)
+}
+
+// UserInfo returns the claims of the user behind an access token, limited
+// to what its scopes allow.
+func (s *oauthService) UserInfo(ctx context.Context, subject string, scopes []string) (*dtos.UserInfo, error) {
+	ctx, span := tracing.Start(ctx, "OAuthService.UserInfo")
+	defer span.End()
+
+	info, err := s.userInfo(ctx, subject, scopes)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return info, nil
+}
+
+func (s *oauthService) userInfo(ctx context.Context, subject string, scopes []string) (*dtos.UserInfo, error) {
+	id, err := strconv.Atoi(subject)
+	if err != nil {
+		return nil, oauthError("invalid_token", "token does not belong to a user", http.StatusUnauthorized)
+	}
+	user, err := s.userRepository.GetUserByID(ctx, id)
+	if err != nil {
+		if isContextError(err) {
+			return nil, err
+		}
+		return nil, oauthError("invalid_token", "user no longer exists", http.StatusUnauthorized)
+	}
+
+	info := &dtos.UserInfo{Sub: subject}
+	if slices.Contains(scopes, models.ScopeProfile) {
+		info.Name = user.Name
+	}
+	if slices.Contains(scopes, models.ScopeEmail) {
+		info.Email = user.Email
+		info.EmailVerified = &user.EmailVerified
+	}
+	return info, nil
+}
+
+func (s *oauthService) clientCredentials(ctx context.Context, client *models.OAuthClient, req *dtos.TokenRequest) (*dtos.TokenResponse, error) {
+	scope, ok := grantedScope(client, req.Scope)
+	if !ok {
+		return nil, oauthError("invalid_scope", "requested scope is not allowed for this client", http.StatusBadRequest)
+	}
+	return issueToken(client, scope, nil)
+}
+
+// Introspect implements RFC 7662. Only confidential clients may introspect,
+// and any token that is invalid, expired or revoked is simply inactive.
+func (s *oauthService) Introspect(ctx context.Context, creds dtos.ClientCredentials, token string) (*dtos.IntrospectionResponse, error) {
+	ctx, span := tracing.Start(ctx, "OAuthService.Introspect")
+	defer span.End()
+
+	client, err := s.authenticateClient(ctx, creds)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	if client.Public {
+		return nil, oauthError("unauthorized_client", "public clients may not introspect tokens", http.StatusUnauthorized)
+	}
+
+	claims, err := utils.VerifyToken(token)
+	if err != nil {
+		return &dtos.IntrospectionResponse{Active: false}, nil
+	}
+	// Login and impersonation tokens are signed with the same key but are
+	// not access tokens, so they are never reported to clients.
+	if clientID, _ := claims["client_id"].(string); clientID == "" {
+		return &dtos.IntrospectionResponse{Active: false}, nil
+	}
+	jti, _ := claims["jti"].(string)
+	if jti != "" {
+		revoked, err := s.IsTokenRevoked(ctx, jti)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		if revoked {
+			return &dtos.IntrospectionResponse{Active: false}, nil
+		}
+	}
+	active, err := s.ownerActive(ctx, claims)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	if !active {
+		return &dtos.IntrospectionResponse{Active: false}, nil
+	}
+
+	response := &dtos.IntrospectionResponse{Active: true, TokenType: "Bearer", Jti: jti}
+	response.Scope, _ = claims["scope"].(string)
+	response.ClientID, _ = claims["client_id"].(string)
+	response.Username, _ = claims["email"].(string)
+	response.Sub, _ = claims["sub"].(string)
+	if exp, ok := claims["exp"].(float64); ok {
+		response.Exp = int64(exp)
+	}
+	if iat, ok := claims["iat"].(float64); ok {
+		response.Iat = int64(iat)
+	}
+	return response, nil
+}
+
+// ownerActive applies the checks AuthenticationMiddleware makes on top of
+// the signature: the session a token belongs to must not be revoked, and the
+// user it was issued for must be active.
+func (s *oauthService) ownerActive(ctx context.Context, claims jwt.MapClaims) (bool, error) {
+	tenantID, _ := claims["tenantId"].(string)
+	if tenantID == "" {
+		tenantID = tenant.DefaultID
+	}
+	ctx = tenant.WithID(ctx, tenantID)
+
+	if sessionID, ok := claims["sid"].(string); ok {
+		err := s.sessionService.ValidateSession(ctx, sessionID)
+		if errors.Is(err, ErrSessionRevoked) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	if _, ok := claims["email"]; ok {
+		userID, _ := claims["sub"].(string)
+		err := s.accountChecker.CheckAccount(ctx, userID)
+		if errors.Is(err, ErrAccountInactive) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Revoke implements RFC 7009. Unknown or invalid tokens are ignored, and a
+// client may only revoke tokens that were issued to it.
+func (s *oauthService) Revoke(ctx context.Context, creds dtos.ClientCredentials, token string) error {
+	ctx, span := tracing.Start(ctx, "OAuthService.Revoke")
+	defer span.End()
+
+	client, err := s.authenticateClient(ctx, creds)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+
+	claims, err := utils.VerifyToken(token)
+	if err != nil {
+		return nil
+	}
+	if clientID, _ := claims["client_id"].(string); clientID != client.ClientID {
+		return oauthError("unauthorized_client", "token was not issued to this client", http.StatusBadRequest)
+	}
+	jti, _ := claims["jti"].(string)
+	exp, _ := claims["exp"].(float64)
+	if jti == "" {
+		return nil
+	}
+	if err := s.oauthRepository.RevokeToken(ctx, jti, time.Unix(int64(exp), 0)); err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	logging.Audit(ctx, "oauth.revoke", "client_id", client.ClientID, "jti", jti)
+	return nil
+}
+
+func (s *oauthService) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return s.oauthRepository.IsTokenRevoked(ctx, jti)
+}
+
+// authenticateClient verifies the client secret of confidential clients.
+// Public clients are identified by client_id alone.
+func (s *oauthService) authenticateClient(ctx context.Context, creds dtos.ClientCredentials) (*models.OAuthClient, error) {
+	invalidClient := oauthError("invalid_client", "client authentication failed", http.StatusUnauthorized)
+	if creds.ClientID == "" {
+		return nil, invalidClient
+	}
+	client, err := s.oauthRepository.GetClientByClientID(ctx, creds.ClientID)
+	if err != nil {
+		if isContextError(err) {
+			return nil, err
+		}
+		return nil, invalidClient
+	}
+	if client.Public {
+		if creds.ClientSecret != "" {
+			return nil, invalidClient
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(sha256Hex(creds.ClientSecret))) != 1 {
+		return nil, invalidClient
+	}
+	return client, nil
+}
+
+func issueToken(client *models.OAuthClient, scope string, owner map[string]interface{}) (*dtos.TokenResponse, error) {
+	accessToken, err := utils.GenerateOAuthToken(client.ClientID, clientTenant(client), scope, owner, AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &dtos.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(AccessTokenTTL.Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// clientTenant treats clients registered before organizations existed as
+// members of the default tenant.
+func clientTenant(client *models.OAuthClient) string {
+	if client.TenantID == "" {
+		return tenant.DefaultID
+	}
+	return client.TenantID
+}
+
+func validateClient(input *dtos.OAuthClientCreate) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidOAuthClient, fmt.Sprintf(format, args...))
+	}
+	for _, grantType := range input.GrantTypes {
+		switch grantType {
+		case models.GrantTypeAuthorizationCode:
+			if len(input.RedirectURIs) == 0 {
+				return invalid("authorization_code clients need at least one redirect URI")
+			}
+		case models.GrantTypeClientCredentials:
+			if input.Public {
+				return invalid("public clients cannot use client_credentials")
+			}
+		default:
+			return invalid("unsupported grant type %q", grantType)
+		}
+	}
+	for _, scope := range input.Scopes {
+		if !slices.Contains(models.Scopes, scope) && !slices.Contains(models.OIDCScopes, scope) {
+			return invalid("unknown scope %q", scope)
+		}
+	}
+	for _, uri := range input.RedirectURIs {
+		u, err := url.Parse(uri)
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			return invalid("redirect URI %q must be absolute and have no fragment", uri)
+		}
+		if u.Scheme == "http" && u.Hostname() != "localhost" && u.Hostname() != "127.0.0.1" {
+			return invalid("redirect URI %q must use https", uri)
+		}
+	}
+	return nil
+}
+
+// grantedScope returns the requested scope, or all of the client's scopes
+// when none was requested. ok is false if any requested scope is not
+// allowed for the client.
+func grantedScope(client *models.OAuthClient, requested string) (string, bool) {
+	if requested == "" {
+		return strings.Join(client.Scopes, " "), true
+	}
+	for _, scope := range strings.Fields(requested) {
+		if !slices.Contains(client.Scopes, scope) {
+			return "", false
+		}
+	}
+	return strings.Join(strings.Fields(requested), " "), true
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func redirectURI(base string, params url.Values) string {
+	u, _ := url.Parse(base)
+	query := u.Query()
+	for key, values := range params {
+		if len(values) > 0 && values[0] != "" {
+			query.Set(key, values[0])
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, utils.ErrCanceled) || errors.Is(err, utils.ErrTimeout)
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func sha256Hex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func toOAuthClientResponse(client *models.OAuthClient) dtos.OAuthClientResponse {
+	return dtos.OAuthClientResponse{
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		GrantTypes:   client.GrantTypes,
+		Scopes:       client.Scopes,
+		Public:       client.Public,
+		TenantID:     client.TenantID,
+		CreatedBy:    client.CreatedBy,
+		CreatedAt:    client.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/logging"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var ErrInvalidOrganization = errors.New("invalid organization")
+
+// organizationIDPattern keeps ids usable in URLs, tokens and env var
+// names: lowercase letters, digits and inner hyphens.
+var organizationIDPattern = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?$`)
+
+type OrganizationService interface {
+	CreateOrganization(ctx context.Context, input *dtos.OrganizationCreate) (*dtos.OrganizationResponse, error)
+	GetOrganization(ctx context.Context, id string) (*dtos.OrganizationResponse, error)
+	GetAllOrganizations(ctx context.Context) ([]dtos.OrganizationResponse, error)
+}
+
+type organizationService struct {
+	organizationRepository repositories.OrganizationRepository
+	now                    func() time.Time
+}
+
+func NewOrganizationService(organizationRepository repositories.OrganizationRepository) OrganizationService {
+	return &organizationService{
+		organizationRepository: organizationRepository,
+		now:                    time.Now,
+	}
+}
+
+func (s *organizationService) CreateOrganization(ctx context.Context, input *dtos.OrganizationCreate) (*dtos.OrganizationResponse, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.CreateOrganization")
+	defer span.End()
+
+	if !organizationIDPattern.MatchString(input.ID) {
+		return nil, fmt.Errorf("%w: id must be a lowercase slug of at most 63 characters", ErrInvalidOrganization)
+	}
+
+	organization := &models.Organization{
+		ID:        input.ID,
+		Name:      input.Name,
+		CreatedAt: s.now(),
+	}
+	if err := s.organizationRepository.CreateOrganization(ctx, organization); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	logging.Audit(ctx, "organization.create", "organization_id", organization.ID)
+	response := toOrganizationResponse(organization)
+	return &response, nil
+}
+
+func (s *organizationService) GetOrganization(ctx context.Context, id string) (*dtos.OrganizationResponse, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.GetOrganization")
+	defer span.End()
+
+	organization, err := s.organizationRepository.GetOrganization(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	response := toOrganizationResponse(organization)
+	return &response, nil
+}
+
+func (s *organizationService) GetAllOrganizations(ctx context.Context) ([]dtos.OrganizationResponse, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.GetAllOrganizations")
+	defer span.End()
+
+	organizations, err := s.organizationRepository.GetAllOrganizations(ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	responses := make([]dtos.OrganizationResponse, 0, len(organizations))
+	for i := range organizations {
+		responses = append(responses, toOrganizationResponse(&organizations[i]))
+	}
+	return responses, nil
+}
+
+func toOrganizationResponse(organization *models.Organization) dtos.OrganizationResponse {
+	return dtos.OrganizationResponse{
+		ID:        organization.ID,
+		Name:      organization.Name,
+		CreatedAt: organization.CreatedAt.Format(time.RFC3339),
+	}
+}
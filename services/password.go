@@ -0,0 +1,24 @@
+package services
+
+import (
+	"7-solutions/tracing"
+	"7-solutions/utils"
+	"context"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// hashPassword wraps bcrypt in its own span since it dominates the latency
+// of registration, and skips the work entirely if the caller already gave up.
+func hashPassword(ctx context.Context, password string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, utils.ContextError(err)
+	}
+
+	_, span := tracing.Start(ctx, "bcrypt.GenerateFromPassword")
+	defer span.End()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	tracing.RecordError(span, err)
+	return hashedPassword, err
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/logging"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/tenant"
+	"7-solutions/tracing"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErasureResumeAfter is how long a running erasure may go without progress
+// before it is considered interrupted and resumed by ResumeErasures.
+const ErasureResumeAfter = 5 * time.Minute
+
+var ErrErasureNotAllowed = errors.New("erasing this user is not allowed")
+
+type PrivacyService interface {
+	ExportUserData(ctx context.Context, id int) (*dtos.UserDataExport, error)
+	EraseUser(ctx context.Context, requestedBy string, id int) (*dtos.ErasureResponse, error)
+	GetErasure(ctx context.Context, id int) (*dtos.ErasureResponse, error)
+	// ResumeErasures finishes the erasures that were interrupted and
+	// returns how many it completed.
+	ResumeErasures(ctx context.Context) (int, error)
+}
+
+type privacyService struct {
+	privacyRepository repositories.PrivacyRepository
+	groupRepository   repositories.GroupRepository
+	auditRepository   repositories.AuditRepository
+	authorizer        Authorizer
+	pseudonymKey      []byte
+	now               func() time.Time
+}
+
+// NewPrivacyService pseudonymizes erased users with an HMAC of their id
+// keyed with pseudonymKey, which must stay the same for erasures to be
+// found again.
+func NewPrivacyService(
+	privacyRepository repositories.PrivacyRepository,
+	groupRepository repositories.GroupRepository,
+	auditRepository repositories.AuditRepository,
+	authorizer Authorizer,
+	pseudonymKey string,
+) PrivacyService {
+	return &privacyService{
+		privacyRepository: privacyRepository,
+		groupRepository:   groupRepository,
+		auditRepository:   auditRepository,
+		authorizer:        authorizer,
+		pseudonymKey:      []byte(pseudonymKey),
+		now:               time.Now,
+	}
+}
+
+// ExportUserData assembles the profile of the user with their status
+// history, sessions, linked identities, group memberships and the audit
+// events about them.
+func (s *privacyService) ExportUserData(ctx context.Context, id int) (*dtos.UserDataExport, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.ExportUserData")
+	defer span.End()
+
+	if err := s.authorizer.Authorize(ctx, ActionUsersDataExport, userResource(id)); err != nil {
+		return nil, err
+	}
+
+	export, err := s.exportUserData(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	logging.Audit(ctx, "user.data_export", "target_user_id", id)
+	return export, nil
+}
+
+func (s *privacyService) exportUserData(ctx context.Context, id int) (*dtos.UserDataExport, error) {
+	user, err := s.privacyRepository.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.privacyRepository.GetUserSessions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := s.privacyRepository.GetUserIdentities(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	memberships, err := s.groupRepository.GetMembershipsByUserID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	groupIDs := make([]primitive.ObjectID, len(memberships))
+	for i, membership := range memberships {
+		groupIDs[i] = membership.GroupID
+	}
+	groups, err := s.groupRepository.GetGroupsByIDs(ctx, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.auditRepository.GetUserAuditEvents(ctx, strconv.Itoa(id), user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &dtos.UserDataExport{
+		ExportedAt:    s.now().Format(time.RFC3339),
+		Profile:       toUserExportRow(user),
+		StatusHistory: toUserStatusChanges(user.StatusHistory),
+		Sessions:      make([]dtos.UserDataSession, 0, len(sessions)),
+		Identities:    make([]dtos.IdentityResponse, 0, len(identities)),
+		Memberships:   make([]dtos.UserDataMembership, 0, len(memberships)),
+		AuditEvents:   make([]dtos.UserDataAuditEvent, 0, len(events)),
+	}
+	for _, session := range sessions {
+		data := dtos.UserDataSession{
+			ID:         session.ID.Hex(),
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt.Format(time.RFC3339),
+			LastSeenAt: session.LastSeenAt.Format(time.RFC3339),
+			ExpiresAt:  session.ExpiresAt.Format(time.RFC3339),
+		}
+		if session.RevokedAt != nil {
+			data.RevokedAt = session.RevokedAt.Format(time.RFC3339)
+		}
+		export.Sessions = append(export.Sessions, data)
+	}
+	for i := range identities {
+		export.Identities = append(export.Identities, toIdentityResponse(&identities[i]))
+	}
+	groupNames := map[primitive.ObjectID]string{}
+	for _, group := range groups {
+		groupNames[group.ID] = group.Name
+	}
+	for _, membership := range memberships {
+		export.Memberships = append(export.Memberships, dtos.UserDataMembership{
+			GroupID:   membership.GroupID.Hex(),
+			GroupName: groupNames[membership.GroupID],
+			AddedAt:   membership.AddedAt.Format(time.RFC3339),
+		})
+	}
+	for _, event := range events {
+		export.AuditEvents = append(export.AuditEvents, dtos.UserDataAuditEvent{
+			Action:       event.Action,
+			UserID:       event.UserID,
+			ActorID:      event.ActorID,
+			TargetUserID: event.TargetUserID,
+			RequestID:    event.RequestID,
+			Attributes:   event.Attributes,
+			CreatedAt:    event.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return export, nil
+}
+
+// EraseUser deletes the data of the user and pseudonymizes the records
+// about them that are kept, step by step. Erasing a user again returns the
+// erasure; an erasure that was interrupted continues where it stopped.
+func (s *privacyService) EraseUser(ctx context.Context, requestedBy string, id int) (*dtos.ErasureResponse, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.EraseUser")
+	defer span.End()
+
+	if err := s.authorizer.Authorize(ctx, ActionUsersErase, userResource(id)); err != nil {
+		return nil, err
+	}
+
+	erasure, err := s.startErasure(ctx, requestedBy, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	// Finish even if the caller goes away; a stopped erasure would have to
+	// wait for ResumeErasures.
+	if err := s.run(context.WithoutCancel(ctx), erasure); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return toErasureResponse(erasure), nil
+}
+
+// startErasure returns the erasure of the user id, creating it unless the
+// user was already erased.
+func (s *privacyService) startErasure(ctx context.Context, requestedBy string, id int) (*models.Erasure, error) {
+	subject := s.subject(id)
+	user, err := s.privacyRepository.GetUser(ctx, id)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		erasure, err := s.privacyRepository.GetErasure(ctx, subject)
+		if errors.Is(err, repositories.ErrErasureNotFound) {
+			return nil, repositories.ErrUserNotFound
+		}
+		return erasure, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == models.RoleSuperAdmin {
+		return nil, ErrErasureNotAllowed
+	}
+
+	now := s.now()
+	return s.privacyRepository.StartErasure(ctx, &models.Erasure{
+		TenantID:    user.TenantID,
+		Subject:     subject,
+		UserID:      user.ID,
+		Email:       user.Email,
+		Status:      models.ErasureRunning,
+		Steps:       []string{},
+		RequestedBy: requestedBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+}
+
+// run runs the steps of erasure that are not done yet.
+func (s *privacyService) run(ctx context.Context, erasure *models.Erasure) error {
+	if erasure.Status == models.ErasureCompleted {
+		return nil
+	}
+	ctx = tenant.Restrict(ctx, erasure.TenantID)
+
+	for _, step := range repositories.ErasureSteps {
+		if slices.Contains(erasure.Steps, step) {
+			continue
+		}
+		if err := s.privacyRepository.EraseUserData(ctx, step, erasure); err != nil {
+			return err
+		}
+		if err := s.privacyRepository.CompleteErasureStep(ctx, erasure.ID, step, s.now()); err != nil {
+			return err
+		}
+		erasure.Steps = append(erasure.Steps, step)
+	}
+
+	now := s.now()
+	if err := s.privacyRepository.CompleteErasure(ctx, erasure.ID, now); err != nil {
+		return err
+	}
+	erasure.Status = models.ErasureCompleted
+	erasure.CompletedAt = &now
+	erasure.UserID, erasure.Email = 0, ""
+	logging.Audit(ctx, "user.erase", "subject", erasure.Subject, "requested_by", erasure.RequestedBy)
+	return nil
+}
+
+func (s *privacyService) GetErasure(ctx context.Context, id int) (*dtos.ErasureResponse, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.GetErasure")
+	defer span.End()
+
+	if err := s.authorizer.Authorize(ctx, ActionUsersErase, userResource(id)); err != nil {
+		return nil, err
+	}
+
+	erasure, err := s.privacyRepository.GetErasure(ctx, s.subject(id))
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return toErasureResponse(erasure), nil
+}
+
+func (s *privacyService) ResumeErasures(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.ResumeErasures")
+	defer span.End()
+
+	ctx = tenant.WithAllTenants(ctx)
+	erasures, err := s.privacyRepository.GetStaleErasures(ctx, s.now().Add(-ErasureResumeAfter))
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+
+	var errs []error
+	completed := 0
+	for i := range erasures {
+		if err := s.run(ctx, &erasures[i]); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		completed++
+	}
+	err = errors.Join(errs...)
+	if err != nil {
+		tracing.RecordError(span, err)
+	}
+	return completed, err
+}
+
+// subject is the pseudonym of the user id in what is kept after erasure.
+func (s *privacyService) subject(id int) string {
+	mac := hmac.New(sha256.New, s.pseudonymKey)
+	mac.Write([]byte("user:" + strconv.Itoa(id)))
+	return "erased-" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func toErasureResponse(erasure *models.Erasure) *dtos.ErasureResponse {
+	response := &dtos.ErasureResponse{
+		Subject:     erasure.Subject,
+		Status:      erasure.Status,
+		Steps:       erasure.Steps,
+		RequestedBy: erasure.RequestedBy,
+		CreatedAt:   erasure.CreatedAt.Format(time.RFC3339),
+	}
+	if erasure.CompletedAt != nil {
+		response.CompletedAt = erasure.CompletedAt.Format(time.RFC3339)
+	}
+	return response
+}
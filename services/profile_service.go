@@ -0,0 +1,401 @@
+package services
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/logging"
+	"7-solutions/models"
+	"7-solutions/policy"
+	"7-solutions/repositories"
+	"7-solutions/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxAttributeValueLength bounds the length of string attribute values.
+const MaxAttributeValueLength = 1000
+
+var ErrInvalidAttribute = errors.New("invalid attribute")
+
+type ProfileService interface {
+	CreateAttribute(ctx context.Context, input *dtos.AttributeDefinitionCreate) (*dtos.AttributeDefinitionResponse, error)
+	// GetAttributes returns the definitions the caller may see.
+	GetAttributes(ctx context.Context) ([]dtos.AttributeDefinitionResponse, error)
+	UpdateAttribute(ctx context.Context, key string, input *dtos.AttributeDefinitionUpdate) (*dtos.AttributeDefinitionResponse, error)
+	DeleteAttribute(ctx context.Context, key string) error
+	// UpdateUserAttributes merges input into the attributes of the user and
+	// returns the attributes the caller may see.
+	UpdateUserAttributes(ctx context.Context, id int, input *dtos.UserAttributesUpdate) (map[string]any, error)
+}
+
+type profileService struct {
+	profileRepository repositories.ProfileRepository
+	authorizer        Authorizer
+	now               func() time.Time
+}
+
+func NewProfileService(profileRepository repositories.ProfileRepository, authorizer Authorizer) ProfileService {
+	return &profileService{
+		profileRepository: profileRepository,
+		authorizer:        authorizer,
+		now:               time.Now,
+	}
+}
+
+func (s *profileService) CreateAttribute(ctx context.Context, input *dtos.AttributeDefinitionCreate) (*dtos.AttributeDefinitionResponse, error) {
+	ctx, span := tracing.Start(ctx, "ProfileService.CreateAttribute")
+	defer span.End()
+
+	if !models.AttributeKeyPattern.MatchString(input.Key) {
+		return nil, fmt.Errorf("%w: key must be lowercase letters, digits and underscores, starting with a letter", ErrInvalidAttribute)
+	}
+	now := s.now()
+	definition := &models.AttributeDefinition{
+		TenantID:  input.TenantID,
+		Key:       input.Key,
+		Type:      input.Type,
+		CreatedAt: now,
+	}
+	setAttributeDefinition(definition, &dtos.AttributeDefinitionUpdate{
+		Required:    input.Required,
+		Pattern:     input.Pattern,
+		Enum:        input.Enum,
+		Visibility:  input.Visibility,
+		Description: input.Description,
+	}, now)
+	if err := checkAttributeDefinition(definition); err != nil {
+		return nil, err
+	}
+
+	if err := s.profileRepository.CreateAttribute(ctx, definition); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	logging.Audit(ctx, "attribute.create", "key", definition.Key, "type", definition.Type)
+	response := toAttributeDefinitionResponse(definition)
+	return &response, nil
+}
+
+func (s *profileService) GetAttributes(ctx context.Context) ([]dtos.AttributeDefinitionResponse, error) {
+	ctx, span := tracing.Start(ctx, "ProfileService.GetAttributes")
+	defer span.End()
+
+	definitions, err := s.profileRepository.GetAttributes(ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	_, admin := attributeCaller(ctx)
+	responses := make([]dtos.AttributeDefinitionResponse, 0, len(definitions))
+	for i := range definitions {
+		if admin || definitions[i].Visibility != models.VisibilityAdmin {
+			responses = append(responses, toAttributeDefinitionResponse(&definitions[i]))
+		}
+	}
+	return responses, nil
+}
+
+// UpdateAttribute does not check the values users already have against the
+// new definition; they are checked when next written.
+func (s *profileService) UpdateAttribute(ctx context.Context, key string, input *dtos.AttributeDefinitionUpdate) (*dtos.AttributeDefinitionResponse, error) {
+	ctx, span := tracing.Start(ctx, "ProfileService.UpdateAttribute")
+	defer span.End()
+
+	definition, err := s.profileRepository.GetAttribute(ctx, key)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	setAttributeDefinition(definition, input, s.now())
+	if err := checkAttributeDefinition(definition); err != nil {
+		return nil, err
+	}
+
+	if err := s.profileRepository.UpdateAttribute(ctx, definition); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	logging.Audit(ctx, "attribute.update", "key", key)
+	response := toAttributeDefinitionResponse(definition)
+	return &response, nil
+}
+
+// DeleteAttribute also deletes the values users have for the attribute.
+func (s *profileService) DeleteAttribute(ctx context.Context, key string) error {
+	ctx, span := tracing.Start(ctx, "ProfileService.DeleteAttribute")
+	defer span.End()
+
+	definition, err := s.profileRepository.GetAttribute(ctx, key)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	if err := s.profileRepository.DeleteAttribute(ctx, definition); err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	logging.Audit(ctx, "attribute.delete", "key", key)
+	return nil
+}
+
+// UpdateUserAttributes authorizes each attribute as the field
+// attributes.<key>. Callers may only set the attributes they may edit, and
+// must leave every required one of those with a value.
+func (s *profileService) UpdateUserAttributes(ctx context.Context, id int, input *dtos.UserAttributesUpdate) (map[string]any, error) {
+	ctx, span := tracing.Start(ctx, "ProfileService.UpdateUserAttributes")
+	defer span.End()
+
+	keys := sortedKeys(input.Attributes)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no attributes given", ErrInvalidAttribute)
+	}
+	fields := make([]string, len(keys))
+	for i, key := range keys {
+		fields[i] = "attributes." + key
+	}
+	if err := s.authorizer.Authorize(ctx, ActionUsersUpdate, userResource(id), fields...); err != nil {
+		return nil, err
+	}
+
+	user, err := s.profileRepository.GetUser(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	definitions, err := s.profileRepository.GetAttributes(ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	schema := attributeSchema(definitions, user.TenantID)
+	callerID, admin := attributeCaller(ctx)
+	self := callerID == strconv.Itoa(id)
+
+	values := maps.Clone(user.Attributes)
+	if values == nil {
+		values = map[string]any{}
+	}
+	set := map[string]any{}
+	var unset []string
+	for _, key := range keys {
+		definition, ok := schema[key]
+		if !ok || !canWriteAttribute(definition, admin, self) {
+			return nil, fmt.Errorf("%w: unknown attribute %q", ErrInvalidAttribute, key)
+		}
+		if input.Attributes[key] == nil {
+			unset = append(unset, key)
+			delete(values, key)
+			continue
+		}
+		value, err := checkAttributeValue(definition, input.Attributes[key])
+		if err != nil {
+			return nil, err
+		}
+		set[key] = value
+		values[key] = value
+	}
+	for _, key := range sortedKeys(schema) {
+		definition := schema[key]
+		if definition.Required && canWriteAttribute(definition, admin, self) && values[key] == nil {
+			return nil, fmt.Errorf("%w: %s is required", ErrInvalidAttribute, key)
+		}
+	}
+
+	if err := s.profileRepository.SetUserAttributes(ctx, id, set, unset); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	logging.Audit(ctx, "user.attributes_update", "target_user_id", id, "attributes", keys)
+	return visibleAttributes(schema, values, admin, self), nil
+}
+
+// setAttributeDefinition copies input into definition.
+func setAttributeDefinition(definition *models.AttributeDefinition, input *dtos.AttributeDefinitionUpdate, now time.Time) {
+	definition.Required = input.Required
+	definition.Pattern = input.Pattern
+	definition.Enum = input.Enum
+	definition.Visibility = input.Visibility
+	if definition.Visibility == "" {
+		definition.Visibility = models.VisibilityPrivate
+	}
+	definition.Description = strings.TrimSpace(input.Description)
+	definition.UpdatedAt = now
+}
+
+func checkAttributeDefinition(definition *models.AttributeDefinition) error {
+	if definition.Type != models.AttributeString && (definition.Pattern != "" || len(definition.Enum) > 0) {
+		return fmt.Errorf("%w: only string attributes take a pattern or an enum", ErrInvalidAttribute)
+	}
+	if definition.Pattern != "" {
+		if _, err := compileAttributePattern(definition.Pattern); err != nil {
+			return fmt.Errorf("%w: invalid pattern: %v", ErrInvalidAttribute, err)
+		}
+	}
+	for i, value := range definition.Enum {
+		if value == "" || slices.Contains(definition.Enum[:i], value) {
+			return fmt.Errorf("%w: enum values must be unique and not empty", ErrInvalidAttribute)
+		}
+	}
+	return nil
+}
+
+// compileAttributePattern anchors pattern so it must match values in full.
+func compileAttributePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(`^(?:` + pattern + `)$`)
+}
+
+// checkAttributeValue returns value, decoded from JSON, when it is valid for
+// definition.
+func checkAttributeValue(definition *models.AttributeDefinition, value any) (any, error) {
+	switch definition.Type {
+	case models.AttributeNumber:
+		if _, ok := value.(float64); !ok {
+			return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidAttribute, definition.Key)
+		}
+	case models.AttributeBoolean:
+		if _, ok := value.(bool); !ok {
+			return nil, fmt.Errorf("%w: %s must be a boolean", ErrInvalidAttribute, definition.Key)
+		}
+	default:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be a string", ErrInvalidAttribute, definition.Key)
+		}
+		if len(text) > MaxAttributeValueLength {
+			return nil, fmt.Errorf("%w: %s must be at most %d characters", ErrInvalidAttribute, definition.Key, MaxAttributeValueLength)
+		}
+		if len(definition.Enum) > 0 && !slices.Contains(definition.Enum, text) {
+			return nil, fmt.Errorf("%w: %s must be one of %s", ErrInvalidAttribute, definition.Key, strings.Join(definition.Enum, ", "))
+		}
+		if definition.Pattern != "" {
+			pattern, err := compileAttributePattern(definition.Pattern)
+			if err != nil || !pattern.MatchString(text) {
+				return nil, fmt.Errorf("%w: %s does not match %s", ErrInvalidAttribute, definition.Key, definition.Pattern)
+			}
+		}
+	}
+	return value, nil
+}
+
+// parseAttributeValue converts raw, taken from a query string, to the type
+// of definition.
+func parseAttributeValue(definition *models.AttributeDefinition, raw string) (any, error) {
+	switch definition.Type {
+	case models.AttributeNumber:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidAttribute, definition.Key)
+		}
+		return value, nil
+	case models.AttributeBoolean:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be a boolean", ErrInvalidAttribute, definition.Key)
+		}
+		return value, nil
+	}
+	return raw, nil
+}
+
+// parseAttributeFilter converts the values of filter to the types of the
+// attributes they match. Only admins may filter on attributes that are not
+// public, so other callers cannot find out who has which private value.
+func parseAttributeFilter(definitions []models.AttributeDefinition, filter map[string]any, admin bool) error {
+	for key, raw := range filter {
+		index := slices.IndexFunc(definitions, func(definition models.AttributeDefinition) bool {
+			return definition.Key == key
+		})
+		if index < 0 || (!admin && definitions[index].Visibility != models.VisibilityPublic) {
+			return fmt.Errorf("%w: unknown attribute %q", ErrInvalidAttribute, key)
+		}
+		text, _ := raw.(string)
+		value, err := parseAttributeValue(&definitions[index], text)
+		if err != nil {
+			return err
+		}
+		filter[key] = value
+	}
+	return nil
+}
+
+// attributeCaller returns the id of the caller and whether they are an
+// admin. Only users signed in with an admin role are admins; API keys and
+// OAuth clients see public attributes only.
+func attributeCaller(ctx context.Context) (id string, admin bool) {
+	subject, ok := policy.SubjectFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return subject.ID, subject.Role == models.RoleAdmin || subject.Role == models.RoleSuperAdmin
+}
+
+// attributeSchema returns the definitions of tenantID by key.
+func attributeSchema(definitions []models.AttributeDefinition, tenantID string) map[string]*models.AttributeDefinition {
+	schema := make(map[string]*models.AttributeDefinition, len(definitions))
+	for i := range definitions {
+		if definitions[i].TenantID == tenantID {
+			schema[definitions[i].Key] = &definitions[i]
+		}
+	}
+	return schema
+}
+
+func canReadAttribute(definition *models.AttributeDefinition, admin, self bool) bool {
+	switch definition.Visibility {
+	case models.VisibilityPublic:
+		return true
+	case models.VisibilityPrivate:
+		return admin || self
+	}
+	return admin
+}
+
+func canWriteAttribute(definition *models.AttributeDefinition, admin, self bool) bool {
+	return admin || (self && definition.Visibility != models.VisibilityAdmin)
+}
+
+// visibleAttributes returns the values of the defined attributes the caller
+// may read, nil when there are none.
+func visibleAttributes(schema map[string]*models.AttributeDefinition, values map[string]any, admin, self bool) map[string]any {
+	var visible map[string]any
+	for key, value := range values {
+		definition, ok := schema[key]
+		if !ok || !canReadAttribute(definition, admin, self) {
+			continue
+		}
+		if visible == nil {
+			visible = map[string]any{}
+		}
+		visible[key] = value
+	}
+	return visible
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func toAttributeDefinitionResponse(definition *models.AttributeDefinition) dtos.AttributeDefinitionResponse {
+	return dtos.AttributeDefinitionResponse{
+		Key:         definition.Key,
+		Type:        definition.Type,
+		Required:    definition.Required,
+		Pattern:     definition.Pattern,
+		Enum:        definition.Enum,
+		Visibility:  definition.Visibility,
+		Description: definition.Description,
+		CreatedAt:   definition.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   definition.UpdatedAt.Format(time.RFC3339),
+	}
+}
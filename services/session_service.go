@@ -0,0 +1,126 @@
+package services
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/logging"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/tracing"
+	"context"
+	"errors"
+	"time"
+)
+
+// SessionTouchInterval is how stale the last-seen time of a session may
+// get before a request updates it, which saves a write per request.
+const SessionTouchInterval = time.Minute
+
+// ErrSessionRevoked is returned for tokens whose session was revoked, has
+// expired or no longer exists.
+var ErrSessionRevoked = errors.New("session revoked or expired")
+
+type SessionService interface {
+	GetSessions(ctx context.Context, userID int, currentID string) ([]dtos.SessionResponse, error)
+	RevokeSession(ctx context.Context, userID int, sessionID string) error
+	RevokeAllSessions(ctx context.Context, userID int) (int64, error)
+	ValidateSession(ctx context.Context, sessionID string) error
+}
+
+type sessionService struct {
+	sessionRepository repositories.SessionRepository
+	now               func() time.Time
+}
+
+func NewSessionService(sessionRepository repositories.SessionRepository) SessionService {
+	return &sessionService{
+		sessionRepository: sessionRepository,
+		now:               time.Now,
+	}
+}
+
+// GetSessions lists the active sessions of userID and marks currentID, the
+// session of the caller, as current.
+func (s *sessionService) GetSessions(ctx context.Context, userID int, currentID string) ([]dtos.SessionResponse, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.GetSessions")
+	defer span.End()
+
+	sessions, err := s.sessionRepository.GetActiveSessions(ctx, userID, s.now())
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	responses := make([]dtos.SessionResponse, 0, len(sessions))
+	for i := range sessions {
+		response := toSessionResponse(&sessions[i])
+		response.Current = response.ID == currentID
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+func (s *sessionService) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	ctx, span := tracing.Start(ctx, "SessionService.RevokeSession")
+	defer span.End()
+
+	if err := s.sessionRepository.RevokeSession(ctx, userID, sessionID, s.now()); err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	logging.Audit(ctx, "session.revoke", "session_id", sessionID, "target_user_id", userID)
+	return nil
+}
+
+// RevokeAllSessions signs userID out everywhere and returns the number of
+// sessions revoked.
+func (s *sessionService) RevokeAllSessions(ctx context.Context, userID int) (int64, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.RevokeAllSessions")
+	defer span.End()
+
+	revoked, err := s.sessionRepository.RevokeUserSessions(ctx, userID, s.now())
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+	logging.Audit(ctx, "session.revoke_all", "target_user_id", userID, "revoked", revoked)
+	return revoked, nil
+}
+
+// ValidateSession returns ErrSessionRevoked unless sessionID is active, and
+// records the request as activity on the session. Failing to record it
+// only gets logged.
+func (s *sessionService) ValidateSession(ctx context.Context, sessionID string) error {
+	ctx, span := tracing.Start(ctx, "SessionService.ValidateSession")
+	defer span.End()
+
+	session, err := s.sessionRepository.GetSession(ctx, sessionID)
+	if errors.Is(err, repositories.ErrSessionNotFound) {
+		return ErrSessionRevoked
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+
+	now := s.now()
+	if !session.Active(now) {
+		return ErrSessionRevoked
+	}
+	if now.Sub(session.LastSeenAt) >= SessionTouchInterval {
+		if err := s.sessionRepository.TouchSession(ctx, session.ID, now); err != nil {
+			tracing.RecordError(span, err)
+			logging.FromContext(ctx).Warn("failed to update session last-seen time", "session_id", sessionID, "error", err)
+		}
+	}
+	return nil
+}
+
+func toSessionResponse(session *models.Session) dtos.SessionResponse {
+	return dtos.SessionResponse{
+		ID:         session.ID.Hex(),
+		UserAgent:  session.UserAgent,
+		IP:         session.IP,
+		CreatedAt:  session.CreatedAt.Format(time.RFC3339),
+		LastSeenAt: session.LastSeenAt.Format(time.RFC3339),
+		ExpiresAt:  session.ExpiresAt.Format(time.RFC3339),
+	}
+}
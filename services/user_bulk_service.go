@@ -0,0 +1,302 @@
+package services
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/logging"
+	"7-solutions/models"
+	"7-solutions/policy"
+	"7-solutions/repositories"
+	"7-solutions/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	BulkOpUpdate = "update"
+	BulkOpDelete = "delete"
+	// BulkOpDeactivate changes the status of the user to deactivated, like
+	// UserStatusService.ChangeStatus.
+	BulkOpDeactivate = "deactivate"
+)
+
+// Statuses of the operations of a bulk request.
+const (
+	BulkSucceeded = "succeeded"
+	BulkFailed    = "failed"
+	// BulkSkipped operations were valid but not applied because another
+	// operation of an atomic request failed.
+	BulkSkipped = "skipped"
+)
+
+// ErrBulkRolledBack is returned with the response of an atomic request in
+// which an operation failed, so none were applied.
+var ErrBulkRolledBack = errors.New("bulk operations rolled back")
+
+type UserBulkService interface {
+	// ApplyBulk applies input on behalf of actorID.
+	ApplyBulk(ctx context.Context, actorID string, input *dtos.UserBulk) (*dtos.UserBulkResponse, error)
+}
+
+type userBulkService struct {
+	bulkRepository    repositories.BulkRepository
+	sessionRepository repositories.SessionRepository
+	authorizer        Authorizer
+	now               func() time.Time
+}
+
+func NewUserBulkService(
+	bulkRepository repositories.BulkRepository,
+	sessionRepository repositories.SessionRepository,
+	authorizer Authorizer,
+) UserBulkService {
+	return &userBulkService{
+		bulkRepository:    bulkRepository,
+		sessionRepository: sessionRepository,
+		authorizer:        authorizer,
+		now:               time.Now,
+	}
+}
+
+// ApplyBulk validates and authorizes every operation on its own, then
+// applies the ones that passed with a single bulk write. An atomic request
+// is applied only when every operation passes and succeeds; otherwise the
+// response comes with ErrBulkRolledBack. Deactivated users are signed out of
+// every session.
+func (s *userBulkService) ApplyBulk(ctx context.Context, actorID string, input *dtos.UserBulk) (*dtos.UserBulkResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserBulkService.ApplyBulk")
+	defer span.End()
+
+	ids := make([]int, 0, len(input.Operations))
+	for _, op := range input.Operations {
+		ids = append(ids, op.ID)
+	}
+	users, err := s.bulkRepository.FindUsersByID(ctx, ids)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	byID := map[int]models.User{}
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	response := &dtos.UserBulkResponse{
+		Atomic:  input.Atomic,
+		Results: make([]dtos.UserBulkItemResult, len(input.Operations)),
+	}
+	now := s.now()
+	seen := map[int]int{}
+	var writes []repositories.UserWrite
+	var writeItems []int
+	for i, op := range input.Operations {
+		response.Results[i] = dtos.UserBulkItemResult{Index: i, Op: op.Op, ID: op.ID, Status: BulkSucceeded}
+
+		err := validateBulkOperation(&op)
+		if first, ok := seen[op.ID]; ok && err == nil {
+			err = fmt.Errorf("duplicate of operation %d", first)
+		}
+		if err == nil {
+			seen[op.ID] = i
+			err = s.authorizeBulkOperation(ctx, actorID, op, byID)
+			if err != nil && !isBulkItemError(err) {
+				tracing.RecordError(span, err)
+				return nil, err
+			}
+		}
+		if err != nil {
+			bulkFailure(&response.Results[i], err)
+			continue
+		}
+
+		write := repositories.UserWrite{UserID: op.ID, Delete: op.Op == BulkOpDelete}
+		if op.Update != nil {
+			write.Name, write.Email, write.Role = op.Update.Name, op.Update.Email, op.Update.Role
+		}
+		if op.Op == BulkOpDeactivate {
+			user := byID[op.ID]
+			write.Status = &models.StatusChange{
+				From:    user.AccountStatus(),
+				To:      models.StatusDeactivated,
+				Reason:  op.Reason,
+				ActorID: actorID,
+				At:      now,
+			}
+		}
+		writes = append(writes, write)
+		writeItems = append(writeItems, i)
+	}
+
+	if len(writes) > 0 && (!input.Atomic || summarizeBulk(response.Results).Failed == 0) {
+		failed, err := s.bulkRepository.WriteUsers(ctx, writes, input.Atomic)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		for j, i := range writeItems {
+			if err, ok := failed[j]; ok {
+				bulkFailure(&response.Results[i], err)
+			}
+		}
+	}
+
+	response.Summary = summarizeBulk(response.Results)
+	response.Applied = !input.Atomic || response.Summary.Failed == 0
+	if !response.Applied {
+		for i := range response.Results {
+			if response.Results[i].Status == BulkSucceeded {
+				response.Results[i].Status = BulkSkipped
+			}
+		}
+		response.Summary = summarizeBulk(response.Results)
+	}
+	for j, i := range writeItems {
+		change := writes[j].Status
+		if change == nil || response.Results[i].Status != BulkSucceeded {
+			continue
+		}
+		signOutInactiveUser(ctx, span, s.sessionRepository, writes[j].UserID, change.At)
+		logging.Audit(ctx, "user.status_change", "target_user_id", writes[j].UserID, "from", change.From, "to", change.To, "reason", change.Reason)
+	}
+
+	logging.Audit(ctx, "user.bulk",
+		"atomic", input.Atomic, "applied", response.Applied, "operations", len(input.Operations),
+		"succeeded", response.Summary.Succeeded, "failed", response.Summary.Failed, "skipped", response.Summary.Skipped)
+	if !response.Applied {
+		return response, ErrBulkRolledBack
+	}
+	return response, nil
+}
+
+// authorizeBulkOperation checks that the target of op exists and that the
+// caller may delete it, change each field the update changes, or deactivate
+// it the way UserStatusService.ChangeStatus would.
+func (s *userBulkService) authorizeBulkOperation(ctx context.Context, actorID string, op dtos.UserBulkOperation, users map[int]models.User) error {
+	user, ok := users[op.ID]
+	if !ok {
+		return repositories.ErrUserNotFound
+	}
+	if user.Role == models.RoleSuperAdmin {
+		return errBulkSuperAdmin
+	}
+	switch op.Op {
+	case BulkOpDelete:
+		return s.authorizer.Authorize(ctx, ActionUsersDelete, userResource(op.ID))
+	case BulkOpDeactivate:
+		if err := s.authorizer.Authorize(ctx, ActionUsersUpdate, userResource(op.ID), "status"); err != nil {
+			return err
+		}
+		if actorID == strconv.Itoa(op.ID) {
+			return ErrStatusChangeNotAllowed
+		}
+		if from := user.AccountStatus(); !models.CanTransitionStatus(from, models.StatusDeactivated) {
+			return fmt.Errorf("%w: from %s to %s", ErrInvalidStatusTransition, from, models.StatusDeactivated)
+		}
+		return nil
+	}
+
+	var fields []string
+	if op.Update.Name != nil && *op.Update.Name != user.Name {
+		fields = append(fields, "name")
+	}
+	if op.Update.Email != nil && *op.Update.Email != user.Email {
+		fields = append(fields, "email")
+	}
+	if op.Update.Role != nil && *op.Update.Role != user.Role {
+		fields = append(fields, "role")
+	}
+	return s.authorizer.Authorize(ctx, ActionUsersUpdate, userResource(op.ID), fields...)
+}
+
+var errBulkSuperAdmin = errors.New("super admins cannot be changed in bulk")
+
+// isBulkItemError tells whether err only fails its own operation rather
+// than the whole request.
+func isBulkItemError(err error) bool {
+	for _, target := range []error{
+		policy.ErrDenied, repositories.ErrUserNotFound, errBulkSuperAdmin,
+		ErrStatusChangeNotAllowed, ErrInvalidStatusTransition,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateBulkOperation normalizes the fields of an update and checks them
+// the way a single update would.
+func validateBulkOperation(op *dtos.UserBulkOperation) error {
+	if op.Op != BulkOpDeactivate && op.Reason != "" {
+		return fmt.Errorf("%s takes no reason", op.Op)
+	}
+	switch op.Op {
+	case BulkOpDelete:
+		if op.Update != nil {
+			return errors.New("delete takes no update")
+		}
+		return nil
+	case BulkOpDeactivate:
+		if op.Update != nil {
+			return errors.New("deactivate takes no update")
+		}
+		op.Reason = strings.TrimSpace(op.Reason)
+		if op.Reason == "" {
+			return ErrStatusReasonRequired
+		}
+		return nil
+	}
+
+	update := op.Update
+	if update == nil || (update.Name == nil && update.Email == nil && update.Role == nil) {
+		return errors.New("update requires at least one of name, email or role")
+	}
+	normalized := *update
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if name == "" {
+			return errors.New("name cannot be empty")
+		}
+		normalized.Name = &name
+	}
+	if update.Email != nil {
+		email := strings.TrimSpace(*update.Email)
+		if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+			return fmt.Errorf("invalid email %q", email)
+		}
+		normalized.Email = &email
+	}
+	if update.Role != nil {
+		role := strings.ToLower(strings.TrimSpace(*update.Role))
+		if role != models.RoleUser && role != models.RoleAdmin {
+			return fmt.Errorf("role must be %s or %s", models.RoleUser, models.RoleAdmin)
+		}
+		normalized.Role = &role
+	}
+	op.Update = &normalized
+	return nil
+}
+
+func bulkFailure(result *dtos.UserBulkItemResult, err error) {
+	result.Status = BulkFailed
+	result.Error = err.Error()
+}
+
+func summarizeBulk(results []dtos.UserBulkItemResult) dtos.UserBulkSummary {
+	var summary dtos.UserBulkSummary
+	for _, result := range results {
+		switch result.Status {
+		case BulkSucceeded:
+			summary.Succeeded++
+		case BulkFailed:
+			summary.Failed++
+		case BulkSkipped:
+			summary.Skipped++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,443 @@
+package services
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/logging"
+	"7-solutions/models"
+	"7-solutions/policy"
+	"7-solutions/repositories"
+	"7-solutions/tenant"
+	"7-solutions/tracing"
+	"7-solutions/xlsx"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatXLSX   = "xlsx"
+)
+
+// ExportJobRetention is how long the file of a background export can be
+// downloaded.
+const ExportJobRetention = 24 * time.Hour
+
+var (
+	ErrInvalidExport  = errors.New("invalid export")
+	ErrExportNotReady = errors.New("export has not completed")
+)
+
+var exportColumns = []string{"id", "name", "email", "emailVerified", "role", "tenantId", "createdAt"}
+
+var exportContentTypes = map[string]string{
+	ExportFormatCSV:    "text/csv; charset=utf-8",
+	ExportFormatNDJSON: "application/x-ndjson",
+	ExportFormatXLSX:   xlsx.ContentType,
+}
+
+// ExportContentType returns the media type of an export format.
+func ExportContentType(format string) string {
+	return exportContentTypes[format]
+}
+
+// ExportFileName names the file of an export started at createdAt.
+func ExportFileName(format string, createdAt time.Time) string {
+	return "users-" + createdAt.UTC().Format("20060102T150405Z") + "." + format
+}
+
+type UserExportService interface {
+	// ExportUsers writes the users matching input to w as they are read
+	// and returns how many were written.
+	ExportUsers(ctx context.Context, input *dtos.UserExport, w io.Writer) (int, error)
+	StartExport(ctx context.Context, startedBy string, input *dtos.UserExport) (*dtos.UserExportResponse, error)
+	GetExportJob(ctx context.Context, id string) (*dtos.UserExportResponse, error)
+	// OpenExportFile returns the file of a completed export, which the
+	// caller must close.
+	OpenExportFile(ctx context.Context, id string) (*dtos.UserExportResponse, io.ReadCloser, error)
+	PurgeExpiredExports(ctx context.Context) (int64, error)
+	// Wait blocks until the background exports have finished, and
+	// interrupts them when ctx is done first.
+	Wait(ctx context.Context) error
+}
+
+type userExportService struct {
+	exportRepository  repositories.ExportRepository
+	profileRepository repositories.ProfileRepository
+	authorizer        Authorizer
+	now               func() time.Time
+	jobs              *backgroundJobs
+}
+
+// NewUserExportService reads the attribute definitions from
+// profileRepository to filter users by attribute and to add a column per
+// attribute.
+func NewUserExportService(exportRepository repositories.ExportRepository, profileRepository repositories.ProfileRepository, authorizer Authorizer) UserExportService {
+	return &userExportService{
+		exportRepository:  exportRepository,
+		profileRepository: profileRepository,
+		authorizer:        authorizer,
+		now:               time.Now,
+		jobs:              newBackgroundJobs(),
+	}
+}
+
+func (s *userExportService) authorize(ctx context.Context, input *dtos.UserExport) error {
+	if ExportContentType(input.Format) == "" {
+		return fmt.Errorf("%w: unsupported format %q, expected csv, ndjson or xlsx", ErrInvalidExport, input.Format)
+	}
+	tenantID, _ := tenant.FromContext(ctx)
+	return s.authorizer.Authorize(ctx, ActionUsersExport, policy.Resource{Type: "user", TenantID: tenantID})
+}
+
+// attributes returns the attribute definitions and converts the attribute
+// filter of input to their types. Exports hold every attribute, so any of
+// them may be filtered on.
+func (s *userExportService) attributes(ctx context.Context, input *dtos.UserExport) ([]models.AttributeDefinition, error) {
+	definitions, err := s.profileRepository.GetAttributes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return definitions, parseAttributeFilter(definitions, input.Attributes, true)
+}
+
+func (s *userExportService) ExportUsers(ctx context.Context, input *dtos.UserExport, w io.Writer) (int, error) {
+	ctx, span := tracing.Start(ctx, "UserExportService.ExportUsers")
+	defer span.End()
+
+	if err := s.authorize(ctx, input); err != nil {
+		return 0, err
+	}
+	definitions, err := s.attributes(ctx, input)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+	rows, err := writeUserExport(ctx, s.exportRepository, definitions, input, w)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return rows, err
+	}
+	logging.Audit(ctx, "user.export", "format", input.Format, "rows", rows)
+	return rows, nil
+}
+
+// StartExport runs an export in the background, writing its file to the
+// database, and returns the job to poll.
+func (s *userExportService) StartExport(ctx context.Context, startedBy string, input *dtos.UserExport) (*dtos.UserExportResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserExportService.StartExport")
+	defer span.End()
+
+	if err := s.authorize(ctx, input); err != nil {
+		return nil, err
+	}
+	tenantID, err := tenant.ForWrite(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	definitions, err := s.attributes(ctx, input)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	// The file id is known up front so the file can be purged even when
+	// the job dies half-way.
+	fileID := primitive.NewObjectID()
+	now := s.now()
+	job := &models.ExportJob{
+		TenantID:  tenantID,
+		Status:    models.ExportRunning,
+		Format:    input.Format,
+		StartedBy: startedBy,
+		FileID:    &fileID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ExportJobRetention),
+	}
+	if err := s.exportRepository.CreateExportJob(ctx, job); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	logging.Audit(ctx, "user.export_start", "job_id", job.ID.Hex(), "format", job.Format)
+
+	response := toUserExportResponse(job)
+	filter := input.UserFilter
+	s.jobs.Go(ctx, func(ctx context.Context) {
+		s.runJob(ctx, job, definitions, &dtos.UserExport{Format: job.Format, UserFilter: filter})
+	})
+	return response, nil
+}
+
+func (s *userExportService) runJob(ctx context.Context, job *models.ExportJob, definitions []models.AttributeDefinition, input *dtos.UserExport) {
+	ctx, span := tracing.Start(ctx, "UserExportService.runJob")
+	defer span.End()
+
+	size, err := s.exportRepository.SaveExportFile(ctx, *job.FileID, ExportFileName(job.Format, job.CreatedAt), func(w io.Writer) error {
+		var err error
+		job.Rows, err = writeUserExport(ctx, s.exportRepository, definitions, input, w)
+		return err
+	})
+	now := s.now()
+	job.CompletedAt = &now
+	job.ExpiresAt = now.Add(ExportJobRetention)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.FromContext(ctx).Error("user export failed", "job_id", job.ID.Hex(), "error", err)
+		job.Status = models.ExportFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = models.ExportCompleted
+		job.Size = size
+		logging.Audit(ctx, "user.export", "job_id", job.ID.Hex(), "format", job.Format, "rows", job.Rows)
+	}
+
+	// Record the outcome even when the job was interrupted.
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := s.exportRepository.UpdateExportJob(saveCtx, job); err != nil {
+		tracing.RecordError(span, err)
+		logging.FromContext(ctx).Error("failed to save user export", "job_id", job.ID.Hex(), "error", err)
+	}
+}
+
+func (s *userExportService) GetExportJob(ctx context.Context, id string) (*dtos.UserExportResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserExportService.GetExportJob")
+	defer span.End()
+
+	job, err := s.exportRepository.GetExportJob(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return toUserExportResponse(job), nil
+}
+
+func (s *userExportService) OpenExportFile(ctx context.Context, id string) (*dtos.UserExportResponse, io.ReadCloser, error) {
+	ctx, span := tracing.Start(ctx, "UserExportService.OpenExportFile")
+	defer span.End()
+
+	job, err := s.exportRepository.GetExportJob(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, nil, err
+	}
+	if job.Status != models.ExportCompleted || job.FileID == nil {
+		return nil, nil, fmt.Errorf("%w: export is %s", ErrExportNotReady, job.Status)
+	}
+	file, err := s.exportRepository.OpenExportFile(ctx, *job.FileID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, nil, err
+	}
+	logging.Audit(ctx, "user.export_download", "job_id", id)
+	return toUserExportResponse(job), file, nil
+}
+
+// PurgeExpiredExports deletes the exports of every tenant that are past
+// their retention, with their files.
+func (s *userExportService) PurgeExpiredExports(ctx context.Context) (int64, error) {
+	ctx, span := tracing.Start(ctx, "UserExportService.PurgeExpiredExports")
+	defer span.End()
+
+	deleted, err := s.exportRepository.DeleteExpiredExports(tenant.WithAllTenants(ctx), s.now())
+	if err != nil {
+		tracing.RecordError(span, err)
+		return deleted, err
+	}
+	return deleted, nil
+}
+
+func (s *userExportService) Wait(ctx context.Context) error {
+	return s.jobs.Wait(ctx, "user exports")
+}
+
+// writeUserExport streams the users matching input to w, with a column per
+// attribute of definitions. Nothing is written until the first user has
+// been read, so callers can still report a failing query as an error.
+func writeUserExport(ctx context.Context, exportRepository repositories.ExportRepository, definitions []models.AttributeDefinition, input *dtos.UserExport, w io.Writer) (int, error) {
+	// Definitions are sorted by key; tenants may share keys.
+	var attributes []string
+	for _, definition := range definitions {
+		if !slices.Contains(attributes, definition.Key) {
+			attributes = append(attributes, definition.Key)
+		}
+	}
+
+	var writer rowWriter
+	rows := 0
+	err := exportRepository.StreamUsers(ctx, &input.UserFilter, func(user *models.User) error {
+		if writer == nil {
+			var err error
+			if writer, err = newRowWriter(input.Format, attributes, w); err != nil {
+				return err
+			}
+		}
+		rows++
+		return writer.Write(toUserExportRow(user))
+	})
+	if err != nil {
+		return rows, err
+	}
+	if writer == nil {
+		if writer, err = newRowWriter(input.Format, attributes, w); err != nil {
+			return rows, err
+		}
+	}
+	return rows, writer.Close()
+}
+
+// rowWriter writes exported users in one of the export formats. Close
+// flushes what is buffered and finishes the file.
+type rowWriter interface {
+	Write(row dtos.UserExportRow) error
+	Close() error
+}
+
+// newRowWriter writes the values of attributes, in this order, after the
+// exportColumns of csv and xlsx files.
+func newRowWriter(format string, attributes []string, w io.Writer) (rowWriter, error) {
+	columns := slices.Clone(exportColumns)
+	for _, key := range attributes {
+		columns = append(columns, "attributes."+key)
+	}
+
+	switch format {
+	case ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		return &csvRowWriter{writer: writer, attributes: attributes}, writer.Write(columns)
+	case ExportFormatNDJSON:
+		return &ndjsonRowWriter{encoder: json.NewEncoder(w)}, nil
+	case ExportFormatXLSX:
+		writer, err := xlsx.NewWriter(w, "Users")
+		if err != nil {
+			return nil, err
+		}
+		header := make([]any, len(columns))
+		for i, column := range columns {
+			header[i] = column
+		}
+		return &xlsxRowWriter{writer: writer, attributes: attributes}, writer.WriteRow(header...)
+	}
+	return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidExport, format)
+}
+
+type csvRowWriter struct {
+	writer     *csv.Writer
+	attributes []string
+}
+
+func (w *csvRowWriter) Write(row dtos.UserExportRow) error {
+	record := []string{
+		strconv.Itoa(row.ID),
+		csvText(row.Name),
+		csvText(row.Email),
+		strconv.FormatBool(row.EmailVerified),
+		row.Role,
+		row.TenantID,
+		row.CreatedAt,
+	}
+	for _, key := range w.attributes {
+		switch value := row.Attributes[key].(type) {
+		case string:
+			record = append(record, csvText(value))
+		case float64:
+			record = append(record, strconv.FormatFloat(value, 'f', -1, 64))
+		case bool:
+			record = append(record, strconv.FormatBool(value))
+		default:
+			record = append(record, "")
+		}
+	}
+	return w.writer.Write(record)
+}
+
+func (w *csvRowWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// csvText keeps spreadsheets from running user-controlled text as a
+// formula.
+func csvText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+type ndjsonRowWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonRowWriter) Write(row dtos.UserExportRow) error {
+	return w.encoder.Encode(row)
+}
+
+func (w *ndjsonRowWriter) Close() error {
+	return nil
+}
+
+type xlsxRowWriter struct {
+	writer     *xlsx.Writer
+	attributes []string
+}
+
+func (w *xlsxRowWriter) Write(row dtos.UserExportRow) error {
+	values := []any{row.ID, row.Name, row.Email, row.EmailVerified, row.Role, row.TenantID, row.CreatedAt}
+	for _, key := range w.attributes {
+		value, ok := row.Attributes[key]
+		if !ok {
+			value = ""
+		}
+		values = append(values, value)
+	}
+	return w.writer.WriteRow(values...)
+}
+
+func (w *xlsxRowWriter) Close() error {
+	return w.writer.Close()
+}
+
+func toUserExportRow(user *models.User) dtos.UserExportRow {
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+	return dtos.UserExportRow{
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Role:          role,
+		TenantID:      user.TenantID,
+		CreatedAt:     user.CreatedAt.Format(time.RFC3339),
+		Attributes:    user.Attributes,
+	}
+}
+
+func toUserExportResponse(job *models.ExportJob) *dtos.UserExportResponse {
+	response := &dtos.UserExportResponse{
+		ID:        job.ID.Hex(),
+		Status:    job.Status,
+		Format:    job.Format,
+		Rows:      job.Rows,
+		Size:      job.Size,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt.Format(time.RFC3339),
+		ExpiresAt: job.ExpiresAt.Format(time.RFC3339),
+	}
+	if job.CompletedAt != nil {
+		response.CompletedAt = job.CompletedAt.Format(time.RFC3339)
+	}
+	return response
+}
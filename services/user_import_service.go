@@ -0,0 +1,569 @@
+package services
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/logging"
+	"7-solutions/models"
+	"7-solutions/policy"
+	"7-solutions/repositories"
+	"7-solutions/tenant"
+	"7-solutions/tracing"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+)
+
+const (
+	// MaxImportRows bounds the size of an import file.
+	MaxImportRows = 10000
+	// ImportSyncRows is the largest import that is answered right away;
+	// bigger ones run in the background.
+	ImportSyncRows = 1000
+	// ImportBatchSize is how many users are written per InsertMany and
+	// BulkWrite.
+	ImportBatchSize = 500
+	// ImportJobRetention is how long finished background imports can be
+	// looked up.
+	ImportJobRetention = 7 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidImport  = errors.New("invalid import")
+	ErrImportTooLarge = fmt.Errorf("imports are limited to %d rows", MaxImportRows)
+)
+
+var importColumns = []string{"email", "name", "password", "role"}
+
+type UserImportService interface {
+	ImportUsers(ctx context.Context, startedBy string, input *dtos.UserImport, body io.Reader) (*dtos.UserImportResponse, error)
+	GetImportJob(ctx context.Context, id string) (*dtos.UserImportResponse, error)
+	// Wait blocks until the background imports have finished, and
+	// interrupts them when ctx is done first.
+	Wait(ctx context.Context) error
+}
+
+type userImportService struct {
+	importRepository repositories.ImportRepository
+	authorizer       Authorizer
+	now              func() time.Time
+	jobs             *backgroundJobs
+}
+
+func NewUserImportService(importRepository repositories.ImportRepository, authorizer Authorizer) UserImportService {
+	return &userImportService{
+		importRepository: importRepository,
+		authorizer:       authorizer,
+		now:              time.Now,
+		jobs:             newBackgroundJobs(),
+	}
+}
+
+// importRow is a parsed row of an import file; err is set for rows that
+// could not be parsed.
+type importRow struct {
+	number int
+	user   dtos.UserImportRow
+	err    error
+}
+
+// ImportUsers creates the users of an import file and updates the ones
+// whose email already exists in the tenant. Every row is validated first.
+// Imports of up to ImportSyncRows rows are applied before returning; bigger
+// ones continue in the background as a job whose id is returned.
+func (s *userImportService) ImportUsers(ctx context.Context, startedBy string, input *dtos.UserImport, body io.Reader) (*dtos.UserImportResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserImportService.ImportUsers")
+	defer span.End()
+
+	tenantID, err := tenant.ForWrite(ctx, input.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = tenant.Restrict(ctx, tenantID)
+
+	rows, err := parseUserImport(input.Format, body)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	job := &models.ImportJob{
+		TenantID:  tenantID,
+		Status:    models.ImportRunning,
+		DryRun:    input.DryRun,
+		StartedBy: startedBy,
+		Total:     len(rows),
+		Results:   []models.ImportRowResult{},
+		CreatedAt: s.now(),
+	}
+	if len(rows) <= ImportSyncRows {
+		err := s.run(ctx, job, rows, func() error { return nil })
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		completedAt := s.now()
+		job.CompletedAt = &completedAt
+		return toUserImportResponse(job), nil
+	}
+
+	job.ExpiresAt = job.CreatedAt.Add(ImportJobRetention)
+	if err := s.importRepository.CreateImportJob(ctx, job); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	logging.Audit(ctx, "user.import_start", "job_id", job.ID.Hex(), "rows", job.Total, "dry_run", job.DryRun)
+
+	response := toUserImportResponse(job)
+	s.jobs.Go(ctx, func(ctx context.Context) {
+		s.runJob(ctx, job, rows)
+	})
+	return response, nil
+}
+
+// runJob runs a background import, saving the results after every batch.
+func (s *userImportService) runJob(ctx context.Context, job *models.ImportJob, rows []importRow) {
+	ctx, span := tracing.Start(ctx, "UserImportService.runJob")
+	defer span.End()
+
+	err := s.run(ctx, job, rows, func() error {
+		return s.importRepository.UpdateImportJob(ctx, job)
+	})
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.FromContext(ctx).Error("user import failed", "job_id", job.ID.Hex(), "error", err)
+		job.Status = models.ImportFailed
+		job.Error = err.Error()
+	}
+	now := s.now()
+	job.CompletedAt = &now
+	job.ExpiresAt = now.Add(ImportJobRetention)
+
+	// Record the outcome even when the job was interrupted.
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := s.importRepository.UpdateImportJob(saveCtx, job); err != nil {
+		tracing.RecordError(span, err)
+		logging.FromContext(ctx).Error("failed to save user import", "job_id", job.ID.Hex(), "error", err)
+	}
+}
+
+// run validates rows and applies them batch by batch, appending the result
+// of each row to job and calling progress after every batch. In a dry run
+// nothing is written.
+func (s *userImportService) run(ctx context.Context, job *models.ImportJob, rows []importRow, progress func() error) error {
+	seen := map[string]int{}
+	var valid []importRow
+	invalid := map[int]models.ImportRowResult{}
+	for _, row := range rows {
+		err := row.err
+		if err == nil {
+			err = validateImportRow(&row.user)
+		}
+		if err != nil {
+			invalid[row.number] = importResult(row, models.ImportRowInvalid, err)
+			continue
+		}
+		key := strings.ToLower(row.user.Email)
+		if first, ok := seen[key]; ok {
+			invalid[row.number] = importResult(row, models.ImportRowConflict, fmt.Errorf("duplicate of row %d", first))
+			continue
+		}
+		seen[key] = row.number
+		valid = append(valid, row)
+	}
+
+	// Results are reported in file order, so invalid rows are flushed
+	// along with the batch that follows them.
+	next := 1
+	flushInvalid := func(upTo int) {
+		for ; next <= upTo; next++ {
+			if result, ok := invalid[next]; ok {
+				job.Results = append(job.Results, result)
+			}
+		}
+		job.Processed = next - 1
+	}
+
+	for start := 0; start < len(valid); start += ImportBatchSize {
+		batch := valid[start:min(start+ImportBatchSize, len(valid))]
+		results, err := s.applyBatch(ctx, batch, job.DryRun)
+		if err != nil {
+			return err
+		}
+		for i, row := range batch {
+			flushInvalid(row.number - 1)
+			job.Results = append(job.Results, results[i])
+			next = row.number + 1
+		}
+		job.Processed = next - 1
+		if err := progress(); err != nil {
+			return err
+		}
+	}
+	flushInvalid(len(rows))
+
+	job.Status = models.ImportCompleted
+	summary := summarizeImport(job.Results)
+	args := []any{
+		"dry_run", job.DryRun, "rows", job.Total,
+		"created", summary.Created, "updated", summary.Updated, "invalid", summary.Invalid,
+		"conflicts", summary.Conflicts, "failed", summary.Failed,
+	}
+	if !job.ID.IsZero() {
+		args = append(args, "job_id", job.ID.Hex())
+	}
+	logging.Audit(ctx, "user.import", args...)
+	return nil
+}
+
+// applyBatch creates or updates the users of valid rows and returns their
+// results in the same order.
+func (s *userImportService) applyBatch(ctx context.Context, batch []importRow, dryRun bool) ([]models.ImportRowResult, error) {
+	emails := make([]string, len(batch))
+	for i, row := range batch {
+		emails[i] = row.user.Email
+	}
+	existing, err := s.importRepository.FindUsersByEmail(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+	byEmail := map[string]models.User{}
+	for _, user := range existing {
+		byEmail[strings.ToLower(user.Email)] = user
+	}
+
+	tenantID, _ := tenant.FromContext(ctx)
+	results := make([]models.ImportRowResult, len(batch))
+	var creates, updates []models.User
+	var createRows, updateRows []int
+	for i, row := range batch {
+		user, exists := byEmail[strings.ToLower(row.user.Email)]
+		if !exists {
+			err := s.authorizer.Authorize(ctx, ActionUsersCreate, policy.Resource{Type: "user", TenantID: tenantID})
+			if errors.Is(err, policy.ErrDenied) {
+				results[i] = importResult(row, models.ImportRowFailed, err)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			results[i] = importResult(row, models.ImportRowCreated, nil)
+			if dryRun {
+				continue
+			}
+			newUser, err := s.newImportedUser(ctx, row.user)
+			if err != nil {
+				return nil, err
+			}
+			creates = append(creates, newUser)
+			createRows = append(createRows, i)
+			continue
+		}
+
+		results[i] = importResult(row, models.ImportRowUpdated, nil)
+		results[i].UserID = user.ID
+		if user.Role == models.RoleSuperAdmin {
+			results[i] = importResult(row, models.ImportRowFailed, errors.New("super admins cannot be changed by an import"))
+			continue
+		}
+		var fields []string
+		if row.user.Name != user.Name {
+			fields = append(fields, "name")
+		}
+		if row.user.Role != "" && row.user.Role != user.Role {
+			fields = append(fields, "role")
+		}
+		err := s.authorizer.Authorize(ctx, ActionUsersUpdate, userResource(user.ID), fields...)
+		if errors.Is(err, policy.ErrDenied) {
+			results[i] = importResult(row, models.ImportRowFailed, err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if dryRun || len(fields) == 0 {
+			continue
+		}
+		updates = append(updates, models.User{ID: user.ID, Name: row.user.Name, Role: row.user.Role})
+		updateRows = append(updateRows, i)
+	}
+
+	if len(creates) > 0 {
+		failed, err := s.importRepository.InsertUsers(ctx, creates)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range createRows {
+			results[i].UserID = creates[j].ID
+			if err, ok := failed[j]; ok {
+				results[i] = writeFailure(batch[i], err)
+			}
+		}
+	}
+	if len(updates) > 0 {
+		failed, err := s.importRepository.UpdateUsers(ctx, updates)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range updateRows {
+			if err, ok := failed[j]; ok {
+				results[i] = writeFailure(batch[i], err)
+			}
+		}
+	}
+	return results, nil
+}
+
+// newImportedUser builds a new user from row. Without a password the user
+// can only sign in through an external identity provider.
+func (s *userImportService) newImportedUser(ctx context.Context, row dtos.UserImportRow) (models.User, error) {
+	user := models.User{
+		Name:      row.Name,
+		Email:     row.Email,
+		Role:      row.Role,
+		CreatedAt: s.now(),
+	}
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	if row.Password != "" {
+		hashedPassword, err := hashPassword(ctx, row.Password)
+		if err != nil {
+			return user, err
+		}
+		user.Password = string(hashedPassword)
+	}
+	return user, nil
+}
+
+func (s *userImportService) GetImportJob(ctx context.Context, id string) (*dtos.UserImportResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserImportService.GetImportJob")
+	defer span.End()
+
+	job, err := s.importRepository.GetImportJob(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return toUserImportResponse(job), nil
+}
+
+func (s *userImportService) Wait(ctx context.Context) error {
+	return s.jobs.Wait(ctx, "user imports")
+}
+
+// parseUserImport reads CSV with a header row naming the columns, or
+// NDJSON with one user object per line. Rows that cannot be parsed are
+// returned with an error; problems with the file as a whole fail the
+// import.
+func parseUserImport(format string, body io.Reader) ([]importRow, error) {
+	var rows []importRow
+	var err error
+	switch format {
+	case ImportFormatCSV:
+		rows, err = parseCSVImport(body)
+	case ImportFormatNDJSON:
+		rows, err = parseNDJSONImport(body)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q, expected csv or ndjson", ErrInvalidImport, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: no rows", ErrInvalidImport)
+	}
+	return rows, nil
+}
+
+func parseCSVImport(body io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImport, err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		if i == 0 {
+			// Spreadsheets like to start the file with a byte order mark.
+			name = strings.TrimPrefix(name, "\uFEFF")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(importColumns, name) {
+			return nil, fmt.Errorf("%w: unknown column %q, expected %s", ErrInvalidImport, name, strings.Join(importColumns, ", "))
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, fmt.Errorf("%w: missing email column", ErrInvalidImport)
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		var parseErr *csv.ParseError
+		if err != nil && !errors.As(err, &parseErr) {
+			return nil, err
+		}
+		if len(rows) == MaxImportRows {
+			return nil, ErrImportTooLarge
+		}
+
+		row := importRow{number: len(rows) + 1, err: err}
+		if err == nil {
+			field := func(name string) string {
+				if i, ok := columns[name]; ok {
+					return record[i]
+				}
+				return ""
+			}
+			row.user = dtos.UserImportRow{
+				Email:    field("email"),
+				Name:     field("name"),
+				Password: field("password"),
+				Role:     field("role"),
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func parseNDJSONImport(body io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var rows []importRow
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if len(rows) == MaxImportRows {
+			return nil, ErrImportTooLarge
+		}
+
+		row := importRow{number: len(rows) + 1}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row.user); err != nil {
+			row.err = fmt.Errorf("invalid JSON: %w", err)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, fmt.Errorf("%w: line %d is too long", ErrInvalidImport, len(rows)+1)
+		}
+		return nil, err
+	}
+	return rows, nil
+}
+
+// validateImportRow trims row and checks it describes a user.
+func validateImportRow(row *dtos.UserImportRow) error {
+	row.Email = strings.TrimSpace(row.Email)
+	row.Name = strings.TrimSpace(row.Name)
+	row.Role = strings.ToLower(strings.TrimSpace(row.Role))
+
+	if row.Email == "" {
+		return errors.New("email is required")
+	}
+	if address, err := mail.ParseAddress(row.Email); err != nil || address.Address != row.Email {
+		return fmt.Errorf("invalid email %q", row.Email)
+	}
+	if row.Name == "" {
+		return errors.New("name is required")
+	}
+	if row.Role != "" && row.Role != models.RoleUser && row.Role != models.RoleAdmin {
+		return fmt.Errorf("role must be %s or %s", models.RoleUser, models.RoleAdmin)
+	}
+	if row.Password != "" && len(row.Password) < 6 {
+		return errors.New("password must be at least 6 characters")
+	}
+	return nil
+}
+
+func importResult(row importRow, status string, err error) models.ImportRowResult {
+	result := models.ImportRowResult{Row: row.number, Email: row.user.Email, Status: status}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func writeFailure(row importRow, err error) models.ImportRowResult {
+	if errors.Is(err, repositories.ErrEmailTaken) {
+		return importResult(row, models.ImportRowConflict, err)
+	}
+	return importResult(row, models.ImportRowFailed, err)
+}
+
+func summarizeImport(results []models.ImportRowResult) dtos.UserImportSummary {
+	var summary dtos.UserImportSummary
+	for _, result := range results {
+		switch result.Status {
+		case models.ImportRowCreated:
+			summary.Created++
+		case models.ImportRowUpdated:
+			summary.Updated++
+		case models.ImportRowInvalid:
+			summary.Invalid++
+		case models.ImportRowConflict:
+			summary.Conflicts++
+		case models.ImportRowFailed:
+			summary.Failed++
+		}
+	}
+	return summary
+}
+
+func toUserImportResponse(job *models.ImportJob) *dtos.UserImportResponse {
+	response := &dtos.UserImportResponse{
+		Status:    job.Status,
+		DryRun:    job.DryRun,
+		Total:     job.Total,
+		Processed: job.Processed,
+		Summary:   summarizeImport(job.Results),
+		Results:   make([]dtos.UserImportRowResponse, 0, len(job.Results)),
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt.Format(time.RFC3339),
+	}
+	if !job.ID.IsZero() {
+		response.ID = job.ID.Hex()
+	}
+	if job.CompletedAt != nil {
+		response.CompletedAt = job.CompletedAt.Format(time.RFC3339)
+	}
+	for _, result := range job.Results {
+		response.Results = append(response.Results, dtos.UserImportRowResponse{
+			Row:    result.Row,
+			Email:  result.Email,
+			UserID: result.UserID,
+			Status: result.Status,
+			Error:  result.Error,
+		})
+	}
+	return response
+}
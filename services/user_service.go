@@ -2,71 +2,207 @@ package services
 
 import (
 	"7-solutions/dtos"
+	"7-solutions/logging"
+	"7-solutions/models"
+	"7-solutions/policy"
 	"7-solutions/repositories"
+	"7-solutions/tracing"
+	"context"
+	"strconv"
+)
 
-	"golang.org/x/crypto/bcrypt"
+// Policy actions on resources of type user, checked by UserService, by
+// user imports and exports and by PrivacyService.
+const (
+	ActionUsersCreate     = "users:create"
+	ActionUsersRead       = "users:read"
+	ActionUsersUpdate     = "users:update"
+	ActionUsersDelete     = "users:delete"
+	ActionUsersExport     = "users:export"
+	ActionUsersDataExport = "users:data_export"
+	ActionUsersErase      = "users:erase"
 )
 
 type UserService interface {
-	CreateUser(userDto *dtos.UserRegister) (*dtos.UserResponse, error)
-	GetUserByID(id int) (*dtos.UserResponse, error)
-	GetAllUsers() ([]dtos.UserResponse, error)
-	UpdateUser(id int, userDto *dtos.UserUpdate) (*dtos.UserResponse, error)
-	DeleteUser(id int) error
+	CreateUser(ctx context.Context, userDto *dtos.UserRegister) (*dtos.UserResponse, error)
+	GetUserByID(ctx context.Context, id int) (*dtos.UserResponse, error)
+	GetAllUsers(ctx context.Context, filter *dtos.UserFilter) ([]dtos.UserResponse, error)
+	UpdateUser(ctx context.Context, id int, userDto *dtos.UserUpdate) (*dtos.UserResponse, error)
+	DeleteUser(ctx context.Context, id int) error
 }
 
 type userService struct {
-	userRepository repositories.UserRepository
+	userRepository    repositories.UserRepository
+	profileRepository repositories.ProfileRepository
+	authorizer        Authorizer
 }
 
-func NewUserService(userRepository repositories.UserRepository) UserService {
+// NewUserService reads the attribute definitions from profileRepository to
+// filter users by attribute and to only show the attributes callers may see.
+func NewUserService(userRepository repositories.UserRepository, profileRepository repositories.ProfileRepository, authorizer Authorizer) UserService {
 	return &userService{
-		userRepository: userRepository,
+		userRepository:    userRepository,
+		profileRepository: profileRepository,
+		authorizer:        authorizer,
 	}
 }
 
-func (s *userService) CreateUser(userDto *dtos.UserRegister) (*dtos.UserResponse, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(userDto.Password), bcrypt.DefaultCost)
+func userResource(id int) policy.Resource {
+	return policy.Resource{Type: "user", ID: strconv.Itoa(id)}
+}
+
+func (s *userService) CreateUser(ctx context.Context, userDto *dtos.UserRegister) (*dtos.UserResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+
+	if err := s.authorizer.Authorize(ctx, ActionUsersCreate, policy.Resource{Type: "user", TenantID: userDto.TenantID}); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := hashPassword(ctx, userDto.Password)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 	userDto.Password = string(hashedPassword)
 
-	user, err := s.userRepository.CreateUser(userDto)
+	user, err := s.userRepository.CreateUser(ctx, userDto)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
+	logging.Audit(ctx, "user.create", "target_user_id", user.ID)
 	return user, nil
 }
 
-func (s *userService) GetUserByID(id int) (*dtos.UserResponse, error) {
-	user, err := s.userRepository.GetUserByID(id)
+func (s *userService) GetUserByID(ctx context.Context, id int) (*dtos.UserResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByID")
+	defer span.End()
+
+	if err := s.authorizer.Authorize(ctx, ActionUsersRead, userResource(id)); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepository.GetUserByID(ctx, id)
 	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	if err := s.showAttributes(ctx, nil, user); err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *userService) GetAllUsers() ([]dtos.UserResponse, error) {
-	users, err := s.userRepository.GetAllUsers()
+func (s *userService) GetAllUsers(ctx context.Context, filter *dtos.UserFilter) ([]dtos.UserResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetAllUsers")
+	defer span.End()
+
+	if err := s.authorizer.Authorize(ctx, ActionUsersRead, policy.Resource{Type: "user"}); err != nil {
+		return nil, err
+	}
+
+	var definitions []models.AttributeDefinition
+	if filter != nil && len(filter.Attributes) > 0 {
+		var err error
+		if definitions, err = s.profileRepository.GetAttributes(ctx); err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		_, admin := attributeCaller(ctx)
+		if err := parseAttributeFilter(definitions, filter.Attributes, admin); err != nil {
+			return nil, err
+		}
+	}
+
+	users, err := s.userRepository.GetAllUsers(ctx, filter)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
+	for i := range users {
+		if err := s.showAttributes(ctx, &definitions, &users[i]); err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+	}
 	return users, nil
 }
 
-func (s *userService) UpdateUser(id int, userDto *dtos.UserUpdate) (*dtos.UserResponse, error) {
-	user, err := s.userRepository.UpdateUser(id, userDto)
+// showAttributes drops the attributes of user the caller may not see. The
+// definitions are read into *definitions the first time they are needed, so
+// they are read once per request at most.
+func (s *userService) showAttributes(ctx context.Context, definitions *[]models.AttributeDefinition, user *dtos.UserResponse) error {
+	if len(user.Attributes) == 0 {
+		return nil
+	}
+	if definitions == nil {
+		definitions = new([]models.AttributeDefinition)
+	}
+	if *definitions == nil {
+		loaded, err := s.profileRepository.GetAttributes(ctx)
+		if err != nil {
+			return err
+		}
+		*definitions = loaded
+	}
+	callerID, admin := attributeCaller(ctx)
+	self := callerID == strconv.Itoa(user.ID)
+	user.Attributes = visibleAttributes(attributeSchema(*definitions, user.TenantID), user.Attributes, admin, self)
+	return nil
+}
+
+// UpdateUser authorizes the update before loading the user, so callers
+// cannot tell which ids exist, then each field that changes, so policies can
+// allow editing a name but not an email.
+func (s *userService) UpdateUser(ctx context.Context, id int, userDto *dtos.UserUpdate) (*dtos.UserResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUser")
+	defer span.End()
+
+	if err := s.authorizer.Authorize(ctx, ActionUsersUpdate, userResource(id)); err != nil {
+		return nil, err
+	}
+	current, err := s.userRepository.GetUserByID(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	var fields []string
+	if userDto.Name != current.Name {
+		fields = append(fields, "name")
+	}
+	if userDto.Email != current.Email {
+		fields = append(fields, "email")
+	}
+	if len(fields) > 0 {
+		if err := s.authorizer.Authorize(ctx, ActionUsersUpdate, userResource(id), fields...); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.userRepository.UpdateUser(ctx, id, userDto)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
+	logging.Audit(ctx, "user.update", "target_user_id", id)
 	return user, nil
 }
 
-func (s *userService) DeleteUser(id int) error {
-	err := s.userRepository.DeleteUser(id)
+func (s *userService) DeleteUser(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteUser")
+	defer span.End()
+
+	if err := s.authorizer.Authorize(ctx, ActionUsersDelete, userResource(id)); err != nil {
+		return err
+	}
+
+	err := s.userRepository.DeleteUser(ctx, id)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return err
 	}
+	logging.Audit(ctx, "user.delete", "target_user_id", id)
 	return nil
 }
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/logging"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrAccountInactive is returned when a user who is not active, or no
+	// longer exists, signs in or uses a token.
+	ErrAccountInactive         = errors.New("account is not active")
+	ErrInvalidStatusTransition = errors.New("status transition not allowed")
+	ErrStatusReasonRequired    = errors.New("a reason is required for this status")
+	// ErrStatusChangeNotAllowed is returned for changes to the status of
+	// super admins or of the caller themselves.
+	ErrStatusChangeNotAllowed = errors.New("changing the status of this user is not allowed")
+)
+
+type UserStatusService interface {
+	GetStatus(ctx context.Context, id int) (*dtos.UserStatusResponse, error)
+	// ChangeStatus moves the user to a new status on behalf of actorID.
+	ChangeStatus(ctx context.Context, actorID string, id int, input *dtos.UserStatusUpdate) (*dtos.UserStatusResponse, error)
+}
+
+type userStatusService struct {
+	statusRepository  repositories.StatusRepository
+	sessionRepository repositories.SessionRepository
+	authorizer        Authorizer
+	now               func() time.Time
+}
+
+func NewUserStatusService(
+	statusRepository repositories.StatusRepository,
+	sessionRepository repositories.SessionRepository,
+	authorizer Authorizer,
+) UserStatusService {
+	return &userStatusService{
+		statusRepository:  statusRepository,
+		sessionRepository: sessionRepository,
+		authorizer:        authorizer,
+		now:               time.Now,
+	}
+}
+
+func (s *userStatusService) GetStatus(ctx context.Context, id int) (*dtos.UserStatusResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserStatusService.GetStatus")
+	defer span.End()
+
+	if err := s.authorizer.Authorize(ctx, ActionUsersRead, userResource(id)); err != nil {
+		return nil, err
+	}
+
+	user, err := s.statusRepository.GetUser(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return toUserStatusResponse(user), nil
+}
+
+// ChangeStatus checks the transition against models.StatusTransitions and
+// the policies, as an update of the status field. Users who stop being
+// active are signed out of every session.
+func (s *userStatusService) ChangeStatus(ctx context.Context, actorID string, id int, input *dtos.UserStatusUpdate) (*dtos.UserStatusResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserStatusService.ChangeStatus")
+	defer span.End()
+
+	if err := s.authorizer.Authorize(ctx, ActionUsersUpdate, userResource(id), "status"); err != nil {
+		return nil, err
+	}
+	if actorID == strconv.Itoa(id) {
+		return nil, ErrStatusChangeNotAllowed
+	}
+
+	user, err := s.statusRepository.GetUser(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	if user.Role == models.RoleSuperAdmin {
+		return nil, ErrStatusChangeNotAllowed
+	}
+
+	change := models.StatusChange{
+		From:    user.AccountStatus(),
+		To:      input.Status,
+		Reason:  strings.TrimSpace(input.Reason),
+		ActorID: actorID,
+		At:      s.now(),
+	}
+	if !models.CanTransitionStatus(change.From, change.To) {
+		return nil, fmt.Errorf("%w: from %s to %s", ErrInvalidStatusTransition, change.From, change.To)
+	}
+	if change.To != models.StatusActive && change.Reason == "" {
+		return nil, ErrStatusReasonRequired
+	}
+
+	if err := s.statusRepository.ChangeUserStatus(ctx, id, &change); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	if change.To != models.StatusActive {
+		signOutInactiveUser(ctx, span, s.sessionRepository, id, change.At)
+	}
+	logging.Audit(ctx, "user.status_change", "target_user_id", id, "from", change.From, "to", change.To, "reason", change.Reason)
+
+	user.Status = change.To
+	user.StatusHistory = append(user.StatusHistory, change)
+	return toUserStatusResponse(user), nil
+}
+
+// signOutInactiveUser revokes every session of the user id, who stopped
+// being active at. Requests are refused once the status changed, so failing
+// to revoke the sessions only gets logged.
+func signOutInactiveUser(ctx context.Context, span trace.Span, sessions repositories.SessionRepository, id int, at time.Time) {
+	if _, err := sessions.RevokeUserSessions(ctx, id, at); err != nil {
+		tracing.RecordError(span, err)
+		logging.FromContext(ctx).Warn("failed to revoke sessions of inactive user", "target_user_id", id, "error", err)
+	}
+}
+
+// AccountChecker tells whether a user may still use their tokens.
+type AccountChecker interface {
+	// CheckAccount returns ErrAccountInactive unless userID is the id of an
+	// active user.
+	CheckAccount(ctx context.Context, userID string) error
+}
+
+type accountChecker struct {
+	statusRepository repositories.StatusRepository
+}
+
+func NewAccountChecker(statusRepository repositories.StatusRepository) AccountChecker {
+	return &accountChecker{statusRepository: statusRepository}
+}
+
+func (c *accountChecker) CheckAccount(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "AccountChecker.CheckAccount")
+	defer span.End()
+
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("%w: invalid user id", ErrAccountInactive)
+	}
+	status, err := c.statusRepository.GetUserStatus(ctx, id)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return fmt.Errorf("%w: user not found", ErrAccountInactive)
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	return checkAccountStatus(status)
+}
+
+// checkAccountStatus returns ErrAccountInactive, naming status, unless
+// status is active.
+func checkAccountStatus(status string) error {
+	if status != models.StatusActive {
+		return fmt.Errorf("%w: %s", ErrAccountInactive, status)
+	}
+	return nil
+}
+
+func toUserStatusResponse(user *models.User) *dtos.UserStatusResponse {
+	return &dtos.UserStatusResponse{
+		UserID:  user.ID,
+		Status:  user.AccountStatus(),
+		History: toUserStatusChanges(user.StatusHistory),
+	}
+}
+
+func toUserStatusChanges(changes []models.StatusChange) []dtos.UserStatusChange {
+	responses := make([]dtos.UserStatusChange, 0, len(changes))
+	for _, change := range changes {
+		responses = append(responses, dtos.UserStatusChange{
+			From:    change.From,
+			To:      change.To,
+			Reason:  change.Reason,
+			ActorID: change.ActorID,
+			At:      change.At.Format(time.RFC3339),
+		})
+	}
+	return responses
+}
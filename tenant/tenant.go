@@ -0,0 +1,68 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+)
+
+// DefaultID is the tenant of self-registered users and of everything that
+// existed before organizations were introduced.
+const DefaultID = "default"
+
+var (
+	// ErrMissing is returned when a tenant-scoped operation runs without a
+	// tenant in its context. Scoping fails closed rather than returning
+	// every tenant's data.
+	ErrMissing = errors.New("no tenant in context")
+	// ErrCrossTenant is returned when a caller tries to write into another
+	// tenant.
+	ErrCrossTenant = errors.New("cross-tenant access denied")
+)
+
+type contextKey int
+
+const (
+	idKey contextKey = iota
+	allKey
+)
+
+// WithID scopes ctx to the tenant id.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idKey, id)
+}
+
+// WithAllTenants lifts the scoping for super-admins and background jobs.
+// The tenant id, if any, is kept as the default for writes.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allKey, true)
+}
+
+// Restrict scopes ctx to the tenant id only, dropping all-tenants access,
+// for work a super-admin does on behalf of one tenant.
+func Restrict(ctx context.Context, id string) context.Context {
+	return context.WithValue(WithID(ctx, id), allKey, false)
+}
+
+// FromContext returns the tenant id of ctx and whether ctx may access every
+// tenant.
+func FromContext(ctx context.Context) (id string, all bool) {
+	id, _ = ctx.Value(idKey).(string)
+	all, _ = ctx.Value(allKey).(bool)
+	return id, all
+}
+
+// ForWrite returns the tenant new records are created in: requested when the
+// caller may write there, otherwise the tenant of ctx.
+func ForWrite(ctx context.Context, requested string) (string, error) {
+	id, all := FromContext(ctx)
+	if requested != "" && requested != id {
+		if !all {
+			return "", ErrCrossTenant
+		}
+		return requested, nil
+	}
+	if id == "" {
+		return "", ErrMissing
+	}
+	return id, nil
+}
@@ -0,0 +1,177 @@
+package app_test
+
+import (
+	"7-solutions/app"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func testConfig() app.Config {
+	return app.Config{
+		Port:               "0",
+		ReadTimeout:        5 * time.Second,
+		WriteTimeout:       5 * time.Second,
+		IdleTimeout:        5 * time.Second,
+		ShutdownTimeout:    5 * time.Second,
+		HealthCheckTimeout: 100 * time.Millisecond,
+	}
+}
+
+// newTestApp uses a lazily connecting client that the app owns, so Shutdown
+// can disconnect it without a running server.
+func newTestApp(t *testing.T) *app.App {
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	require.NoError(t, err)
+	return app.New(testConfig(), client.Database("testdb"))
+}
+
+func TestApp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("TestStartAndShutdown", func(t *testing.T) {
+		a := newTestApp(t)
+		require.NoError(t, a.Start())
+
+		resp, err := http.Get("http://" + a.Addr() + "/users/")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		resp, err = http.Get("http://" + a.Addr() + "/healthz")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp, err = http.Get("http://" + a.Addr() + "/metrics")
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, string(body), `api_http_requests_total{method="GET",route="/healthz",status="200"}`)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, a.Shutdown(ctx))
+		assert.NoError(t, a.Shutdown(ctx))
+
+		ready, _ := a.HealthService.Ready(ctx)
+		assert.False(t, ready)
+
+		_, err = http.Get("http://" + a.Addr() + "/users/")
+		assert.Error(t, err)
+	})
+
+	t.Run("TestShutdownDrainsInFlightRequests", func(t *testing.T) {
+		a := newTestApp(t)
+		started := make(chan struct{})
+		a.Engine.GET("/slow", func(c *gin.Context) {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+			c.JSON(200, gin.H{"message": "done"})
+		})
+		require.NoError(t, a.Start())
+
+		result := make(chan int, 1)
+		go func() {
+			resp, err := http.Get("http://" + a.Addr() + "/slow")
+			if err != nil {
+				result <- 0
+				return
+			}
+			resp.Body.Close()
+			result <- resp.StatusCode
+		}()
+
+		<-started
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, a.Shutdown(ctx))
+		assert.Equal(t, http.StatusOK, <-result)
+	})
+
+	t.Run("TestRunStopsOnContextCancel", func(t *testing.T) {
+		a := newTestApp(t)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() { done <- a.Run(ctx) }()
+
+		cancel()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("app did not stop after context was cancelled")
+		}
+	})
+}
+
+func TestTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	clientIP := func(config app.Config) string {
+		client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+		require.NoError(t, err)
+		a := app.New(config, client.Database("testdb"))
+		defer a.Shutdown(context.Background())
+
+		a.Engine.GET("/ip", func(c *gin.Context) {
+			c.String(http.StatusOK, c.ClientIP())
+		})
+		req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+		req.RemoteAddr = "10.0.0.5:1234"
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		w := httptest.NewRecorder()
+		a.Engine.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	t.Run("TestUntrustedByDefault", func(t *testing.T) {
+		assert.Equal(t, "10.0.0.5", clientIP(testConfig()))
+	})
+
+	t.Run("TestTrustedCIDR", func(t *testing.T) {
+		config := testConfig()
+		config.Security.TrustedProxies = []string{"10.0.0.0/8"}
+		assert.Equal(t, "203.0.113.7", clientIP(config))
+	})
+}
+
+func TestNewConfigFromEnvRequiresPseudonymKey(t *testing.T) {
+	t.Setenv("PSEUDONYM_KEY", "")
+	_, err := app.NewConfigFromEnv()
+	assert.EqualError(t, err, "PSEUDONYM_KEY is required")
+
+	t.Setenv("PSEUDONYM_KEY", "secret")
+	config, err := app.NewConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, "secret", config.Privacy.PseudonymKey)
+}
+
+func TestNewConfigFromEnvRejectsInvalidSecuritySettings(t *testing.T) {
+	t.Setenv("PSEUDONYM_KEY", "secret")
+	t.Setenv("TRUSTED_PROXIES", "not-a-cidr")
+	_, err := app.NewConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,192.168.1.1")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	_, err = app.NewConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com")
+	config, err := app.NewConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1"}, config.Security.TrustedProxies)
+}
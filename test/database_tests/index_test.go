@@ -0,0 +1,63 @@
+package database_test
+
+import (
+	"7-solutions/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestEnsureEmailUniqueIndex(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestIgnoresCase", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 27, Name: "IndexNotFound", Message: "index not found"}),
+		)
+
+		require.NoError(mt, utils.EnsureEmailUniqueIndex(mt.Client.Database("testdb")))
+
+		index := mt.GetStartedEvent().Command.Lookup("indexes").Array().Index(0).Value().Document()
+		assert.Equal(mt, utils.EmailUniqueIndexName, index.Lookup("name").StringValue())
+		assert.True(mt, index.Lookup("unique").Boolean())
+		assert.Equal(mt, "en", index.Lookup("collation", "locale").StringValue())
+		assert.Equal(mt, int32(2), index.Lookup("collation", "strength").Int32())
+	})
+
+	mt.Run("TestReplacesCaseSensitiveIndex", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 85, Name: "IndexOptionsConflict", Message: "index already exists with different options"}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 27, Name: "IndexNotFound", Message: "index not found"}),
+		)
+
+		require.NoError(mt, utils.EnsureEmailUniqueIndex(mt.Client.Database("testdb")))
+
+		var commands []bson.Raw
+		for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+			commands = append(commands, event.Command)
+		}
+		require.Len(mt, commands, 4)
+		assert.Equal(mt, utils.EmailUniqueIndexName, commands[1].Lookup("index").StringValue())
+		assert.NotNil(mt, commands[2].Lookup("createIndexes").Value)
+		assert.Equal(mt, "email_1", commands[3].Lookup("index").StringValue())
+	})
+
+	mt.Run("TestFailsOnCaseDuplicates", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 85, Name: "IndexOptionsConflict", Message: "index already exists with different options"}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11000, Name: "DuplicateKey", Message: "duplicate key"}),
+		)
+
+		err := utils.EnsureEmailUniqueIndex(mt.Client.Database("testdb"))
+
+		assert.True(mt, mongo.IsDuplicateKeyError(err), "got %v", err)
+	})
+}
@@ -0,0 +1,714 @@
+package federation_test
+
+import (
+	"7-solutions/federation"
+	"7-solutions/handlers"
+	"7-solutions/middleware"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryFederationRepository is an in-memory
+// repositories.FederationRepository.
+type memoryFederationRepository struct {
+	mu         sync.Mutex
+	states     map[string]*models.FederationState
+	identities []*models.Identity
+	users      []*models.User
+}
+
+func newMemoryFederationRepository() *memoryFederationRepository {
+	return &memoryFederationRepository{states: map[string]*models.FederationState{}}
+}
+
+func (r *memoryFederationRepository) CreateState(ctx context.Context, state *models.FederationState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states[state.StateHash] = state
+	return nil
+}
+
+func (r *memoryFederationRepository) ConsumeState(ctx context.Context, stateHash string) (*models.FederationState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.states[stateHash]
+	if !ok {
+		return nil, errors.New("federation state not found")
+	}
+	delete(r.states, stateHash)
+	return state, nil
+}
+
+func (r *memoryFederationRepository) FindIdentity(ctx context.Context, provider, subject string) (*models.Identity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryFederationRepository) GetIdentitiesByUserID(ctx context.Context, userID int) ([]models.Identity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	identities := []models.Identity{}
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			identities = append(identities, *identity)
+		}
+	}
+	return identities, nil
+}
+
+func (r *memoryFederationRepository) CreateIdentity(ctx context.Context, identity *models.Identity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	identity.ID = primitive.NewObjectID()
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+func (r *memoryFederationRepository) DeleteIdentity(ctx context.Context, userID int, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, identity := range r.identities {
+		if identity.ID.Hex() == id && identity.UserID == userID {
+			r.identities = append(r.identities[:i], r.identities[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("identity not found")
+}
+
+func (r *memoryFederationRepository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *memoryFederationRepository) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryFederationRepository) CreateUser(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user.ID = len(r.users) + 1
+	r.users = append(r.users, user)
+	return nil
+}
+
+func (r *memoryFederationRepository) VerifyUserEmail(ctx context.Context, id int, clearPassword bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.ID == id {
+			user.EmailVerified = true
+			if clearPassword {
+				user.Password = ""
+			}
+			return nil
+		}
+	}
+	return errors.New("user not found")
+}
+
+// memorySessionRepository is an in-memory repositories.SessionRepository.
+type memorySessionRepository struct {
+	mu       sync.Mutex
+	sessions []*models.Session
+}
+
+func (r *memorySessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session.ID = primitive.NewObjectID()
+	stored := *session
+	r.sessions = append(r.sessions, &stored)
+	return nil
+}
+
+func (r *memorySessionRepository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, session := range r.sessions {
+		if session.ID.Hex() == id {
+			copied := *session
+			return &copied, nil
+		}
+	}
+	return nil, repositories.ErrSessionNotFound
+}
+
+func (r *memorySessionRepository) GetActiveSessions(ctx context.Context, userID int, now time.Time) ([]models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sessions := []models.Session{}
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.Active(now) {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *memorySessionRepository) TouchSession(ctx context.Context, id primitive.ObjectID, lastSeenAt time.Time) error {
+	return nil
+}
+
+func (r *memorySessionRepository) RevokeSession(ctx context.Context, userID int, id string, revokedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, session := range r.sessions {
+		if session.ID.Hex() == id && session.UserID == userID && session.Active(revokedAt) {
+			session.RevokedAt = &revokedAt
+			return nil
+		}
+	}
+	return repositories.ErrSessionNotFound
+}
+
+func (r *memorySessionRepository) RevokeUserSessions(ctx context.Context, userID int, revokedAt time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var revoked int64
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.Active(revokedAt) {
+			session.RevokedAt = &revokedAt
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// fakeIdP is a minimal OpenID Provider. Its authorization endpoint signs the
+// current user in without a login page and redirects straight back.
+type fakeIdP struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+	secret   string
+
+	mu    sync.Mutex
+	user  jwt.MapClaims
+	codes map[string]url.Values
+	// tamper, when set, edits the ID token claims before signing.
+	tamper func(claims jwt.MapClaims)
+	// signWith, when set, signs ID tokens with a key missing from the JWKS.
+	signWith *rsa.PrivateKey
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp := &fakeIdP{key: key, clientID: "api-client", secret: "s3cr=t&", codes: map[string]url.Values{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, 200, map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, 200, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"kid": "idp-key",
+			"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func (idp *fakeIdP) signIn(claims jwt.MapClaims) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.user = claims
+}
+
+func (idp *fakeIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != idp.clientID || query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" || !strings.Contains(query.Get("scope"), "openid") {
+		http.Error(w, "bad authorization request", 400)
+		return
+	}
+
+	code := randomString()
+	idp.mu.Lock()
+	idp.codes[code] = query
+	idp.mu.Unlock()
+
+	callback, _ := url.Parse(query.Get("redirect_uri"))
+	params := callback.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	callback.RawQuery = params.Encode()
+	http.Redirect(w, r, callback.String(), http.StatusFound)
+}
+
+func (idp *fakeIdP) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, _ := r.BasicAuth()
+	id, _ = url.QueryUnescape(id)
+	secret, _ = url.QueryUnescape(secret)
+	if id != idp.clientID || secret != idp.secret {
+		writeJSON(w, 401, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	code := r.PostFormValue("code")
+	request, ok := idp.codes[code]
+	delete(idp.codes, code)
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || r.PostFormValue("redirect_uri") != request.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != request.Get("code_challenge") {
+		writeJSON(w, 400, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   idp.URL,
+		"aud":   idp.clientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(5 * time.Minute).Unix(),
+		"nonce": request.Get("nonce"),
+	}
+	for k, v := range idp.user {
+		claims[k] = v
+	}
+	if idp.tamper != nil {
+		idp.tamper(claims)
+	}
+	key := idp.key
+	if idp.signWith != nil {
+		key = idp.signWith
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "idp-key"
+	idToken, err := token.SignedString(key)
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, 200, map[string]string{"access_token": "opaque", "token_type": "Bearer", "id_token": idToken})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+type federationServer struct {
+	*httptest.Server
+	idp      *fakeIdP
+	repo     *memoryFederationRepository
+	sessions *memorySessionRepository
+	client   *http.Client
+}
+
+func newFederationServer(t *testing.T) *federationServer {
+	gin.SetMode(gin.TestMode)
+	idp := newFakeIdP(t)
+	repo := newMemoryFederationRepository()
+	sessions := &memorySessionRepository{}
+	s := &federationServer{idp: idp, repo: repo, sessions: sessions}
+
+	r := gin.New()
+	s.Server = httptest.NewServer(r)
+	t.Cleanup(s.Close)
+
+	provider := federation.NewProvider(federation.Config{
+		Name:         "corp",
+		Issuer:       idp.URL,
+		ClientID:     idp.clientID,
+		ClientSecret: idp.secret,
+		Scopes:       []string{"openid", "profile", "email"},
+		RedirectURL:  s.URL + "/auth/federation/corp/callback",
+	}, idp.Client())
+	h := handlers.NewFederationHandler(services.NewFederationService(repo, sessions, []*federation.Provider{provider}))
+
+	r.GET("/auth/federation/", h.GetProviders)
+	r.GET("/auth/federation/:provider/login", h.Login)
+	r.GET("/auth/federation/:provider/callback", h.Callback)
+	identities := r.Group("/identities", middleware.AuthenticationMiddleware(nil, nil, services.NewSessionService(sessions), nil))
+	identities.GET("/", h.GetIdentities)
+	identities.DELETE("/:id", h.UnlinkIdentity)
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	s.client = &http.Client{Jar: jar, CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	return s
+}
+
+// start begins a login and follows the redirect through the IdP, returning
+// the callback URL the browser would be sent to.
+func (s *federationServer) start(t *testing.T) string {
+	resp, err := s.client.Get(s.URL + "/auth/federation/corp/login")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	require.True(t, strings.HasPrefix(resp.Header.Get("Location"), s.idp.URL+"/authorize?"))
+
+	resp, err = s.client.Get(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	return resp.Header.Get("Location")
+}
+
+func (s *federationServer) getJSON(t *testing.T, client *http.Client, target string) (int, map[string]interface{}) {
+	resp, err := client.Get(target)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var body map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body
+}
+
+// login runs a full login and returns the verified API token claims.
+func (s *federationServer) login(t *testing.T) (string, jwt.MapClaims) {
+	status, body := s.getJSON(t, s.client, s.start(t))
+	require.Equal(t, 200, status, body)
+	token, _ := body["token"].(string)
+	claims, err := utils.VerifyToken(token)
+	require.NoError(t, err)
+	return token, claims
+}
+
+func TestFirstLoginCreatesVerifiedUser(t *testing.T) {
+	s := newFederationServer(t)
+	s.idp.signIn(jwt.MapClaims{"sub": "idp-42", "email": "bob@corp.example", "email_verified": true, "name": "Bob"})
+
+	_, claims := s.login(t)
+	assert.Equal(t, "bob@corp.example", claims["email"])
+	assert.Equal(t, "Bob", claims["name"])
+	assert.Equal(t, models.RoleUser, claims["role"])
+
+	require.Len(t, s.repo.users, 1)
+	assert.True(t, s.repo.users[0].EmailVerified)
+	assert.Empty(t, s.repo.users[0].Password)
+	require.Len(t, s.repo.identities, 1)
+	assert.Equal(t, "corp", s.repo.identities[0].Provider)
+	assert.Equal(t, "idp-42", s.repo.identities[0].Subject)
+
+	// The second login finds the linked identity even if the email changed.
+	s.idp.signIn(jwt.MapClaims{"sub": "idp-42", "email": "robert@corp.example", "email_verified": true})
+	_, claims = s.login(t)
+	assert.Equal(t, "bob@corp.example", claims["email"])
+	assert.Len(t, s.repo.users, 1)
+	assert.Len(t, s.repo.identities, 1)
+}
+
+func TestLoginStartsRevocableSession(t *testing.T) {
+	s := newFederationServer(t)
+	s.idp.signIn(jwt.MapClaims{"sub": "idp-43", "email": "ivy@corp.example", "email_verified": true})
+
+	token, claims := s.login(t)
+	require.Len(t, s.sessions.sessions, 1)
+	session := s.sessions.sessions[0]
+	assert.Equal(t, session.ID.Hex(), claims["sid"])
+	assert.Equal(t, s.repo.users[0].ID, session.UserID)
+	assert.NotEmpty(t, session.UserAgent)
+
+	get := func() int {
+		req, err := http.NewRequest("GET", s.URL+"/identities/", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, 200, get())
+	require.NoError(t, s.sessions.RevokeSession(context.Background(), session.UserID, session.ID.Hex(), time.Now()))
+	assert.Equal(t, 401, get())
+}
+
+func TestLoginLinksExistingUserByVerifiedEmail(t *testing.T) {
+	s := newFederationServer(t)
+	s.repo.users = []*models.User{
+		{ID: 1, Name: "Admin", Email: "Admin@Corp.example", Password: "hash", Role: models.RoleAdmin, EmailVerified: true},
+		{ID: 2, Name: "Squatter", Email: "carol@corp.example", Password: "hash", Role: models.RoleUser},
+	}
+
+	s.idp.signIn(jwt.MapClaims{"sub": "idp-1", "email": "admin@corp.example", "email_verified": true})
+	_, claims := s.login(t)
+	assert.Equal(t, "1", claims["sub"])
+	assert.Equal(t, models.RoleAdmin, claims["role"])
+	assert.Equal(t, "hash", s.repo.users[0].Password)
+
+	// Linking an unverified account verifies it and drops its password, which
+	// whoever registered the address first might know.
+	s.idp.signIn(jwt.MapClaims{"sub": "idp-2", "email": "carol@corp.example", "email_verified": "true"})
+	_, claims = s.login(t)
+	assert.Equal(t, "2", claims["sub"])
+	assert.True(t, s.repo.users[1].EmailVerified)
+	assert.Empty(t, s.repo.users[1].Password)
+
+	assert.Len(t, s.repo.users, 2)
+	assert.Len(t, s.repo.identities, 2)
+}
+
+func TestLoginRequiresVerifiedEmail(t *testing.T) {
+	s := newFederationServer(t)
+	s.repo.users = []*models.User{{ID: 1, Name: "Dave", Email: "dave@corp.example", EmailVerified: true}}
+
+	for _, user := range []jwt.MapClaims{
+		{"sub": "idp-3", "email": "dave@corp.example", "email_verified": false},
+		{"sub": "idp-3", "email": "dave@corp.example"},
+		{"sub": "idp-3"},
+	} {
+		s.idp.signIn(user)
+		status, body := s.getJSON(t, s.client, s.start(t))
+		assert.Equal(t, 403, status, body)
+	}
+	assert.Len(t, s.repo.users, 1)
+	assert.Empty(t, s.repo.identities)
+}
+
+func TestCallbackValidatesState(t *testing.T) {
+	s := newFederationServer(t)
+	s.idp.signIn(jwt.MapClaims{"sub": "idp-4", "email": "erin@corp.example", "email_verified": true})
+
+	t.Run("callback from another browser", func(t *testing.T) {
+		callback := s.start(t)
+		status, _ := s.getJSON(t, &http.Client{}, callback)
+		assert.Equal(t, 400, status)
+	})
+
+	t.Run("tampered state", func(t *testing.T) {
+		callback, _ := url.Parse(s.start(t))
+		query := callback.Query()
+		query.Set("state", "forged")
+		callback.RawQuery = query.Encode()
+		status, _ := s.getJSON(t, s.client, callback.String())
+		assert.Equal(t, 400, status)
+	})
+
+	t.Run("replayed callback", func(t *testing.T) {
+		callback := s.start(t)
+		status, _ := s.getJSON(t, s.client, callback)
+		require.Equal(t, 200, status)
+
+		// Put the cookie back; the state itself must still be rejected.
+		u, _ := url.Parse(callback)
+		s.client.Jar.SetCookies(u, []*http.Cookie{{Name: "federation_state", Value: u.Query().Get("state"), Path: "/auth/federation/corp"}})
+		status, _ = s.getJSON(t, s.client, callback)
+		assert.Equal(t, 400, status)
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		status, body := s.getJSON(t, s.client, s.URL+"/auth/federation/corp/callback?error=access_denied&state=x")
+		assert.Equal(t, 401, status)
+		assert.Contains(t, body["error"], "access_denied")
+	})
+
+	t.Run("missing code", func(t *testing.T) {
+		status, _ := s.getJSON(t, s.client, s.URL+"/auth/federation/corp/callback?state=x")
+		assert.Equal(t, 400, status)
+	})
+}
+
+func TestCallbackRejectsInvalidIDTokens(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	cases := map[string]func(idp *fakeIdP){
+		"nonce mismatch": func(idp *fakeIdP) { idp.tamper = func(c jwt.MapClaims) { c["nonce"] = "replayed" } },
+		"missing nonce":  func(idp *fakeIdP) { idp.tamper = func(c jwt.MapClaims) { delete(c, "nonce") } },
+		"wrong audience": func(idp *fakeIdP) { idp.tamper = func(c jwt.MapClaims) { c["aud"] = "someone-else" } },
+		"wrong issuer":   func(idp *fakeIdP) { idp.tamper = func(c jwt.MapClaims) { c["iss"] = "https://evil.example" } },
+		"expired": func(idp *fakeIdP) {
+			idp.tamper = func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }
+		},
+		"unknown key": func(idp *fakeIdP) { idp.signWith = otherKey },
+		"other azp": func(idp *fakeIdP) {
+			idp.tamper = func(c jwt.MapClaims) { c["aud"] = []string{idp.clientID, "x"}; c["azp"] = "x" }
+		},
+		"rejected client": func(idp *fakeIdP) { idp.secret = "rotated" },
+		"missing subject": func(idp *fakeIdP) { idp.tamper = func(c jwt.MapClaims) { delete(c, "sub") } },
+	}
+	for name, setup := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := newFederationServer(t)
+			s.idp.signIn(jwt.MapClaims{"sub": "idp-5", "email": "frank@corp.example", "email_verified": true})
+			callback := s.start(t)
+			setup(s.idp)
+
+			status, body := s.getJSON(t, s.client, callback)
+			assert.Equal(t, 401, status, body)
+			assert.Empty(t, s.repo.users)
+		})
+	}
+}
+
+func TestAudienceListIncludingClient(t *testing.T) {
+	s := newFederationServer(t)
+	s.idp.signIn(jwt.MapClaims{"sub": "idp-6", "email": "gina@corp.example", "email_verified": true})
+	s.idp.tamper = func(c jwt.MapClaims) {
+		c["aud"] = []string{s.idp.clientID, "other"}
+		c["azp"] = s.idp.clientID
+	}
+
+	_, claims := s.login(t)
+	assert.Equal(t, "gina@corp.example", claims["email"])
+}
+
+func TestUnknownProviderAndProviderList(t *testing.T) {
+	s := newFederationServer(t)
+
+	status, _ := s.getJSON(t, s.client, s.URL+"/auth/federation/nope/login")
+	assert.Equal(t, 404, status)
+
+	status, body := s.getJSON(t, s.client, s.URL+"/auth/federation/")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, []interface{}{"corp"}, body["providers"])
+}
+
+func TestUnreachableProvider(t *testing.T) {
+	s := newFederationServer(t)
+	s.idp.Close()
+
+	status, _ := s.getJSON(t, s.client, s.URL+"/auth/federation/corp/login")
+	assert.Equal(t, 502, status)
+}
+
+func TestListAndUnlinkIdentities(t *testing.T) {
+	s := newFederationServer(t)
+	s.idp.signIn(jwt.MapClaims{"sub": "idp-7", "email": "hank@corp.example", "email_verified": true})
+	token, _ := s.login(t)
+
+	request := func(method, target, bearer string) (int, map[string]interface{}) {
+		req, err := http.NewRequest(method, s.URL+target, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	status, body := request("GET", "/identities/", token)
+	require.Equal(t, 200, status)
+	identities := body["identities"].([]interface{})
+	require.Len(t, identities, 1)
+	identity := identities[0].(map[string]interface{})
+	assert.Equal(t, "corp", identity["provider"])
+	assert.Equal(t, "idp-7", identity["subject"])
+	id := identity["id"].(string)
+
+	// Another user cannot unlink it.
+	session := &models.Session{UserID: 99, ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, s.sessions.CreateSession(context.Background(), session))
+	other, err := utils.GenerateSessionToken(99, "Mallory", "mallory@example.com", models.RoleUser, tenant.DefaultID, session.ID.Hex())
+	require.NoError(t, err)
+	status, _ = request("DELETE", "/identities/"+id, other)
+	assert.Equal(t, 404, status)
+
+	status, _ = request("DELETE", "/identities/"+id, token)
+	assert.Equal(t, 204, status)
+	status, body = request("GET", "/identities/", token)
+	require.Equal(t, 200, status)
+	assert.Empty(t, body["identities"])
+	status, _ = request("DELETE", "/identities/"+id, token)
+	assert.Equal(t, 404, status)
+}
+
+func TestNewConfigsFromEnv(t *testing.T) {
+	t.Setenv("FEDERATION_PROVIDERS", "corp, azure-ad")
+	t.Setenv("FEDERATION_CORP_ISSUER", "https://idp.corp.example/")
+	t.Setenv("FEDERATION_CORP_CLIENT_ID", "corp-client")
+	t.Setenv("FEDERATION_CORP_CLIENT_SECRET", "corp-secret")
+	t.Setenv("FEDERATION_AZURE_AD_ISSUER", "https://login.example/tenant/v2.0")
+	t.Setenv("FEDERATION_AZURE_AD_CLIENT_ID", "azure-client")
+	t.Setenv("FEDERATION_AZURE_AD_SCOPES", "openid,email")
+
+	configs, err := federation.NewConfigsFromEnv("https://api.example")
+	require.NoError(t, err)
+	require.Len(t, configs, 2)
+	assert.Equal(t, federation.Config{
+		Name:         "corp",
+		Issuer:       "https://idp.corp.example",
+		ClientID:     "corp-client",
+		ClientSecret: "corp-secret",
+		Scopes:       []string{"openid", "profile", "email"},
+		RedirectURL:  "https://api.example/auth/federation/corp/callback",
+		Tenant:       tenant.DefaultID,
+	}, configs[0])
+	assert.Equal(t, "azure-ad", configs[1].Name)
+	assert.Equal(t, []string{"openid", "email"}, configs[1].Scopes)
+
+	for name, env := range map[string][2]string{
+		"bad name":       {"FEDERATION_PROVIDERS", "Corp"},
+		"missing issuer": {"FEDERATION_CORP_ISSUER", ""},
+		"missing client": {"FEDERATION_CORP_CLIENT_ID", ""},
+		"no openid":      {"FEDERATION_AZURE_AD_SCOPES", "email"},
+		"duplicate":      {"FEDERATION_PROVIDERS", "corp,corp"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(env[0], env[1])
+			_, err := federation.NewConfigsFromEnv("https://api.example")
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package handlers_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/handlers"
+	"7-solutions/models"
+	"7-solutions/services"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAPIKeyService struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyService) CreateAPIKey(ctx context.Context, createdBy string, input *dtos.APIKeyCreate) (*dtos.APIKeyCreated, error) {
+	args := m.Called(ctx, createdBy, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.APIKeyCreated), args.Error(1)
+}
+
+func (m *MockAPIKeyService) GetAllAPIKeys(ctx context.Context) ([]dtos.APIKeyResponse, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dtos.APIKeyResponse), args.Error(1)
+}
+
+func (m *MockAPIKeyService) RevokeAPIKey(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyService) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func newAPIKeyRouter(mockService *MockAPIKeyService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	h := handlers.NewAPIKeyHandler(mockService)
+	r.Use(func(c *gin.Context) { c.Set("userID", "1") })
+	r.POST("/api-keys", h.CreateAPIKey)
+	r.GET("/api-keys", h.GetAllAPIKeys)
+	r.DELETE("/api-keys/:id", h.RevokeAPIKey)
+	return r
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	mockService := new(MockAPIKeyService)
+	r := newAPIKeyRouter(mockService)
+
+	body := fmt.Sprintf(`{"name":"batch","scopes":["users:read"],"expiresAt":%q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+	mockService.On("CreateAPIKey", mock.Anything, "1", mock.Anything).
+		Return(&dtos.APIKeyCreated{APIKeyResponse: dtos.APIKeyResponse{Name: "batch"}, Key: "7s_x_y"}, nil)
+
+	req, _ := http.NewRequest(http.MethodPost, "/api-keys", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 201, w.Code)
+	assert.Contains(t, w.Body.String(), `"key":"7s_x_y"`)
+}
+
+func TestCreateAPIKey_InvalidRequest(t *testing.T) {
+	mockService := new(MockAPIKeyService)
+	r := newAPIKeyRouter(mockService)
+
+	body := fmt.Sprintf(`{"name":"batch","scopes":["bogus"],"expiresAt":%q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+	mockService.On("CreateAPIKey", mock.Anything, "1", mock.Anything).
+		Return(nil, fmt.Errorf("%w: unknown scope", services.ErrInvalidAPIKeyRequest))
+
+	req, _ := http.NewRequest(http.MethodPost, "/api-keys", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+
+	req, _ = http.NewRequest(http.MethodPost, "/api-keys", strings.NewReader(`{"name":"batch"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestGetAllAPIKeys(t *testing.T) {
+	mockService := new(MockAPIKeyService)
+	r := newAPIKeyRouter(mockService)
+
+	mockService.On("GetAllAPIKeys", mock.Anything).Return([]dtos.APIKeyResponse{{Name: "batch", Prefix: "abc"}}, nil)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api-keys", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"prefix":"abc"`)
+	assert.NotContains(t, w.Body.String(), `"key"`)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	mockService := new(MockAPIKeyService)
+	r := newAPIKeyRouter(mockService)
+
+	mockService.On("RevokeAPIKey", mock.Anything, "found").Return(nil)
+	mockService.On("RevokeAPIKey", mock.Anything, "missing").Return(errors.New("api key not found"))
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api-keys/found", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+
+	req, _ = http.NewRequest(http.MethodDelete, "/api-keys/missing", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+}
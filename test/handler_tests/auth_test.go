@@ -3,6 +3,8 @@ package handlers_test
 import (
 	"7-solutions/dtos"
 	"7-solutions/handlers"
+	"7-solutions/models"
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -17,19 +19,27 @@ type MockAuthService struct {
 	mock.Mock
 }
 
-func (m *MockAuthService) RegisterUser(userDto *dtos.UserRegister) error {
-	args := m.Called(userDto)
+func (m *MockAuthService) RegisterUser(ctx context.Context, userDto *dtos.UserRegister) error {
+	args := m.Called(ctx, userDto)
 	return args.Error(0)
 }
 
-func (m *MockAuthService) AuthenticateUser(input *dtos.UserAuthenticate) (*string, error) {
-	args := m.Called(input)
+func (m *MockAuthService) AuthenticateUser(ctx context.Context, input *dtos.UserAuthenticate) (*string, error) {
+	args := m.Called(ctx, input)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*string), args.Error(1)
 }
 
+func (m *MockAuthService) VerifyCredentials(ctx context.Context, input *dtos.UserAuthenticate) (*models.User, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 func TestRegisterUser(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
@@ -39,7 +49,7 @@ func TestRegisterUser(t *testing.T) {
 	r.POST("/register", h.RegisterUser)
 
 	user := `{"name":"Test","email":"test@example.com","password":"pass123"}`
-	mockService.On("RegisterUser", mock.Anything).Return(nil)
+	mockService.On("RegisterUser", mock.Anything, mock.Anything).Return(nil)
 
 	req, _ := http.NewRequest(http.MethodPost, "/register", strings.NewReader(user))
 	req.Header.Set("Content-Type", "application/json")
@@ -47,7 +57,7 @@ func TestRegisterUser(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, 201, w.Code)
-	mockService.AssertCalled(t, "RegisterUser", mock.Anything)
+	mockService.AssertCalled(t, "RegisterUser", mock.Anything, mock.Anything)
 }
 
 func TestAuthenticateUser(t *testing.T) {
@@ -58,7 +68,7 @@ func TestAuthenticateUser(t *testing.T) {
 	r.POST("/login", h.AuthenticateUser)
 
 	token := "mocked-token"
-	mockService.On("AuthenticateUser", mock.Anything).Return(&token, nil)
+	mockService.On("AuthenticateUser", mock.Anything, mock.Anything).Return(&token, nil)
 
 	payload := `{"email":"test@example.com","password":"pass123"}`
 	req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(payload))
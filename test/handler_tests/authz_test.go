@@ -0,0 +1,48 @@
+package handlers_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/handlers"
+	"7-solutions/policy"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuthzService struct {
+	mock.Mock
+}
+
+func (m *MockAuthzService) Authorize(ctx context.Context, action string, resource policy.Resource, fields ...string) error {
+	return m.Called(ctx, action, resource, fields).Error(0)
+}
+
+func (m *MockAuthzService) Check(ctx context.Context, input *dtos.AuthzCheck) (*policy.Decision, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*policy.Decision), args.Error(1)
+}
+
+func TestAuthzCheck(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	mockService := new(MockAuthzService)
+	r.POST("/authz/check", handlers.NewAuthzHandler(mockService).Check)
+
+	input := &dtos.AuthzCheck{Action: "users:update", Resource: policy.Resource{Type: "user", ID: "2", Field: "email"}}
+	mockService.On("Check", mock.Anything, input).
+		Return(&policy.Decision{PolicyID: "emails-are-admin-only", Reason: "denied by policy emails-are-admin-only", Matched: []string{"emails-are-admin-only"}}, nil)
+
+	w := serveGroup(r, http.MethodPost, "/authz/check", `{"action":"users:update","resource":{"type":"user","id":"2","field":"email"}}`)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"allowed":false`)
+	assert.Contains(t, w.Body.String(), `"policyId":"emails-are-admin-only"`)
+
+	assert.Equal(t, 400, serveGroup(r, http.MethodPost, "/authz/check", `{"resource":{"type":"user"}}`).Code)
+}
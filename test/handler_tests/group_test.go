@@ -0,0 +1,183 @@
+package handlers_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/handlers"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockGroupService struct {
+	mock.Mock
+}
+
+func (m *MockGroupService) CreateGroup(ctx context.Context, input *dtos.GroupCreate) (*dtos.GroupResponse, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.GroupResponse), args.Error(1)
+}
+
+func (m *MockGroupService) GetGroup(ctx context.Context, id string) (*dtos.GroupResponse, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.GroupResponse), args.Error(1)
+}
+
+func (m *MockGroupService) GetAllGroups(ctx context.Context) ([]dtos.GroupResponse, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dtos.GroupResponse), args.Error(1)
+}
+
+func (m *MockGroupService) UpdateGroup(ctx context.Context, id string, input *dtos.GroupUpdate) (*dtos.GroupResponse, error) {
+	args := m.Called(ctx, id, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.GroupResponse), args.Error(1)
+}
+
+func (m *MockGroupService) DeleteGroup(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockGroupService) AddMember(ctx context.Context, groupID string, userID int) error {
+	return m.Called(ctx, groupID, userID).Error(0)
+}
+
+func (m *MockGroupService) RemoveMember(ctx context.Context, groupID string, userID int) error {
+	return m.Called(ctx, groupID, userID).Error(0)
+}
+
+func (m *MockGroupService) GetMembers(ctx context.Context, groupID string, page, limit int) (*dtos.GroupMemberPage, error) {
+	args := m.Called(ctx, groupID, page, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.GroupMemberPage), args.Error(1)
+}
+
+func (m *MockGroupService) GetUserGroups(ctx context.Context, userID int) ([]dtos.UserGroupResponse, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dtos.UserGroupResponse), args.Error(1)
+}
+
+func newGroupRouter(mockService *MockGroupService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	h := handlers.NewGroupHandler(mockService)
+	r.POST("/groups", h.CreateGroup)
+	r.PATCH("/groups/:id", h.UpdateGroup)
+	r.DELETE("/groups/:id", h.DeleteGroup)
+	r.GET("/groups/:id/members", h.GetMembers)
+	r.POST("/groups/:id/members", h.AddMember)
+	r.DELETE("/groups/:id/members/:userId", h.RemoveMember)
+	r.GET("/users/:id/groups", h.GetUserGroups)
+	return r
+}
+
+func serveGroup(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateGroup(t *testing.T) {
+	mockService := new(MockGroupService)
+	r := newGroupRouter(mockService)
+	mockService.On("CreateGroup", mock.Anything, &dtos.GroupCreate{Name: "engineering"}).
+		Return(&dtos.GroupResponse{ID: "g1", Name: "engineering"}, nil)
+
+	w := serveGroup(r, http.MethodPost, "/groups", `{"name":"engineering"}`)
+
+	assert.Equal(t, 201, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"engineering"`)
+}
+
+func TestUpdateGroup_Cycle(t *testing.T) {
+	mockService := new(MockGroupService)
+	r := newGroupRouter(mockService)
+	parent := "g2"
+	mockService.On("UpdateGroup", mock.Anything, "g1", &dtos.GroupUpdate{ParentID: &parent}).Return(nil, services.ErrGroupCycle)
+
+	w := serveGroup(r, http.MethodPatch, "/groups/g1", `{"parentId":"g2"}`)
+
+	assert.Equal(t, 409, w.Code)
+}
+
+func TestDeleteGroup_NotFound(t *testing.T) {
+	mockService := new(MockGroupService)
+	r := newGroupRouter(mockService)
+	mockService.On("DeleteGroup", mock.Anything, "g1").Return(repositories.ErrGroupNotFound)
+
+	w := serveGroup(r, http.MethodDelete, "/groups/g1", "")
+
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestGetGroupMembers_Pagination(t *testing.T) {
+	mockService := new(MockGroupService)
+	r := newGroupRouter(mockService)
+	mockService.On("GetMembers", mock.Anything, "g1", 2, 10).
+		Return(&dtos.GroupMemberPage{Members: []dtos.GroupMemberResponse{{UserID: 11}}, Page: 2, Limit: 10, Total: 11}, nil)
+
+	w := serveGroup(r, http.MethodGet, "/groups/g1/members?page=2&limit=10", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":11`)
+
+	w = serveGroup(r, http.MethodGet, "/groups/g1/members?limit=1000", "")
+	assert.Equal(t, 400, w.Code)
+	mockService.AssertNumberOfCalls(t, "GetMembers", 1)
+}
+
+func TestAddGroupMember(t *testing.T) {
+	mockService := new(MockGroupService)
+	r := newGroupRouter(mockService)
+	mockService.On("AddMember", mock.Anything, "g1", 7).Return(nil)
+	mockService.On("AddMember", mock.Anything, "g1", 8).Return(repositories.ErrAlreadyMember)
+
+	assert.Equal(t, 201, serveGroup(r, http.MethodPost, "/groups/g1/members", `{"userId":7}`).Code)
+	assert.Equal(t, 409, serveGroup(r, http.MethodPost, "/groups/g1/members", `{"userId":8}`).Code)
+	assert.Equal(t, 400, serveGroup(r, http.MethodPost, "/groups/g1/members", `{}`).Code)
+}
+
+func TestRemoveGroupMember(t *testing.T) {
+	mockService := new(MockGroupService)
+	r := newGroupRouter(mockService)
+	mockService.On("RemoveMember", mock.Anything, "g1", 7).Return(repositories.ErrMemberNotFound)
+
+	assert.Equal(t, 404, serveGroup(r, http.MethodDelete, "/groups/g1/members/7", "").Code)
+	assert.Equal(t, 400, serveGroup(r, http.MethodDelete, "/groups/g1/members/x", "").Code)
+}
+
+func TestGetUserGroups(t *testing.T) {
+	mockService := new(MockGroupService)
+	r := newGroupRouter(mockService)
+	mockService.On("GetUserGroups", mock.Anything, 1).
+		Return([]dtos.UserGroupResponse{{GroupResponse: dtos.GroupResponse{ID: "g1", Name: "engineering"}, Direct: false}}, nil)
+
+	w := serveGroup(r, http.MethodGet, "/users/1/groups", "")
+
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"direct":false`)
+}
@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/handlers"
+	"7-solutions/services"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockHealthService struct {
+	mock.Mock
+}
+
+func (m *MockHealthService) Live() bool {
+	return m.Called().Bool(0)
+}
+
+func (m *MockHealthService) Ready(ctx context.Context) (bool, []dtos.DependencyHealth) {
+	args := m.Called(ctx)
+	return args.Bool(0), args.Get(1).([]dtos.DependencyHealth)
+}
+
+func (m *MockHealthService) Health(ctx context.Context) dtos.HealthResponse {
+	args := m.Called(ctx)
+	return args.Get(0).(dtos.HealthResponse)
+}
+
+func (m *MockHealthService) SetShuttingDown() {
+	m.Called()
+}
+
+func (m *MockHealthService) RecordJobRun(name string, startedAt time.Time, duration time.Duration, err error) {
+	m.Called(name, startedAt, duration, err)
+}
+
+func TestLiveness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	mockService := new(MockHealthService)
+	h := handlers.NewHealthHandler(mockService)
+	r.GET("/healthz", h.Liveness)
+
+	mockService.On("Live").Return(true)
+
+	req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestReadiness_NotReady(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	mockService := new(MockHealthService)
+	h := handlers.NewHealthHandler(mockService)
+	r.GET("/readyz", h.Readiness)
+
+	mockService.On("Ready", mock.Anything).Return(false, []dtos.DependencyHealth{
+		{Name: "mongo", Status: services.StatusDown, Error: "timeout"},
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 503, w.Code)
+	assert.Contains(t, w.Body.String(), "timeout")
+}
+
+func TestHealth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	mockService := new(MockHealthService)
+	h := handlers.NewHealthHandler(mockService)
+	r.GET("/health", h.Health)
+
+	mockService.On("Health", mock.Anything).Return(dtos.HealthResponse{
+		Status:  services.StatusUp,
+		Version: "1.2.3",
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), "1.2.3")
+}
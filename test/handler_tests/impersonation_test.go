@@ -0,0 +1,52 @@
+package handlers_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/handlers"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockImpersonationService struct {
+	mock.Mock
+}
+
+func (m *MockImpersonationService) Impersonate(ctx context.Context, actorID, actorRole string, userID int) (*dtos.ImpersonationResponse, error) {
+	args := m.Called(ctx, actorID, actorRole, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.ImpersonationResponse), args.Error(1)
+}
+
+func TestImpersonate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	mockService := new(MockImpersonationService)
+	h := handlers.NewImpersonationHandler(mockService)
+	r.POST("/admin/impersonate/:id", func(c *gin.Context) {
+		c.Set("userID", "1")
+		c.Set("role", models.RoleAdmin)
+	}, h.Impersonate)
+
+	mockService.On("Impersonate", mock.Anything, "1", models.RoleAdmin, 2).
+		Return(&dtos.ImpersonationResponse{Token: "token", ActorID: "1", User: dtos.UserResponse{ID: 2}}, nil)
+	mockService.On("Impersonate", mock.Anything, "1", models.RoleAdmin, 3).Return(nil, services.ErrImpersonationNotAllowed)
+	mockService.On("Impersonate", mock.Anything, "1", models.RoleAdmin, 4).Return(nil, repositories.ErrUserNotFound)
+
+	w := serveGroup(r, http.MethodPost, "/admin/impersonate/2", "")
+	assert.Equal(t, 201, w.Code)
+	assert.Contains(t, w.Body.String(), `"actorId":"1"`)
+
+	assert.Equal(t, 403, serveGroup(r, http.MethodPost, "/admin/impersonate/3", "").Code)
+	assert.Equal(t, 404, serveGroup(r, http.MethodPost, "/admin/impersonate/4", "").Code)
+	assert.Equal(t, 400, serveGroup(r, http.MethodPost, "/admin/impersonate/x", "").Code)
+}
@@ -0,0 +1,125 @@
+package handlers_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/handlers"
+	"7-solutions/mailer"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockInvitationService struct {
+	mock.Mock
+}
+
+func (m *MockInvitationService) CreateInvitation(ctx context.Context, invitedBy string, input *dtos.InvitationCreate) (*dtos.InvitationResponse, error) {
+	args := m.Called(ctx, invitedBy, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.InvitationResponse), args.Error(1)
+}
+
+func (m *MockInvitationService) GetPendingInvitations(ctx context.Context) ([]dtos.InvitationResponse, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dtos.InvitationResponse), args.Error(1)
+}
+
+func (m *MockInvitationService) ResendInvitation(ctx context.Context, id string) (*dtos.InvitationResponse, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.InvitationResponse), args.Error(1)
+}
+
+func (m *MockInvitationService) RevokeInvitation(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockInvitationService) AcceptInvitation(ctx context.Context, input *dtos.InvitationAccept) (*dtos.UserResponse, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.UserResponse), args.Error(1)
+}
+
+func newInvitationRouter(mockService *MockInvitationService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	h := handlers.NewInvitationHandler(mockService)
+	r.POST("/invitations/accept", h.AcceptInvitation)
+	admin := r.Group("/invitations", func(c *gin.Context) { c.Set("userID", "1") })
+	admin.POST("", h.CreateInvitation)
+	admin.GET("", h.GetPendingInvitations)
+	admin.POST("/:id/resend", h.ResendInvitation)
+	admin.DELETE("/:id", h.RevokeInvitation)
+	return r
+}
+
+func TestCreateInvitation(t *testing.T) {
+	mockService := new(MockInvitationService)
+	r := newInvitationRouter(mockService)
+	mockService.On("CreateInvitation", mock.Anything, "1", &dtos.InvitationCreate{Email: "new@example.com", Role: "admin"}).
+		Return(&dtos.InvitationResponse{ID: "i1", Email: "new@example.com", Status: "pending"}, nil)
+	mockService.On("CreateInvitation", mock.Anything, "1", &dtos.InvitationCreate{Email: "taken@example.com"}).
+		Return(nil, repositories.ErrEmailTaken)
+
+	w := serveGroup(r, http.MethodPost, "/invitations", `{"email":"new@example.com","role":"admin"}`)
+	assert.Equal(t, 201, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"pending"`)
+
+	assert.Equal(t, 409, serveGroup(r, http.MethodPost, "/invitations", `{"email":"taken@example.com"}`).Code)
+	assert.Equal(t, 400, serveGroup(r, http.MethodPost, "/invitations", `{"email":"new@example.com","role":"superadmin"}`).Code)
+	assert.Equal(t, 400, serveGroup(r, http.MethodPost, "/invitations", `{"email":"not-an-email"}`).Code)
+}
+
+func TestCreateInvitation_DeliveryFailure(t *testing.T) {
+	mockService := new(MockInvitationService)
+	r := newInvitationRouter(mockService)
+	mockService.On("CreateInvitation", mock.Anything, "1", mock.Anything).
+		Return(&dtos.InvitationResponse{ID: "i1"}, fmt.Errorf("%w: timeout", mailer.ErrDelivery))
+
+	assert.Equal(t, 502, serveGroup(r, http.MethodPost, "/invitations", `{"email":"new@example.com"}`).Code)
+}
+
+func TestResendAndRevokeInvitation(t *testing.T) {
+	mockService := new(MockInvitationService)
+	r := newInvitationRouter(mockService)
+	mockService.On("ResendInvitation", mock.Anything, "i1").Return(&dtos.InvitationResponse{ID: "i1"}, nil)
+	mockService.On("ResendInvitation", mock.Anything, "i2").Return(nil, repositories.ErrInvitationNotFound)
+	mockService.On("RevokeInvitation", mock.Anything, "i1").Return(nil)
+
+	assert.Equal(t, 200, serveGroup(r, http.MethodPost, "/invitations/i1/resend", "").Code)
+	assert.Equal(t, 404, serveGroup(r, http.MethodPost, "/invitations/i2/resend", "").Code)
+	assert.Equal(t, 204, serveGroup(r, http.MethodDelete, "/invitations/i1", "").Code)
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	mockService := new(MockInvitationService)
+	r := newInvitationRouter(mockService)
+	accept := &dtos.InvitationAccept{Token: "good", Name: "New", Password: "secret123"}
+	mockService.On("AcceptInvitation", mock.Anything, accept).Return(&dtos.UserResponse{ID: 5, Email: "new@example.com"}, nil)
+	mockService.On("AcceptInvitation", mock.Anything, mock.Anything).Return(nil, services.ErrInvalidInvitation)
+
+	w := serveGroup(r, http.MethodPost, "/invitations/accept", `{"token":"good","name":"New","password":"secret123"}`)
+	assert.Equal(t, 201, w.Code)
+	assert.Contains(t, w.Body.String(), `"email":"new@example.com"`)
+
+	assert.Equal(t, 400, serveGroup(r, http.MethodPost, "/invitations/accept", `{"token":"used","name":"New","password":"secret123"}`).Code)
+	assert.Equal(t, 400, serveGroup(r, http.MethodPost, "/invitations/accept", `{"token":"good"}`).Code)
+	assert.Equal(t, 400, serveGroup(r, http.MethodPost, "/invitations/accept", `{"token":"good","name":"New","password":"12345"}`).Code)
+	mockService.AssertNumberOfCalls(t, "AcceptInvitation", 2)
+}
@@ -0,0 +1,104 @@
+package handlers_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/handlers"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOrganizationService struct {
+	mock.Mock
+}
+
+func (m *MockOrganizationService) CreateOrganization(ctx context.Context, input *dtos.OrganizationCreate) (*dtos.OrganizationResponse, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.OrganizationResponse), args.Error(1)
+}
+
+func (m *MockOrganizationService) GetOrganization(ctx context.Context, id string) (*dtos.OrganizationResponse, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.OrganizationResponse), args.Error(1)
+}
+
+func (m *MockOrganizationService) GetAllOrganizations(ctx context.Context) ([]dtos.OrganizationResponse, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dtos.OrganizationResponse), args.Error(1)
+}
+
+func newOrganizationRouter(mockService *MockOrganizationService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	h := handlers.NewOrganizationHandler(mockService)
+	r.POST("/organizations", h.CreateOrganization)
+	r.GET("/organizations", h.GetAllOrganizations)
+	r.GET("/organizations/:id", h.GetOrganization)
+	return r
+}
+
+func TestCreateOrganization(t *testing.T) {
+	mockService := new(MockOrganizationService)
+	r := newOrganizationRouter(mockService)
+
+	mockService.On("CreateOrganization", mock.Anything, &dtos.OrganizationCreate{ID: "acme", Name: "Acme"}).
+		Return(&dtos.OrganizationResponse{ID: "acme", Name: "Acme"}, nil)
+
+	req, _ := http.NewRequest(http.MethodPost, "/organizations", strings.NewReader(`{"id":"acme","name":"Acme"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 201, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"acme"`)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateOrganization_Errors(t *testing.T) {
+	cases := map[error]int{
+		fmt.Errorf("%w: bad id", services.ErrInvalidOrganization): 400,
+		repositories.ErrOrganizationExists:                        409,
+	}
+	for err, status := range cases {
+		mockService := new(MockOrganizationService)
+		r := newOrganizationRouter(mockService)
+		mockService.On("CreateOrganization", mock.Anything, mock.Anything).Return(nil, err)
+
+		req, _ := http.NewRequest(http.MethodPost, "/organizations", strings.NewReader(`{"id":"Acme!","name":"Acme"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, status, w.Code, err.Error())
+	}
+}
+
+func TestGetOrganization_NotFound(t *testing.T) {
+	mockService := new(MockOrganizationService)
+	r := newOrganizationRouter(mockService)
+	mockService.On("GetOrganization", mock.Anything, "nope").Return(nil, fmt.Errorf("organization not found"))
+
+	req, _ := http.NewRequest(http.MethodGet, "/organizations/nope", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 404, w.Code)
+}
@@ -0,0 +1,111 @@
+package handlers_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/handlers"
+	"7-solutions/policy"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPrivacyService struct {
+	mock.Mock
+}
+
+func (m *MockPrivacyService) ExportUserData(ctx context.Context, id int) (*dtos.UserDataExport, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.UserDataExport), args.Error(1)
+}
+
+func (m *MockPrivacyService) EraseUser(ctx context.Context, requestedBy string, id int) (*dtos.ErasureResponse, error) {
+	args := m.Called(ctx, requestedBy, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.ErasureResponse), args.Error(1)
+}
+
+func (m *MockPrivacyService) GetErasure(ctx context.Context, id int) (*dtos.ErasureResponse, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.ErasureResponse), args.Error(1)
+}
+
+func (m *MockPrivacyService) ResumeErasures(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func newPrivacyRouter(service *MockPrivacyService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := handlers.NewPrivacyHandler(service)
+	group := r.Group("/users/:id", func(c *gin.Context) {
+		c.Set("userID", "9")
+	})
+	group.GET("/data-export", h.ExportUserData)
+	group.POST("/erasure", h.EraseUser)
+	group.GET("/erasure", h.GetErasure)
+	return r
+}
+
+func TestExportUserData(t *testing.T) {
+	mockService := new(MockPrivacyService)
+	r := newPrivacyRouter(mockService)
+	mockService.On("ExportUserData", mock.Anything, 7).
+		Return(&dtos.UserDataExport{Profile: dtos.UserExportRow{ID: 7, Email: "ann@acme.example"}}, nil)
+
+	w := serveGroup(r, http.MethodGet, "/users/7/data-export", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "attachment; filename=user-7-data.json", w.Header().Get("Content-Disposition"))
+	assert.Contains(t, w.Body.String(), `"email":"ann@acme.example"`)
+}
+
+func TestExportUserData_Errors(t *testing.T) {
+	mockService := new(MockPrivacyService)
+	r := newPrivacyRouter(mockService)
+	mockService.On("ExportUserData", mock.Anything, 7).Return(nil, repositories.ErrUserNotFound)
+	mockService.On("ExportUserData", mock.Anything, 8).Return(nil, policy.ErrDenied)
+
+	assert.Equal(t, http.StatusBadRequest, serveGroup(r, http.MethodGet, "/users/abc/data-export", "").Code)
+	assert.Equal(t, http.StatusNotFound, serveGroup(r, http.MethodGet, "/users/7/data-export", "").Code)
+	assert.Equal(t, http.StatusForbidden, serveGroup(r, http.MethodGet, "/users/8/data-export", "").Code)
+}
+
+func TestEraseUser(t *testing.T) {
+	mockService := new(MockPrivacyService)
+	r := newPrivacyRouter(mockService)
+	mockService.On("EraseUser", mock.Anything, "9", 7).
+		Return(&dtos.ErasureResponse{Subject: "erased-ab12", Status: "completed"}, nil)
+	mockService.On("EraseUser", mock.Anything, "9", 1).Return(nil, services.ErrErasureNotAllowed)
+
+	w := serveGroup(r, http.MethodPost, "/users/7/erasure", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"subject":"erased-ab12"`)
+
+	assert.Equal(t, http.StatusForbidden, serveGroup(r, http.MethodPost, "/users/1/erasure", "").Code)
+}
+
+func TestGetErasure_NotFound(t *testing.T) {
+	mockService := new(MockPrivacyService)
+	r := newPrivacyRouter(mockService)
+	mockService.On("GetErasure", mock.Anything, 7).Return(nil, repositories.ErrErasureNotFound)
+
+	w := serveGroup(r, http.MethodGet, "/users/7/erasure", "")
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "erasure not found")
+}
@@ -0,0 +1,131 @@
+package handlers_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/handlers"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockProfileService struct {
+	mock.Mock
+}
+
+func (m *MockProfileService) CreateAttribute(ctx context.Context, input *dtos.AttributeDefinitionCreate) (*dtos.AttributeDefinitionResponse, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.AttributeDefinitionResponse), args.Error(1)
+}
+
+func (m *MockProfileService) GetAttributes(ctx context.Context) ([]dtos.AttributeDefinitionResponse, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]dtos.AttributeDefinitionResponse), args.Error(1)
+}
+
+func (m *MockProfileService) UpdateAttribute(ctx context.Context, key string, input *dtos.AttributeDefinitionUpdate) (*dtos.AttributeDefinitionResponse, error) {
+	args := m.Called(ctx, key, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.AttributeDefinitionResponse), args.Error(1)
+}
+
+func (m *MockProfileService) DeleteAttribute(ctx context.Context, key string) error {
+	return m.Called(ctx, key).Error(0)
+}
+
+func (m *MockProfileService) UpdateUserAttributes(ctx context.Context, id int, input *dtos.UserAttributesUpdate) (map[string]any, error) {
+	args := m.Called(ctx, id, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]any), args.Error(1)
+}
+
+func newProfileRouter(service *MockProfileService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := handlers.NewProfileHandler(service)
+	r.POST("/attributes", h.CreateAttribute)
+	r.GET("/attributes", h.GetAttributes)
+	r.PUT("/attributes/:key", h.UpdateAttribute)
+	r.DELETE("/attributes/:key", h.DeleteAttribute)
+	r.PUT("/users/:id/attributes", h.UpdateUserAttributes)
+	return r
+}
+
+func TestCreateAttribute(t *testing.T) {
+	mockService := new(MockProfileService)
+	r := newProfileRouter(mockService)
+	mockService.On("CreateAttribute", mock.Anything, &dtos.AttributeDefinitionCreate{Key: "department", Type: "string", Enum: []string{"eng"}}).
+		Return(&dtos.AttributeDefinitionResponse{Key: "department", Type: "string", Visibility: "private"}, nil)
+	mockService.On("CreateAttribute", mock.Anything, &dtos.AttributeDefinitionCreate{Key: "Bad", Type: "string"}).
+		Return(nil, fmt.Errorf("%w: bad key", services.ErrInvalidAttribute))
+	mockService.On("CreateAttribute", mock.Anything, &dtos.AttributeDefinitionCreate{Key: "taken", Type: "number"}).
+		Return(nil, repositories.ErrAttributeExists)
+
+	w := serveGroup(r, http.MethodPost, "/attributes", `{"key":"department","type":"string","enum":["eng"]}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"visibility":"private"`)
+
+	assert.Equal(t, http.StatusBadRequest, serveGroup(r, http.MethodPost, "/attributes", `{"key":"x","type":"date"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serveGroup(r, http.MethodPost, "/attributes", `{"key":"Bad","type":"string"}`).Code)
+	assert.Equal(t, http.StatusConflict, serveGroup(r, http.MethodPost, "/attributes", `{"key":"taken","type":"number"}`).Code)
+}
+
+func TestUpdateAndDeleteAttribute(t *testing.T) {
+	mockService := new(MockProfileService)
+	r := newProfileRouter(mockService)
+	mockService.On("UpdateAttribute", mock.Anything, "department", &dtos.AttributeDefinitionUpdate{Required: true, Visibility: "public"}).
+		Return(&dtos.AttributeDefinitionResponse{Key: "department", Required: true}, nil)
+	mockService.On("DeleteAttribute", mock.Anything, "department").Return(nil)
+	mockService.On("DeleteAttribute", mock.Anything, "missing").Return(repositories.ErrAttributeNotFound)
+
+	w := serveGroup(r, http.MethodPut, "/attributes/department", `{"required":true,"visibility":"public"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"required":true`)
+
+	assert.Equal(t, http.StatusNoContent, serveGroup(r, http.MethodDelete, "/attributes/department", "").Code)
+	assert.Equal(t, http.StatusNotFound, serveGroup(r, http.MethodDelete, "/attributes/missing", "").Code)
+}
+
+func TestUpdateUserAttributes(t *testing.T) {
+	mockService := new(MockProfileService)
+	r := newProfileRouter(mockService)
+	mockService.On("UpdateUserAttributes", mock.Anything, 7, &dtos.UserAttributesUpdate{Attributes: map[string]any{"department": "eng", "phone": nil}}).
+		Return(map[string]any{"department": "eng"}, nil)
+	mockService.On("UpdateUserAttributes", mock.Anything, 8, mock.Anything).
+		Return(nil, fmt.Errorf("%w: department is required", services.ErrInvalidAttribute))
+
+	w := serveGroup(r, http.MethodPut, "/users/7/attributes", `{"attributes":{"department":"eng","phone":null}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"attributes":{"department":"eng"}}`, w.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, serveGroup(r, http.MethodPut, "/users/8/attributes", `{"attributes":{"remote":true}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serveGroup(r, http.MethodPut, "/users/7/attributes", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serveGroup(r, http.MethodPut, "/users/abc/attributes", `{"attributes":{}}`).Code)
+}
+
+func TestGetAllUsers_AttributeFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	mockService := new(MockUserService)
+	r.GET("/users", handlers.NewUserHandler(mockService).GetAllUsers)
+	mockService.On("GetAllUsers", mock.Anything, &dtos.UserFilter{Attributes: map[string]any{"department": "eng", "remote": "true"}}).
+		Return([]dtos.UserResponse{}, nil)
+	mockService.On("GetAllUsers", mock.Anything, &dtos.UserFilter{Attributes: map[string]any{"unknown": "x"}}).
+		Return([]dtos.UserResponse(nil), fmt.Errorf("%w: unknown attribute", services.ErrInvalidAttribute))
+
+	assert.Equal(t, http.StatusOK, serveGroup(r, http.MethodGet, "/users?attributes[department]=eng&attributes[remote]=true", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serveGroup(r, http.MethodGet, "/users?attributes[unknown]=x", "").Code)
+}
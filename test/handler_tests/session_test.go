@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/handlers"
+	"7-solutions/repositories"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSessionService struct {
+	mock.Mock
+}
+
+func (m *MockSessionService) GetSessions(ctx context.Context, userID int, currentID string) ([]dtos.SessionResponse, error) {
+	args := m.Called(ctx, userID, currentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dtos.SessionResponse), args.Error(1)
+}
+
+func (m *MockSessionService) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockSessionService) RevokeAllSessions(ctx context.Context, userID int) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSessionService) ValidateSession(ctx context.Context, sessionID string) error {
+	args := m.Called(ctx, sessionID)
+	return args.Error(0)
+}
+
+func newSessionRouter(service *MockSessionService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := handlers.NewSessionHandler(service)
+	me := r.Group("/users/me/sessions", func(c *gin.Context) {
+		c.Set("userID", "7")
+		c.Set("sessionID", "s1")
+	})
+	me.GET("", h.GetMySessions)
+	me.DELETE("/:sessionId", h.RevokeMySession)
+	admin := r.Group("/admin/users/:id/sessions")
+	admin.GET("", h.GetUserSessions)
+	admin.DELETE("", h.RevokeAllUserSessions)
+	admin.DELETE("/:sessionId", h.RevokeUserSession)
+	return r
+}
+
+func TestGetMySessions(t *testing.T) {
+	mockService := new(MockSessionService)
+	r := newSessionRouter(mockService)
+	mockService.On("GetSessions", mock.Anything, 7, "s1").
+		Return([]dtos.SessionResponse{{ID: "s1", UserAgent: "curl/8.0", Current: true}}, nil)
+
+	w := serveGroup(r, http.MethodGet, "/users/me/sessions", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"current":true`)
+}
+
+func TestRevokeMySession(t *testing.T) {
+	mockService := new(MockSessionService)
+	r := newSessionRouter(mockService)
+	mockService.On("RevokeSession", mock.Anything, 7, "s2").Return(nil)
+	mockService.On("RevokeSession", mock.Anything, 7, "s3").Return(repositories.ErrSessionNotFound)
+
+	assert.Equal(t, http.StatusNoContent, serveGroup(r, http.MethodDelete, "/users/me/sessions/s2", "").Code)
+	assert.Equal(t, http.StatusNotFound, serveGroup(r, http.MethodDelete, "/users/me/sessions/s3", "").Code)
+}
+
+func TestAdminUserSessions(t *testing.T) {
+	mockService := new(MockSessionService)
+	r := newSessionRouter(mockService)
+	mockService.On("GetSessions", mock.Anything, 9, "").Return([]dtos.SessionResponse{}, nil)
+	mockService.On("RevokeSession", mock.Anything, 9, "s4").Return(nil)
+	mockService.On("RevokeAllSessions", mock.Anything, 9).Return(int64(3), nil)
+
+	assert.Equal(t, http.StatusOK, serveGroup(r, http.MethodGet, "/admin/users/9/sessions", "").Code)
+	assert.Equal(t, http.StatusNoContent, serveGroup(r, http.MethodDelete, "/admin/users/9/sessions/s4", "").Code)
+
+	w := serveGroup(r, http.MethodDelete, "/admin/users/9/sessions", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"revoked":3}`, w.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, serveGroup(r, http.MethodGet, "/admin/users/x/sessions", "").Code)
+}
@@ -0,0 +1,100 @@
+package handlers_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/handlers"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUserBulkService struct {
+	mock.Mock
+}
+
+func (m *MockUserBulkService) ApplyBulk(ctx context.Context, actorID string, input *dtos.UserBulk) (*dtos.UserBulkResponse, error) {
+	args := m.Called(ctx, actorID, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.UserBulkResponse), args.Error(1)
+}
+
+func newUserBulkRouter(service *MockUserBulkService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := handlers.NewUserBulkHandler(service)
+	r.POST("/users/bulk", h.ApplyBulk)
+	return r
+}
+
+func TestApplyBulk_BestEffort(t *testing.T) {
+	mockService := new(MockUserBulkService)
+	r := newUserBulkRouter(mockService)
+	mockService.On("ApplyBulk", mock.Anything, mock.Anything, &dtos.UserBulk{Operations: []dtos.UserBulkOperation{
+		{Op: "delete", ID: 1},
+		{Op: "delete", ID: 2},
+	}}).Return(&dtos.UserBulkResponse{Applied: true, Summary: dtos.UserBulkSummary{Succeeded: 1, Failed: 1}}, nil)
+
+	w := serveGroup(r, http.MethodPost, "/users/bulk", `{"operations":[{"op":"delete","id":1},{"op":"delete","id":2}]}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"failed":1`)
+}
+
+func TestApplyBulk_Deactivate(t *testing.T) {
+	mockService := new(MockUserBulkService)
+	r := newUserBulkRouter(mockService)
+	mockService.On("ApplyBulk", mock.Anything, mock.Anything, &dtos.UserBulk{Operations: []dtos.UserBulkOperation{
+		{Op: "deactivate", ID: 1, Reason: "Left the company"},
+	}}).Return(&dtos.UserBulkResponse{Applied: true, Summary: dtos.UserBulkSummary{Succeeded: 1}}, nil)
+
+	w := serveGroup(r, http.MethodPost, "/users/bulk", `{"operations":[{"op":"deactivate","id":1,"reason":"Left the company"}]}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"succeeded":1`)
+}
+
+func TestApplyBulk_RolledBack(t *testing.T) {
+	mockService := new(MockUserBulkService)
+	r := newUserBulkRouter(mockService)
+	mockService.On("ApplyBulk", mock.Anything, mock.Anything, mock.Anything).
+		Return(&dtos.UserBulkResponse{Atomic: true, Summary: dtos.UserBulkSummary{Failed: 1}}, services.ErrBulkRolledBack)
+
+	w := serveGroup(r, http.MethodPost, "/users/bulk", `{"atomic":true,"operations":[{"op":"delete","id":1}]}`)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"error":"Failed to apply bulk operations: bulk operations rolled back"`)
+	assert.Contains(t, w.Body.String(), `"applied":false`)
+}
+
+func TestApplyBulk_TransactionsUnsupported(t *testing.T) {
+	mockService := new(MockUserBulkService)
+	r := newUserBulkRouter(mockService)
+	mockService.On("ApplyBulk", mock.Anything, mock.Anything, mock.Anything).Return(nil, repositories.ErrTransactionsUnsupported)
+
+	w := serveGroup(r, http.MethodPost, "/users/bulk", `{"atomic":true,"operations":[{"op":"delete","id":1}]}`)
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestApplyBulk_InvalidInput(t *testing.T) {
+	mockService := new(MockUserBulkService)
+	r := newUserBulkRouter(mockService)
+
+	for _, body := range []string{
+		`{"operations":[]}`,
+		`{"operations":[{"op":"create","id":1}]}`,
+		`{"operations":[{"op":"delete"}]}`,
+	} {
+		w := serveGroup(r, http.MethodPost, "/users/bulk", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	mockService.AssertNotCalled(t, "ApplyBulk", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,134 @@
+package handlers_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/handlers"
+	"7-solutions/services"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUserExportService struct {
+	mock.Mock
+}
+
+func (m *MockUserExportService) ExportUsers(ctx context.Context, input *dtos.UserExport, w io.Writer) (int, error) {
+	args := m.Called(ctx, input)
+	if body := args.String(0); body != "" {
+		_, _ = io.WriteString(w, body)
+	}
+	return strings.Count(args.String(0), "\n"), args.Error(1)
+}
+
+func (m *MockUserExportService) StartExport(ctx context.Context, startedBy string, input *dtos.UserExport) (*dtos.UserExportResponse, error) {
+	args := m.Called(ctx, startedBy, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.UserExportResponse), args.Error(1)
+}
+
+func (m *MockUserExportService) GetExportJob(ctx context.Context, id string) (*dtos.UserExportResponse, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.UserExportResponse), args.Error(1)
+}
+
+func (m *MockUserExportService) OpenExportFile(ctx context.Context, id string) (*dtos.UserExportResponse, io.ReadCloser, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*dtos.UserExportResponse), args.Get(1).(io.ReadCloser), args.Error(2)
+}
+
+func (m *MockUserExportService) PurgeExpiredExports(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserExportService) Wait(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func newUserExportRouter(service *MockUserExportService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := handlers.NewUserExportHandler(service)
+	users := r.Group("/users", func(c *gin.Context) { c.Set("userID", "1") })
+	users.GET("/export", h.ExportUsers)
+	users.POST("/export", h.StartExport)
+	users.GET("/export/:id", h.GetExportJob)
+	users.GET("/export/:id/download", h.DownloadExport)
+	return r
+}
+
+func TestExportUsers_Streams(t *testing.T) {
+	mockService := new(MockUserExportService)
+	r := newUserExportRouter(mockService)
+	verified := true
+	mockService.On("ExportUsers", mock.Anything, &dtos.UserExport{
+		Format:     services.ExportFormatCSV,
+		UserFilter: dtos.UserFilter{Role: "admin", EmailVerified: &verified},
+	}).Return("id,name\n1,Ann\n", nil)
+
+	w := serveGroup(r, http.MethodGet, "/users/export?role=admin&emailVerified=true", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `attachment; filename=users-`)
+	assert.Equal(t, "id,name\n1,Ann\n", w.Body.String())
+}
+
+func TestExportUsers_Errors(t *testing.T) {
+	mockService := new(MockUserExportService)
+	r := newUserExportRouter(mockService)
+	mockService.On("ExportUsers", mock.Anything, &dtos.UserExport{Format: "pdf"}).Return("", services.ErrInvalidExport)
+
+	w := serveGroup(r, http.MethodGet, "/users/export?format=pdf", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+
+	assert.Equal(t, http.StatusBadRequest, serveGroup(r, http.MethodGet, "/users/export?role=owner", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serveGroup(r, http.MethodGet, "/users/export?createdAfter=yesterday", "").Code)
+	mockService.AssertNumberOfCalls(t, "ExportUsers", 1)
+}
+
+func TestStartExport(t *testing.T) {
+	mockService := new(MockUserExportService)
+	r := newUserExportRouter(mockService)
+	mockService.On("StartExport", mock.Anything, "1", &dtos.UserExport{Format: services.ExportFormatXLSX}).
+		Return(&dtos.UserExportResponse{ID: "e1", Status: "running", Format: "xlsx"}, nil)
+
+	w := serveGroup(r, http.MethodPost, "/users/export?format=xlsx", "")
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "/users/export/e1", w.Header().Get("Location"))
+}
+
+func TestDownloadExport(t *testing.T) {
+	mockService := new(MockUserExportService)
+	r := newUserExportRouter(mockService)
+	mockService.On("OpenExportFile", mock.Anything, "e1").Return(
+		&dtos.UserExportResponse{ID: "e1", Format: "ndjson", Size: 3, CreatedAt: "2026-01-02T03:04:05Z"},
+		io.NopCloser(strings.NewReader("{}\n")), nil)
+	mockService.On("OpenExportFile", mock.Anything, "e2").Return(nil, nil, services.ErrExportNotReady)
+
+	w := serveGroup(r, http.MethodGet, "/users/export/e1/download", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=users-20260102T030405Z.ndjson", w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "{}\n", w.Body.String())
+
+	assert.Equal(t, http.StatusConflict, serveGroup(r, http.MethodGet, "/users/export/e2/download", "").Code)
+}
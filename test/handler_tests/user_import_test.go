@@ -0,0 +1,113 @@
+package handlers_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/handlers"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUserImportService struct {
+	mock.Mock
+}
+
+func (m *MockUserImportService) ImportUsers(ctx context.Context, startedBy string, input *dtos.UserImport, body io.Reader) (*dtos.UserImportResponse, error) {
+	data, _ := io.ReadAll(body)
+	args := m.Called(ctx, startedBy, input, string(data))
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.UserImportResponse), args.Error(1)
+}
+
+func (m *MockUserImportService) GetImportJob(ctx context.Context, id string) (*dtos.UserImportResponse, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.UserImportResponse), args.Error(1)
+}
+
+func (m *MockUserImportService) Wait(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func newUserImportRouter(service *MockUserImportService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := handlers.NewUserImportHandler(service)
+	users := r.Group("/users", func(c *gin.Context) { c.Set("userID", "1") })
+	users.POST("/import", h.ImportUsers)
+	users.GET("/import/:id", h.GetImportJob)
+	return r
+}
+
+func postImport(r *gin.Engine, path, contentType, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestImportUsers_Sync(t *testing.T) {
+	mockService := new(MockUserImportService)
+	r := newUserImportRouter(mockService)
+	csv := "email,name\nbob@acme.example,Bob\n"
+	mockService.On("ImportUsers", mock.Anything, "1", &dtos.UserImport{Format: services.ImportFormatCSV, DryRun: true}, csv).
+		Return(&dtos.UserImportResponse{Status: "completed", DryRun: true, Summary: dtos.UserImportSummary{Created: 1}}, nil)
+
+	w := postImport(r, "/users/import?dryRun=true", "text/csv; charset=utf-8", csv)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"created":1`)
+	assert.Empty(t, w.Header().Get("Location"))
+}
+
+func TestImportUsers_Job(t *testing.T) {
+	mockService := new(MockUserImportService)
+	r := newUserImportRouter(mockService)
+	mockService.On("ImportUsers", mock.Anything, "1", &dtos.UserImport{Format: services.ImportFormatNDJSON}, mock.Anything).
+		Return(&dtos.UserImportResponse{ID: "j1", Status: "running"}, nil)
+
+	w := postImport(r, "/users/import", "application/x-ndjson", `{"email":"bob@acme.example","name":"Bob"}`)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "/users/import/j1", w.Header().Get("Location"))
+}
+
+func TestImportUsers_Errors(t *testing.T) {
+	mockService := new(MockUserImportService)
+	r := newUserImportRouter(mockService)
+	mockService.On("ImportUsers", mock.Anything, "1", &dtos.UserImport{Format: services.ImportFormatCSV}, "bad").
+		Return(nil, services.ErrInvalidImport)
+	mockService.On("ImportUsers", mock.Anything, "1", &dtos.UserImport{Format: services.ImportFormatCSV}, "big").
+		Return(nil, services.ErrImportTooLarge)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, postImport(r, "/users/import", "application/xml", "<users/>").Code)
+	assert.Equal(t, http.StatusBadRequest, postImport(r, "/users/import", "text/csv", "bad").Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, postImport(r, "/users/import?format=csv", "text/plain", "big").Code)
+	mockService.AssertNumberOfCalls(t, "ImportUsers", 2)
+}
+
+func TestGetImportJob(t *testing.T) {
+	mockService := new(MockUserImportService)
+	r := newUserImportRouter(mockService)
+	mockService.On("GetImportJob", mock.Anything, "j1").Return(&dtos.UserImportResponse{ID: "j1", Status: "completed"}, nil)
+	mockService.On("GetImportJob", mock.Anything, "j2").Return(nil, repositories.ErrImportJobNotFound)
+
+	w := serveGroup(r, http.MethodGet, "/users/import/j1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"completed"`)
+	assert.Equal(t, http.StatusNotFound, serveGroup(r, http.MethodGet, "/users/import/j2", "").Code)
+}
@@ -0,0 +1,89 @@
+package handlers_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/handlers"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUserStatusService struct {
+	mock.Mock
+}
+
+func (m *MockUserStatusService) GetStatus(ctx context.Context, id int) (*dtos.UserStatusResponse, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.UserStatusResponse), args.Error(1)
+}
+
+func (m *MockUserStatusService) ChangeStatus(ctx context.Context, actorID string, id int, input *dtos.UserStatusUpdate) (*dtos.UserStatusResponse, error) {
+	args := m.Called(ctx, actorID, id, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dtos.UserStatusResponse), args.Error(1)
+}
+
+func newUserStatusRouter(service *MockUserStatusService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := handlers.NewUserStatusHandler(service)
+	group := r.Group("/admin/users/:id/status", func(c *gin.Context) {
+		c.Set("userID", "9")
+	})
+	group.GET("", h.GetStatus)
+	group.PUT("", h.ChangeStatus)
+	return r
+}
+
+func TestChangeUserStatus(t *testing.T) {
+	mockService := new(MockUserStatusService)
+	r := newUserStatusRouter(mockService)
+	mockService.On("ChangeStatus", mock.Anything, "9", 7, &dtos.UserStatusUpdate{Status: "suspended", Reason: "chargeback"}).
+		Return(&dtos.UserStatusResponse{UserID: 7, Status: "suspended", History: []dtos.UserStatusChange{{From: "active", To: "suspended"}}}, nil)
+
+	w := serveGroup(r, http.MethodPut, "/admin/users/7/status", `{"status":"suspended","reason":"chargeback"}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"suspended"`)
+	assert.Contains(t, w.Body.String(), `"from":"active"`)
+}
+
+func TestChangeUserStatus_Errors(t *testing.T) {
+	mockService := new(MockUserStatusService)
+	r := newUserStatusRouter(mockService)
+	mockService.On("ChangeStatus", mock.Anything, "9", 7, mock.Anything).
+		Return(nil, fmt.Errorf("%w: from deactivated to locked", services.ErrInvalidStatusTransition))
+	mockService.On("ChangeStatus", mock.Anything, "9", 8, mock.Anything).Return(nil, services.ErrStatusReasonRequired)
+	mockService.On("ChangeStatus", mock.Anything, "9", 9, mock.Anything).Return(nil, services.ErrStatusChangeNotAllowed)
+	mockService.On("ChangeStatus", mock.Anything, "9", 10, mock.Anything).Return(nil, repositories.ErrStatusChanged)
+
+	body := `{"status":"locked","reason":"brute force"}`
+	assert.Equal(t, http.StatusConflict, serveGroup(r, http.MethodPut, "/admin/users/7/status", body).Code)
+	assert.Equal(t, http.StatusBadRequest, serveGroup(r, http.MethodPut, "/admin/users/8/status", body).Code)
+	assert.Equal(t, http.StatusForbidden, serveGroup(r, http.MethodPut, "/admin/users/9/status", body).Code)
+	assert.Equal(t, http.StatusConflict, serveGroup(r, http.MethodPut, "/admin/users/10/status", body).Code)
+	assert.Equal(t, http.StatusBadRequest, serveGroup(r, http.MethodPut, "/admin/users/7/status", `{"status":"banned"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serveGroup(r, http.MethodPut, "/admin/users/abc/status", body).Code)
+}
+
+func TestGetUserStatus_NotFound(t *testing.T) {
+	mockService := new(MockUserStatusService)
+	r := newUserStatusRouter(mockService)
+	mockService.On("GetStatus", mock.Anything, 7).Return(nil, repositories.ErrUserNotFound)
+
+	w := serveGroup(r, http.MethodGet, "/admin/users/7/status", "")
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
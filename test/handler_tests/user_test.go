@@ -4,6 +4,11 @@ import (
 	"7-solutions/dtos"
 	"7-solutions/handlers"
 	"7-solutions/models"
+	"7-solutions/policy"
+	"7-solutions/repositories"
+	"7-solutions/utils"
+	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -18,28 +23,28 @@ type MockUserService struct {
 	mock.Mock
 }
 
-func (m *MockUserService) CreateUser(userDto *dtos.UserRegister) (*dtos.UserResponse, error) {
-	args := m.Called(userDto)
+func (m *MockUserService) CreateUser(ctx context.Context, userDto *dtos.UserRegister) (*dtos.UserResponse, error) {
+	args := m.Called(ctx, userDto)
 	return args.Get(0).(*dtos.UserResponse), args.Error(1)
 }
 
-func (m *MockUserService) GetUserByID(id int) (*dtos.UserResponse, error) {
-	args := m.Called(id)
+func (m *MockUserService) GetUserByID(ctx context.Context, id int) (*dtos.UserResponse, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(*dtos.UserResponse), args.Error(1)
 }
 
-func (m *MockUserService) GetAllUsers() ([]dtos.UserResponse, error) {
-	args := m.Called()
+func (m *MockUserService) GetAllUsers(ctx context.Context, filter *dtos.UserFilter) ([]dtos.UserResponse, error) {
+	args := m.Called(ctx, filter)
 	return args.Get(0).([]dtos.UserResponse), args.Error(1)
 }
 
-func (m *MockUserService) UpdateUser(id int, userDto *dtos.UserUpdate) (*dtos.UserResponse, error) {
-	args := m.Called(id, userDto)
+func (m *MockUserService) UpdateUser(ctx context.Context, id int, userDto *dtos.UserUpdate) (*dtos.UserResponse, error) {
+	args := m.Called(ctx, id, userDto)
 	return args.Get(0).(*dtos.UserResponse), args.Error(1)
 }
 
-func (m *MockUserService) DeleteUser(id int) error {
-	args := m.Called(id)
+func (m *MockUserService) DeleteUser(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
@@ -51,7 +56,7 @@ func TestGetUserByID(t *testing.T) {
 	r.GET("/users/:id", h.GetUserByID)
 
 	user := &models.User{ID: 1, Name: "Test", Email: "test@example.com"}
-	mockService.On("GetUserByID", 1).Return(&dtos.UserResponse{
+	mockService.On("GetUserByID", mock.Anything, 1).Return(&dtos.UserResponse{
 		ID:        user.ID,
 		Name:      user.Name,
 		Email:     user.Email,
@@ -73,7 +78,7 @@ func TestDeleteUser(t *testing.T) {
 	h := handlers.NewUserHandler(mockService)
 	r.DELETE("/users/:id", h.DeleteUser)
 
-	mockService.On("DeleteUser", 1).Return(nil)
+	mockService.On("DeleteUser", mock.Anything, 1).Return(nil)
 
 	req, _ := http.NewRequest(http.MethodDelete, "/users/1", nil)
 	w := httptest.NewRecorder()
@@ -82,6 +87,40 @@ func TestDeleteUser(t *testing.T) {
 	assert.Equal(t, 204, w.Code)
 }
 
+func TestDeleteUser_OtherTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	mockService := new(MockUserService)
+	h := handlers.NewUserHandler(mockService)
+	r.DELETE("/users/:id", h.DeleteUser)
+
+	mockService.On("DeleteUser", mock.Anything, 7).Return(repositories.ErrUserNotFound)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/users/7", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestUpdateUser_PolicyDenied(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	mockService := new(MockUserService)
+	h := handlers.NewUserHandler(mockService)
+	r.PUT("/users/:id", h.UpdateUser)
+
+	mockService.On("UpdateUser", mock.Anything, 1, mock.Anything).Return((*dtos.UserResponse)(nil), fmt.Errorf("%w: users:update on field email", policy.ErrDenied))
+
+	req, _ := http.NewRequest(http.MethodPut, "/users/1", strings.NewReader(`{"name":"User","email":"new@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 403, w.Code)
+	assert.Contains(t, w.Body.String(), "field email")
+}
+
 // เพิ่มได้อีกเช่น UpdateUser, GetAllUsers, CreateUser
 func TestUpdateUser(t *testing.T) {
 	gin.SetMode(gin.TestMode)
@@ -91,7 +130,7 @@ func TestUpdateUser(t *testing.T) {
 	r.PUT("/users/:id", h.UpdateUser)
 
 	userUpdate := &dtos.UserUpdate{Name: "Updated User", Email: "test@example.com"}
-	mockService.On("UpdateUser", 1, userUpdate).Return(&dtos.UserResponse{ID: 1, Name: "Updated User", Email: "test@example.com"}, nil)
+	mockService.On("UpdateUser", mock.Anything, 1, userUpdate).Return(&dtos.UserResponse{ID: 1, Name: "Updated User", Email: "test@example.com"}, nil)
 	payload := `{"name":"Updated User","email":"test@example.com"}`
 	req, _ := http.NewRequest(http.MethodPut, "/users/1", strings.NewReader(payload))
 	req.Header.Set("Content-Type", "application/json")
@@ -99,7 +138,7 @@ func TestUpdateUser(t *testing.T) {
 	r.ServeHTTP(w, req)
 	assert.Equal(t, 200, w.Code)
 	assert.Contains(t, w.Body.String(), "Updated User")
-	mockService.AssertCalled(t, "UpdateUser", 1, userUpdate)
+	mockService.AssertCalled(t, "UpdateUser", mock.Anything, 1, userUpdate)
 }
 
 func TestGetAllUsers(t *testing.T) {
@@ -113,14 +152,14 @@ func TestGetAllUsers(t *testing.T) {
 		{ID: 1, Name: "User1", Email: "test@example.com"},
 		{ID: 2, Name: "User2", Email: "test2@example.com"},
 	}
-	mockService.On("GetAllUsers").Return(users, nil)
+	mockService.On("GetAllUsers", mock.Anything, &dtos.UserFilter{}).Return(users, nil)
 	req, _ := http.NewRequest(http.MethodGet, "/users", nil)
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 	assert.Equal(t, 200, w.Code)
 	assert.Contains(t, w.Body.String(), "User1")
 	assert.Contains(t, w.Body.String(), "User2")
-	mockService.AssertCalled(t, "GetAllUsers")
+	mockService.AssertCalled(t, "GetAllUsers", mock.Anything, &dtos.UserFilter{})
 }
 
 func TestCreateUser(t *testing.T) {
@@ -131,7 +170,7 @@ func TestCreateUser(t *testing.T) {
 	r.POST("/users", h.CreateUser)
 
 	userDto := &dtos.UserRegister{Name: "New User", Email: "test@example.com", Password: "password123"}
-	mockService.On("CreateUser", userDto).Return(&dtos.UserResponse{ID: 1, Name: "New User", Email: "test@example.com"}, nil)
+	mockService.On("CreateUser", mock.Anything, userDto).Return(&dtos.UserResponse{ID: 1, Name: "New User", Email: "test@example.com"}, nil)
 	payload := `{"name":"New User","email":"test@example.com","password":"password123"}`
 	req, _ := http.NewRequest(http.MethodPost, "/users", strings.NewReader(payload))
 	req.Header.Set("Content-Type", "application/json")
@@ -139,5 +178,36 @@ func TestCreateUser(t *testing.T) {
 	r.ServeHTTP(w, req)
 	assert.Equal(t, 201, w.Code)
 	assert.Contains(t, w.Body.String(), "New User")
-	mockService.AssertCalled(t, "CreateUser", userDto)
+	mockService.AssertCalled(t, "CreateUser", mock.Anything, userDto)
+}
+
+func TestGetAllUsers_Timeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	mockService := new(MockUserService)
+	h := handlers.NewUserHandler(mockService)
+	r.GET("/users", h.GetAllUsers)
+
+	err := fmt.Errorf("failed to retrieve users: %w", utils.ErrTimeout)
+	mockService.On("GetAllUsers", mock.Anything, mock.Anything).Return([]dtos.UserResponse(nil), err)
+	req, _ := http.NewRequest(http.MethodGet, "/users", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
+func TestDeleteUser_Canceled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	mockService := new(MockUserService)
+	h := handlers.NewUserHandler(mockService)
+	r.DELETE("/users/:id", h.DeleteUser)
+
+	mockService.On("DeleteUser", mock.Anything, 1).Return(utils.ErrCanceled)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/users/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, handlers.StatusClientClosedRequest, w.Code)
 }
@@ -0,0 +1,85 @@
+package logging_test
+
+import (
+	"7-solutions/logging"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogger_RedactsSensitiveFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.NewLogger(&buf, logging.Config{Level: slog.LevelInfo, Format: logging.FormatJSON})
+
+	logger.Info("login",
+		"email", "test@example.com",
+		"password", "hunter2",
+		"Authorization", "Bearer abc",
+		slog.Group("body", "access_token", "xyz", "name", "Test"),
+	)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "test@example.com", line["email"])
+	assert.Equal(t, "[REDACTED]", line["password"])
+	assert.Equal(t, "[REDACTED]", line["Authorization"])
+	body := line["body"].(map[string]any)
+	assert.Equal(t, "[REDACTED]", body["access_token"])
+	assert.Equal(t, "Test", body["name"])
+	assert.NotContains(t, buf.String(), "hunter2")
+}
+
+func TestNewLogger_RespectsLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.NewLogger(&buf, logging.Config{Level: slog.LevelWarn, Format: logging.FormatText})
+
+	logger.Info("hidden")
+	logger.Warn("shown")
+
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "shown")
+}
+
+func TestFromContext_AddsRequestAndUserID(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.NewLogger(&buf, logging.Config{Level: slog.LevelInfo, Format: logging.FormatJSON}))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	ctx := logging.WithUserID(logging.WithRequestID(context.Background(), "req-1"), "42")
+	logging.Audit(ctx, "user.delete", "target_user_id", 7)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "req-1", line["request_id"])
+	assert.Equal(t, "42", line["user_id"])
+	assert.Equal(t, "user.delete", line["action"])
+	assert.Equal(t, true, line["audit"])
+}
+
+func TestAudit_PassesEventsToSink(t *testing.T) {
+	var events []logging.AuditEvent
+	logging.SetAuditSink(func(ctx context.Context, event logging.AuditEvent) {
+		events = append(events, event)
+	})
+	t.Cleanup(func() { logging.SetAuditSink(nil) })
+
+	ctx := logging.WithActorID(logging.WithUserID(context.Background(), "42"), "1")
+	logging.Audit(ctx, "auth.login_failed", "email", "ann@example.com", "password", "hunter2", "error", errors.New("bad credentials"))
+
+	require.Len(t, events, 1)
+	assert.Equal(t, "auth.login_failed", events[0].Action)
+	assert.Equal(t, "42", events[0].UserID)
+	assert.Equal(t, "1", events[0].ActorID)
+	assert.Equal(t, map[string]any{"email": "ann@example.com", "password": "[REDACTED]", "error": "bad credentials"}, events[0].Attrs)
+
+	logging.SetAuditSink(nil)
+	logging.Audit(ctx, "user.delete")
+	assert.Len(t, events, 1)
+}
@@ -0,0 +1,107 @@
+package mailer_test
+
+import (
+	"7-solutions/mailer"
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts one connection, speaks just enough SMTP for
+// net/smtp and returns the DATA section it received.
+func fakeSMTPServer(t *testing.T) (host, port string, data <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch command := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(command, "EHLO"):
+				reply("250 localhost")
+			case command == "DATA":
+				reply("354 go ahead")
+				var b strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					b.WriteString(line)
+				}
+				received <- b.String()
+				reply("250 queued")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	host, port, err = net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	return host, port, received
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	host, port, data := fakeSMTPServer(t)
+	m := mailer.New(mailer.Config{Host: host, Port: port, From: "no-reply@example.com"})
+
+	err := m.Send(context.Background(), mailer.Message{
+		To:      "new@example.com",
+		Subject: "Welcome\r\nBcc: attacker@example.com",
+		Body:    "line one\nline two",
+	})
+	require.NoError(t, err)
+
+	select {
+	case message := <-data:
+		assert.Contains(t, message, "To: new@example.com\r\n")
+		assert.Contains(t, message, "Subject: WelcomeBcc: attacker@example.com\r\n")
+		assert.NotContains(t, message, "\r\nBcc:")
+		assert.Contains(t, message, "line one\r\nline two\r\n")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+}
+
+func TestSMTPMailer_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+
+	m := mailer.New(mailer.Config{Host: host, Port: port, From: "no-reply@example.com"})
+	err = m.Send(context.Background(), mailer.Message{To: "new@example.com", Subject: "Hi", Body: "Hi"})
+	assert.ErrorIs(t, err, mailer.ErrDelivery)
+}
+
+func TestNew_WithoutHostLogs(t *testing.T) {
+	m := mailer.New(mailer.Config{})
+
+	assert.IsType(t, mailer.LogMailer{}, m)
+	assert.NoError(t, m.Send(context.Background(), mailer.Message{To: "new@example.com"}))
+}
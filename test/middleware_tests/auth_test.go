@@ -0,0 +1,291 @@
+package middleware_test
+
+import (
+	"7-solutions/logging"
+	"7-solutions/middleware"
+	"7-solutions/models"
+	"7-solutions/policy"
+	"7-solutions/services"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeAPIKeyAuthenticator map[string]*models.APIKey
+
+func (f fakeAPIKeyAuthenticator) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	if apiKey, ok := f[key]; ok {
+		return apiKey, nil
+	}
+	return nil, services.ErrInvalidAPIKey
+}
+
+func newScopedRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	apiKeys := fakeAPIKeyAuthenticator{
+		"reader": {ID: primitive.NewObjectID(), Scopes: []string{models.ScopeUsersRead}, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	r := gin.New()
+	r.Use(middleware.AuthenticationMiddleware(apiKeys, nil, nil, nil))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/users", middleware.RequireScope(models.ScopeUsersRead), ok)
+	r.POST("/users", middleware.RequireScope(models.ScopeUsersWrite), ok)
+	r.GET("/admin", middleware.RequireRole(models.RoleAdmin), ok)
+	return r
+}
+
+func serveWithAuth(r *gin.Engine, method, path, authorization string) int {
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", authorization)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAuthenticationMiddleware_APIKeyScopes(t *testing.T) {
+	r := newScopedRouter()
+
+	assert.Equal(t, http.StatusOK, serveWithAuth(r, http.MethodGet, "/users", "ApiKey reader"))
+	assert.Equal(t, http.StatusForbidden, serveWithAuth(r, http.MethodPost, "/users", "ApiKey reader"))
+	assert.Equal(t, http.StatusForbidden, serveWithAuth(r, http.MethodGet, "/admin", "ApiKey reader"))
+	assert.Equal(t, http.StatusUnauthorized, serveWithAuth(r, http.MethodGet, "/users", "ApiKey unknown"))
+}
+
+func TestAuthenticationMiddleware_JWTRoles(t *testing.T) {
+	r := newScopedRouter()
+
+	userToken, _ := utils.GenerateSessionToken(1, "User", "user@example.com", models.RoleUser, tenant.DefaultID, "session")
+	adminToken, _ := utils.GenerateSessionToken(2, "Admin", "admin@example.com", models.RoleAdmin, tenant.DefaultID, "session")
+
+	assert.Equal(t, http.StatusOK, serveWithAuth(r, http.MethodPost, "/users", "Bearer "+userToken))
+	assert.Equal(t, http.StatusForbidden, serveWithAuth(r, http.MethodGet, "/admin", "Bearer "+userToken))
+	assert.Equal(t, http.StatusOK, serveWithAuth(r, http.MethodGet, "/admin", "Bearer "+adminToken))
+}
+
+func TestAuthenticationMiddleware_APIKeysDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", middleware.AuthenticationMiddleware(nil, nil, nil, nil), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	assert.Equal(t, http.StatusUnauthorized, serveWithAuth(r, http.MethodGet, "/health", "ApiKey reader"))
+}
+
+func TestAuthenticationMiddleware_Tenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	apiKeys := fakeAPIKeyAuthenticator{
+		"acme":   {ID: primitive.NewObjectID(), TenantID: "acme", ExpiresAt: time.Now().Add(time.Hour)},
+		"legacy": {ID: primitive.NewObjectID(), ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	r := gin.New()
+	r.Use(middleware.AuthenticationMiddleware(apiKeys, nil, nil, nil))
+	r.GET("/tenant", func(c *gin.Context) {
+		id, all := tenant.FromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"tenantId": c.GetString("tenantID"), "context": id, "all": all})
+	})
+
+	serve := func(authorization string) string {
+		req, _ := http.NewRequest(http.MethodGet, "/tenant", nil)
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	userToken, _ := utils.GenerateSessionToken(1, "User", "user@acme.example", models.RoleUser, "acme", "session")
+	superToken, _ := utils.GenerateSessionToken(2, "Root", "root@example.com", models.RoleSuperAdmin, tenant.DefaultID, "session")
+	clientToken, _ := utils.GenerateOAuthToken("client", "globex", "users:read", nil, time.Minute)
+
+	assert.JSONEq(t, `{"tenantId":"acme","context":"acme","all":false}`, serve("Bearer "+userToken))
+	assert.JSONEq(t, `{"tenantId":"default","context":"default","all":true}`, serve("Bearer "+superToken))
+	assert.JSONEq(t, `{"tenantId":"globex","context":"globex","all":false}`, serve("Bearer "+clientToken))
+	assert.JSONEq(t, `{"tenantId":"acme","context":"acme","all":false}`, serve("ApiKey acme"))
+	assert.JSONEq(t, `{"tenantId":"default","context":"default","all":false}`, serve("ApiKey legacy"))
+}
+
+func TestAuthenticationMiddleware_PolicySubject(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	apiKeyID := primitive.NewObjectID()
+	apiKeys := fakeAPIKeyAuthenticator{
+		"reader": {ID: apiKeyID, TenantID: "acme", Scopes: []string{models.ScopeUsersRead}, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	r := gin.New()
+	r.Use(middleware.AuthenticationMiddleware(apiKeys, nil, nil, nil))
+	r.GET("/subject", func(c *gin.Context) {
+		subject, _ := policy.SubjectFromContext(c.Request.Context())
+		c.JSON(http.StatusOK, subject)
+	})
+
+	serve := func(authorization string) string {
+		req, _ := http.NewRequest(http.MethodGet, "/subject", nil)
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	adminToken, _ := utils.GenerateSessionToken(2, "Admin", "admin@acme.example", models.RoleAdmin, "acme", "session")
+	clientToken, _ := utils.GenerateOAuthToken("client", "globex", "users:read", nil, time.Minute)
+
+	assert.JSONEq(t, `{"id":"2","role":"admin","tenantId":"acme"}`, serve("Bearer "+adminToken))
+	assert.JSONEq(t, `{"id":"client:client","tenantId":"globex","scopes":["users:read"]}`, serve("Bearer "+clientToken))
+	assert.JSONEq(t, `{"id":"apikey:`+apiKeyID.Hex()+`","tenantId":"acme","scopes":["users:read"]}`, serve("ApiKey reader"))
+}
+
+func TestAuthenticationMiddleware_Impersonation(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.NewLogger(&buf, logging.Config{Level: slog.LevelInfo, Format: logging.FormatJSON}))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.AuthenticationMiddleware(nil, nil, nil, nil))
+	r.GET("/me", func(c *gin.Context) {
+		subject, _ := policy.SubjectFromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"userId": c.GetString("userID"), "actorId": c.GetString("actorID"), "subjectActor": subject.ActorID})
+	})
+	r.POST("/api-keys", middleware.RejectImpersonation(), func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	serve := func(method, path, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	token, _ := utils.GenerateImpersonationToken(7, "User", "user@example.com", models.RoleUser, tenant.DefaultID, "2", time.Minute)
+	w := serve(http.MethodGet, "/me", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"userId":"7","actorId":"2","subjectActor":"2"}`, w.Body.String())
+	assert.Contains(t, buf.String(), `"action":"impersonation.request"`)
+	assert.Contains(t, buf.String(), `"actor_id":"2"`)
+	assert.Contains(t, buf.String(), `"path":"/me"`)
+
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/api-keys", token).Code)
+
+	regular, _ := utils.GenerateSessionToken(7, "User", "user@example.com", models.RoleUser, tenant.DefaultID, "session")
+	assert.Equal(t, http.StatusCreated, serve(http.MethodPost, "/api-keys", regular).Code)
+
+	malformed, _ := utils.GenerateImpersonationToken(7, "User", "user@example.com", models.RoleUser, tenant.DefaultID, "", time.Minute)
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/me", malformed).Code)
+}
+
+func TestRequireRole_SuperAdmin(t *testing.T) {
+	r := newScopedRouter()
+
+	superToken, _ := utils.GenerateSessionToken(3, "Root", "root@example.com", models.RoleSuperAdmin, tenant.DefaultID, "session")
+
+	assert.Equal(t, http.StatusOK, serveWithAuth(r, http.MethodGet, "/admin", "Bearer "+superToken))
+}
+
+type fakeSessionValidator map[string]bool
+
+func (f fakeSessionValidator) ValidateSession(ctx context.Context, sessionID string) error {
+	if !f[sessionID] {
+		return services.ErrSessionRevoked
+	}
+	return nil
+}
+
+func TestAuthenticationMiddleware_Sessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	sessions := fakeSessionValidator{"active": true, "revoked": false}
+	r.GET("/me", middleware.AuthenticationMiddleware(nil, nil, sessions, nil), middleware.RequireLogin(), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("sessionID"))
+	})
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	active, _ := utils.GenerateSessionToken(7, "User", "user@example.com", models.RoleUser, tenant.DefaultID, "active")
+	w := serve(active)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "active", w.Body.String())
+
+	revoked, _ := utils.GenerateSessionToken(7, "User", "user@example.com", models.RoleUser, tenant.DefaultID, "revoked")
+	assert.Equal(t, http.StatusUnauthorized, serve(revoked).Code)
+
+	// Tokens without a sid, such as impersonation tokens or login tokens
+	// issued before sessions existed, skip the session check.
+	unbound, _ := utils.GenerateImpersonationToken(7, "User", "user@example.com", models.RoleUser, tenant.DefaultID, "2", time.Minute)
+	assert.Equal(t, http.StatusOK, serve(unbound).Code)
+}
+
+func TestRequireLogin_RejectsAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	apiKeys := fakeAPIKeyAuthenticator{
+		"reader": {ID: primitive.NewObjectID(), Scopes: []string{models.ScopeUsersRead}, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	r := gin.New()
+	r.GET("/me", middleware.AuthenticationMiddleware(apiKeys, nil, nil, nil), middleware.RequireLogin(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	admin, _ := utils.GenerateSessionToken(2, "Admin", "admin@example.com", models.RoleAdmin, tenant.DefaultID, "session")
+	assert.Equal(t, http.StatusOK, serveWithAuth(r, http.MethodGet, "/me", "Bearer "+admin))
+	assert.Equal(t, http.StatusForbidden, serveWithAuth(r, http.MethodGet, "/me", "ApiKey reader"))
+}
+
+func TestRequireSelfOrRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	apiKeys := fakeAPIKeyAuthenticator{
+		"reader": {ID: primitive.NewObjectID(), Scopes: []string{models.ScopeUsersRead}, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	r := gin.New()
+	r.GET("/users/:id/data", middleware.AuthenticationMiddleware(apiKeys, nil, nil, nil), middleware.RequireSelfOrRole(models.RoleAdmin),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	user, _ := utils.GenerateSessionToken(7, "User", "user@example.com", models.RoleUser, tenant.DefaultID, "session")
+	admin, _ := utils.GenerateSessionToken(2, "Admin", "admin@example.com", models.RoleAdmin, tenant.DefaultID, "session")
+	assert.Equal(t, http.StatusOK, serveWithAuth(r, http.MethodGet, "/users/7/data", "Bearer "+user))
+	assert.Equal(t, http.StatusForbidden, serveWithAuth(r, http.MethodGet, "/users/8/data", "Bearer "+user))
+	assert.Equal(t, http.StatusOK, serveWithAuth(r, http.MethodGet, "/users/8/data", "Bearer "+admin))
+	assert.Equal(t, http.StatusForbidden, serveWithAuth(r, http.MethodGet, "/users/8/data", "ApiKey reader"))
+}
+
+// fakeAccountChecker reports the users mapped to false as inactive.
+type fakeAccountChecker map[string]bool
+
+func (f fakeAccountChecker) CheckAccount(ctx context.Context, userID string) error {
+	if active, ok := f[userID]; ok && !active {
+		return services.ErrAccountInactive
+	}
+	return nil
+}
+
+func TestAuthenticationMiddleware_InactiveAccounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	apiKeys := fakeAPIKeyAuthenticator{
+		"reader": {ID: primitive.NewObjectID(), Scopes: []string{models.ScopeUsersRead}, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	accounts := fakeAccountChecker{"7": true, "8": false, "2": true, "3": false}
+	r := gin.New()
+	r.GET("/me", middleware.AuthenticationMiddleware(apiKeys, nil, nil, accounts), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	active, _ := utils.GenerateSessionToken(7, "User", "user@example.com", models.RoleUser, tenant.DefaultID, "session")
+	suspended, _ := utils.GenerateSessionToken(8, "User", "other@example.com", models.RoleUser, tenant.DefaultID, "session")
+	assert.Equal(t, http.StatusOK, serveWithAuth(r, http.MethodGet, "/me", "Bearer "+active))
+	assert.Equal(t, http.StatusUnauthorized, serveWithAuth(r, http.MethodGet, "/me", "Bearer "+suspended))
+	assert.Equal(t, http.StatusOK, serveWithAuth(r, http.MethodGet, "/me", "ApiKey reader"))
+
+	// Impersonation tokens stop working when either user is inactive.
+	byActive, _ := utils.GenerateImpersonationToken(7, "User", "user@example.com", models.RoleUser, tenant.DefaultID, "2", time.Minute)
+	byInactive, _ := utils.GenerateImpersonationToken(7, "User", "user@example.com", models.RoleUser, tenant.DefaultID, "3", time.Minute)
+	assert.Equal(t, http.StatusOK, serveWithAuth(r, http.MethodGet, "/me", "Bearer "+byActive))
+	assert.Equal(t, http.StatusUnauthorized, serveWithAuth(r, http.MethodGet, "/me", "Bearer "+byInactive))
+}
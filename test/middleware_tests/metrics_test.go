@@ -0,0 +1,60 @@
+package middleware_test
+
+import (
+	"7-solutions/metrics"
+	"7-solutions/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware_UsesRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.MetricsMiddleware())
+	r.GET("/things/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	before := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues("GET", "/things/:id", "200"))
+
+	for _, path := range []string{"/things/1", "/things/2"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	after := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues("GET", "/things/:id", "200"))
+	assert.Equal(t, float64(2), after-before)
+}
+
+func TestAuthenticationMiddleware_CountsFailureReasons(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/protected", middleware.AuthenticationMiddleware(nil, nil, nil, nil), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		header string
+		reason string
+	}{
+		{"", "missing_token"},
+		{"Token abc", "invalid_format"},
+		{"Bearer not-a-jwt", "malformed"},
+	}
+
+	for _, tc := range cases {
+		before := testutil.ToFloat64(metrics.TokenVerificationFailuresTotal.WithLabelValues(tc.reason))
+
+		req, _ := http.NewRequest(http.MethodGet, "/protected", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		after := testutil.ToFloat64(metrics.TokenVerificationFailuresTotal.WithLabelValues(tc.reason))
+		assert.Equal(t, float64(1), after-before, tc.reason)
+	}
+}
@@ -0,0 +1,100 @@
+package middleware_test
+
+import (
+	"7-solutions/middleware"
+	"7-solutions/ratelimit"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store down")
+}
+
+func newRateLimitedRouter(store ratelimit.Store, keyFunc middleware.RateLimitKeyFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RateLimitMiddleware("test", store, ratelimit.Limit{Rate: 1.0 / 60, Burst: 2}, keyFunc))
+	r.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestRateLimitMiddleware_RejectsWhenExhausted(t *testing.T) {
+	r := newRateLimitedRouter(ratelimit.NewMemoryStore(), middleware.RateLimitByIP)
+
+	var codes []int
+	var last *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+		last = httptest.NewRecorder()
+		r.ServeHTTP(last, req)
+		codes = append(codes, last.Code)
+	}
+
+	assert.Equal(t, []int{200, 200, 429}, codes)
+	assert.Equal(t, "2", last.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", last.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", last.Header().Get("Retry-After"))
+	assert.Equal(t, "2;w=120", last.Header().Get("RateLimit-Policy"))
+}
+
+func TestRateLimitMiddleware_KeysByAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	apiKeys := fakeAPIKeyAuthenticator{
+		"key-a": {ID: primitive.NewObjectID(), ExpiresAt: time.Now().Add(time.Hour)},
+		"key-b": {ID: primitive.NewObjectID(), ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	r := gin.New()
+	r.Use(middleware.AuthenticationMiddleware(apiKeys, nil, nil, nil))
+	r.Use(middleware.RateLimitMiddleware("test", ratelimit.NewMemoryStore(), ratelimit.Limit{Rate: 1.0 / 60, Burst: 2}, middleware.RateLimitByAPIKey))
+	r.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(key string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+		req.Header.Set("Authorization", "ApiKey "+key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	send("key-a")
+	send("key-a")
+	assert.Equal(t, 429, send("key-a"))
+	assert.Equal(t, 200, send("key-b"))
+}
+
+func TestRateLimitMiddleware_APIKeyFallsBackToIP(t *testing.T) {
+	r := newRateLimitedRouter(ratelimit.NewMemoryStore(), middleware.RateLimitByAPIKey)
+
+	send := func(key string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+		req.Header.Set("Authorization", "ApiKey "+key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	send("forged-1")
+	send("forged-2")
+	assert.Equal(t, 429, send("forged-3"))
+}
+
+func TestRateLimitMiddleware_FailsOpen(t *testing.T) {
+	r := newRateLimitedRouter(failingStore{}, middleware.RateLimitByIP)
+
+	req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+}
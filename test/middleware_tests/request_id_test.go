@@ -0,0 +1,108 @@
+package middleware_test
+
+import (
+	"7-solutions/logging"
+	"7-solutions/middleware"
+	"7-solutions/utils"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRequestIDRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RequestIDMiddleware(), middleware.LoggerMiddleware())
+	r.GET("/fail", func(c *gin.Context) {
+		c.JSON(http.StatusBadRequest, utils.ErrorBody(c, "bad request"))
+	})
+	return r
+}
+
+func TestRequestIDMiddleware_GeneratesID(t *testing.T) {
+	r := newRequestIDRouter()
+
+	req, _ := http.NewRequest(http.MethodGet, "/fail", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	requestID := w.Header().Get(middleware.RequestIDHeader)
+	assert.Len(t, requestID, 32)
+
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, requestID, body["requestId"])
+}
+
+func TestRequestIDMiddleware_EchoesIncomingID(t *testing.T) {
+	r := newRequestIDRouter()
+
+	req, _ := http.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set(middleware.RequestIDHeader, "upstream-123")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "upstream-123", w.Header().Get(middleware.RequestIDHeader))
+}
+
+func TestRequestIDMiddleware_ReplacesInvalidID(t *testing.T) {
+	r := newRequestIDRouter()
+
+	req, _ := http.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set(middleware.RequestIDHeader, "bad id\nwith newline")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.NotEqual(t, "bad id\nwith newline", w.Header().Get(middleware.RequestIDHeader))
+	assert.Len(t, w.Header().Get(middleware.RequestIDHeader), 32)
+}
+
+func TestLoggerMiddleware_LogsRequestFields(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.NewLogger(&buf, logging.Config{Level: slog.LevelInfo, Format: logging.FormatJSON}))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	r := newRequestIDRouter()
+	req, _ := http.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "req-42", line["request_id"])
+	assert.Equal(t, "/fail", line["route"])
+	assert.Equal(t, float64(400), line["status"])
+	assert.Equal(t, "WARN", line["level"])
+	assert.Contains(t, line, "latency_ms")
+}
+
+func TestRecoveryMiddleware_LogsStack(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.NewLogger(&buf, logging.Config{Level: slog.LevelInfo, Format: logging.FormatJSON}))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RequestIDMiddleware(), middleware.RecoveryMiddleware())
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/panic", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "panic recovered", line["msg"])
+	assert.Equal(t, "boom", line["panic"])
+	assert.Contains(t, line["stack"], "request_id_test.go")
+}
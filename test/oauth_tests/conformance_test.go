@@ -0,0 +1,532 @@
+package oauth_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/handlers"
+	"7-solutions/middleware"
+	"7-solutions/models"
+	"7-solutions/services"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryOAuthRepository is an in-memory repositories.OAuthRepository.
+type memoryOAuthRepository struct {
+	mu      sync.Mutex
+	clients map[string]*models.OAuthClient
+	codes   map[string]*models.AuthorizationCode
+	revoked map[string]time.Time
+}
+
+func newMemoryOAuthRepository() *memoryOAuthRepository {
+	return &memoryOAuthRepository{
+		clients: map[string]*models.OAuthClient{},
+		codes:   map[string]*models.AuthorizationCode{},
+		revoked: map[string]time.Time{},
+	}
+}
+
+func (r *memoryOAuthRepository) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients[client.ClientID] = client
+	return nil
+}
+
+func (r *memoryOAuthRepository) GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if client, ok := r.clients[clientID]; ok {
+		return client, nil
+	}
+	return nil, errors.New("oauth client not found")
+}
+
+func (r *memoryOAuthRepository) GetAllClients(ctx context.Context) ([]models.OAuthClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clients := []models.OAuthClient{}
+	for _, client := range r.clients {
+		clients = append(clients, *client)
+	}
+	return clients, nil
+}
+
+func (r *memoryOAuthRepository) CreateAuthorizationCode(ctx context.Context, code *models.AuthorizationCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codes[code.CodeHash] = code
+	return nil
+}
+
+func (r *memoryOAuthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string, usedAt time.Time) (*models.AuthorizationCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code, ok := r.codes[codeHash]
+	if !ok || code.UsedAt != nil {
+		return nil, errors.New("authorization code not found")
+	}
+	code.UsedAt = &usedAt
+	return code, nil
+}
+
+func (r *memoryOAuthRepository) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revoked[jti] = expiresAt
+	return nil
+}
+
+func (r *memoryOAuthRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.revoked[jti]
+	return ok, nil
+}
+
+// stubAuthService knows a single user, alice@example.com / secret. Signing in
+// fails, since consent must not start a login session.
+type stubAuthService struct{}
+
+func (stubAuthService) RegisterUser(ctx context.Context, userDto *dtos.UserRegister) error {
+	return nil
+}
+
+func (stubAuthService) AuthenticateUser(ctx context.Context, input *dtos.UserAuthenticate) (*string, error) {
+	return nil, errors.New("unexpected login")
+}
+
+func (stubAuthService) VerifyCredentials(ctx context.Context, input *dtos.UserAuthenticate) (*models.User, error) {
+	if input.Email != "alice@example.com" || input.Password != "secret" {
+		return nil, errors.New("authentication failed")
+	}
+	return &models.User{ID: 7, Name: "Alice", Email: input.Email, Role: models.RoleAdmin, TenantID: tenant.DefaultID}, nil
+}
+
+// stubUserRepository stores alice as user 7 with a verified email that
+// differs in case from the one typed on the login page.
+type stubUserRepository struct{}
+
+func (stubUserRepository) CreateUser(ctx context.Context, userDto *dtos.UserRegister) (*dtos.UserResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (stubUserRepository) GetUserByID(ctx context.Context, id int) (*dtos.UserResponse, error) {
+	if id != 7 {
+		return nil, errors.New("user not found")
+	}
+	return &dtos.UserResponse{ID: 7, Name: "Alice Liddell", Email: "Alice@Example.com", EmailVerified: true}, nil
+}
+
+func (stubUserRepository) GetAllUsers(ctx context.Context, filter *dtos.UserFilter) ([]dtos.UserResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (stubUserRepository) UpdateUser(ctx context.Context, id int, userDto *dtos.UserUpdate) (*dtos.UserResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (stubUserRepository) DeleteUser(ctx context.Context, id int) error {
+	return errors.New("not implemented")
+}
+
+func (stubUserRepository) CountUsers(ctx context.Context) (int64, error) {
+	return 1, nil
+}
+
+// stubSessionService treats every session as active.
+type stubSessionService struct{}
+
+func (stubSessionService) GetSessions(ctx context.Context, userID int, currentID string) ([]dtos.SessionResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (stubSessionService) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	return errors.New("not implemented")
+}
+
+func (stubSessionService) RevokeAllSessions(ctx context.Context, userID int) (int64, error) {
+	return 0, errors.New("not implemented")
+}
+
+func (stubSessionService) ValidateSession(ctx context.Context, sessionID string) error {
+	return nil
+}
+
+// stubAccountChecker treats every user but 8 as active.
+type stubAccountChecker struct{}
+
+func (stubAccountChecker) CheckAccount(ctx context.Context, userID string) error {
+	if userID == "8" {
+		return services.ErrAccountInactive
+	}
+	return nil
+}
+
+type oauthServer struct {
+	*httptest.Server
+	service      services.OAuthService
+	client       *http.Client
+	redirectURI  string
+	publicID     string
+	confidential *dtos.OAuthClientCreated
+}
+
+func newOAuthServer(t *testing.T) *oauthServer {
+	gin.SetMode(gin.TestMode)
+	service := services.NewOAuthService(newMemoryOAuthRepository(), stubUserRepository{}, stubAuthService{}, stubSessionService{}, stubAccountChecker{})
+	h := handlers.NewOAuthHandler(service)
+
+	r := gin.New()
+	auth := r.Group("/auth")
+	auth.GET("/authorize", h.AuthorizePage)
+	auth.POST("/authorize", h.Authorize)
+	auth.POST("/token", h.Token)
+	auth.POST("/introspect", h.Introspect)
+	auth.POST("/revoke", h.Revoke)
+
+	users := r.Group("/users", middleware.AuthenticationMiddleware(nil, service, nil, nil))
+	users.GET("/", middleware.RequireScope(models.ScopeUsersRead), func(c *gin.Context) {
+		c.JSON(200, gin.H{"userID": c.GetString("userID"), "clientID": c.GetString("clientID")})
+	})
+	users.POST("/", middleware.RequireScope(models.ScopeUsersWrite), func(c *gin.Context) { c.Status(201) })
+
+	oidc := handlers.NewOIDCHandler(service)
+	r.GET("/.well-known/openid-configuration", oidc.Discovery)
+	r.GET("/.well-known/jwks.json", oidc.JWKS)
+	r.GET("/userinfo", middleware.AuthenticationMiddleware(nil, service, nil, nil), middleware.RequireScope(models.ScopeOpenID), oidc.UserInfo)
+
+	s := &oauthServer{
+		Server:      httptest.NewServer(r),
+		service:     service,
+		redirectURI: "http://localhost:9999/callback",
+		client: &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}},
+	}
+	t.Cleanup(s.Close)
+
+	public, err := service.RegisterClient(context.Background(), "7", &dtos.OAuthClientCreate{
+		Name:         "Internal SPA",
+		RedirectURIs: []string{s.redirectURI},
+		GrantTypes:   []string{models.GrantTypeAuthorizationCode},
+		Scopes:       []string{models.ScopeUsersRead},
+		Public:       true,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, public.ClientSecret)
+	s.publicID = public.ClientID
+
+	s.confidential, err = service.RegisterClient(context.Background(), "7", &dtos.OAuthClientCreate{
+		Name:         "Batch",
+		RedirectURIs: []string{s.redirectURI},
+		GrantTypes:   []string{models.GrantTypeClientCredentials, models.GrantTypeAuthorizationCode},
+		Scopes:       []string{models.ScopeUsersRead, models.ScopeUsersWrite},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, s.confidential.ClientSecret)
+	return s
+}
+
+func (s *oauthServer) postForm(t *testing.T, path string, form url.Values, basicID, basicSecret string) (*http.Response, map[string]interface{}) {
+	req, err := http.NewRequest(http.MethodPost, s.URL+path, strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basicID != "" {
+		req.SetBasicAuth(url.QueryEscape(basicID), url.QueryEscape(basicSecret))
+	}
+	resp, err := s.client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body := map[string]interface{}{}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	return resp, body
+}
+
+func (s *oauthServer) get(t *testing.T, path, token string) *http.Response {
+	req, err := http.NewRequest(http.MethodGet, s.URL+path, nil)
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := s.client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func pkce(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (s *oauthServer) authorizeParams(clientID, verifier string) url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {s.redirectURI},
+		"scope":                 {models.ScopeUsersRead},
+		"state":                 {"xyz"},
+		"code_challenge":        {pkce(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+// authorize logs in as alice and returns the redirect back to the client.
+func (s *oauthServer) authorize(t *testing.T, params url.Values, email, password, action string) *url.URL {
+	form := url.Values{}
+	for key, values := range params {
+		form[key] = values
+	}
+	form.Set("email", email)
+	form.Set("password", password)
+	form.Set("action", action)
+
+	resp, _ := s.postForm(t, "/auth/authorize", form, "", "")
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location
+}
+
+func TestClientCredentialsGrant(t *testing.T) {
+	s := newOAuthServer(t)
+	id, secret := s.confidential.ClientID, s.confidential.ClientSecret
+
+	resp, body := s.postForm(t, "/auth/token", url.Values{"grant_type": {"client_credentials"}, "scope": {"users:read"}}, id, secret)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	assert.Equal(t, "Bearer", body["token_type"])
+	assert.Equal(t, "users:read", body["scope"])
+	assert.Equal(t, float64(3600), body["expires_in"])
+	token := body["access_token"].(string)
+
+	assert.Equal(t, http.StatusOK, s.get(t, "/users/", token).StatusCode)
+
+	req, _ := http.NewRequest(http.MethodPost, s.URL+"/users/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	write, err := s.client.Do(req)
+	require.NoError(t, err)
+	write.Body.Close()
+	assert.Equal(t, http.StatusForbidden, write.StatusCode)
+
+	t.Run("TestCredentialsInBody", func(t *testing.T) {
+		resp, body := s.postForm(t, "/auth/token", url.Values{
+			"grant_type": {"client_credentials"}, "client_id": {id}, "client_secret": {secret},
+		}, "", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "users:read users:write", body["scope"])
+	})
+
+	t.Run("TestInvalidSecret", func(t *testing.T) {
+		resp, body := s.postForm(t, "/auth/token", url.Values{"grant_type": {"client_credentials"}}, id, "wrong")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, "invalid_client", body["error"])
+		assert.NotEmpty(t, resp.Header.Get("WWW-Authenticate"))
+	})
+
+	t.Run("TestInvalidScope", func(t *testing.T) {
+		resp, body := s.postForm(t, "/auth/token", url.Values{"grant_type": {"client_credentials"}, "scope": {"users:admin"}}, id, secret)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "invalid_scope", body["error"])
+	})
+
+	t.Run("TestUnsupportedGrantType", func(t *testing.T) {
+		resp, body := s.postForm(t, "/auth/token", url.Values{"grant_type": {"password"}}, id, secret)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "unsupported_grant_type", body["error"])
+	})
+
+	t.Run("TestPublicClientCannotUseClientCredentials", func(t *testing.T) {
+		resp, body := s.postForm(t, "/auth/token", url.Values{"grant_type": {"client_credentials"}, "client_id": {s.publicID}}, "", "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "unauthorized_client", body["error"])
+	})
+}
+
+func TestAuthorizationCodeGrantWithPKCE(t *testing.T) {
+	s := newOAuthServer(t)
+	verifier := strings.Repeat("v", 50)
+	params := s.authorizeParams(s.publicID, verifier)
+
+	page := s.get(t, "/auth/authorize?"+params.Encode(), "")
+	require.Equal(t, http.StatusOK, page.StatusCode)
+	assert.Contains(t, page.Header.Get("Content-Type"), "text/html")
+	assert.Contains(t, page.Header.Get("Content-Security-Policy"), "form-action 'self' http://localhost:9999")
+
+	location := s.authorize(t, params, "alice@example.com", "secret", "approve")
+	assert.Equal(t, "xyz", location.Query().Get("state"))
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	exchange := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.redirectURI},
+		"code_verifier": {verifier},
+		"client_id":     {s.publicID},
+	}
+	resp, body := s.postForm(t, "/auth/token", exchange, "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "users:read", body["scope"])
+
+	claims, err := utils.VerifyToken(body["access_token"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, "7", claims["sub"])
+	assert.Equal(t, s.publicID, claims["client_id"])
+	assert.Nil(t, claims["role"], "delegated tokens must not carry the user's role")
+
+	t.Run("TestCodeIsSingleUse", func(t *testing.T) {
+		resp, body := s.postForm(t, "/auth/token", exchange, "", "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "invalid_grant", body["error"])
+	})
+
+	t.Run("TestWrongVerifier", func(t *testing.T) {
+		code := s.authorize(t, params, "alice@example.com", "secret", "approve").Query().Get("code")
+		resp, body := s.postForm(t, "/auth/token", url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {s.redirectURI},
+			"code_verifier": {strings.Repeat("x", 50)},
+			"client_id":     {s.publicID},
+		}, "", "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "invalid_grant", body["error"])
+	})
+
+	t.Run("TestCodeBoundToClient", func(t *testing.T) {
+		code := s.authorize(t, params, "alice@example.com", "secret", "approve").Query().Get("code")
+		resp, body := s.postForm(t, "/auth/token", url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {s.redirectURI},
+			"code_verifier": {verifier},
+		}, s.confidential.ClientID, s.confidential.ClientSecret)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "invalid_grant", body["error"])
+	})
+
+	t.Run("TestDenied", func(t *testing.T) {
+		location := s.authorize(t, params, "", "", "deny")
+		assert.Equal(t, "access_denied", location.Query().Get("error"))
+		assert.Equal(t, "xyz", location.Query().Get("state"))
+	})
+
+	t.Run("TestWrongPassword", func(t *testing.T) {
+		form := s.authorizeParams(s.publicID, verifier)
+		form.Set("email", "alice@example.com")
+		form.Set("password", "nope")
+		form.Set("action", "approve")
+		resp, _ := s.postForm(t, "/auth/authorize", form, "", "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("TestPKCERequired", func(t *testing.T) {
+		params := s.authorizeParams(s.publicID, verifier)
+		params.Del("code_challenge")
+		resp := s.get(t, "/auth/authorize?"+params.Encode(), "")
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+		location, _ := url.Parse(resp.Header.Get("Location"))
+		assert.Equal(t, "invalid_request", location.Query().Get("error"))
+	})
+
+	t.Run("TestUnregisteredRedirectURIIsNotFollowed", func(t *testing.T) {
+		params := s.authorizeParams(s.publicID, verifier)
+		params.Set("redirect_uri", "https://evil.example.com/callback")
+		resp := s.get(t, "/auth/authorize?"+params.Encode(), "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Location"))
+	})
+}
+
+func TestIntrospectionAndRevocation(t *testing.T) {
+	s := newOAuthServer(t)
+	id, secret := s.confidential.ClientID, s.confidential.ClientSecret
+
+	_, body := s.postForm(t, "/auth/token", url.Values{"grant_type": {"client_credentials"}}, id, secret)
+	token := body["access_token"].(string)
+
+	resp, body := s.postForm(t, "/auth/introspect", url.Values{"token": {token}}, id, secret)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, true, body["active"])
+	assert.Equal(t, id, body["client_id"])
+	assert.Equal(t, "Bearer", body["token_type"])
+	assert.NotZero(t, body["exp"])
+
+	_, body = s.postForm(t, "/auth/introspect", url.Values{"token": {"garbage"}}, id, secret)
+	assert.Equal(t, map[string]interface{}{"active": false}, body)
+
+	resp, body = s.postForm(t, "/auth/introspect", url.Values{"token": {token}, "client_id": {s.publicID}}, "", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "unauthorized_client", body["error"])
+
+	resp, _ = s.postForm(t, "/auth/revoke", url.Values{"token": {token}}, id, secret)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, body = s.postForm(t, "/auth/introspect", url.Values{"token": {token}}, id, secret)
+	assert.Equal(t, false, body["active"])
+	assert.Equal(t, http.StatusUnauthorized, s.get(t, "/users/", token).StatusCode)
+
+	t.Run("TestLoginTokensAreInactive", func(t *testing.T) {
+		loginToken, err := utils.GenerateSessionToken(7, "Alice", "alice@example.com", models.RoleAdmin, tenant.DefaultID, "session")
+		require.NoError(t, err)
+
+		_, body := s.postForm(t, "/auth/introspect", url.Values{"token": {loginToken}}, id, secret)
+		assert.Equal(t, map[string]interface{}{"active": false}, body)
+	})
+
+	t.Run("TestTokensOfInactiveUsersAreInactive", func(t *testing.T) {
+		owner := jwt.MapClaims{"sub": "8", "email": "bob@example.com", "name": "Bob"}
+		userToken, err := utils.GenerateOAuthToken(id, tenant.DefaultID, models.ScopeUsersRead, owner, time.Minute)
+		require.NoError(t, err)
+
+		_, body := s.postForm(t, "/auth/introspect", url.Values{"token": {userToken}}, id, secret)
+		assert.Equal(t, map[string]interface{}{"active": false}, body)
+	})
+
+	t.Run("TestRevokingUnknownTokenSucceeds", func(t *testing.T) {
+		resp, _ := s.postForm(t, "/auth/revoke", url.Values{"token": {"garbage"}}, id, secret)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("TestRevokeRequiresClientAuthentication", func(t *testing.T) {
+		resp, body := s.postForm(t, "/auth/revoke", url.Values{"token": {token}}, id, "wrong")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, "invalid_client", body["error"])
+	})
+}
+
+func TestRegisterClientValidation(t *testing.T) {
+	service := services.NewOAuthService(newMemoryOAuthRepository(), stubUserRepository{}, stubAuthService{}, stubSessionService{}, stubAccountChecker{})
+
+	cases := map[string]dtos.OAuthClientCreate{
+		"missing redirect":  {Name: "a", GrantTypes: []string{"authorization_code"}, Scopes: []string{"users:read"}},
+		"public m2m":        {Name: "a", GrantTypes: []string{"client_credentials"}, Scopes: []string{"users:read"}, Public: true},
+		"unknown grant":     {Name: "a", GrantTypes: []string{"password"}, Scopes: []string{"users:read"}},
+		"unknown scope":     {Name: "a", GrantTypes: []string{"client_credentials"}, Scopes: []string{"root"}},
+		"insecure redirect": {Name: "a", GrantTypes: []string{"authorization_code"}, Scopes: []string{"users:read"}, RedirectURIs: []string{"http://app.example.com/cb"}},
+	}
+	for name, input := range cases {
+		_, err := service.RegisterClient(context.Background(), "7", &input)
+		assert.ErrorIs(t, err, services.ErrInvalidOAuthClient, name)
+	}
+}
@@ -0,0 +1,161 @@
+package oauth_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/models"
+	"7-solutions/utils"
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *oauthServer) getJSON(t *testing.T, path, token string) (*http.Response, map[string]interface{}) {
+	req, err := http.NewRequest(http.MethodGet, s.URL+path, nil)
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := s.client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body := map[string]interface{}{}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	return resp, body
+}
+
+// oidcLogin runs the authorization code flow for a client with the OIDC
+// scopes and returns the token response.
+func (s *oauthServer) oidcLogin(t *testing.T, scope string) (string, map[string]interface{}) {
+	client, err := s.service.RegisterClient(context.Background(), "7", &dtos.OAuthClientCreate{
+		Name:         "OIDC app",
+		RedirectURIs: []string{s.redirectURI},
+		GrantTypes:   []string{models.GrantTypeAuthorizationCode},
+		Scopes:       []string{models.ScopeOpenID, models.ScopeProfile, models.ScopeEmail, models.ScopeUsersRead},
+		Public:       true,
+	})
+	require.NoError(t, err)
+
+	verifier := strings.Repeat("n", 64)
+	params := s.authorizeParams(client.ClientID, verifier)
+	params.Set("scope", scope)
+	params.Set("nonce", "n-0S6_WzA2Mj")
+	code := s.authorize(t, params, "alice@example.com", "secret", "approve").Query().Get("code")
+
+	resp, body := s.postForm(t, "/auth/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.redirectURI},
+		"code_verifier": {verifier},
+		"client_id":     {client.ClientID},
+	}, "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	return client.ClientID, body
+}
+
+// jwksKey turns the published JWKS into a verification key, the way a
+// relying party would.
+func jwksKey(t *testing.T, s *oauthServer) (string, *rsa.PublicKey) {
+	_, jwks := s.getJSON(t, "/.well-known/jwks.json", "")
+	keys := jwks["keys"].([]interface{})
+	require.Len(t, keys, 1)
+	key := keys[0].(map[string]interface{})
+	assert.Equal(t, "RS256", key["alg"])
+
+	n, err := base64.RawURLEncoding.DecodeString(key["n"].(string))
+	require.NoError(t, err)
+	e, err := base64.RawURLEncoding.DecodeString(key["e"].(string))
+	require.NoError(t, err)
+	return key["kid"].(string), &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+}
+
+func TestDiscovery(t *testing.T) {
+	s := newOAuthServer(t)
+	require.NoError(t, utils.SetupIDTokenSigning(s.URL, ""))
+
+	resp, body := s.getJSON(t, "/.well-known/openid-configuration", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, s.URL, body["issuer"])
+	assert.Equal(t, s.URL+"/auth/authorize", body["authorization_endpoint"])
+	assert.Equal(t, s.URL+"/auth/token", body["token_endpoint"])
+	assert.Equal(t, s.URL+"/userinfo", body["userinfo_endpoint"])
+	assert.Equal(t, s.URL+"/.well-known/jwks.json", body["jwks_uri"])
+	assert.Contains(t, body["scopes_supported"], "openid")
+	assert.Equal(t, []interface{}{"S256"}, body["code_challenge_methods_supported"])
+}
+
+func TestIDToken(t *testing.T) {
+	s := newOAuthServer(t)
+	require.NoError(t, utils.SetupIDTokenSigning(s.URL, ""))
+
+	clientID, body := s.oidcLogin(t, "openid profile email")
+	rawIDToken, ok := body["id_token"].(string)
+	require.True(t, ok, "id_token missing from %v", body)
+
+	kid, key := jwksKey(t, s)
+	token, err := jwt.Parse(rawIDToken, func(token *jwt.Token) (interface{}, error) {
+		assert.Equal(t, kid, token.Header["kid"])
+		return key, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "RS256", token.Method.Alg())
+
+	claims := token.Claims.(jwt.MapClaims)
+	assert.Equal(t, s.URL, claims["iss"])
+	assert.Equal(t, clientID, claims["aud"])
+	assert.Equal(t, "7", claims["sub"])
+	assert.Equal(t, "n-0S6_WzA2Mj", claims["nonce"])
+	assert.NotZero(t, claims["iat"])
+	assert.NotZero(t, claims["exp"])
+	// Taken from the stored user, not the login form.
+	assert.Equal(t, "Alice Liddell", claims["name"])
+	assert.Equal(t, "Alice@Example.com", claims["email"])
+	assert.Equal(t, true, claims["email_verified"])
+
+	_, err = utils.VerifyToken(rawIDToken)
+	assert.Error(t, err, "ID tokens must not be accepted as access tokens")
+}
+
+func TestIDTokenRespectsScopes(t *testing.T) {
+	s := newOAuthServer(t)
+
+	_, body := s.oidcLogin(t, "openid")
+	claims, err := utils.VerifyIDToken(body["id_token"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, "7", claims["sub"])
+	assert.Nil(t, claims["email"])
+	assert.Nil(t, claims["name"])
+
+	_, body = s.oidcLogin(t, "users:read")
+	assert.Nil(t, body["id_token"], "no ID token without the openid scope")
+}
+
+func TestUserInfo(t *testing.T) {
+	s := newOAuthServer(t)
+
+	_, body := s.oidcLogin(t, "openid email")
+	resp, info := s.getJSON(t, "/userinfo", body["access_token"].(string))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"sub": "7", "email": "Alice@Example.com", "email_verified": true}, info)
+
+	t.Run("TestRequiresOpenIDScope", func(t *testing.T) {
+		_, body := s.oidcLogin(t, "users:read")
+		resp, _ := s.getJSON(t, "/userinfo", body["access_token"].(string))
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("TestRequiresToken", func(t *testing.T) {
+		resp, _ := s.getJSON(t, "/userinfo", "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
@@ -0,0 +1,149 @@
+package policy_test
+
+import (
+	"7-solutions/policy"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newEngine(t *testing.T, dryRun bool, policies ...policy.Policy) *policy.Engine {
+	t.Helper()
+	engine := policy.NewEngine(dryRun)
+	require.NoError(t, engine.Load(policies))
+	return engine
+}
+
+func TestEvaluate_DenyOverridesAllow(t *testing.T) {
+	engine := newEngine(t, false,
+		policy.Policy{ID: "users", Effect: policy.EffectAllow, Actions: []string{"users:*"}},
+		policy.Policy{ID: "no-deletes", Effect: policy.EffectDeny, Actions: []string{"users:delete"}},
+	)
+
+	decision := engine.Evaluate(policy.Request{Action: "users:update", Resource: policy.Resource{Type: "user"}})
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, "users", decision.PolicyID)
+
+	decision = engine.Evaluate(policy.Request{Action: "users:delete", Resource: policy.Resource{Type: "user"}})
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "no-deletes", decision.PolicyID)
+	assert.Equal(t, []string{"users", "no-deletes"}, decision.Matched)
+
+	decision = engine.Evaluate(policy.Request{Action: "groups:read"})
+	assert.False(t, decision.Allowed)
+	assert.Empty(t, decision.PolicyID)
+}
+
+func TestEvaluate_Matching(t *testing.T) {
+	engine := newEngine(t, false,
+		policy.Policy{ID: "acme-admins", Effect: policy.EffectAllow, TenantID: "acme", Roles: []string{"admin"}, Actions: []string{"*"}},
+		policy.Policy{ID: "self", Effect: policy.EffectAllow, Actions: []string{"users:update"}, Resources: []string{"user"}, Fields: []string{"name"},
+			Conditions: []policy.Condition{{Attr: "resource.id", Op: policy.OpEq, Ref: "subject.id"}}},
+		policy.Policy{ID: "office", Effect: policy.EffectAllow, Actions: []string{"users:read"},
+			Conditions: []policy.Condition{{Attr: "context.ip", Op: policy.OpIn, Value: []any{"10.0.0.1", "10.0.0.2"}}}},
+	)
+
+	cases := []struct {
+		name    string
+		req     policy.Request
+		allowed bool
+	}{
+		{"admin of the tenant", policy.Request{Subject: policy.Subject{Role: "admin", TenantID: "acme"}, Action: "users:delete"}, true},
+		{"admin of another tenant", policy.Request{Subject: policy.Subject{Role: "admin", TenantID: "globex"}, Action: "users:delete"}, false},
+		{"own name", policy.Request{Subject: policy.Subject{ID: "7"}, Action: "users:update", Resource: policy.Resource{Type: "user", ID: "7", Field: "name"}}, true},
+		{"own email", policy.Request{Subject: policy.Subject{ID: "7"}, Action: "users:update", Resource: policy.Resource{Type: "user", ID: "7", Field: "email"}}, false},
+		{"whole update", policy.Request{Subject: policy.Subject{ID: "7"}, Action: "users:update", Resource: policy.Resource{Type: "user", ID: "7"}}, false},
+		{"someone else", policy.Request{Subject: policy.Subject{ID: "7"}, Action: "users:update", Resource: policy.Resource{Type: "user", ID: "8", Field: "name"}}, false},
+		{"from the office", policy.Request{Action: "users:read", Context: map[string]any{"ip": "10.0.0.2"}}, true},
+		{"from elsewhere", policy.Request{Action: "users:read", Context: map[string]any{"ip": "10.9.9.9"}}, false},
+		{"missing attribute", policy.Request{Action: "users:read"}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.allowed, engine.Evaluate(tc.req).Allowed)
+		})
+	}
+}
+
+func TestEvaluate_ValueTypes(t *testing.T) {
+	// Values decoded from JSON and BSON compare by their text.
+	engine := newEngine(t, false,
+		policy.Policy{ID: "user-7", Effect: policy.EffectAllow, Actions: []string{"a"},
+			Conditions: []policy.Condition{{Attr: "resource.id", Op: policy.OpEq, Value: float64(7)}}},
+		policy.Policy{ID: "bson-list", Effect: policy.EffectAllow, Actions: []string{"b"},
+			Conditions: []policy.Condition{{Attr: "subject.role", Op: policy.OpIn, Value: primitive.A{"admin", "lead"}}}},
+	)
+
+	assert.True(t, engine.Evaluate(policy.Request{Action: "a", Resource: policy.Resource{ID: "7"}}).Allowed)
+	assert.True(t, engine.Evaluate(policy.Request{Action: "b", Subject: policy.Subject{Role: "lead"}}).Allowed)
+	assert.False(t, engine.Evaluate(policy.Request{Action: "b", Subject: policy.Subject{Role: "user"}}).Allowed)
+}
+
+func TestAuthorize_DryRun(t *testing.T) {
+	req := policy.Request{Action: "users:delete"}
+
+	err := newEngine(t, false).Authorize(context.Background(), req)
+	assert.ErrorIs(t, err, policy.ErrDenied)
+
+	assert.NoError(t, newEngine(t, true).Authorize(context.Background(), req))
+}
+
+func TestValidate(t *testing.T) {
+	invalid := map[string]policy.Policy{
+		"missing id":     {Effect: policy.EffectAllow, Actions: []string{"*"}},
+		"unknown effect": {ID: "p", Effect: "maybe", Actions: []string{"*"}},
+		"no actions":     {ID: "p", Effect: policy.EffectAllow},
+		"bad pattern":    {ID: "p", Effect: policy.EffectAllow, Actions: []string{"users:["}},
+		"bad attr":       {ID: "p", Effect: policy.EffectAllow, Actions: []string{"*"}, Conditions: []policy.Condition{{Attr: "role", Op: policy.OpEq, Value: "x"}}},
+		"unknown op":     {ID: "p", Effect: policy.EffectAllow, Actions: []string{"*"}, Conditions: []policy.Condition{{Attr: "subject.role", Op: "like", Value: "x"}}},
+		"value and ref":  {ID: "p", Effect: policy.EffectAllow, Actions: []string{"*"}, Conditions: []policy.Condition{{Attr: "subject.id", Op: policy.OpEq, Value: "x", Ref: "resource.id"}}},
+	}
+	for name, p := range invalid {
+		assert.ErrorIs(t, policy.Validate([]policy.Policy{p}), policy.ErrInvalidPolicy, name)
+	}
+
+	duplicate := policy.Policy{ID: "p", Effect: policy.EffectAllow, Actions: []string{"*"}}
+	assert.ErrorIs(t, policy.Validate([]policy.Policy{duplicate, duplicate}), policy.ErrInvalidPolicy)
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "policies.json")
+	require.NoError(t, os.WriteFile(valid, []byte(`{"policies": [
+		{"id": "self", "effect": "allow", "actions": ["users:update"], "fields": ["name"],
+		 "conditions": [{"attr": "resource.id", "op": "eq", "ref": "subject.id"}]}
+	]}`), 0o600))
+
+	policies, err := policy.LoadFile(valid)
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	assert.Equal(t, "subject.id", policies[0].Conditions[0].Ref)
+
+	empty := filepath.Join(dir, "empty.json")
+	require.NoError(t, os.WriteFile(empty, []byte(`{"polices": []}`), 0o600))
+	_, err = policy.LoadFile(empty)
+	assert.Error(t, err)
+
+	_, err = policy.LoadFile(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestNewConfigFromEnv(t *testing.T) {
+	config, err := policy.NewConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, policy.SourceDefault, config.Source)
+	assert.Equal(t, policy.DefaultPolicies(), config.Policies)
+
+	t.Setenv("POLICY_SOURCE", "file")
+	_, err = policy.NewConfigFromEnv()
+	assert.Error(t, err, "POLICY_FILE is required")
+
+	t.Setenv("POLICY_SOURCE", "ldap")
+	_, err = policy.NewConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package ratelimit_test
+
+import (
+	"7-solutions/ratelimit"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ratelimit.ParseLimit("10/m")
+	require.NoError(t, err)
+	assert.Equal(t, 10, limit.Burst)
+	assert.InDelta(t, 10.0/60, limit.Rate, 1e-9)
+	assert.Equal(t, time.Minute, limit.Window())
+
+	for _, invalid := range []string{"", "10", "ten/m", "0/s", "10/d"} {
+		_, err := ratelimit.ParseLimit(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := ratelimit.NewMemoryStoreWithClock(func() time.Time { return now })
+	limit := ratelimit.Limit{Rate: 1, Burst: 2}
+	ctx := context.Background()
+
+	first, _ := store.Take(ctx, "ip:1", limit)
+	second, _ := store.Take(ctx, "ip:1", limit)
+	third, _ := store.Take(ctx, "ip:1", limit)
+
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 1, first.Remaining)
+	assert.True(t, second.Allowed)
+	assert.Equal(t, 0, second.Remaining)
+	assert.False(t, third.Allowed)
+	assert.Equal(t, time.Second, third.RetryAfter)
+
+	other, _ := store.Take(ctx, "ip:2", limit)
+	assert.True(t, other.Allowed)
+
+	now = now.Add(time.Second)
+	refilled, _ := store.Take(ctx, "ip:1", limit)
+	assert.True(t, refilled.Allowed)
+}
@@ -0,0 +1,58 @@
+package repositories_test
+
+import (
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestAPIKeyRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestCreateAPIKey", func(mt *mtest.T) {
+		repo := repositories.NewAPIKeyRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		key := &models.APIKey{Name: "batch", Prefix: "abcdefabcdef", Hash: "hash"}
+		err := repo.CreateAPIKey(acmeContext(), key)
+		assert.NoError(t, err)
+		assert.False(t, key.ID.IsZero())
+	})
+
+	mt.Run("TestGetAPIKeyByPrefix", func(mt *mtest.T) {
+		repo := repositories.NewAPIKeyRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "testdb.api_keys", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "name", Value: "batch"},
+			{Key: "prefix", Value: "abcdefabcdef"},
+			{Key: "hash", Value: "hash"},
+			{Key: "scopes", Value: bson.A{"users:read"}},
+		}))
+
+		key, err := repo.GetAPIKeyByPrefix(acmeContext(), "abcdefabcdef")
+		assert.NoError(t, err)
+		assert.Equal(t, "batch", key.Name)
+		assert.Equal(t, []string{"users:read"}, key.Scopes)
+	})
+
+	mt.Run("TestRevokeAPIKey_NotFound", func(mt *mtest.T) {
+		repo := repositories.NewAPIKeyRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}})
+
+		err := repo.RevokeAPIKey(acmeContext(), primitive.NewObjectID().Hex(), time.Now())
+		assert.EqualError(t, err, "api key not found")
+	})
+
+	mt.Run("TestRevokeAPIKey_InvalidID", func(mt *mtest.T) {
+		repo := repositories.NewAPIKeyRepository(mt.Client.Database("testdb"))
+
+		err := repo.RevokeAPIKey(acmeContext(), "nope", time.Now())
+		assert.Error(t, err)
+	})
+}
@@ -3,12 +3,16 @@ package repositories_test
 import (
 	"7-solutions/dtos"
 	"7-solutions/repositories"
+	"7-solutions/services"
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestAuthRepository(t *testing.T) {
@@ -41,7 +45,7 @@ func TestAuthRepository(t *testing.T) {
 			},
 		)
 
-		err := repo.RegisterUser(user)
+		err := repo.RegisterUser(acmeContext(), user)
 		assert.NoError(t, err)
 	})
 
@@ -57,7 +61,70 @@ func TestAuthRepository(t *testing.T) {
 		mt.AddMockResponses(mtest.CreateCursorResponse(1, "test.users", mtest.FirstBatch,
 			bson.D{{Key: "n", Value: int64(3)}},
 		))
-		_, err := repo.AuthenticateUser(input)
+		_, err := repo.AuthenticateUser(acmeContext(), input)
 		assert.Error(t, err)
 	})
+
+	mt.Run("TestAuthenticateUser_IgnoresEmailCase", func(mt *mtest.T) {
+		repo := repositories.NewAuthRepository(mt.Client.Database("testdb"))
+		hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+		require.NoError(t, err)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.users", mtest.FirstBatch, bson.D{
+			{Key: "id", Value: 3},
+			{Key: "email", Value: "Alice@x.io"},
+			{Key: "password", Value: string(hash)},
+		}))
+
+		user, err := repo.AuthenticateUser(acmeContext(), &dtos.UserAuthenticate{Email: "alice@x.io", Password: "password"})
+		require.NoError(t, err)
+		assert.Equal(t, 3, user.ID)
+
+		collation := mt.GetStartedEvent().Command.Lookup("collation")
+		assert.Equal(t, "en", collation.Document().Lookup("locale").StringValue())
+		assert.Equal(t, int32(2), collation.Document().Lookup("strength").Int32())
+	})
+
+	mt.Run("TestRegisterThenAuthenticate", func(mt *mtest.T) {
+		db := mt.Client.Database("testdb")
+		service := services.NewAuthService(repositories.NewAuthRepository(db), repositories.NewSessionRepository(db))
+
+		mt.AddMockResponses(
+			bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "value", Value: bson.D{
+					{Key: "_id", Value: "users"},
+					{Key: "seq", Value: 1},
+				}},
+			},
+			mtest.CreateSuccessResponse(),
+		)
+		err := service.RegisterUser(context.Background(), &dtos.UserRegister{
+			Name:     "Test",
+			Email:    "test@user.com",
+			Password: "password",
+		})
+		require.NoError(t, err)
+
+		var stored bson.Raw
+		for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+			if event.CommandName == "insert" {
+				stored = event.Command.Lookup("documents", "0").Document()
+			}
+		}
+		require.NotNil(t, stored)
+
+		var user bson.D
+		require.NoError(t, bson.Unmarshal(stored, &user))
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "testdb.users", mtest.FirstBatch, user),
+			mtest.CreateSuccessResponse(),
+		)
+
+		token, err := service.AuthenticateUser(context.Background(), &dtos.UserAuthenticate{
+			Email:    "test@user.com",
+			Password: "password",
+		})
+		require.NoError(t, err)
+		assert.NotNil(t, token)
+	})
 }
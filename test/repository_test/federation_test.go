@@ -0,0 +1,68 @@
+package repositories_test
+
+import (
+	"7-solutions/repositories"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestFederationRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestConsumeState", func(mt *mtest.T) {
+		repo := repositories.NewFederationRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{
+			{Key: "ok", Value: 1},
+			{Key: "value", Value: bson.D{
+				{Key: "_id", Value: "hash"},
+				{Key: "provider", Value: "corp"},
+				{Key: "nonce", Value: "n"},
+			}},
+		})
+
+		state, err := repo.ConsumeState(acmeContext(), "hash")
+		assert.NoError(t, err)
+		assert.Equal(t, "corp", state.Provider)
+		assert.Equal(t, "n", state.Nonce)
+	})
+
+	mt.Run("TestFindIdentity_NotLinked", func(mt *mtest.T) {
+		repo := repositories.NewFederationRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.identities", mtest.FirstBatch))
+
+		identity, err := repo.FindIdentity(acmeContext(), "corp", "sub")
+		assert.NoError(t, err)
+		assert.Nil(t, identity)
+	})
+
+	mt.Run("TestFindUserByEmail", func(mt *mtest.T) {
+		repo := repositories.NewFederationRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "testdb.users", mtest.FirstBatch, bson.D{
+			{Key: "id", Value: 3},
+			{Key: "email", Value: "Bob@Example.com"},
+		}))
+
+		user, err := repo.FindUserByEmail(acmeContext(), "bob@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, user.ID)
+	})
+
+	mt.Run("TestDeleteIdentity_NotFound", func(mt *mtest.T) {
+		repo := repositories.NewFederationRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}})
+
+		err := repo.DeleteIdentity(acmeContext(), 3, primitive.NewObjectID().Hex())
+		assert.EqualError(t, err, "identity not found")
+	})
+
+	mt.Run("TestDeleteIdentity_InvalidID", func(mt *mtest.T) {
+		repo := repositories.NewFederationRepository(mt.Client.Database("testdb"))
+
+		err := repo.DeleteIdentity(acmeContext(), 3, "nope")
+		assert.Error(t, err)
+	})
+}
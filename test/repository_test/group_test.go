@@ -0,0 +1,92 @@
+package repositories_test
+
+import (
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestGroupRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestCreateGroup_Duplicate", func(mt *mtest.T) {
+		repo := repositories.NewGroupRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}))
+
+		err := repo.CreateGroup(acmeContext(), &models.Group{Name: "engineering"})
+		assert.ErrorIs(t, err, repositories.ErrGroupExists)
+	})
+
+	mt.Run("TestGetGroup_InvalidID", func(mt *mtest.T) {
+		repo := repositories.NewGroupRepository(mt.Client.Database("testdb"))
+
+		_, err := repo.GetGroup(acmeContext(), "nope")
+		assert.ErrorIs(t, err, repositories.ErrGroupNotFound)
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+
+	mt.Run("TestGetGroup_OtherTenant", func(mt *mtest.T) {
+		repo := repositories.NewGroupRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.groups", mtest.FirstBatch))
+
+		_, err := repo.GetGroup(acmeContext(), primitive.NewObjectID().Hex())
+		assert.ErrorIs(t, err, repositories.ErrGroupNotFound)
+		id, _ := lookupTenant(mt, "filter")
+		assert.Equal(t, "acme", id)
+	})
+
+	mt.Run("TestGetMembers_Page", func(mt *mtest.T) {
+		repo := repositories.NewGroupRepository(mt.Client.Database("testdb"))
+		groupID := primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "testdb.group_members", mtest.FirstBatch, bson.D{{Key: "n", Value: int64(25)}}),
+			mtest.CreateCursorResponse(0, "testdb.group_members", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "groupId", Value: groupID},
+				{Key: "userId", Value: 21},
+				{Key: "addedAt", Value: time.Now()},
+			}),
+		)
+
+		members, total, err := repo.GetMembers(acmeContext(), groupID, 20, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(25), total)
+		require.Len(t, members, 1)
+		assert.Equal(t, 21, members[0].UserID)
+
+		mt.GetStartedEvent()
+		find := mt.GetStartedEvent()
+		assert.Equal(t, int64(20), find.Command.Lookup("skip").Int64())
+		assert.Equal(t, int64(10), find.Command.Lookup("limit").Int64())
+	})
+
+	mt.Run("TestGetChildGroupIDs", func(mt *mtest.T) {
+		repo := repositories.NewGroupRepository(mt.Client.Database("testdb"))
+		parentID, childID := primitive.NewObjectID(), primitive.NewObjectID()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.groups", mtest.FirstBatch, bson.D{{Key: "_id", Value: childID}}))
+
+		ids, err := repo.GetChildGroupIDs(acmeContext(), []primitive.ObjectID{parentID})
+		require.NoError(t, err)
+		assert.Equal(t, []primitive.ObjectID{childID}, ids)
+
+		event := mt.GetStartedEvent()
+		require.NotNil(mt, event)
+		assert.Equal(t, "acme", event.Command.Lookup("filter", "tenantId").StringValue())
+		assert.Equal(t, parentID, event.Command.Lookup("filter", "parentId", "$in").Array().Index(0).Value().ObjectID())
+	})
+
+	mt.Run("TestRemoveMember_NotMember", func(mt *mtest.T) {
+		repo := repositories.NewGroupRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+
+		err := repo.RemoveMember(acmeContext(), primitive.NewObjectID(), 7)
+		assert.ErrorIs(t, err, repositories.ErrMemberNotFound)
+	})
+}
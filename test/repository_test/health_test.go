@@ -0,0 +1,42 @@
+package repositories_test
+
+import (
+	"7-solutions/repositories"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestHealthRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestHasIndex", func(mt *mtest.T) {
+		db := mt.Client.Database("testdb")
+		repo := repositories.NewHealthRepository(db)
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.users", mtest.FirstBatch,
+			bson.D{{Key: "name", Value: "_id_"}},
+			bson.D{{Key: "name", Value: "email_1"}},
+		))
+
+		ok, err := repo.HasIndex(context.Background(), "users", "email_1")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	mt.Run("TestHasIndex_Missing", func(mt *mtest.T) {
+		db := mt.Client.Database("testdb")
+		repo := repositories.NewHealthRepository(db)
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.users", mtest.FirstBatch,
+			bson.D{{Key: "name", Value: "_id_"}},
+		))
+
+		ok, err := repo.HasIndex(context.Background(), "users", "email_1")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,75 @@
+package repositories_test
+
+import (
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestInvitationRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestConsumeInvitation_Unscoped", func(mt *mtest.T) {
+		repo := repositories.NewInvitationRepository(mt.Client.Database("testdb"))
+		now := time.Now()
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "tenantId", Value: "acme"},
+			{Key: "email", Value: "new@example.com"},
+			{Key: "tokenHash", Value: "hash"},
+			{Key: "acceptedAt", Value: now},
+		}}})
+
+		// The invitee has no tenant yet; the token decides it.
+		invitation, err := repo.ConsumeInvitation(context.Background(), "hash", now)
+		require.NoError(t, err)
+		assert.Equal(t, "acme", invitation.TenantID)
+		assert.Equal(t, models.InvitationAccepted, invitation.Status(now))
+	})
+
+	mt.Run("TestConsumeInvitation_NotFound", func(mt *mtest.T) {
+		repo := repositories.NewInvitationRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}})
+
+		_, err := repo.ConsumeInvitation(context.Background(), "hash", time.Now())
+		assert.ErrorIs(t, err, repositories.ErrInvitationNotFound)
+	})
+
+	mt.Run("TestGetPendingInvitations_Scoped", func(mt *mtest.T) {
+		repo := repositories.NewInvitationRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.invitations", mtest.FirstBatch))
+
+		invitations, err := repo.GetPendingInvitations(acmeContext(), time.Now())
+		require.NoError(t, err)
+		assert.Empty(t, invitations)
+		id, _ := lookupTenant(mt, "filter")
+		assert.Equal(t, "acme", id)
+	})
+
+	mt.Run("TestRevokeInvitation_NotPending", func(mt *mtest.T) {
+		repo := repositories.NewInvitationRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}})
+
+		err := repo.RevokeInvitation(acmeContext(), primitive.NewObjectID(), time.Now())
+		assert.ErrorIs(t, err, repositories.ErrInvitationNotFound)
+	})
+
+	mt.Run("TestCreateUser_EmailTaken", func(mt *mtest.T) {
+		repo := repositories.NewInvitationRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{{Key: "_id", Value: "users"}, {Key: "seq", Value: 7}}}},
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}),
+		)
+
+		err := repo.CreateUser(acmeContext(), &models.User{Email: "taken@example.com"})
+		assert.ErrorIs(t, err, repositories.ErrEmailTaken)
+	})
+}
@@ -0,0 +1,65 @@
+package repositories_test
+
+import (
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestOAuthRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestGetClientByClientID", func(mt *mtest.T) {
+		repo := repositories.NewOAuthRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "testdb.oauth_clients", mtest.FirstBatch, bson.D{
+			{Key: "clientId", Value: "abc"},
+			{Key: "name", Value: "Batch"},
+			{Key: "grantTypes", Value: bson.A{models.GrantTypeClientCredentials}},
+		}))
+
+		client, err := repo.GetClientByClientID(context.Background(), "abc")
+		assert.NoError(t, err)
+		assert.True(t, client.HasGrantType(models.GrantTypeClientCredentials))
+	})
+
+	mt.Run("TestConsumeAuthorizationCode", func(mt *mtest.T) {
+		repo := repositories.NewOAuthRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{
+			{Key: "ok", Value: 1},
+			{Key: "value", Value: bson.D{
+				{Key: "codeHash", Value: "hash"},
+				{Key: "clientId", Value: "abc"},
+				{Key: "subject", Value: "7"},
+			}},
+		})
+
+		code, err := repo.ConsumeAuthorizationCode(context.Background(), "hash", time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, "7", code.Subject)
+	})
+
+	mt.Run("TestConsumeAuthorizationCode_AlreadyUsed", func(mt *mtest.T) {
+		repo := repositories.NewOAuthRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}})
+
+		_, err := repo.ConsumeAuthorizationCode(context.Background(), "hash", time.Now())
+		assert.Error(t, err)
+	})
+
+	mt.Run("TestIsTokenRevoked", func(mt *mtest.T) {
+		repo := repositories.NewOAuthRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "testdb.revoked_tokens", mtest.FirstBatch,
+			bson.D{{Key: "n", Value: int64(1)}},
+		))
+
+		revoked, err := repo.IsTokenRevoked(context.Background(), "jti")
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	})
+}
@@ -0,0 +1,124 @@
+package repositories_test
+
+import (
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestPrivacyRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestGetUser_NotFound", func(mt *mtest.T) {
+		repo := repositories.NewPrivacyRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.users", mtest.FirstBatch))
+
+		_, err := repo.GetUser(acmeContext(), 7)
+		assert.ErrorIs(t, err, repositories.ErrUserNotFound)
+	})
+
+	mt.Run("TestStartErasure", func(mt *mtest.T) {
+		repo := repositories.NewPrivacyRepository(mt.Client.Database("testdb"))
+		id := primitive.NewObjectID()
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{
+			{Key: "_id", Value: id},
+			{Key: "subject", Value: "erased-ab12"},
+			{Key: "status", Value: models.ErasureRunning},
+			{Key: "steps", Value: bson.A{"sessions"}},
+		}}})
+
+		erasure, err := repo.StartErasure(acmeContext(), &models.Erasure{Subject: "erased-ab12", Steps: []string{}})
+		require.NoError(t, err)
+		assert.Equal(t, id, erasure.ID)
+		assert.Equal(t, []string{"sessions"}, erasure.Steps)
+
+		event := mt.GetStartedEvent()
+		assert.Equal(t, "erased-ab12", event.Command.Lookup("query", "subject").StringValue())
+		assert.True(t, event.Command.Lookup("upsert").Boolean())
+	})
+
+	mt.Run("TestGetErasure_NotFound", func(mt *mtest.T) {
+		repo := repositories.NewPrivacyRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.erasures", mtest.FirstBatch))
+
+		_, err := repo.GetErasure(acmeContext(), "erased-ab12")
+		assert.ErrorIs(t, err, repositories.ErrErasureNotFound)
+	})
+
+	mt.Run("TestEraseUserData_Pseudonymizes", func(mt *mtest.T) {
+		repo := repositories.NewPrivacyRepository(mt.Client.Database("testdb"))
+		for range 4 {
+			mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+		}
+		erasure := &models.Erasure{Subject: "erased-ab12", UserID: 7, Email: "ann@acme.example"}
+
+		require.NoError(t, repo.EraseUserData(acmeContext(), "audit_events", erasure))
+
+		event := mt.GetStartedEvent()
+		assert.Equal(t, "audit_events", event.Command.Lookup("update").StringValue())
+		assert.Equal(t, "7", event.Command.Lookup("updates", "0", "q", "userId").StringValue())
+		assert.Equal(t, "erased-ab12", event.Command.Lookup("updates", "0", "u", "$set", "userId").StringValue())
+	})
+
+	mt.Run("TestEraseUserData_DeletesUser", func(mt *mtest.T) {
+		repo := repositories.NewPrivacyRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}})
+
+		require.NoError(t, repo.EraseUserData(acmeContext(), "user", &models.Erasure{UserID: 7}))
+		id, _ := lookupTenant(mt, "deletes", "0", "q")
+		assert.Equal(t, "acme", id)
+	})
+
+	mt.Run("TestEraseUserData_UnknownStep", func(mt *mtest.T) {
+		repo := repositories.NewPrivacyRepository(mt.Client.Database("testdb"))
+
+		err := repo.EraseUserData(acmeContext(), "everything", &models.Erasure{UserID: 7})
+		assert.ErrorContains(t, err, "unknown erasure step")
+	})
+
+	mt.Run("TestCompleteErasure", func(mt *mtest.T) {
+		repo := repositories.NewPrivacyRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		require.NoError(t, repo.CompleteErasure(acmeContext(), primitive.NewObjectID(), time.Now()))
+		event := mt.GetStartedEvent()
+		unset := event.Command.Lookup("updates", "0", "u", "$unset").Document()
+		assert.NotNil(t, unset.Lookup("userId").Value)
+		assert.NotNil(t, unset.Lookup("email").Value)
+	})
+}
+
+func TestAuditRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestGetUserAuditEvents", func(mt *mtest.T) {
+		repo := repositories.NewAuditRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.audit_events", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "action", Value: "user.update"},
+			{Key: "targetUserId", Value: "7"},
+		}))
+
+		events, err := repo.GetUserAuditEvents(acmeContext(), "7", "ann@acme.example")
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "user.update", events[0].Action)
+	})
+
+	mt.Run("TestInsertAuditEvent", func(mt *mtest.T) {
+		repo := repositories.NewAuditRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		event := &models.AuditEvent{Action: "user.delete", TargetUserID: "7"}
+		require.NoError(t, repo.InsertAuditEvent(acmeContext(), event))
+		sent := mt.GetStartedEvent().Command.Lookup("documents", "0")
+		assert.Equal(t, "7", sent.Document().Lookup("targetUserId").StringValue())
+	})
+}
@@ -0,0 +1,98 @@
+package repositories_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestProfileRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestCreateAttribute", func(mt *mtest.T) {
+		repo := repositories.NewProfileRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		definition := &models.AttributeDefinition{Key: "department", Type: models.AttributeString}
+		require.NoError(t, repo.CreateAttribute(acmeContext(), definition))
+		assert.Equal(t, "acme", definition.TenantID)
+		assert.False(t, definition.ID.IsZero())
+	})
+
+	mt.Run("TestCreateAttribute_Duplicate", func(mt *mtest.T) {
+		repo := repositories.NewProfileRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}))
+
+		err := repo.CreateAttribute(acmeContext(), &models.AttributeDefinition{Key: "department"})
+		assert.ErrorIs(t, err, repositories.ErrAttributeExists)
+	})
+
+	mt.Run("TestGetAttribute_NotFound", func(mt *mtest.T) {
+		repo := repositories.NewProfileRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.attribute_definitions", mtest.FirstBatch))
+
+		_, err := repo.GetAttribute(acmeContext(), "department")
+		assert.ErrorIs(t, err, repositories.ErrAttributeNotFound)
+		id, _ := lookupTenant(mt, "filter")
+		assert.Equal(t, "acme", id)
+	})
+
+	mt.Run("TestDeleteAttribute_UnsetsValues", func(mt *mtest.T) {
+		repo := repositories.NewProfileRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 3}, {Key: "nModified", Value: 3}},
+		)
+
+		definition := &models.AttributeDefinition{ID: primitive.NewObjectID(), TenantID: "acme", Key: "department"}
+		require.NoError(t, repo.DeleteAttribute(acmeContext(), definition))
+
+		event := mt.GetStartedEvent()
+		assert.Equal(t, "attribute_definitions", event.Command.Lookup("delete").StringValue())
+		event = mt.GetStartedEvent()
+		assert.Equal(t, "users", event.Command.Lookup("update").StringValue())
+		assert.Equal(t, "acme", event.Command.Lookup("updates", "0", "q", "tenantId").StringValue())
+		_, err := event.Command.LookupErr("updates", "0", "u", "$unset", "attributes.department")
+		assert.NoError(t, err)
+	})
+
+	mt.Run("TestSetUserAttributes", func(mt *mtest.T) {
+		repo := repositories.NewProfileRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		require.NoError(t, repo.SetUserAttributes(acmeContext(), 7, map[string]any{"department": "eng"}, []string{"phone"}))
+		update := mt.GetStartedEvent().Command.Lookup("updates", "0", "u").Document()
+		assert.Equal(t, "eng", update.Lookup("$set", "attributes.department").StringValue())
+		_, err := update.LookupErr("$unset", "attributes.phone")
+		assert.NoError(t, err)
+	})
+
+	mt.Run("TestSetUserAttributes_NotFound", func(mt *mtest.T) {
+		repo := repositories.NewProfileRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}})
+
+		err := repo.SetUserAttributes(acmeContext(), 7, map[string]any{"remote": true}, nil)
+		assert.ErrorIs(t, err, repositories.ErrUserNotFound)
+	})
+
+	mt.Run("TestGetAllUsers_AttributeFilter", func(mt *mtest.T) {
+		repo := repositories.NewUserRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.users", mtest.FirstBatch, bson.D{
+			{Key: "id", Value: 7},
+			{Key: "attributes", Value: bson.D{{Key: "remote", Value: true}}},
+		}))
+
+		users, err := repo.GetAllUsers(acmeContext(), &dtos.UserFilter{Attributes: map[string]any{"remote": true}})
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, map[string]any{"remote": true}, users[0].Attributes)
+		assert.True(t, mt.GetStartedEvent().Command.Lookup("filter", "attributes.remote").Boolean())
+	})
+}
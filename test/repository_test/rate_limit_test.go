@@ -0,0 +1,54 @@
+package repositories_test
+
+import (
+	"7-solutions/ratelimit"
+	"7-solutions/repositories"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestRateLimitRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	limit := ratelimit.Limit{Rate: 1, Burst: 5}
+
+	mt.Run("TestTake_Allowed", func(mt *mtest.T) {
+		store := repositories.NewRateLimitRepository(mt.Client.Database("testdb"))
+
+		mt.AddMockResponses(bson.D{
+			{Key: "ok", Value: 1},
+			{Key: "value", Value: bson.D{
+				{Key: "_id", Value: "auth:ip:127.0.0.1"},
+				{Key: "tokens", Value: 4.0},
+				{Key: "allowed", Value: true},
+			}},
+		})
+
+		result, err := store.Take(context.Background(), "auth:ip:127.0.0.1", limit)
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 4, result.Remaining)
+	})
+
+	mt.Run("TestTake_Rejected", func(mt *mtest.T) {
+		store := repositories.NewRateLimitRepository(mt.Client.Database("testdb"))
+
+		mt.AddMockResponses(bson.D{
+			{Key: "ok", Value: 1},
+			{Key: "value", Value: bson.D{
+				{Key: "_id", Value: "auth:ip:127.0.0.1"},
+				{Key: "tokens", Value: 0.5},
+				{Key: "allowed", Value: false},
+			}},
+		})
+
+		result, err := store.Take(context.Background(), "auth:ip:127.0.0.1", limit)
+		assert.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+		assert.Greater(t, result.RetryAfter.Seconds(), 0.0)
+	})
+}
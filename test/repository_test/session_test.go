@@ -0,0 +1,75 @@
+package repositories_test
+
+import (
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestSessionRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestCreateSession_Tenant", func(mt *mtest.T) {
+		repo := repositories.NewSessionRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		session := &models.Session{UserID: 7, CreatedAt: time.Now()}
+		require.NoError(t, repo.CreateSession(acmeContext(), session))
+		assert.Equal(t, "acme", session.TenantID)
+		assert.False(t, session.ID.IsZero())
+	})
+
+	mt.Run("TestGetActiveSessions_Scoped", func(mt *mtest.T) {
+		repo := repositories.NewSessionRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.sessions", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "tenantId", Value: "acme"},
+			{Key: "userId", Value: 7},
+			{Key: "userAgent", Value: "curl/8.0"},
+		}))
+
+		sessions, err := repo.GetActiveSessions(acmeContext(), 7, time.Now())
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, "curl/8.0", sessions[0].UserAgent)
+		id, _ := lookupTenant(mt, "filter")
+		assert.Equal(t, "acme", id)
+	})
+
+	mt.Run("TestGetSession_NotFound", func(mt *mtest.T) {
+		repo := repositories.NewSessionRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.sessions", mtest.FirstBatch))
+
+		_, err := repo.GetSession(acmeContext(), primitive.NewObjectID().Hex())
+		assert.ErrorIs(t, err, repositories.ErrSessionNotFound)
+
+		_, err = repo.GetSession(acmeContext(), "not-an-id")
+		assert.ErrorIs(t, err, repositories.ErrSessionNotFound)
+	})
+
+	mt.Run("TestRevokeSession_NotActive", func(mt *mtest.T) {
+		repo := repositories.NewSessionRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}})
+
+		err := repo.RevokeSession(acmeContext(), 7, primitive.NewObjectID().Hex(), time.Now())
+		assert.ErrorIs(t, err, repositories.ErrSessionNotFound)
+	})
+
+	mt.Run("TestRevokeUserSessions", func(mt *mtest.T) {
+		repo := repositories.NewSessionRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}, {Key: "nModified", Value: 2}})
+
+		revoked, err := repo.RevokeUserSessions(acmeContext(), 7, time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), revoked)
+		id, _ := lookupTenant(mt, "updates", "0", "q")
+		assert.Equal(t, "acme", id)
+	})
+}
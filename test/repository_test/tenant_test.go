@@ -0,0 +1,156 @@
+package repositories_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/tenant"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// acmeContext is the context of a request signed in to the "acme" tenant.
+func acmeContext() context.Context {
+	return tenant.WithID(context.Background(), "acme")
+}
+
+// lookupTenant returns the tenantId the last command filtered on, following
+// path to its filter.
+func lookupTenant(mt *mtest.T, path ...string) (string, bool) {
+	event := mt.GetStartedEvent()
+	require.NotNil(mt, event)
+	value, err := event.Command.LookupErr(append(path, "tenantId")...)
+	if err != nil {
+		return "", false
+	}
+	return value.StringValue(), true
+}
+
+func TestUserRepositoryTenantIsolation(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestReadsAreScoped", func(mt *mtest.T) {
+		repo := repositories.NewUserRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.users", mtest.FirstBatch))
+
+		_, err := repo.GetUserByID(acmeContext(), 1)
+		assert.Error(t, err)
+		id, ok := lookupTenant(mt, "filter")
+		assert.True(t, ok)
+		assert.Equal(t, "acme", id)
+	})
+
+	mt.Run("TestListIsScoped", func(mt *mtest.T) {
+		repo := repositories.NewUserRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.users", mtest.FirstBatch))
+
+		_, err := repo.GetAllUsers(acmeContext(), nil)
+		assert.NoError(t, err)
+		id, _ := lookupTenant(mt, "filter")
+		assert.Equal(t, "acme", id)
+	})
+
+	mt.Run("TestUpdateOfOtherTenantMatchesNothing", func(mt *mtest.T) {
+		repo := repositories.NewUserRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+
+		_, err := repo.UpdateUser(acmeContext(), 7, &dtos.UserUpdate{Name: "Eve", Email: "eve@example.com"})
+		assert.ErrorIs(t, err, repositories.ErrUserNotFound)
+		id, _ := lookupTenant(mt, "updates", "0", "q")
+		assert.Equal(t, "acme", id)
+	})
+
+	mt.Run("TestDeleteOfOtherTenantMatchesNothing", func(mt *mtest.T) {
+		repo := repositories.NewUserRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+
+		err := repo.DeleteUser(acmeContext(), 7)
+		assert.ErrorIs(t, err, repositories.ErrUserNotFound)
+		id, _ := lookupTenant(mt, "deletes", "0", "q")
+		assert.Equal(t, "acme", id)
+	})
+
+	mt.Run("TestCreateInOtherTenantIsDenied", func(mt *mtest.T) {
+		repo := repositories.NewUserRepository(mt.Client.Database("testdb"))
+
+		_, err := repo.CreateUser(acmeContext(), &dtos.UserRegister{Name: "Eve", Email: "eve@example.com", TenantID: "globex"})
+		assert.ErrorIs(t, err, tenant.ErrCrossTenant)
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+
+	mt.Run("TestCreateJoinsCallerTenant", func(mt *mtest.T) {
+		repo := repositories.NewUserRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{{Key: "_id", Value: "users"}, {Key: "seq", Value: 5}}}},
+			mtest.CreateSuccessResponse(),
+		)
+
+		user, err := repo.CreateUser(acmeContext(), &dtos.UserRegister{Name: "Ann", Email: "ann@example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, "acme", user.TenantID)
+		mt.GetStartedEvent()
+		id, _ := lookupTenant(mt, "documents", "0")
+		assert.Equal(t, "acme", id)
+	})
+
+	mt.Run("TestWithoutTenantFailsClosed", func(mt *mtest.T) {
+		repo := repositories.NewUserRepository(mt.Client.Database("testdb"))
+
+		_, err := repo.GetAllUsers(context.Background(), nil)
+		assert.ErrorIs(t, err, tenant.ErrMissing)
+		_, err = repo.CreateUser(context.Background(), &dtos.UserRegister{Name: "Ann", Email: "ann@example.com"})
+		assert.ErrorIs(t, err, tenant.ErrMissing)
+		err = repo.DeleteUser(context.Background(), 1)
+		assert.ErrorIs(t, err, tenant.ErrMissing)
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+
+	mt.Run("TestAllTenantsIsUnscoped", func(mt *mtest.T) {
+		repo := repositories.NewUserRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.users", mtest.FirstBatch))
+
+		_, err := repo.GetAllUsers(tenant.WithAllTenants(acmeContext()), nil)
+		assert.NoError(t, err)
+		_, ok := lookupTenant(mt, "filter")
+		assert.False(t, ok)
+	})
+
+	mt.Run("TestAllTenantsMayCreateElsewhere", func(mt *mtest.T) {
+		repo := repositories.NewUserRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{{Key: "_id", Value: "users"}, {Key: "seq", Value: 6}}}},
+			mtest.CreateSuccessResponse(),
+		)
+
+		ctx := tenant.WithAllTenants(acmeContext())
+		user, err := repo.CreateUser(ctx, &dtos.UserRegister{Name: "Gus", Email: "gus@example.com", TenantID: "globex"})
+		require.NoError(t, err)
+		assert.Equal(t, "globex", user.TenantID)
+	})
+}
+
+func TestOrganizationRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestCreateOrganization_Duplicate", func(mt *mtest.T) {
+		repo := repositories.NewOrganizationRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}))
+
+		err := repo.CreateOrganization(context.Background(), &models.Organization{ID: "acme", Name: "Acme"})
+		assert.ErrorIs(t, err, repositories.ErrOrganizationExists)
+	})
+
+	mt.Run("TestGetOrganization_NotFound", func(mt *mtest.T) {
+		repo := repositories.NewOrganizationRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.organizations", mtest.FirstBatch))
+
+		_, err := repo.GetOrganization(context.Background(), "acme")
+		assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+	})
+}
@@ -0,0 +1,93 @@
+package repositories_test
+
+import (
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestBulkRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestWriteUsers_ReportsDuplicates", func(mt *mtest.T) {
+		repo := repositories.NewBulkRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 1, Code: 11000, Message: "duplicate key"}))
+
+		name, email := "Ann", "taken@acme.example"
+		failed, err := repo.WriteUsers(acmeContext(), []repositories.UserWrite{
+			{UserID: 1, Name: &name},
+			{UserID: 2, Email: &email},
+		}, false)
+		require.NoError(t, err)
+		assert.Equal(t, map[int]error{1: repositories.ErrEmailTaken}, failed)
+
+		event := mt.GetStartedEvent()
+		require.NotNil(mt, event)
+		assert.Equal(t, false, event.Command.Lookup("ordered").Boolean())
+		assert.Equal(t, "acme", event.Command.Lookup("updates", "1", "q", "tenantId").StringValue())
+		assert.Equal(t, "Ann", event.Command.Lookup("updates", "0", "u", "$set", "name").StringValue())
+		emailStage := event.Command.Lookup("updates", "1", "u", "0", "$set")
+		assert.Equal(t, email, emailStage.Document().Lookup("email", "$literal").StringValue())
+		assert.Equal(t, "$$REMOVE", emailStage.Document().Lookup("emailVerified", "$cond").Array().Index(2).Value().StringValue())
+	})
+
+	mt.Run("TestWriteUsers_Deactivates", func(mt *mtest.T) {
+		repo := repositories.NewBulkRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		failed, err := repo.WriteUsers(acmeContext(), []repositories.UserWrite{{UserID: 1, Status: &models.StatusChange{
+			From: models.StatusSuspended, To: models.StatusDeactivated, Reason: "Left", ActorID: "9", At: time.Now(),
+		}}}, false)
+		require.NoError(t, err)
+		assert.Empty(t, failed)
+
+		update := mt.GetStartedEvent().Command.Lookup("updates", "0").Document()
+		assert.Equal(t, models.StatusSuspended, update.Lookup("q", "status").StringValue(), "only while the status is unchanged")
+		assert.Equal(t, models.StatusDeactivated, update.Lookup("u", "$set", "status").StringValue())
+		assert.Equal(t, "Left", update.Lookup("u", "$push", "statusHistory", "reason").StringValue())
+	})
+
+	mt.Run("TestWriteUsers_DeletesMemberships", func(mt *mtest.T) {
+		repo := repositories.NewBulkRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+		)
+
+		failed, err := repo.WriteUsers(acmeContext(), []repositories.UserWrite{
+			{UserID: 1, Delete: true},
+			{UserID: 2, Delete: true},
+		}, false)
+		require.NoError(t, err)
+		assert.Empty(t, failed)
+
+		mt.GetStartedEvent()
+		event := mt.GetStartedEvent()
+		require.NotNil(mt, event)
+		assert.Equal(t, "group_members", event.Command.Lookup("delete").StringValue())
+		ids := event.Command.Lookup("deletes", "0", "q", "userId", "$in").Array()
+		values, _ := ids.Values()
+		assert.Len(t, values, 2)
+	})
+
+	mt.Run("TestFindUsersByID_Scoped", func(mt *mtest.T) {
+		repo := repositories.NewBulkRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.users", mtest.FirstBatch, bson.D{
+			{Key: "id", Value: 7},
+			{Key: "tenantId", Value: "acme"},
+		}))
+
+		users, err := repo.FindUsersByID(acmeContext(), []int{7, 8})
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, 7, users[0].ID)
+		id, _ := lookupTenant(mt, "filter")
+		assert.Equal(t, "acme", id)
+	})
+}
@@ -0,0 +1,64 @@
+package repositories_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestExportRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestStreamUsers_FilteredWithoutPasswords", func(mt *mtest.T) {
+		repo := repositories.NewExportRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.users", mtest.FirstBatch,
+			bson.D{{Key: "id", Value: 1}, {Key: "email", Value: "ann@acme.example"}},
+			bson.D{{Key: "id", Value: 2}, {Key: "email", Value: "bob@acme.example"}},
+		))
+
+		verified := false
+		after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		filter := &dtos.UserFilter{Role: models.RoleAdmin, EmailVerified: &verified, CreatedAfter: &after}
+		var emails []string
+		err := repo.StreamUsers(acmeContext(), filter, func(user *models.User) error {
+			emails = append(emails, user.Email)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"ann@acme.example", "bob@acme.example"}, emails)
+
+		command := mt.GetStartedEvent().Command
+		assert.Equal(t, "acme", command.Lookup("filter", "tenantId").StringValue())
+		assert.Equal(t, "admin", command.Lookup("filter", "role").StringValue())
+		assert.True(t, command.Lookup("filter", "emailVerified", "$ne").Boolean())
+		assert.Equal(t, after, command.Lookup("filter", "createdAt", "$gte").Time().UTC())
+		assert.Equal(t, int32(0), command.Lookup("projection", "password").Int32())
+	})
+
+	mt.Run("TestGetExportJob_NotFound", func(mt *mtest.T) {
+		repo := repositories.NewExportRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.export_jobs", mtest.FirstBatch))
+
+		_, err := repo.GetExportJob(acmeContext(), primitive.NewObjectID().Hex())
+		assert.ErrorIs(t, err, repositories.ErrExportJobNotFound)
+		id, _ := lookupTenant(mt, "filter")
+		assert.Equal(t, "acme", id)
+	})
+
+	mt.Run("TestDeleteExpiredExports_NothingExpired", func(mt *mtest.T) {
+		repo := repositories.NewExportRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.export_jobs", mtest.FirstBatch))
+
+		deleted, err := repo.DeleteExpiredExports(acmeContext(), time.Now())
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+	})
+}
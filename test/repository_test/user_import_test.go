@@ -0,0 +1,59 @@
+package repositories_test
+
+import (
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestImportRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestInsertUsers_AssignsIDsAndReportsDuplicates", func(mt *mtest.T) {
+		repo := repositories.NewImportRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{{Key: "_id", Value: "users"}, {Key: "seq", Value: 12}}}},
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 1, Code: 11000, Message: "duplicate key"}),
+		)
+
+		users := []models.User{{Email: "a@acme.example"}, {Email: "b@acme.example"}, {Email: "c@acme.example"}}
+		failed, err := repo.InsertUsers(acmeContext(), users)
+		require.NoError(t, err)
+		assert.Equal(t, map[int]error{1: repositories.ErrEmailTaken}, failed)
+		assert.Equal(t, []int{10, 11, 12}, []int{users[0].ID, users[1].ID, users[2].ID})
+		assert.Equal(t, "acme", users[2].TenantID)
+	})
+
+	mt.Run("TestFindUsersByEmail_Scoped", func(mt *mtest.T) {
+		repo := repositories.NewImportRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.users", mtest.FirstBatch, bson.D{
+			{Key: "id", Value: 7},
+			{Key: "email", Value: "ann@acme.example"},
+			{Key: "tenantId", Value: "acme"},
+		}))
+
+		users, err := repo.FindUsersByEmail(acmeContext(), []string{"ANN@acme.example"})
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, 7, users[0].ID)
+		id, _ := lookupTenant(mt, "filter")
+		assert.Equal(t, "acme", id)
+	})
+
+	mt.Run("TestGetImportJob_NotFound", func(mt *mtest.T) {
+		repo := repositories.NewImportRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.import_jobs", mtest.FirstBatch))
+
+		_, err := repo.GetImportJob(acmeContext(), primitive.NewObjectID().Hex())
+		assert.ErrorIs(t, err, repositories.ErrImportJobNotFound)
+
+		_, err = repo.GetImportJob(acmeContext(), "not-an-id")
+		assert.ErrorIs(t, err, repositories.ErrImportJobNotFound)
+	})
+}
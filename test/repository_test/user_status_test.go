@@ -0,0 +1,62 @@
+package repositories_test
+
+import (
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestStatusRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestGetUserStatus_DefaultsToActive", func(mt *mtest.T) {
+		repo := repositories.NewStatusRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.users", mtest.FirstBatch, bson.D{{Key: "id", Value: 7}}))
+
+		status, err := repo.GetUserStatus(acmeContext(), 7)
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusActive, status)
+		id, _ := lookupTenant(mt, "filter")
+		assert.Equal(t, "acme", id)
+	})
+
+	mt.Run("TestGetUser_NotFound", func(mt *mtest.T) {
+		repo := repositories.NewStatusRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "testdb.users", mtest.FirstBatch))
+
+		_, err := repo.GetUser(acmeContext(), 7)
+		assert.ErrorIs(t, err, repositories.ErrUserNotFound)
+	})
+
+	mt.Run("TestChangeUserStatus", func(mt *mtest.T) {
+		repo := repositories.NewStatusRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		change := &models.StatusChange{From: models.StatusActive, To: models.StatusLocked, Reason: "brute force", ActorID: "9", At: time.Now()}
+		require.NoError(t, repo.ChangeUserStatus(acmeContext(), 7, change))
+
+		event := mt.GetStartedEvent()
+		// Users without a stored status are active.
+		from := event.Command.Lookup("updates", "0", "q", "status", "$in").Array()
+		values, _ := from.Values()
+		require.Len(t, values, 2)
+		assert.Equal(t, bson.TypeNull, values[0].Type)
+		assert.Equal(t, models.StatusLocked, event.Command.Lookup("updates", "0", "u", "$set", "status").StringValue())
+		assert.Equal(t, "9", event.Command.Lookup("updates", "0", "u", "$push", "statusHistory", "actorId").StringValue())
+	})
+
+	mt.Run("TestChangeUserStatus_Changed", func(mt *mtest.T) {
+		repo := repositories.NewStatusRepository(mt.Client.Database("testdb"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}})
+
+		change := &models.StatusChange{From: models.StatusSuspended, To: models.StatusActive, ActorID: "9", At: time.Now()}
+		err := repo.ChangeUserStatus(acmeContext(), 7, change)
+		assert.ErrorIs(t, err, repositories.ErrStatusChanged)
+	})
+}
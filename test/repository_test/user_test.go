@@ -3,9 +3,13 @@ package repositories_test
 import (
 	"7-solutions/dtos"
 	"7-solutions/repositories"
+	"7-solutions/utils"
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
 )
@@ -21,7 +25,7 @@ func TestUserRepository(t *testing.T) {
 			bson.D{{Key: "n", Value: int64(3)}},
 		))
 
-		count, err := repo.CountUsers()
+		count, err := repo.CountUsers(acmeContext())
 		assert.NoError(t, err)
 		assert.Equal(t, int64(3), count)
 	})
@@ -45,7 +49,7 @@ func TestUserRepository(t *testing.T) {
 				{Key: "password", Value: expectedUser.Password},
 			}))
 
-		user, err := repo.GetUserByID(userID)
+		user, err := repo.GetUserByID(acmeContext(), userID)
 		assert.NoError(t, err)
 		assert.NotNil(t, user)
 		assert.Equal(t, expectedUser.Name, user.Name)
@@ -79,7 +83,7 @@ func TestUserRepository(t *testing.T) {
 			mtest.CreateCursorResponse(0, "test.users", mtest.NextBatch),
 		)
 
-		users, err := repo.GetAllUsers()
+		users, err := repo.GetAllUsers(acmeContext(), nil)
 		assert.NoError(t, err)
 		assert.Len(t, users, 2)
 		assert.Equal(t, expectedUsers[0].Name, users[0].Name)
@@ -95,10 +99,22 @@ func TestUserRepository(t *testing.T) {
 			Name:  "Updated User",
 			Email: "test@updateuser.com",
 		}
-		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
 
-		_, err := repo.UpdateUser(userID, updatedUser)
+		_, err := repo.UpdateUser(acmeContext(), userID, updatedUser)
 		assert.NoError(t, err)
+
+		event := mt.GetStartedEvent()
+		require.NotNil(mt, event)
+		stage := event.Command.Lookup("updates", "0", "u", "0", "$set").Document()
+		keys, _ := stage.Elements()
+		assert.Len(t, keys, 3)
+		assert.Equal(t, "Updated User", stage.Lookup("name", "$literal").StringValue())
+		assert.Equal(t, "test@updateuser.com", stage.Lookup("email", "$literal").StringValue())
+		cond := stage.Lookup("emailVerified", "$cond").Array()
+		assert.Equal(t, "$email", cond.Index(0).Value().Document().Lookup("$eq").Array().Index(0).Value().StringValue())
+		assert.Equal(t, "$emailVerified", cond.Index(1).Value().StringValue())
+		assert.Equal(t, "$$REMOVE", cond.Index(2).Value().StringValue())
 	})
 
 	mt.Run("TestDeleteUser", func(mt *mtest.T) {
@@ -106,9 +122,39 @@ func TestUserRepository(t *testing.T) {
 		repo := repositories.NewUserRepository(db)
 
 		userID := 1
-		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}),
+		)
 
-		err := repo.DeleteUser(userID)
+		err := repo.DeleteUser(acmeContext(), userID)
 		assert.NoError(t, err)
+
+		mt.GetStartedEvent()
+		cleanup := mt.GetStartedEvent()
+		assert.Equal(t, "group_members", cleanup.Command.Lookup("delete").StringValue())
+		assert.Equal(t, int32(userID), cleanup.Command.Lookup("deletes", "0", "q", "userId").Int32())
+	})
+
+	mt.Run("TestGetAllUsers_Canceled", func(mt *mtest.T) {
+		db := mt.Client.Database("testdb")
+		repo := repositories.NewUserRepository(db)
+
+		ctx, cancel := context.WithCancel(acmeContext())
+		cancel()
+
+		_, err := repo.GetAllUsers(ctx, nil)
+		assert.ErrorIs(t, err, utils.ErrCanceled)
+	})
+
+	mt.Run("TestCountUsers_Timeout", func(mt *mtest.T) {
+		db := mt.Client.Database("testdb")
+		repo := repositories.NewUserRepository(db)
+
+		ctx, cancel := context.WithTimeout(acmeContext(), -time.Second)
+		defer cancel()
+
+		_, err := repo.CountUsers(ctx)
+		assert.ErrorIs(t, err, utils.ErrTimeout)
 	})
 }
@@ -0,0 +1,34 @@
+package router_test
+
+import (
+	"7-solutions/models"
+	"7-solutions/router"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestGroupRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestWritesRequireAdmin", func(mt *mtest.T) {
+		r := gin.New()
+		router.AddGroupRouter(r, mt.Client.Database("testdb"))
+
+		groupID := "65f000000000000000000001"
+		writes := []struct{ method, path string }{
+			{http.MethodPost, "/groups/"},
+			{http.MethodPatch, "/groups/" + groupID},
+			{http.MethodDelete, "/groups/" + groupID},
+			{http.MethodPost, "/groups/" + groupID + "/members"},
+			{http.MethodDelete, "/groups/" + groupID + "/members/2"},
+		}
+		for _, write := range writes {
+			assert.Equal(mt, http.StatusForbidden, serveAsUser(mt, r, write.method, write.path, models.RoleUser), write.method+" "+write.path)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package router_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/models"
+	"7-solutions/router"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+type stubHealthService struct{}
+
+func (stubHealthService) Live() bool { return true }
+
+func (stubHealthService) Ready(ctx context.Context) (bool, []dtos.DependencyHealth) {
+	return true, nil
+}
+
+func (stubHealthService) Health(ctx context.Context) dtos.HealthResponse {
+	return dtos.HealthResponse{Status: "up"}
+}
+
+func (stubHealthService) SetShuttingDown() {}
+
+func (stubHealthService) RecordJobRun(name string, startedAt time.Time, duration time.Duration, err error) {
+}
+
+// serveAsUser serves method and path with a login token of a user with role,
+// answering the revocation check with an unrevoked token, the session lookup
+// with an active session and the account status lookup with an active
+// account.
+func serveAsUser(mt *mtest.T, r *gin.Engine, method, path, role string) int {
+	sessionID := primitive.NewObjectID()
+	mt.AddMockResponses(
+		mtest.CreateCursorResponse(0, "testdb.revoked_tokens", mtest.FirstBatch, bson.D{{Key: "n", Value: int64(0)}}),
+		mtest.CreateCursorResponse(0, "testdb.sessions", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: sessionID},
+			{Key: "userId", Value: 1},
+			{Key: "lastSeenAt", Value: time.Now()},
+			{Key: "expiresAt", Value: time.Now().Add(time.Hour)},
+		}),
+		mtest.CreateCursorResponse(0, "testdb.users", mtest.FirstBatch,
+			bson.D{{Key: "id", Value: 1}, {Key: "status", Value: models.StatusActive}},
+		),
+	)
+	token, _ := utils.GenerateSessionToken(1, "Test", "test@example.com", role, tenant.DefaultID, sessionID.Hex())
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestHealthRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TestHealthRequiresAdmin", func(mt *mtest.T) {
+		r := gin.New()
+		router.AddHealthRouter(r, mt.Client.Database("testdb"), stubHealthService{})
+
+		assert.Equal(mt, http.StatusForbidden, serveAsUser(mt, r, http.MethodGet, "/health", models.RoleUser))
+		assert.Equal(mt, http.StatusOK, serveAsUser(mt, r, http.MethodGet, "/health", models.RoleAdmin))
+	})
+}
@@ -0,0 +1,166 @@
+package services_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/models"
+	"7-solutions/services"
+	"7-solutions/utils"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type mockAPIKeyRepository struct {
+	CreateAPIKeyFunc      func(ctx context.Context, key *models.APIKey) error
+	GetAPIKeyByPrefixFunc func(ctx context.Context, prefix string) (*models.APIKey, error)
+	GetAllAPIKeysFunc     func(ctx context.Context) ([]models.APIKey, error)
+	RevokeAPIKeyFunc      func(ctx context.Context, id string, revokedAt time.Time) error
+}
+
+func (m *mockAPIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	return m.CreateAPIKeyFunc(ctx, key)
+}
+
+func (m *mockAPIKeyRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	return m.GetAPIKeyByPrefixFunc(ctx, prefix)
+}
+
+func (m *mockAPIKeyRepository) GetAllAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	return m.GetAllAPIKeysFunc(ctx)
+}
+
+func (m *mockAPIKeyRepository) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error {
+	return m.RevokeAPIKeyFunc(ctx, id, revokedAt)
+}
+
+// newStoringAPIKeyRepository keeps created keys in memory so a created key
+// can be authenticated again.
+func newStoringAPIKeyRepository() (*mockAPIKeyRepository, map[string]*models.APIKey) {
+	stored := map[string]*models.APIKey{}
+	return &mockAPIKeyRepository{
+		CreateAPIKeyFunc: func(ctx context.Context, key *models.APIKey) error {
+			key.ID = primitive.NewObjectID()
+			stored[key.Prefix] = key
+			return nil
+		},
+		GetAPIKeyByPrefixFunc: func(ctx context.Context, prefix string) (*models.APIKey, error) {
+			if key, ok := stored[prefix]; ok {
+				return key, nil
+			}
+			return nil, errors.New("api key not found")
+		},
+	}, stored
+}
+
+func TestAPIKeyService_CreateAndAuthenticate(t *testing.T) {
+	repo, stored := newStoringAPIKeyRepository()
+	service := services.NewAPIKeyService(repo)
+
+	created, err := service.CreateAPIKey(context.Background(), "1", &dtos.APIKeyCreate{
+		Name:      "batch",
+		Scopes:    []string{models.ScopeUsersRead},
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Key, services.APIKeyPrefix+"_"+created.Prefix+"_"))
+	assert.Equal(t, "1", created.CreatedBy)
+
+	// Only the hash is stored.
+	assert.NotContains(t, stored[created.Prefix].Hash, created.Key)
+	assert.NotEqual(t, created.Key, stored[created.Prefix].Hash)
+
+	key, err := service.AuthenticateAPIKey(context.Background(), created.Key)
+	require.NoError(t, err)
+	assert.True(t, key.HasScope(models.ScopeUsersRead))
+	assert.False(t, key.HasScope(models.ScopeUsersWrite))
+
+	tampered := created.Key[:len(created.Key)-1] + "0"
+	if tampered == created.Key {
+		tampered = created.Key[:len(created.Key)-1] + "1"
+	}
+	_, err = service.AuthenticateAPIKey(context.Background(), tampered)
+	assert.ErrorIs(t, err, services.ErrInvalidAPIKey)
+}
+
+func TestAPIKeyService_CreateValidation(t *testing.T) {
+	repo, _ := newStoringAPIKeyRepository()
+	service := services.NewAPIKeyService(repo)
+
+	_, err := service.CreateAPIKey(context.Background(), "1", &dtos.APIKeyCreate{
+		Name:      "batch",
+		Scopes:    []string{"users:admin"},
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	assert.ErrorIs(t, err, services.ErrInvalidAPIKeyRequest)
+
+	_, err = service.CreateAPIKey(context.Background(), "1", &dtos.APIKeyCreate{
+		Name:      "batch",
+		Scopes:    []string{models.ScopeUsersRead},
+		ExpiresAt: time.Now().Add(-time.Hour),
+	})
+	assert.ErrorIs(t, err, services.ErrInvalidAPIKeyRequest)
+}
+
+func TestAPIKeyService_AuthenticateRejectsRevokedAndExpired(t *testing.T) {
+	repo, stored := newStoringAPIKeyRepository()
+	service := services.NewAPIKeyService(repo)
+
+	created, err := service.CreateAPIKey(context.Background(), "1", &dtos.APIKeyCreate{
+		Name:      "batch",
+		Scopes:    []string{models.ScopeUsersRead},
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	stored[created.Prefix].ExpiresAt = time.Now().Add(-time.Minute)
+	_, err = service.AuthenticateAPIKey(context.Background(), created.Key)
+	assert.ErrorIs(t, err, services.ErrAPIKeyExpired)
+
+	revokedAt := time.Now()
+	stored[created.Prefix].RevokedAt = &revokedAt
+	_, err = service.AuthenticateAPIKey(context.Background(), created.Key)
+	assert.ErrorIs(t, err, services.ErrAPIKeyRevoked)
+
+	_, err = service.AuthenticateAPIKey(context.Background(), "not-a-key")
+	assert.ErrorIs(t, err, services.ErrInvalidAPIKey)
+}
+
+func TestAPIKeyService_AuthenticatePropagatesTimeouts(t *testing.T) {
+	repo := &mockAPIKeyRepository{
+		GetAPIKeyByPrefixFunc: func(ctx context.Context, prefix string) (*models.APIKey, error) {
+			return nil, utils.ContextError(context.DeadlineExceeded)
+		},
+	}
+	service := services.NewAPIKeyService(repo)
+
+	_, err := service.AuthenticateAPIKey(context.Background(), services.APIKeyPrefix+"_abcdefabcdef_"+strings.Repeat("a", 64))
+	assert.ErrorIs(t, err, utils.ErrTimeout)
+}
+
+func TestAPIKeyService_RevokeAndList(t *testing.T) {
+	var revokedID string
+	repo := &mockAPIKeyRepository{
+		RevokeAPIKeyFunc: func(ctx context.Context, id string, revokedAt time.Time) error {
+			revokedID = id
+			return nil
+		},
+		GetAllAPIKeysFunc: func(ctx context.Context) ([]models.APIKey, error) {
+			return []models.APIKey{{ID: primitive.NewObjectID(), Name: "batch", Hash: "secret"}}, nil
+		},
+	}
+	service := services.NewAPIKeyService(repo)
+
+	assert.NoError(t, service.RevokeAPIKey(context.Background(), "abc"))
+	assert.Equal(t, "abc", revokedID)
+
+	keys, err := service.GetAllAPIKeys(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.Equal(t, "batch", keys[0].Name)
+}
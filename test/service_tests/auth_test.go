@@ -2,35 +2,43 @@ package services_test
 
 import (
 	"7-solutions/dtos"
+	"7-solutions/metrics"
+	"7-solutions/models"
 	"7-solutions/services"
+	"7-solutions/tenant"
+	"7-solutions/utils"
+	"context"
+	"errors"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type mockAuthRepository struct {
-	RegisterUserFunc     func(userDto *dtos.UserRegister) error
-	AuthenticateUserFunc func(input *dtos.UserAuthenticate) (*string, error)
+	RegisterUserFunc     func(ctx context.Context, userDto *dtos.UserRegister) error
+	AuthenticateUserFunc func(ctx context.Context, input *dtos.UserAuthenticate) (*models.User, error)
 }
 
-func (m *mockAuthRepository) RegisterUser(userDto *dtos.UserRegister) error {
-	return m.RegisterUserFunc(userDto)
+func (m *mockAuthRepository) RegisterUser(ctx context.Context, userDto *dtos.UserRegister) error {
+	return m.RegisterUserFunc(ctx, userDto)
 }
 
-func (m *mockAuthRepository) AuthenticateUser(input *dtos.UserAuthenticate) (*string, error) {
-	return m.AuthenticateUserFunc(input)
+func (m *mockAuthRepository) AuthenticateUser(ctx context.Context, input *dtos.UserAuthenticate) (*models.User, error) {
+	return m.AuthenticateUserFunc(ctx, input)
 }
 
 func TestRegisterUser_Success(t *testing.T) {
 	mockRepo := &mockAuthRepository{
-		RegisterUserFunc: func(userDto *dtos.UserRegister) error {
+		RegisterUserFunc: func(ctx context.Context, userDto *dtos.UserRegister) error {
 			return nil
 		},
 	}
 
-	service := services.NewAuthService(mockRepo)
+	service := services.NewAuthService(mockRepo, newMemorySessionRepository())
 
-	err := service.RegisterUser(&dtos.UserRegister{
+	err := service.RegisterUser(context.Background(), &dtos.UserRegister{
 		Name:     "Test User",
 		Email:    "test@user.com",
 		Password: "password123",
@@ -41,20 +49,126 @@ func TestRegisterUser_Success(t *testing.T) {
 
 func TestAuthenticateUser_Success(t *testing.T) {
 	mockRepo := &mockAuthRepository{
-		AuthenticateUserFunc: func(input *dtos.UserAuthenticate) (*string, error) {
-			token := "mock_token"
-			return &token, nil
+		AuthenticateUserFunc: func(ctx context.Context, input *dtos.UserAuthenticate) (*models.User, error) {
+			return &models.User{ID: 7, Name: "Test", Email: input.Email, Role: models.RoleUser, TenantID: tenant.DefaultID}, nil
 		},
 	}
+	sessions := newMemorySessionRepository()
 
-	service := services.NewAuthService(mockRepo)
+	service := services.NewAuthService(mockRepo, sessions)
 
-	token, err := service.AuthenticateUser(&dtos.UserAuthenticate{
+	token, err := service.AuthenticateUser(context.Background(), &dtos.UserAuthenticate{
+		Email:     "test@user.com",
+		Password:  "password123",
+		UserAgent: "curl/8.0",
+		IP:        "192.0.2.1",
+	})
+
+	assert.NoError(t, err)
+	assert.NotNil(t, token)
+	claims, err := utils.VerifyToken(*token)
+	assert.NoError(t, err)
+	assert.Equal(t, "7", claims["sub"])
+
+	require.Len(t, sessions.sessions, 1)
+	session := sessions.sessions[0]
+	assert.Equal(t, session.ID.Hex(), claims["sid"])
+	assert.Equal(t, 7, session.UserID)
+	assert.Equal(t, tenant.DefaultID, session.TenantID)
+	assert.Equal(t, "curl/8.0", session.UserAgent)
+	assert.Equal(t, "192.0.2.1", session.IP)
+}
+
+func TestVerifyCredentials_StartsNoSession(t *testing.T) {
+	mockRepo := &mockAuthRepository{
+		AuthenticateUserFunc: func(ctx context.Context, input *dtos.UserAuthenticate) (*models.User, error) {
+			return &models.User{ID: 7, Name: "Test", Email: input.Email, Role: models.RoleUser, TenantID: tenant.DefaultID}, nil
+		},
+	}
+	sessions := newMemorySessionRepository()
+
+	service := services.NewAuthService(mockRepo, sessions)
+
+	user, err := service.VerifyCredentials(context.Background(), &dtos.UserAuthenticate{
 		Email:    "test@user.com",
 		Password: "password123",
 	})
 
+	require.NoError(t, err)
+	assert.Equal(t, 7, user.ID)
+	assert.Empty(t, sessions.sessions)
+}
+
+func TestRegisterUser_DefaultTenant(t *testing.T) {
+	var tenantID string
+	mockRepo := &mockAuthRepository{
+		RegisterUserFunc: func(ctx context.Context, userDto *dtos.UserRegister) error {
+			tenantID, _ = tenant.FromContext(ctx)
+			return nil
+		},
+	}
+
+	service := services.NewAuthService(mockRepo, newMemorySessionRepository())
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	err := service.RegisterUser(ctx, &dtos.UserRegister{Email: "test@user.com", Password: "password123"})
+
 	assert.NoError(t, err)
-	assert.NotNil(t, token)
-	assert.Equal(t, "mock_token", *token)
+	assert.Equal(t, tenant.DefaultID, tenantID)
+}
+
+func TestAuthenticateUser_Tenant(t *testing.T) {
+	var tenants []string
+	mockRepo := &mockAuthRepository{
+		AuthenticateUserFunc: func(ctx context.Context, input *dtos.UserAuthenticate) (*models.User, error) {
+			id, _ := tenant.FromContext(ctx)
+			tenants = append(tenants, id)
+			return &models.User{ID: 7, Email: input.Email, TenantID: id}, nil
+		},
+	}
+
+	service := services.NewAuthService(mockRepo, newMemorySessionRepository())
+
+	_, err := service.AuthenticateUser(context.Background(), &dtos.UserAuthenticate{Email: "test@user.com", Password: "password123"})
+	assert.NoError(t, err)
+	_, err = service.AuthenticateUser(context.Background(), &dtos.UserAuthenticate{Email: "test@user.com", Password: "password123", TenantID: "acme"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{tenant.DefaultID, "acme"}, tenants)
+}
+
+func TestAuthenticateUser_CountsLoginAttempts(t *testing.T) {
+	mockRepo := &mockAuthRepository{
+		AuthenticateUserFunc: func(ctx context.Context, input *dtos.UserAuthenticate) (*models.User, error) {
+			return nil, errors.New("authentication failed")
+		},
+	}
+
+	service := services.NewAuthService(mockRepo, newMemorySessionRepository())
+	before := testutil.ToFloat64(metrics.LoginAttemptsTotal.WithLabelValues("failure"))
+
+	_, err := service.AuthenticateUser(context.Background(), &dtos.UserAuthenticate{
+		Email:    "test@user.com",
+		Password: "wrong",
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.LoginAttemptsTotal.WithLabelValues("failure"))-before)
+}
+
+func TestAuthenticateUser_InactiveAccount(t *testing.T) {
+	mockRepo := &mockAuthRepository{
+		AuthenticateUserFunc: func(ctx context.Context, input *dtos.UserAuthenticate) (*models.User, error) {
+			return &models.User{ID: 7, Email: input.Email, Role: models.RoleUser, Status: models.StatusSuspended}, nil
+		},
+	}
+	sessions := newMemorySessionRepository()
+	service := services.NewAuthService(mockRepo, sessions)
+
+	token, err := service.AuthenticateUser(context.Background(), &dtos.UserAuthenticate{Email: "test@user.com", Password: "password123"})
+
+	assert.ErrorIs(t, err, services.ErrAccountInactive)
+	assert.ErrorContains(t, err, "suspended")
+	assert.Nil(t, token)
+	assert.Empty(t, sessions.sessions)
 }
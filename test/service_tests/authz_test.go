@@ -0,0 +1,137 @@
+package services_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/models"
+	"7-solutions/policy"
+	"7-solutions/services"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// teamPolicies let admins do anything, everyone read users, and members of
+// team-leads rename, but not re-email, users who share a group with them.
+var teamPolicies = []policy.Policy{
+	{ID: "admins", Effect: policy.EffectAllow, Roles: []string{models.RoleAdmin}, Actions: []string{"*"}},
+	{ID: "read-users", Effect: policy.EffectAllow, Actions: []string{services.ActionUsersRead}},
+	{
+		ID:        "team-leads-update-team",
+		Effect:    policy.EffectAllow,
+		Actions:   []string{services.ActionUsersUpdate},
+		Resources: []string{"user"},
+		Conditions: []policy.Condition{
+			{Attr: "subject.groups", Op: policy.OpContains, Value: "team-leads"},
+			{Attr: "subject.groups", Op: policy.OpIntersects, Ref: "resource.groups"},
+		},
+	},
+	{
+		ID:      "emails-are-admin-only",
+		Effect:  policy.EffectDeny,
+		Actions: []string{services.ActionUsersUpdate},
+		Fields:  []string{"email"},
+		Conditions: []policy.Condition{
+			{Attr: "subject.role", Op: policy.OpNe, Value: models.RoleAdmin},
+		},
+	},
+}
+
+// newTeamFixture puts user 1 in team-leads and backend, user 2 in backend
+// and user 3 in sales.
+func newTeamFixture(t *testing.T, dryRun bool) (*MockUserRepository, services.UserService, services.AuthzService) {
+	t.Helper()
+	groups := newMemoryGroupRepository(1, 2, 3)
+	for name, members := range map[string][]int{"team-leads": {1}, "backend": {1, 2}, "sales": {3}} {
+		group := &models.Group{Name: name}
+		require.NoError(t, groups.CreateGroup(context.Background(), group))
+		for _, userID := range members {
+			require.NoError(t, groups.AddMember(context.Background(), &models.GroupMember{GroupID: group.ID, UserID: userID, AddedAt: time.Now()}))
+		}
+	}
+
+	engine := policy.NewEngine(dryRun)
+	require.NoError(t, engine.Load(teamPolicies))
+	authz := services.NewAuthzService(engine, groups)
+	repo := new(MockUserRepository)
+	for id := 2; id <= 3; id++ {
+		repo.On("GetUserByID", mock.Anything, id).Return(&dtos.UserResponse{ID: id, Name: "Old", Email: "old@example.com"}, nil)
+	}
+	return repo, services.NewUserService(repo, newMemoryProfileRepository(), authz), authz
+}
+
+func asUser(id, role string) context.Context {
+	return policy.WithSubject(context.Background(), policy.Subject{ID: id, Role: role, TenantID: "acme"})
+}
+
+func TestUserService_FieldLevelPolicies(t *testing.T) {
+	repo, svc, _ := newTeamFixture(t, false)
+	repo.On("UpdateUser", mock.Anything, 2, mock.Anything).Return(&dtos.UserResponse{ID: 2}, nil)
+
+	_, err := svc.UpdateUser(asUser("1", models.RoleUser), 2, &dtos.UserUpdate{Name: "New", Email: "old@example.com"})
+	assert.NoError(t, err, "team leads may rename their team")
+
+	_, err = svc.UpdateUser(asUser("1", models.RoleUser), 2, &dtos.UserUpdate{Name: "New", Email: "new@example.com"})
+	assert.ErrorIs(t, err, policy.ErrDenied, "but not change emails")
+
+	_, err = svc.UpdateUser(asUser("1", models.RoleUser), 3, &dtos.UserUpdate{Name: "New", Email: "old@example.com"})
+	assert.ErrorIs(t, err, policy.ErrDenied, "nor rename other teams")
+
+	_, err = svc.UpdateUser(asUser("2", models.RoleUser), 2, &dtos.UserUpdate{Name: "New", Email: "old@example.com"})
+	assert.ErrorIs(t, err, policy.ErrDenied, "members are not leads")
+
+	_, err = svc.UpdateUser(asUser("9", models.RoleAdmin), 2, &dtos.UserUpdate{Name: "New", Email: "new@example.com"})
+	assert.NoError(t, err)
+
+	repo.AssertNumberOfCalls(t, "UpdateUser", 2)
+}
+
+func TestUserService_UpdateAuthorizesBeforeLoading(t *testing.T) {
+	repo, svc, _ := newTeamFixture(t, false)
+
+	_, err := svc.UpdateUser(asUser("2", models.RoleUser), 99, &dtos.UserUpdate{Name: "New", Email: "old@example.com"})
+
+	assert.ErrorIs(t, err, policy.ErrDenied)
+	repo.AssertNotCalled(t, "GetUserByID", mock.Anything, 99)
+}
+
+func TestUserService_DeleteNeedsPolicy(t *testing.T) {
+	repo, svc, _ := newTeamFixture(t, false)
+
+	err := svc.DeleteUser(asUser("1", models.RoleUser), 2)
+	assert.ErrorIs(t, err, policy.ErrDenied)
+	repo.AssertNotCalled(t, "DeleteUser", mock.Anything, mock.Anything)
+}
+
+func TestUserService_DryRunOnlyLogs(t *testing.T) {
+	repo, svc, _ := newTeamFixture(t, true)
+	repo.On("DeleteUser", mock.Anything, 2).Return(nil)
+
+	assert.NoError(t, svc.DeleteUser(asUser("1", models.RoleUser), 2))
+}
+
+func TestAuthzService_Check(t *testing.T) {
+	_, _, authz := newTeamFixture(t, false)
+
+	decision, err := authz.Check(asUser("1", models.RoleUser), &dtos.AuthzCheck{
+		Action:   services.ActionUsersUpdate,
+		Resource: policy.Resource{Type: "user", ID: "2", Field: "email"},
+	})
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "emails-are-admin-only", decision.PolicyID)
+	assert.Equal(t, []string{"team-leads-update-team", "emails-are-admin-only"}, decision.Matched)
+
+	// An explicit subject is evaluated as given, without looking up groups.
+	decision, err = authz.Check(context.Background(), &dtos.AuthzCheck{
+		Subject:  &policy.Subject{ID: "5", Groups: []string{"team-leads", "sales"}},
+		Action:   services.ActionUsersUpdate,
+		Resource: policy.Resource{Type: "user", ID: "3", Field: "name"},
+	})
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, "team-leads-update-team", decision.PolicyID)
+}
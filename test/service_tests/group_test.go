@@ -0,0 +1,378 @@
+package services_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"7-solutions/tenant"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryGroupRepository keeps groups, memberships and users in memory.
+type memoryGroupRepository struct {
+	groups  map[primitive.ObjectID]*models.Group
+	members []models.GroupMember
+	users   map[int]models.User
+}
+
+func newMemoryGroupRepository(userIDs ...int) *memoryGroupRepository {
+	repo := &memoryGroupRepository{
+		groups: map[primitive.ObjectID]*models.Group{},
+		users:  map[int]models.User{},
+	}
+	for _, id := range userIDs {
+		repo.users[id] = models.User{ID: id, Name: fmt.Sprintf("User %d", id), Email: fmt.Sprintf("user%d@example.com", id)}
+	}
+	return repo
+}
+
+// inTenant scopes reads like the repositories do. Contexts without a tenant,
+// which most tests use, see everything.
+func inTenant(ctx context.Context, tenantID string) bool {
+	id, all := tenant.FromContext(ctx)
+	return all || id == "" || id == tenantID
+}
+
+// writeTenant picks the tenant of new records like the repositories do.
+func writeTenant(ctx context.Context, requested string) (string, error) {
+	if id, _ := tenant.FromContext(ctx); id == "" {
+		return requested, nil
+	}
+	return tenant.ForWrite(ctx, requested)
+}
+
+func (r *memoryGroupRepository) CreateGroup(ctx context.Context, group *models.Group) error {
+	tenantID, err := writeTenant(ctx, group.TenantID)
+	if err != nil {
+		return err
+	}
+	group.TenantID = tenantID
+	for _, existing := range r.groups {
+		if existing.Name == group.Name {
+			return repositories.ErrGroupExists
+		}
+	}
+	group.ID = primitive.NewObjectID()
+	stored := *group
+	r.groups[group.ID] = &stored
+	return nil
+}
+
+func (r *memoryGroupRepository) GetGroup(ctx context.Context, id string) (*models.Group, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, repositories.ErrGroupNotFound
+	}
+	group, ok := r.groups[objectID]
+	if !ok || !inTenant(ctx, group.TenantID) {
+		return nil, repositories.ErrGroupNotFound
+	}
+	copied := *group
+	return &copied, nil
+}
+
+func (r *memoryGroupRepository) GetGroupsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Group, error) {
+	groups := []models.Group{}
+	for _, id := range ids {
+		if group, ok := r.groups[id]; ok {
+			groups = append(groups, *group)
+		}
+	}
+	return groups, nil
+}
+
+func (r *memoryGroupRepository) GetAllGroups(ctx context.Context) ([]models.Group, error) {
+	groups := []models.Group{}
+	for _, group := range r.groups {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, nil
+}
+
+func (r *memoryGroupRepository) UpdateGroup(ctx context.Context, group *models.Group) error {
+	if _, ok := r.groups[group.ID]; !ok {
+		return repositories.ErrGroupNotFound
+	}
+	stored := *group
+	r.groups[group.ID] = &stored
+	return nil
+}
+
+func (r *memoryGroupRepository) DeleteGroup(ctx context.Context, id primitive.ObjectID) error {
+	if _, ok := r.groups[id]; !ok {
+		return repositories.ErrGroupNotFound
+	}
+	delete(r.groups, id)
+	kept := r.members[:0]
+	for _, member := range r.members {
+		if member.GroupID != id {
+			kept = append(kept, member)
+		}
+	}
+	r.members = kept
+	return nil
+}
+
+func (r *memoryGroupRepository) CountChildGroups(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	var count int64
+	for _, group := range r.groups {
+		if group.ParentID != nil && *group.ParentID == id {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryGroupRepository) GetChildGroupIDs(ctx context.Context, parentIDs []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	ids := []primitive.ObjectID{}
+	for _, group := range r.groups {
+		if group.ParentID != nil && slices.Contains(parentIDs, *group.ParentID) {
+			ids = append(ids, group.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *memoryGroupRepository) AddMember(ctx context.Context, member *models.GroupMember) error {
+	tenantID, err := writeTenant(ctx, member.TenantID)
+	if err != nil {
+		return err
+	}
+	member.TenantID = tenantID
+	for _, existing := range r.members {
+		if existing.GroupID == member.GroupID && existing.UserID == member.UserID {
+			return repositories.ErrAlreadyMember
+		}
+	}
+	member.ID = primitive.NewObjectID()
+	r.members = append(r.members, *member)
+	return nil
+}
+
+func (r *memoryGroupRepository) RemoveMember(ctx context.Context, groupID primitive.ObjectID, userID int) error {
+	for i, member := range r.members {
+		if member.GroupID == groupID && member.UserID == userID && inTenant(ctx, member.TenantID) {
+			r.members = append(r.members[:i], r.members[i+1:]...)
+			return nil
+		}
+	}
+	return repositories.ErrMemberNotFound
+}
+
+func (r *memoryGroupRepository) GetMembers(ctx context.Context, groupID primitive.ObjectID, skip, limit int64) ([]models.GroupMember, int64, error) {
+	var all []models.GroupMember
+	for _, member := range r.members {
+		if member.GroupID == groupID && inTenant(ctx, member.TenantID) {
+			all = append(all, member)
+		}
+	}
+	total := int64(len(all))
+	if skip > total {
+		skip = total
+	}
+	end := min(skip+limit, total)
+	return all[skip:end], total, nil
+}
+
+func (r *memoryGroupRepository) GetMembershipsByUserID(ctx context.Context, userID int) ([]models.GroupMember, error) {
+	var memberships []models.GroupMember
+	for _, member := range r.members {
+		if member.UserID == userID {
+			memberships = append(memberships, member)
+		}
+	}
+	return memberships, nil
+}
+
+func (r *memoryGroupRepository) GetUsersByIDs(ctx context.Context, ids []int) ([]models.User, error) {
+	users := []models.User{}
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok && inTenant(ctx, user.TenantID) {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func createGroup(t *testing.T, service services.GroupService, name, parentID string) string {
+	t.Helper()
+	group, err := service.CreateGroup(context.Background(), &dtos.GroupCreate{Name: name, ParentID: parentID})
+	require.NoError(t, err)
+	return group.ID
+}
+
+func TestGroupService_NestingRejectsCycles(t *testing.T) {
+	service := services.NewGroupService(newMemoryGroupRepository())
+	engineering := createGroup(t, service, "engineering", "")
+	backend := createGroup(t, service, "backend", engineering)
+	payments := createGroup(t, service, "payments", backend)
+
+	for _, parent := range []string{engineering, backend, payments} {
+		_, err := service.UpdateGroup(context.Background(), engineering, &dtos.GroupUpdate{ParentID: &parent})
+		assert.ErrorIs(t, err, services.ErrGroupCycle, parent)
+	}
+
+	topLevel := ""
+	group, err := service.UpdateGroup(context.Background(), payments, &dtos.GroupUpdate{ParentID: &topLevel})
+	require.NoError(t, err)
+	assert.Empty(t, group.ParentID)
+
+	group, err = service.UpdateGroup(context.Background(), engineering, &dtos.GroupUpdate{ParentID: &payments})
+	require.NoError(t, err)
+	assert.Equal(t, payments, group.ParentID)
+}
+
+func TestGroupService_DepthLimit(t *testing.T) {
+	service := services.NewGroupService(newMemoryGroupRepository())
+	levels := []string{}
+	parent := ""
+	for i := 0; i < services.MaxGroupDepth; i++ {
+		parent = createGroup(t, service, fmt.Sprintf("level-%d", i), parent)
+		levels = append(levels, parent)
+	}
+
+	_, err := service.CreateGroup(context.Background(), &dtos.GroupCreate{Name: "too-deep", ParentID: parent})
+	assert.ErrorIs(t, err, services.ErrGroupTooDeep)
+
+	// A lone group still fits below the ninth level, but not with a subgroup.
+	ninth := levels[services.MaxGroupDepth-2]
+	root := createGroup(t, service, "root", "")
+	child := createGroup(t, service, "child", root)
+	_, err = service.UpdateGroup(context.Background(), root, &dtos.GroupUpdate{ParentID: &ninth})
+	assert.ErrorIs(t, err, services.ErrGroupTooDeep)
+
+	require.NoError(t, service.DeleteGroup(context.Background(), child))
+	_, err = service.UpdateGroup(context.Background(), root, &dtos.GroupUpdate{ParentID: &ninth})
+	assert.NoError(t, err)
+}
+
+func TestGroupService_UnknownParent(t *testing.T) {
+	service := services.NewGroupService(newMemoryGroupRepository())
+
+	_, err := service.CreateGroup(context.Background(), &dtos.GroupCreate{Name: "orphan", ParentID: primitive.NewObjectID().Hex()})
+	assert.ErrorIs(t, err, services.ErrInvalidGroup)
+}
+
+func TestGroupService_DeleteWithSubgroups(t *testing.T) {
+	service := services.NewGroupService(newMemoryGroupRepository())
+	parent := createGroup(t, service, "engineering", "")
+	child := createGroup(t, service, "backend", parent)
+
+	assert.ErrorIs(t, service.DeleteGroup(context.Background(), parent), services.ErrGroupHasChildren)
+	assert.NoError(t, service.DeleteGroup(context.Background(), child))
+	assert.NoError(t, service.DeleteGroup(context.Background(), parent))
+}
+
+func TestGroupService_Members(t *testing.T) {
+	repo := newMemoryGroupRepository(1, 2, 3)
+	service := services.NewGroupService(repo)
+	group := createGroup(t, service, "engineering", "")
+
+	for _, userID := range []int{1, 2, 3} {
+		require.NoError(t, service.AddMember(context.Background(), group, userID))
+	}
+	assert.ErrorIs(t, service.AddMember(context.Background(), group, 1), repositories.ErrAlreadyMember)
+	assert.ErrorIs(t, service.AddMember(context.Background(), group, 99), repositories.ErrUserNotFound)
+
+	page, err := service.GetMembers(context.Background(), group, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), page.Total)
+	require.Len(t, page.Members, 1)
+	assert.Equal(t, 3, page.Members[0].UserID)
+	assert.Equal(t, "user3@example.com", page.Members[0].Email)
+
+	page, err = service.GetMembers(context.Background(), group, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, page.Page)
+	assert.Equal(t, services.DefaultPageLimit, page.Limit)
+	assert.Len(t, page.Members, 3)
+
+	page, err = service.GetMembers(context.Background(), group, 1, 100000)
+	require.NoError(t, err)
+	assert.Equal(t, services.MaxPageLimit, page.Limit)
+
+	require.NoError(t, service.RemoveMember(context.Background(), group, 2))
+	assert.ErrorIs(t, service.RemoveMember(context.Background(), group, 2), repositories.ErrMemberNotFound)
+}
+
+func TestGroupService_SuperAdminWritesInTheGroupsTenant(t *testing.T) {
+	repo := newMemoryGroupRepository()
+	repo.users[1] = models.User{ID: 1, TenantID: "acme"}
+	repo.users[2] = models.User{ID: 2, TenantID: "globex"}
+	service := services.NewGroupService(repo)
+	acme := tenant.WithID(context.Background(), "acme")
+	superAdmin := tenant.WithAllTenants(tenant.WithID(context.Background(), "default"))
+
+	parent, err := service.CreateGroup(acme, &dtos.GroupCreate{Name: "engineering"})
+	require.NoError(t, err)
+	child, err := service.CreateGroup(superAdmin, &dtos.GroupCreate{Name: "backend", ParentID: parent.ID})
+	require.NoError(t, err)
+	stored, err := repo.GetGroup(acme, child.ID)
+	require.NoError(t, err, "the subgroup is in its parent's tenant")
+	assert.Equal(t, "acme", stored.TenantID)
+
+	require.NoError(t, service.AddMember(superAdmin, parent.ID, 1))
+	page, err := service.GetMembers(acme, parent.ID, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, page.Members, 1, "the group's admins see the membership")
+	require.NoError(t, service.RemoveMember(acme, parent.ID, 1))
+
+	assert.ErrorIs(t, service.AddMember(superAdmin, parent.ID, 2), repositories.ErrUserNotFound, "users of another tenant cannot join")
+
+	other, err := service.CreateGroup(tenant.WithID(context.Background(), "globex"), &dtos.GroupCreate{Name: "sales"})
+	require.NoError(t, err)
+	_, err = service.UpdateGroup(superAdmin, other.ID, &dtos.GroupUpdate{ParentID: &parent.ID})
+	assert.ErrorIs(t, err, services.ErrInvalidGroup, "groups cannot move under another tenant's group")
+}
+
+func TestGroupService_UserGroupsIncludeAncestors(t *testing.T) {
+	repo := newMemoryGroupRepository(1)
+	service := services.NewGroupService(repo)
+	engineering := createGroup(t, service, "engineering", "")
+	backend := createGroup(t, service, "backend", engineering)
+	payments := createGroup(t, service, "payments", backend)
+	createGroup(t, service, "sales", "")
+
+	require.NoError(t, service.AddMember(context.Background(), payments, 1))
+	require.NoError(t, service.AddMember(context.Background(), engineering, 1))
+
+	groups, err := service.GetUserGroups(context.Background(), 1)
+	require.NoError(t, err)
+
+	direct := map[string]bool{}
+	for _, group := range groups {
+		direct[group.Name] = group.Direct
+	}
+	assert.Equal(t, map[string]bool{"payments": true, "engineering": true, "backend": false}, direct)
+
+	_, err = service.GetUserGroups(context.Background(), 2)
+	assert.ErrorIs(t, err, repositories.ErrUserNotFound)
+}
+
+func TestGroupService_UpdateFields(t *testing.T) {
+	service := services.NewGroupService(newMemoryGroupRepository())
+	id := createGroup(t, service, "engineering", "")
+
+	name, description := "platform", "Runs the platform"
+	group, err := service.UpdateGroup(context.Background(), id, &dtos.GroupUpdate{Name: &name, Description: &description})
+	require.NoError(t, err)
+	assert.Equal(t, "platform", group.Name)
+	assert.Equal(t, "Runs the platform", group.Description)
+
+	empty := ""
+	_, err = service.UpdateGroup(context.Background(), id, &dtos.GroupUpdate{Name: &empty})
+	assert.ErrorIs(t, err, services.ErrInvalidGroup)
+
+	_, err = service.UpdateGroup(context.Background(), "nope", &dtos.GroupUpdate{Name: &name})
+	assert.ErrorIs(t, err, repositories.ErrGroupNotFound)
+}
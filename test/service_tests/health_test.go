@@ -0,0 +1,103 @@
+package services_test
+
+import (
+	"7-solutions/services"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockHealthRepository struct {
+	PingFunc     func(ctx context.Context) error
+	HasIndexFunc func(ctx context.Context, collectionName, indexName string) (bool, error)
+}
+
+func (m *mockHealthRepository) Ping(ctx context.Context) error {
+	return m.PingFunc(ctx)
+}
+
+func (m *mockHealthRepository) HasIndex(ctx context.Context, collectionName, indexName string) (bool, error) {
+	return m.HasIndexFunc(ctx, collectionName, indexName)
+}
+
+func healthyRepository() *mockHealthRepository {
+	return &mockHealthRepository{
+		PingFunc: func(ctx context.Context) error { return nil },
+		HasIndexFunc: func(ctx context.Context, collectionName, indexName string) (bool, error) {
+			return true, nil
+		},
+	}
+}
+
+func TestReady_AllDependenciesUp(t *testing.T) {
+	service := services.NewHealthService(healthyRepository(), "test", time.Second)
+
+	ready, checks := service.Ready(context.Background())
+	assert.True(t, ready)
+	assert.NotEmpty(t, checks)
+	for _, check := range checks {
+		assert.Equal(t, services.StatusUp, check.Status)
+	}
+}
+
+func TestReady_PingFails(t *testing.T) {
+	repo := healthyRepository()
+	repo.PingFunc = func(ctx context.Context) error { return errors.New("connection refused") }
+	service := services.NewHealthService(repo, "test", time.Second)
+
+	ready, checks := service.Ready(context.Background())
+	assert.False(t, ready)
+	assert.Equal(t, "mongo", checks[0].Name)
+	assert.Equal(t, services.StatusDown, checks[0].Status)
+	assert.Contains(t, checks[0].Error, "connection refused")
+}
+
+func TestReady_MissingIndex(t *testing.T) {
+	repo := healthyRepository()
+	repo.HasIndexFunc = func(ctx context.Context, collectionName, indexName string) (bool, error) {
+		return false, nil
+	}
+	service := services.NewHealthService(repo, "test", time.Second)
+
+	ready, _ := service.Ready(context.Background())
+	assert.False(t, ready)
+}
+
+func TestReady_PingTimesOut(t *testing.T) {
+	repo := healthyRepository()
+	repo.PingFunc = func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	service := services.NewHealthService(repo, "test", 10*time.Millisecond)
+
+	ready, checks := service.Ready(context.Background())
+	assert.False(t, ready)
+	assert.Equal(t, services.StatusDown, checks[0].Status)
+}
+
+func TestReady_FalseWhenShuttingDown(t *testing.T) {
+	service := services.NewHealthService(healthyRepository(), "test", time.Second)
+	service.SetShuttingDown()
+
+	ready, _ := service.Ready(context.Background())
+	assert.False(t, ready)
+}
+
+func TestHealth_ReportsJobsAndVersion(t *testing.T) {
+	service := services.NewHealthService(healthyRepository(), "1.2.3", time.Second)
+	service.RecordJobRun("count_users", time.Now(), 5*time.Millisecond, nil)
+	service.RecordJobRun("count_users", time.Now(), 5*time.Millisecond, errors.New("boom"))
+
+	health := service.Health(context.Background())
+	assert.Equal(t, services.StatusUp, health.Status)
+	assert.Equal(t, "1.2.3", health.Version)
+	assert.Len(t, health.Jobs, 1)
+	assert.False(t, health.Jobs[0].Success)
+	assert.Equal(t, "boom", health.Jobs[0].Error)
+	assert.Equal(t, int64(1), health.Jobs[0].SuccessCount)
+	assert.Equal(t, int64(1), health.Jobs[0].FailureCount)
+}
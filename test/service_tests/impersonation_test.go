@@ -0,0 +1,65 @@
+package services_test
+
+import (
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"7-solutions/utils"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryImpersonationRepository map[int]models.User
+
+func (r memoryImpersonationRepository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	user, ok := r[id]
+	if !ok {
+		return nil, repositories.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func newImpersonationService() services.ImpersonationService {
+	return services.NewImpersonationService(memoryImpersonationRepository{
+		1: {ID: 1, Name: "Admin", Email: "admin@acme.example", Role: models.RoleAdmin, TenantID: "acme"},
+		2: {ID: 2, Name: "User", Email: "user@acme.example", Role: models.RoleUser, TenantID: "acme"},
+		3: {ID: 3, Name: "Root", Email: "root@example.com", Role: models.RoleSuperAdmin, TenantID: "default"},
+	}, 5*time.Minute)
+}
+
+func TestImpersonationService_IssuesActorToken(t *testing.T) {
+	impersonation, err := newImpersonationService().Impersonate(context.Background(), "1", models.RoleAdmin, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "1", impersonation.ActorID)
+	assert.Equal(t, "user@acme.example", impersonation.User.Email)
+
+	claims, err := utils.VerifyToken(impersonation.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "2", claims["sub"])
+	assert.Equal(t, models.RoleUser, claims["role"])
+	assert.Equal(t, "acme", claims["tenantId"])
+	assert.Equal(t, map[string]interface{}{"sub": "1"}, claims["act"])
+
+	lifetime := time.Unix(int64(claims["exp"].(float64)), 0).Sub(time.Unix(int64(claims["iat"].(float64)), 0))
+	assert.Equal(t, 5*time.Minute, lifetime)
+}
+
+func TestImpersonationService_NotAllowed(t *testing.T) {
+	service := newImpersonationService()
+
+	_, err := service.Impersonate(context.Background(), "1", models.RoleAdmin, 1)
+	assert.ErrorIs(t, err, services.ErrImpersonationNotAllowed, "oneself")
+
+	_, err = service.Impersonate(context.Background(), "1", models.RoleAdmin, 3)
+	assert.ErrorIs(t, err, services.ErrImpersonationNotAllowed, "a super admin")
+
+	_, err = service.Impersonate(context.Background(), "4", models.RoleSuperAdmin, 3)
+	assert.NoError(t, err)
+
+	_, err = service.Impersonate(context.Background(), "1", models.RoleAdmin, 99)
+	assert.ErrorIs(t, err, repositories.ErrUserNotFound)
+}
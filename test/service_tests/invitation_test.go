@@ -0,0 +1,321 @@
+package services_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/mailer"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"7-solutions/tenant"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryInvitationRepository keeps invitations and the users created from
+// them in memory, scoped by the tenant of the context like the real one.
+type memoryInvitationRepository struct {
+	invitations map[primitive.ObjectID]*models.Invitation
+	users       []models.User
+	createErr   error
+}
+
+func newMemoryInvitationRepository() *memoryInvitationRepository {
+	return &memoryInvitationRepository{invitations: map[primitive.ObjectID]*models.Invitation{}}
+}
+
+func pending(invitation *models.Invitation, now time.Time) bool {
+	return invitation.Status(now) == models.InvitationPending
+}
+
+func (r *memoryInvitationRepository) CreateInvitation(ctx context.Context, invitation *models.Invitation) error {
+	invitation.ID = primitive.NewObjectID()
+	stored := *invitation
+	r.invitations[invitation.ID] = &stored
+	return nil
+}
+
+func (r *memoryInvitationRepository) GetInvitation(ctx context.Context, id string) (*models.Invitation, error) {
+	tenantID, _ := tenant.FromContext(ctx)
+	objectID, _ := primitive.ObjectIDFromHex(id)
+	invitation, ok := r.invitations[objectID]
+	if !ok || invitation.TenantID != tenantID {
+		return nil, repositories.ErrInvitationNotFound
+	}
+	copied := *invitation
+	return &copied, nil
+}
+
+func (r *memoryInvitationRepository) FindPendingInvitation(ctx context.Context, email string, now time.Time) (*models.Invitation, error) {
+	tenantID, _ := tenant.FromContext(ctx)
+	for _, invitation := range r.invitations {
+		if invitation.TenantID == tenantID && strings.EqualFold(invitation.Email, email) && pending(invitation, now) {
+			copied := *invitation
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryInvitationRepository) GetPendingInvitations(ctx context.Context, now time.Time) ([]models.Invitation, error) {
+	tenantID, _ := tenant.FromContext(ctx)
+	invitations := []models.Invitation{}
+	for _, invitation := range r.invitations {
+		if invitation.TenantID == tenantID && pending(invitation, now) {
+			invitations = append(invitations, *invitation)
+		}
+	}
+	return invitations, nil
+}
+
+func (r *memoryInvitationRepository) RenewInvitation(ctx context.Context, id primitive.ObjectID, tokenHash string, expiresAt time.Time) error {
+	invitation, ok := r.invitations[id]
+	if !ok || invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		return repositories.ErrInvitationNotFound
+	}
+	invitation.TokenHash = tokenHash
+	invitation.ExpiresAt = expiresAt
+	return nil
+}
+
+func (r *memoryInvitationRepository) RevokeInvitation(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) error {
+	invitation, ok := r.invitations[id]
+	if !ok || invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		return repositories.ErrInvitationNotFound
+	}
+	invitation.RevokedAt = &revokedAt
+	return nil
+}
+
+func (r *memoryInvitationRepository) ConsumeInvitation(ctx context.Context, tokenHash string, now time.Time) (*models.Invitation, error) {
+	for _, invitation := range r.invitations {
+		if invitation.TokenHash == tokenHash && pending(invitation, now) {
+			invitation.AcceptedAt = &now
+			copied := *invitation
+			return &copied, nil
+		}
+	}
+	return nil, repositories.ErrInvitationNotFound
+}
+
+func (r *memoryInvitationRepository) ReleaseInvitation(ctx context.Context, id primitive.ObjectID) error {
+	r.invitations[id].AcceptedAt = nil
+	return nil
+}
+
+func (r *memoryInvitationRepository) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	tenantID, _ := tenant.FromContext(ctx)
+	for _, user := range r.users {
+		if user.TenantID == tenantID && strings.EqualFold(user.Email, email) {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryInvitationRepository) CreateUser(ctx context.Context, user *models.User) error {
+	if r.createErr != nil {
+		return r.createErr
+	}
+	if existing, _ := r.FindUserByEmail(ctx, user.Email); existing != nil {
+		return repositories.ErrEmailTaken
+	}
+	user.ID = len(r.users) + 1
+	user.TenantID, _ = tenant.FromContext(ctx)
+	r.users = append(r.users, *user)
+	return nil
+}
+
+// fakeMailer records sent messages and fails when err is set.
+type fakeMailer struct {
+	sent []mailer.Message
+	err  error
+}
+
+func (m *fakeMailer) Send(ctx context.Context, message mailer.Message) error {
+	if m.err != nil {
+		return fmt.Errorf("%w: %w", mailer.ErrDelivery, m.err)
+	}
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+// lastToken extracts the token from the link in the last sent message.
+func (m *fakeMailer) lastToken(t *testing.T) string {
+	t.Helper()
+	require.NotEmpty(t, m.sent)
+	body := m.sent[len(m.sent)-1].Body
+	start := strings.Index(body, "https://app.example.com/accept?")
+	require.GreaterOrEqual(t, start, 0, body)
+	link, err := url.Parse(strings.Fields(body[start:])[0])
+	require.NoError(t, err)
+	return link.Query().Get("token")
+}
+
+type invitationFixture struct {
+	repo    *memoryInvitationRepository
+	groups  *memoryGroupRepository
+	mail    *fakeMailer
+	service services.InvitationService
+	ctx     context.Context
+}
+
+func newInvitationFixture() *invitationFixture {
+	f := &invitationFixture{
+		repo:   newMemoryInvitationRepository(),
+		groups: newMemoryGroupRepository(),
+		mail:   &fakeMailer{},
+		ctx:    tenant.WithID(context.Background(), "acme"),
+	}
+	f.service = services.NewInvitationService(f.repo, f.groups, f.mail, "https://app.example.com/accept", time.Hour)
+	return f
+}
+
+func TestInvitationService_CreateAndAccept(t *testing.T) {
+	f := newInvitationFixture()
+
+	invitation, err := f.service.CreateInvitation(f.ctx, "1", &dtos.InvitationCreate{Email: "new@example.com", Role: models.RoleAdmin})
+	require.NoError(t, err)
+	assert.Equal(t, models.InvitationPending, invitation.Status)
+	assert.Equal(t, "acme", invitation.TenantID)
+	require.Len(t, f.mail.sent, 1)
+	assert.Equal(t, "new@example.com", f.mail.sent[0].To)
+
+	token := f.mail.lastToken(t)
+	for _, stored := range f.repo.invitations {
+		assert.NotEqual(t, token, stored.TokenHash, "the raw token must not be stored")
+	}
+
+	// The invitee has no session, so the tenant comes from the token.
+	user, err := f.service.AcceptInvitation(context.Background(), &dtos.InvitationAccept{Token: token, Name: "New", Password: "secret123"})
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", user.Email)
+	assert.Equal(t, "acme", user.TenantID)
+	assert.True(t, user.EmailVerified)
+	require.Len(t, f.repo.users, 1)
+	assert.Equal(t, models.RoleAdmin, f.repo.users[0].Role)
+	assert.NotEqual(t, "secret123", f.repo.users[0].Password)
+
+	_, err = f.service.AcceptInvitation(context.Background(), &dtos.InvitationAccept{Token: token, Name: "Again", Password: "secret123"})
+	assert.ErrorIs(t, err, services.ErrInvalidInvitation, "tokens are single use")
+
+	pendingInvitations, err := f.service.GetPendingInvitations(f.ctx)
+	require.NoError(t, err)
+	assert.Empty(t, pendingInvitations)
+}
+
+func TestInvitationService_CreateConflicts(t *testing.T) {
+	f := newInvitationFixture()
+	f.repo.users = append(f.repo.users, models.User{ID: 1, Email: "taken@example.com", TenantID: "acme"})
+
+	_, err := f.service.CreateInvitation(f.ctx, "1", &dtos.InvitationCreate{Email: "Taken@example.com"})
+	assert.ErrorIs(t, err, repositories.ErrEmailTaken)
+
+	_, err = f.service.CreateInvitation(f.ctx, "1", &dtos.InvitationCreate{Email: "new@example.com"})
+	require.NoError(t, err)
+	_, err = f.service.CreateInvitation(f.ctx, "1", &dtos.InvitationCreate{Email: "NEW@example.com"})
+	assert.ErrorIs(t, err, services.ErrInvitationExists)
+
+	_, err = f.service.CreateInvitation(f.ctx, "1", &dtos.InvitationCreate{Email: "other@example.com", TenantID: "globex"})
+	assert.ErrorIs(t, err, tenant.ErrCrossTenant)
+
+	_, err = f.service.CreateInvitation(f.ctx, "1", &dtos.InvitationCreate{Email: "other@example.com", GroupID: primitive.NewObjectID().Hex()})
+	assert.ErrorIs(t, err, services.ErrInvalidInvitationRequest)
+}
+
+func TestInvitationService_ExpiredToken(t *testing.T) {
+	f := newInvitationFixture()
+	invitation, err := f.service.CreateInvitation(f.ctx, "1", &dtos.InvitationCreate{Email: "new@example.com"})
+	require.NoError(t, err)
+	token := f.mail.lastToken(t)
+
+	id, _ := primitive.ObjectIDFromHex(invitation.ID)
+	f.repo.invitations[id].ExpiresAt = time.Now().Add(-time.Minute)
+
+	_, err = f.service.AcceptInvitation(context.Background(), &dtos.InvitationAccept{Token: token, Name: "New", Password: "secret123"})
+	assert.ErrorIs(t, err, services.ErrInvalidInvitation)
+
+	// Resending issues a fresh link and replaces the old token.
+	resent, err := f.service.ResendInvitation(f.ctx, invitation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.InvitationPending, resent.Status)
+	fresh := f.mail.lastToken(t)
+	assert.NotEqual(t, token, fresh)
+
+	_, err = f.service.AcceptInvitation(context.Background(), &dtos.InvitationAccept{Token: token, Name: "New", Password: "secret123"})
+	assert.ErrorIs(t, err, services.ErrInvalidInvitation)
+	_, err = f.service.AcceptInvitation(context.Background(), &dtos.InvitationAccept{Token: fresh, Name: "New", Password: "secret123"})
+	assert.NoError(t, err)
+}
+
+func TestInvitationService_Revoke(t *testing.T) {
+	f := newInvitationFixture()
+	invitation, err := f.service.CreateInvitation(f.ctx, "1", &dtos.InvitationCreate{Email: "new@example.com"})
+	require.NoError(t, err)
+	token := f.mail.lastToken(t)
+
+	other := tenant.WithID(context.Background(), "globex")
+	assert.ErrorIs(t, f.service.RevokeInvitation(other, invitation.ID), repositories.ErrInvitationNotFound)
+
+	require.NoError(t, f.service.RevokeInvitation(f.ctx, invitation.ID))
+	assert.ErrorIs(t, f.service.RevokeInvitation(f.ctx, invitation.ID), repositories.ErrInvitationNotFound)
+
+	_, err = f.service.AcceptInvitation(context.Background(), &dtos.InvitationAccept{Token: token, Name: "New", Password: "secret123"})
+	assert.ErrorIs(t, err, services.ErrInvalidInvitation)
+	_, err = f.service.ResendInvitation(f.ctx, invitation.ID)
+	assert.ErrorIs(t, err, repositories.ErrInvitationNotFound)
+}
+
+func TestInvitationService_JoinsGroup(t *testing.T) {
+	f := newInvitationFixture()
+	group := &models.Group{Name: "engineering", TenantID: "acme"}
+	require.NoError(t, f.groups.CreateGroup(f.ctx, group))
+
+	_, err := f.service.CreateInvitation(f.ctx, "1", &dtos.InvitationCreate{Email: "new@example.com", GroupID: group.ID.Hex()})
+	require.NoError(t, err)
+
+	user, err := f.service.AcceptInvitation(context.Background(), &dtos.InvitationAccept{Token: f.mail.lastToken(t), Name: "New", Password: "secret123"})
+	require.NoError(t, err)
+
+	memberships, err := f.groups.GetMembershipsByUserID(f.ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, memberships, 1)
+	assert.Equal(t, group.ID, memberships[0].GroupID)
+}
+
+func TestInvitationService_DeliveryFailureKeepsInvitation(t *testing.T) {
+	f := newInvitationFixture()
+	f.mail.err = errors.New("connection refused")
+
+	invitation, err := f.service.CreateInvitation(f.ctx, "1", &dtos.InvitationCreate{Email: "new@example.com"})
+	assert.ErrorIs(t, err, mailer.ErrDelivery)
+	require.NotNil(t, invitation)
+
+	f.mail.err = nil
+	_, err = f.service.ResendInvitation(f.ctx, invitation.ID)
+	require.NoError(t, err)
+	assert.Len(t, f.mail.sent, 1)
+}
+
+func TestInvitationService_AcceptReleasesOnFailure(t *testing.T) {
+	f := newInvitationFixture()
+	_, err := f.service.CreateInvitation(f.ctx, "1", &dtos.InvitationCreate{Email: "new@example.com"})
+	require.NoError(t, err)
+	token := f.mail.lastToken(t)
+
+	f.repo.createErr = errors.New("write failed")
+	_, err = f.service.AcceptInvitation(context.Background(), &dtos.InvitationAccept{Token: token, Name: "New", Password: "secret123"})
+	require.Error(t, err)
+
+	f.repo.createErr = nil
+	_, err = f.service.AcceptInvitation(context.Background(), &dtos.InvitationAccept{Token: token, Name: "New", Password: "secret123"})
+	assert.NoError(t, err, "a failed attempt must not use up the token")
+}
@@ -0,0 +1,263 @@
+package services_test
+
+import (
+	"7-solutions/logging"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"7-solutions/tenant"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryPrivacyRepository keeps users, sessions and erasures in memory and
+// records the erasure steps it runs. The step in failStep fails once.
+type memoryPrivacyRepository struct {
+	users    map[int]models.User
+	sessions []models.Session
+	erasures map[string]*models.Erasure
+	ran      []string
+	failStep string
+}
+
+func newMemoryPrivacyRepository(users ...models.User) *memoryPrivacyRepository {
+	repo := &memoryPrivacyRepository{users: map[int]models.User{}, erasures: map[string]*models.Erasure{}}
+	for _, user := range users {
+		repo.users[user.ID] = user
+	}
+	return repo
+}
+
+func (r *memoryPrivacyRepository) GetUser(ctx context.Context, id int) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repositories.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryPrivacyRepository) GetUserSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	sessions := []models.Session{}
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *memoryPrivacyRepository) GetUserIdentities(ctx context.Context, userID int) ([]models.Identity, error) {
+	return []models.Identity{{ID: primitive.NewObjectID(), UserID: userID, Provider: "google", Subject: "g-1"}}, nil
+}
+
+func (r *memoryPrivacyRepository) StartErasure(ctx context.Context, erasure *models.Erasure) (*models.Erasure, error) {
+	if stored, ok := r.erasures[erasure.Subject]; ok {
+		copied := *stored
+		return &copied, nil
+	}
+	erasure.ID = primitive.NewObjectID()
+	stored := *erasure
+	r.erasures[erasure.Subject] = &stored
+	return erasure, nil
+}
+
+func (r *memoryPrivacyRepository) GetErasure(ctx context.Context, subject string) (*models.Erasure, error) {
+	stored, ok := r.erasures[subject]
+	if !ok {
+		return nil, repositories.ErrErasureNotFound
+	}
+	copied := *stored
+	return &copied, nil
+}
+
+func (r *memoryPrivacyRepository) GetStaleErasures(ctx context.Context, updatedBefore time.Time) ([]models.Erasure, error) {
+	erasures := []models.Erasure{}
+	for _, erasure := range r.erasures {
+		if erasure.Status == models.ErasureRunning && erasure.UpdatedAt.Before(updatedBefore) {
+			erasures = append(erasures, *erasure)
+		}
+	}
+	return erasures, nil
+}
+
+func (r *memoryPrivacyRepository) EraseUserData(ctx context.Context, step string, erasure *models.Erasure) error {
+	if step == r.failStep {
+		r.failStep = ""
+		return errors.New("connection reset")
+	}
+	r.ran = append(r.ran, step)
+	if step == "user" {
+		delete(r.users, erasure.UserID)
+	}
+	return nil
+}
+
+func (r *memoryPrivacyRepository) erasure(id primitive.ObjectID) *models.Erasure {
+	for _, erasure := range r.erasures {
+		if erasure.ID == id {
+			return erasure
+		}
+	}
+	return nil
+}
+
+func (r *memoryPrivacyRepository) CompleteErasureStep(ctx context.Context, id primitive.ObjectID, step string, now time.Time) error {
+	erasure := r.erasure(id)
+	if !slices.Contains(erasure.Steps, step) {
+		erasure.Steps = append(erasure.Steps, step)
+	}
+	return nil
+}
+
+func (r *memoryPrivacyRepository) CompleteErasure(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	erasure := r.erasure(id)
+	erasure.Status = models.ErasureCompleted
+	erasure.CompletedAt = &now
+	erasure.UserID, erasure.Email = 0, ""
+	return nil
+}
+
+// memoryAuditRepository keeps audit events in memory.
+type memoryAuditRepository struct {
+	events []models.AuditEvent
+}
+
+func (r *memoryAuditRepository) InsertAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func (r *memoryAuditRepository) GetUserAuditEvents(ctx context.Context, userID, email string) ([]models.AuditEvent, error) {
+	events := []models.AuditEvent{}
+	for _, event := range r.events {
+		if event.UserID == userID || event.TargetUserID == userID || event.Attributes["email"] == email {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func privacyUsers() []models.User {
+	return []models.User{
+		{ID: 1, TenantID: "acme", Name: "Ann", Email: "ann@acme.example", Role: models.RoleUser, CreatedAt: time.Now()},
+		{ID: 2, TenantID: "acme", Name: "Root", Email: "root@acme.example", Role: models.RoleSuperAdmin},
+	}
+}
+
+func TestPrivacyService_ExportUserData(t *testing.T) {
+	repo := newMemoryPrivacyRepository(privacyUsers()...)
+	revokedAt := time.Now()
+	repo.sessions = []models.Session{
+		{ID: primitive.NewObjectID(), UserID: 1, IP: "10.0.0.1", RevokedAt: &revokedAt},
+		{ID: primitive.NewObjectID(), UserID: 2},
+	}
+	groups := newMemoryGroupRepository(1)
+	group := &models.Group{Name: "engineering"}
+	require.NoError(t, groups.CreateGroup(context.Background(), group))
+	require.NoError(t, groups.AddMember(context.Background(), &models.GroupMember{GroupID: group.ID, UserID: 1, AddedAt: time.Now()}))
+	audit := &memoryAuditRepository{events: []models.AuditEvent{
+		{Action: "auth.login", Attributes: map[string]any{"email": "ann@acme.example"}},
+		{Action: "user.update", UserID: "9", TargetUserID: "1"},
+		{Action: "user.update", UserID: "9", TargetUserID: "3"},
+	}}
+	service := services.NewPrivacyService(repo, groups, audit, allowAll(), "key")
+
+	export, err := service.ExportUserData(tenant.WithID(context.Background(), "acme"), 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, "ann@acme.example", export.Profile.Email)
+	require.Len(t, export.Sessions, 1)
+	assert.Equal(t, "10.0.0.1", export.Sessions[0].IP)
+	assert.NotEmpty(t, export.Sessions[0].RevokedAt)
+	assert.Len(t, export.Identities, 1)
+	require.Len(t, export.Memberships, 1)
+	assert.Equal(t, "engineering", export.Memberships[0].GroupName)
+	assert.Len(t, export.AuditEvents, 2)
+
+	_, err = service.ExportUserData(tenant.WithID(context.Background(), "acme"), 42)
+	assert.ErrorIs(t, err, repositories.ErrUserNotFound)
+}
+
+func TestPrivacyService_EraseUserIsIdempotent(t *testing.T) {
+	repo := newMemoryPrivacyRepository(privacyUsers()...)
+	service := services.NewPrivacyService(repo, newMemoryGroupRepository(), &memoryAuditRepository{}, allowAll(), "key")
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	erasure, err := service.EraseUser(ctx, "9", 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.ErasureCompleted, erasure.Status)
+	assert.Equal(t, repositories.ErasureSteps, erasure.Steps)
+	assert.Equal(t, repositories.ErasureSteps, repo.ran)
+	assert.NotContains(t, repo.users, 1)
+	assert.Regexp(t, `^erased-[0-9a-f]{32}$`, erasure.Subject)
+
+	stored := repo.erasures[erasure.Subject]
+	assert.Zero(t, stored.UserID)
+	assert.Empty(t, stored.Email)
+
+	again, err := service.EraseUser(ctx, "9", 1)
+	require.NoError(t, err)
+	assert.Equal(t, erasure.Subject, again.Subject)
+	assert.Len(t, repo.ran, len(repositories.ErasureSteps))
+
+	status, err := service.GetErasure(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.ErasureCompleted, status.Status)
+
+	_, err = service.EraseUser(ctx, "9", 42)
+	assert.ErrorIs(t, err, repositories.ErrUserNotFound)
+	_, err = service.EraseUser(ctx, "9", 2)
+	assert.ErrorIs(t, err, services.ErrErasureNotAllowed)
+}
+
+func TestPrivacyService_EraseUserResumes(t *testing.T) {
+	repo := newMemoryPrivacyRepository(privacyUsers()...)
+	repo.failStep = "audit_events"
+	service := services.NewPrivacyService(repo, newMemoryGroupRepository(), &memoryAuditRepository{}, allowAll(), "key")
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	_, err := service.EraseUser(ctx, "9", 1)
+	require.Error(t, err)
+	assert.Contains(t, repo.users, 1)
+	status, err := service.GetErasure(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.ErasureRunning, status.Status)
+	assert.NotContains(t, status.Steps, "audit_events")
+
+	// Interrupted erasures are resumed once they have been stale for a while.
+	for _, erasure := range repo.erasures {
+		erasure.UpdatedAt = time.Now().Add(-services.ErasureResumeAfter - time.Minute)
+	}
+	completed, err := service.ResumeErasures(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, completed)
+	assert.Equal(t, repositories.ErasureSteps, repo.ran)
+	assert.NotContains(t, repo.users, 1)
+}
+
+func TestAuditRecorder_StoresEvents(t *testing.T) {
+	audit := &memoryAuditRepository{}
+	record := services.NewAuditRecorder(audit)
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	record(ctx, logging.AuditEvent{
+		Time:   time.Now(),
+		Action: "user.delete",
+		UserID: "9",
+		Attrs:  map[string]any{"target_user_id": 7},
+	})
+	record(ctx, logging.AuditEvent{Action: "auth.login", Attrs: map[string]any{"email": "ann@acme.example"}})
+
+	require.Len(t, audit.events, 2)
+	assert.Equal(t, "acme", audit.events[0].TenantID)
+	assert.Equal(t, "7", audit.events[0].TargetUserID)
+	assert.Nil(t, audit.events[0].Attributes)
+	assert.Equal(t, "ann@acme.example", audit.events[1].Attributes["email"])
+}
@@ -0,0 +1,322 @@
+package services_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/models"
+	"7-solutions/policy"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"7-solutions/tenant"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryProfileRepository keeps attribute definitions and users in memory.
+type memoryProfileRepository struct {
+	definitions []models.AttributeDefinition
+	users       map[int]*models.User
+}
+
+func newMemoryProfileRepository(definitions ...models.AttributeDefinition) *memoryProfileRepository {
+	return &memoryProfileRepository{definitions: definitions, users: map[int]*models.User{}}
+}
+
+func (r *memoryProfileRepository) CreateAttribute(ctx context.Context, definition *models.AttributeDefinition) error {
+	tenantID, err := tenant.ForWrite(ctx, definition.TenantID)
+	if err != nil {
+		return err
+	}
+	if _, err := r.GetAttribute(ctx, definition.Key); err == nil {
+		return repositories.ErrAttributeExists
+	}
+	definition.ID = primitive.NewObjectID()
+	definition.TenantID = tenantID
+	r.definitions = append(r.definitions, *definition)
+	return nil
+}
+
+func (r *memoryProfileRepository) GetAttributes(ctx context.Context) ([]models.AttributeDefinition, error) {
+	definitions := slices.Clone(r.definitions)
+	if definitions == nil {
+		definitions = []models.AttributeDefinition{}
+	}
+	slices.SortFunc(definitions, func(a, b models.AttributeDefinition) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return definitions, nil
+}
+
+func (r *memoryProfileRepository) GetAttribute(ctx context.Context, key string) (*models.AttributeDefinition, error) {
+	for _, definition := range r.definitions {
+		if definition.Key == key {
+			return &definition, nil
+		}
+	}
+	return nil, repositories.ErrAttributeNotFound
+}
+
+func (r *memoryProfileRepository) UpdateAttribute(ctx context.Context, definition *models.AttributeDefinition) error {
+	for i := range r.definitions {
+		if r.definitions[i].ID == definition.ID {
+			r.definitions[i] = *definition
+			return nil
+		}
+	}
+	return repositories.ErrAttributeNotFound
+}
+
+func (r *memoryProfileRepository) DeleteAttribute(ctx context.Context, definition *models.AttributeDefinition) error {
+	r.definitions = slices.DeleteFunc(r.definitions, func(d models.AttributeDefinition) bool {
+		return d.ID == definition.ID
+	})
+	for _, user := range r.users {
+		delete(user.Attributes, definition.Key)
+	}
+	return nil
+}
+
+func (r *memoryProfileRepository) GetUser(ctx context.Context, id int) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repositories.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *memoryProfileRepository) SetUserAttributes(ctx context.Context, id int, set map[string]any, unset []string) error {
+	user, ok := r.users[id]
+	if !ok {
+		return repositories.ErrUserNotFound
+	}
+	attributes := map[string]any{}
+	for key, value := range user.Attributes {
+		attributes[key] = value
+	}
+	for key, value := range set {
+		attributes[key] = value
+	}
+	for _, key := range unset {
+		delete(attributes, key)
+	}
+	user.Attributes = attributes
+	return nil
+}
+
+func matchAttributes(values, filter map[string]any) bool {
+	for key, value := range filter {
+		if values[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func profileDefinitions() []models.AttributeDefinition {
+	return []models.AttributeDefinition{
+		{ID: primitive.NewObjectID(), TenantID: "acme", Key: "department", Type: models.AttributeString, Required: true, Enum: []string{"eng", "sales"}, Visibility: models.VisibilityPublic},
+		{ID: primitive.NewObjectID(), TenantID: "acme", Key: "phone", Type: models.AttributeString, Pattern: `\+[0-9]{6,15}`, Visibility: models.VisibilityPrivate},
+		{ID: primitive.NewObjectID(), TenantID: "acme", Key: "salary", Type: models.AttributeNumber, Visibility: models.VisibilityAdmin},
+		{ID: primitive.NewObjectID(), TenantID: "acme", Key: "remote", Type: models.AttributeBoolean, Visibility: models.VisibilityPublic},
+	}
+}
+
+func profileContext(id, role string) context.Context {
+	ctx := tenant.WithID(context.Background(), "acme")
+	return policy.WithSubject(ctx, policy.Subject{ID: id, Role: role, TenantID: "acme"})
+}
+
+func TestProfileService_CreateAttribute(t *testing.T) {
+	repo := newMemoryProfileRepository()
+	service := services.NewProfileService(repo, allowAll())
+	ctx := profileContext("9", models.RoleAdmin)
+
+	attribute, err := service.CreateAttribute(ctx, &dtos.AttributeDefinitionCreate{Key: "cost_center", Type: models.AttributeString, Pattern: "[0-9]{4}"})
+	require.NoError(t, err)
+	assert.Equal(t, models.VisibilityPrivate, attribute.Visibility)
+	assert.Equal(t, "acme", repo.definitions[0].TenantID)
+
+	_, err = service.CreateAttribute(ctx, &dtos.AttributeDefinitionCreate{Key: "cost_center", Type: models.AttributeString})
+	assert.ErrorIs(t, err, repositories.ErrAttributeExists)
+
+	invalid := []dtos.AttributeDefinitionCreate{
+		{Key: "Cost Center", Type: models.AttributeString},
+		{Key: "level", Type: models.AttributeNumber, Pattern: "[0-9]+"},
+		{Key: "level", Type: models.AttributeBoolean, Enum: []string{"yes"}},
+		{Key: "level", Type: models.AttributeString, Pattern: "(["},
+		{Key: "level", Type: models.AttributeString, Enum: []string{"a", "a"}},
+	}
+	for _, input := range invalid {
+		_, err := service.CreateAttribute(ctx, &input)
+		assert.ErrorIs(t, err, services.ErrInvalidAttribute, input)
+	}
+}
+
+func TestProfileService_GetAttributesHidesAdminAttributes(t *testing.T) {
+	service := services.NewProfileService(newMemoryProfileRepository(profileDefinitions()...), allowAll())
+
+	attributes, err := service.GetAttributes(profileContext("9", models.RoleAdmin))
+	require.NoError(t, err)
+	assert.Len(t, attributes, 4)
+
+	attributes, err = service.GetAttributes(profileContext("1", models.RoleUser))
+	require.NoError(t, err)
+	for _, attribute := range attributes {
+		assert.NotEqual(t, "salary", attribute.Key)
+	}
+	assert.Len(t, attributes, 3)
+}
+
+func TestProfileService_UpdateAttributeKeepsKeyAndType(t *testing.T) {
+	repo := newMemoryProfileRepository(profileDefinitions()...)
+	service := services.NewProfileService(repo, allowAll())
+	ctx := profileContext("9", models.RoleAdmin)
+
+	attribute, err := service.UpdateAttribute(ctx, "salary", &dtos.AttributeDefinitionUpdate{Visibility: models.VisibilityPrivate})
+	require.NoError(t, err)
+	assert.Equal(t, models.AttributeNumber, attribute.Type)
+	assert.Equal(t, models.VisibilityPrivate, attribute.Visibility)
+
+	_, err = service.UpdateAttribute(ctx, "salary", &dtos.AttributeDefinitionUpdate{Enum: []string{"high"}})
+	assert.ErrorIs(t, err, services.ErrInvalidAttribute)
+	_, err = service.UpdateAttribute(ctx, "missing", &dtos.AttributeDefinitionUpdate{})
+	assert.ErrorIs(t, err, repositories.ErrAttributeNotFound)
+}
+
+func TestProfileService_DeleteAttributeRemovesValues(t *testing.T) {
+	repo := newMemoryProfileRepository(profileDefinitions()...)
+	repo.users[1] = &models.User{ID: 1, TenantID: "acme", Attributes: map[string]any{"remote": true, "department": "eng"}}
+	service := services.NewProfileService(repo, allowAll())
+
+	require.NoError(t, service.DeleteAttribute(profileContext("9", models.RoleAdmin), "remote"))
+	assert.Equal(t, map[string]any{"department": "eng"}, repo.users[1].Attributes)
+}
+
+func TestProfileService_UpdateUserAttributes(t *testing.T) {
+	repo := newMemoryProfileRepository(profileDefinitions()...)
+	repo.users[1] = &models.User{ID: 1, TenantID: "acme", Attributes: map[string]any{"salary": 5000.0}}
+	service := services.NewProfileService(repo, allowAll())
+
+	attributes, err := service.UpdateUserAttributes(profileContext("1", models.RoleUser), 1, &dtos.UserAttributesUpdate{
+		Attributes: map[string]any{"department": "eng", "phone": "+6612345678", "remote": true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"department": "eng", "phone": "+6612345678", "remote": true}, attributes)
+	assert.Equal(t, 5000.0, repo.users[1].Attributes["salary"])
+
+	attributes, err = service.UpdateUserAttributes(profileContext("9", models.RoleAdmin), 1, &dtos.UserAttributesUpdate{
+		Attributes: map[string]any{"phone": nil, "salary": 6000.0},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"department": "eng", "remote": true, "salary": 6000.0}, attributes)
+	assert.NotContains(t, repo.users[1].Attributes, "phone")
+}
+
+func TestProfileService_UpdateUserAttributesRejected(t *testing.T) {
+	repo := newMemoryProfileRepository(profileDefinitions()...)
+	repo.users[1] = &models.User{ID: 1, TenantID: "acme", Attributes: map[string]any{"department": "eng"}}
+	service := services.NewProfileService(repo, allowAll())
+	ctx := profileContext("1", models.RoleUser)
+
+	rejected := []map[string]any{
+		{},
+		{"unknown": "x"},
+		{"salary": 1.0},
+		{"department": "legal"},
+		{"department": 7.0},
+		{"phone": "12345"},
+		{"remote": "yes"},
+		{"department": nil},
+		{"department": strings.Repeat("a", services.MaxAttributeValueLength+1)},
+	}
+	for _, values := range rejected {
+		_, err := service.UpdateUserAttributes(ctx, 1, &dtos.UserAttributesUpdate{Attributes: values})
+		assert.ErrorIs(t, err, services.ErrInvalidAttribute, values)
+	}
+	assert.Equal(t, map[string]any{"department": "eng"}, repo.users[1].Attributes)
+
+	// A required attribute the user has no value for must be set along.
+	repo.users[2] = &models.User{ID: 2, TenantID: "acme"}
+	_, err := service.UpdateUserAttributes(profileContext("2", models.RoleUser), 2, &dtos.UserAttributesUpdate{Attributes: map[string]any{"remote": false}})
+	assert.ErrorContains(t, err, "department is required")
+
+	_, err = service.UpdateUserAttributes(profileContext("9", models.RoleAdmin), 42, &dtos.UserAttributesUpdate{Attributes: map[string]any{"remote": false}})
+	assert.ErrorIs(t, err, repositories.ErrUserNotFound)
+}
+
+func TestGetUserByID_AttributeVisibility(t *testing.T) {
+	stored := map[string]any{"department": "eng", "phone": "+6612345678", "salary": 5000.0, "deleted": "x"}
+	cases := []struct {
+		ctx  context.Context
+		want map[string]any
+	}{
+		{profileContext("2", models.RoleUser), map[string]any{"department": "eng"}},
+		{profileContext("1", models.RoleUser), map[string]any{"department": "eng", "phone": "+6612345678"}},
+		{profileContext("9", models.RoleAdmin), map[string]any{"department": "eng", "phone": "+6612345678", "salary": 5000.0}},
+	}
+
+	for _, c := range cases {
+		repo := new(MockUserRepository)
+		svc := services.NewUserService(repo, newMemoryProfileRepository(profileDefinitions()...), allowAll())
+		repo.On("GetUserByID", mock.Anything, 1).Return(&dtos.UserResponse{ID: 1, TenantID: "acme", Attributes: stored}, nil)
+
+		user, err := svc.GetUserByID(c.ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, c.want, user.Attributes)
+	}
+}
+
+func TestGetAllUsers_AttributeFilter(t *testing.T) {
+	repo := new(MockUserRepository)
+	svc := services.NewUserService(repo, newMemoryProfileRepository(profileDefinitions()...), allowAll())
+	repo.On("GetAllUsers", mock.Anything, mock.Anything).Return([]dtos.UserResponse{}, nil)
+
+	filter := &dtos.UserFilter{Attributes: map[string]any{"remote": "true", "department": "eng"}}
+	_, err := svc.GetAllUsers(profileContext("1", models.RoleUser), filter)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"remote": true, "department": "eng"}, filter.Attributes)
+
+	_, err = svc.GetAllUsers(profileContext("1", models.RoleUser), &dtos.UserFilter{Attributes: map[string]any{"phone": "+66"}})
+	assert.ErrorIs(t, err, services.ErrInvalidAttribute)
+	_, err = svc.GetAllUsers(profileContext("9", models.RoleAdmin), &dtos.UserFilter{Attributes: map[string]any{"salary": "lots"}})
+	assert.ErrorIs(t, err, services.ErrInvalidAttribute)
+	_, err = svc.GetAllUsers(profileContext("9", models.RoleAdmin), &dtos.UserFilter{Attributes: map[string]any{"salary": "5000"}})
+	assert.NoError(t, err)
+}
+
+func TestUserExport_Attributes(t *testing.T) {
+	users := exportUsers()
+	users[0].Attributes = map[string]any{"department": "eng", "salary": 5000.5, "remote": true}
+	users[1].Attributes = map[string]any{"department": "=sales"}
+	service := services.NewUserExportService(newMemoryExportRepository(users...), newMemoryProfileRepository(profileDefinitions()...), allowAll())
+	ctx := profileContext("9", models.RoleAdmin)
+
+	var buf bytes.Buffer
+	_, err := service.ExportUsers(ctx, &dtos.UserExport{Format: services.ExportFormatCSV}, &buf)
+	require.NoError(t, err)
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{"attributes.department", "attributes.phone", "attributes.remote", "attributes.salary"}, records[0][7:])
+	assert.Equal(t, []string{"eng", "", "true", "5000.5"}, records[1][7:])
+	assert.Equal(t, []string{"'=sales", "", "", ""}, records[2][7:])
+
+	buf.Reset()
+	input := &dtos.UserExport{Format: services.ExportFormatNDJSON, UserFilter: dtos.UserFilter{Attributes: map[string]any{"remote": "true"}}}
+	rows, err := service.ExportUsers(ctx, input, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rows)
+	assert.Contains(t, buf.String(), `"attributes":{"department":"eng","remote":true,"salary":5000.5}`)
+
+	_, err = service.ExportUsers(ctx, &dtos.UserExport{Format: services.ExportFormatCSV, UserFilter: dtos.UserFilter{Attributes: map[string]any{"nope": "1"}}}, &buf)
+	assert.ErrorIs(t, err, services.ErrInvalidAttribute)
+}
@@ -0,0 +1,159 @@
+package services_test
+
+import (
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memorySessionRepository keeps sessions in memory.
+type memorySessionRepository struct {
+	sessions []*models.Session
+}
+
+func newMemorySessionRepository() *memorySessionRepository {
+	return &memorySessionRepository{}
+}
+
+func (r *memorySessionRepository) add(userID int, lastSeenAt time.Time) *models.Session {
+	session := &models.Session{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		CreatedAt:  lastSeenAt,
+		LastSeenAt: lastSeenAt,
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+	r.sessions = append(r.sessions, session)
+	return session
+}
+
+func (r *memorySessionRepository) find(id string) *models.Session {
+	for _, session := range r.sessions {
+		if session.ID.Hex() == id {
+			return session
+		}
+	}
+	return nil
+}
+
+func (r *memorySessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	session.ID = primitive.NewObjectID()
+	stored := *session
+	r.sessions = append(r.sessions, &stored)
+	return nil
+}
+
+func (r *memorySessionRepository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	session := r.find(id)
+	if session == nil {
+		return nil, repositories.ErrSessionNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (r *memorySessionRepository) GetActiveSessions(ctx context.Context, userID int, now time.Time) ([]models.Session, error) {
+	sessions := []models.Session{}
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.Active(now) {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *memorySessionRepository) TouchSession(ctx context.Context, id primitive.ObjectID, lastSeenAt time.Time) error {
+	if session := r.find(id.Hex()); session != nil {
+		session.LastSeenAt = lastSeenAt
+	}
+	return nil
+}
+
+func (r *memorySessionRepository) RevokeSession(ctx context.Context, userID int, id string, revokedAt time.Time) error {
+	session := r.find(id)
+	if session == nil || session.UserID != userID || !session.Active(revokedAt) {
+		return repositories.ErrSessionNotFound
+	}
+	session.RevokedAt = &revokedAt
+	return nil
+}
+
+func (r *memorySessionRepository) RevokeUserSessions(ctx context.Context, userID int, revokedAt time.Time) (int64, error) {
+	var revoked int64
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.Active(revokedAt) {
+			session.RevokedAt = &revokedAt
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func TestSessionService_RevokeSession(t *testing.T) {
+	repo := newMemorySessionRepository()
+	current := repo.add(1, time.Now())
+	other := repo.add(1, time.Now())
+	service := services.NewSessionService(repo)
+	ctx := context.Background()
+
+	sessions, err := service.GetSessions(ctx, 1, current.ID.Hex())
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.True(t, sessions[0].Current)
+	assert.False(t, sessions[1].Current)
+
+	assert.ErrorIs(t, service.RevokeSession(ctx, 2, other.ID.Hex()), repositories.ErrSessionNotFound, "another user's session")
+	require.NoError(t, service.RevokeSession(ctx, 1, other.ID.Hex()))
+	assert.ErrorIs(t, service.RevokeSession(ctx, 1, other.ID.Hex()), repositories.ErrSessionNotFound, "already revoked")
+
+	assert.ErrorIs(t, service.ValidateSession(ctx, other.ID.Hex()), services.ErrSessionRevoked)
+	assert.NoError(t, service.ValidateSession(ctx, current.ID.Hex()))
+
+	sessions, err = service.GetSessions(ctx, 1, current.ID.Hex())
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, current.ID.Hex(), sessions[0].ID)
+}
+
+func TestSessionService_RevokeAllSessions(t *testing.T) {
+	repo := newMemorySessionRepository()
+	first := repo.add(1, time.Now())
+	repo.add(1, time.Now())
+	kept := repo.add(2, time.Now())
+	service := services.NewSessionService(repo)
+
+	revoked, err := service.RevokeAllSessions(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), revoked)
+
+	assert.ErrorIs(t, service.ValidateSession(context.Background(), first.ID.Hex()), services.ErrSessionRevoked)
+	assert.NoError(t, service.ValidateSession(context.Background(), kept.ID.Hex()))
+}
+
+func TestSessionService_ValidateSession(t *testing.T) {
+	repo := newMemorySessionRepository()
+	service := services.NewSessionService(repo)
+	ctx := context.Background()
+
+	stale := repo.add(1, time.Now().Add(-time.Hour))
+	require.NoError(t, service.ValidateSession(ctx, stale.ID.Hex()))
+	assert.WithinDuration(t, time.Now(), stale.LastSeenAt, time.Second, "last-seen time is updated")
+
+	recent := time.Now().Add(-services.SessionTouchInterval / 2)
+	fresh := repo.add(1, recent)
+	require.NoError(t, service.ValidateSession(ctx, fresh.ID.Hex()))
+	assert.Equal(t, recent, fresh.LastSeenAt, "recent activity is not rewritten")
+
+	expired := repo.add(1, time.Now())
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	assert.ErrorIs(t, service.ValidateSession(ctx, expired.ID.Hex()), services.ErrSessionRevoked)
+
+	assert.ErrorIs(t, service.ValidateSession(ctx, primitive.NewObjectID().Hex()), services.ErrSessionRevoked)
+}
@@ -0,0 +1,279 @@
+package services_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/models"
+	"7-solutions/policy"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"7-solutions/tenant"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryBulkRepository keeps users in memory. Writes setting an email in
+// taken fail as if another user already had it.
+type memoryBulkRepository struct {
+	users  map[int]models.User
+	taken  map[string]bool
+	writes int
+}
+
+func newMemoryBulkRepository(users ...models.User) *memoryBulkRepository {
+	repo := &memoryBulkRepository{users: map[int]models.User{}, taken: map[string]bool{}}
+	for _, user := range users {
+		repo.users[user.ID] = user
+	}
+	return repo
+}
+
+func (r *memoryBulkRepository) FindUsersByID(ctx context.Context, ids []int) ([]models.User, error) {
+	users := []models.User{}
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *memoryBulkRepository) WriteUsers(ctx context.Context, writes []repositories.UserWrite, atomic bool) (map[int]error, error) {
+	r.writes++
+	failed := map[int]error{}
+	for i, write := range writes {
+		if write.Email != nil && r.taken[*write.Email] {
+			failed[i] = repositories.ErrEmailTaken
+		}
+	}
+	if atomic && len(failed) > 0 {
+		return failed, nil
+	}
+	for i, write := range writes {
+		if _, ok := failed[i]; ok {
+			continue
+		}
+		if write.Delete {
+			delete(r.users, write.UserID)
+			continue
+		}
+		user := r.users[write.UserID]
+		if write.Status != nil {
+			user.Status = write.Status.To
+			user.StatusHistory = append(user.StatusHistory, *write.Status)
+		}
+		if write.Name != nil {
+			user.Name = *write.Name
+		}
+		if write.Email != nil {
+			user.Email = *write.Email
+		}
+		if write.Role != nil {
+			user.Role = *write.Role
+		}
+		r.users[write.UserID] = user
+	}
+	return failed, nil
+}
+
+func bulkUsers() []models.User {
+	return []models.User{
+		{ID: 1, TenantID: "acme", Name: "Ann", Email: "ann@acme.example", Role: models.RoleUser},
+		{ID: 2, TenantID: "acme", Name: "Bob", Email: "bob@acme.example", Role: models.RoleUser},
+		{ID: 3, TenantID: "acme", Name: "Root", Email: "root@acme.example", Role: models.RoleSuperAdmin},
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func bulkStatuses(result *dtos.UserBulkResponse) []string {
+	statuses := make([]string, len(result.Results))
+	for i, item := range result.Results {
+		statuses[i] = item.Status
+	}
+	return statuses
+}
+
+func TestUserBulk_BestEffort(t *testing.T) {
+	repo := newMemoryBulkRepository(bulkUsers()...)
+	repo.taken["taken@acme.example"] = true
+	service := services.NewUserBulkService(repo, newMemorySessionRepository(), allowAll())
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	result, err := service.ApplyBulk(ctx, "9", &dtos.UserBulk{Operations: []dtos.UserBulkOperation{
+		{Op: services.BulkOpUpdate, ID: 1, Update: &dtos.UserBulkUpdate{Name: ptr(" Anna "), Role: ptr("Admin")}},
+		{Op: services.BulkOpDelete, ID: 2},
+		{Op: services.BulkOpUpdate, ID: 1, Update: &dtos.UserBulkUpdate{Name: ptr("Again")}},
+		{Op: services.BulkOpDelete, ID: 3},
+		{Op: services.BulkOpDelete, ID: 42},
+		{Op: services.BulkOpUpdate, ID: 2, Update: &dtos.UserBulkUpdate{Email: ptr("not an email")}},
+		{Op: services.BulkOpUpdate, ID: 2, Update: &dtos.UserBulkUpdate{}},
+	}})
+	require.NoError(t, err)
+
+	assert.True(t, result.Applied)
+	assert.Equal(t, []string{
+		services.BulkSucceeded, services.BulkSucceeded, services.BulkFailed, services.BulkFailed,
+		services.BulkFailed, services.BulkFailed, services.BulkFailed,
+	}, bulkStatuses(result))
+	assert.Equal(t, dtos.UserBulkSummary{Succeeded: 2, Failed: 5}, result.Summary)
+	assert.Equal(t, "duplicate of operation 0", result.Results[2].Error)
+	assert.Equal(t, repositories.ErrUserNotFound.Error(), result.Results[4].Error)
+
+	assert.Equal(t, "Anna", repo.users[1].Name)
+	assert.Equal(t, models.RoleAdmin, repo.users[1].Role)
+	assert.NotContains(t, repo.users, 2)
+	assert.Contains(t, repo.users, 3)
+}
+
+func TestUserBulk_WriteFailuresAreReportedPerItem(t *testing.T) {
+	repo := newMemoryBulkRepository(bulkUsers()...)
+	repo.taken["taken@acme.example"] = true
+	service := services.NewUserBulkService(repo, newMemorySessionRepository(), allowAll())
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	result, err := service.ApplyBulk(ctx, "9", &dtos.UserBulk{Operations: []dtos.UserBulkOperation{
+		{Op: services.BulkOpUpdate, ID: 1, Update: &dtos.UserBulkUpdate{Email: ptr("taken@acme.example")}},
+		{Op: services.BulkOpUpdate, ID: 2, Update: &dtos.UserBulkUpdate{Name: ptr("Robert")}},
+	}})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{services.BulkFailed, services.BulkSucceeded}, bulkStatuses(result))
+	assert.Equal(t, repositories.ErrEmailTaken.Error(), result.Results[0].Error)
+	assert.Equal(t, "ann@acme.example", repo.users[1].Email)
+	assert.Equal(t, "Robert", repo.users[2].Name)
+}
+
+func TestUserBulk_AtomicRollsBackOnInvalidItem(t *testing.T) {
+	repo := newMemoryBulkRepository(bulkUsers()...)
+	service := services.NewUserBulkService(repo, newMemorySessionRepository(), allowAll())
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	result, err := service.ApplyBulk(ctx, "9", &dtos.UserBulk{Atomic: true, Operations: []dtos.UserBulkOperation{
+		{Op: services.BulkOpDelete, ID: 1},
+		{Op: services.BulkOpUpdate, ID: 2, Update: &dtos.UserBulkUpdate{Role: ptr(models.RoleSuperAdmin)}},
+	}})
+	require.ErrorIs(t, err, services.ErrBulkRolledBack)
+
+	assert.False(t, result.Applied)
+	assert.Equal(t, []string{services.BulkSkipped, services.BulkFailed}, bulkStatuses(result))
+	assert.Equal(t, dtos.UserBulkSummary{Failed: 1, Skipped: 1}, result.Summary)
+	assert.Zero(t, repo.writes)
+	assert.Contains(t, repo.users, 1)
+}
+
+func TestUserBulk_AtomicRollsBackOnWriteFailure(t *testing.T) {
+	repo := newMemoryBulkRepository(bulkUsers()...)
+	repo.taken["taken@acme.example"] = true
+	service := services.NewUserBulkService(repo, newMemorySessionRepository(), allowAll())
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	result, err := service.ApplyBulk(ctx, "9", &dtos.UserBulk{Atomic: true, Operations: []dtos.UserBulkOperation{
+		{Op: services.BulkOpDelete, ID: 1},
+		{Op: services.BulkOpUpdate, ID: 2, Update: &dtos.UserBulkUpdate{Email: ptr("taken@acme.example")}},
+	}})
+	require.ErrorIs(t, err, services.ErrBulkRolledBack)
+
+	assert.Equal(t, []string{services.BulkSkipped, services.BulkFailed}, bulkStatuses(result))
+	assert.Equal(t, 1, repo.writes)
+	assert.Contains(t, repo.users, 1)
+}
+
+func TestUserBulk_AuthorizesEachItem(t *testing.T) {
+	engine := policy.NewEngine(false)
+	require.NoError(t, engine.Load(append(policy.DefaultPolicies(),
+		policy.Policy{ID: "no-deletes", Effect: policy.EffectDeny, Actions: []string{services.ActionUsersDelete}})))
+	repo := newMemoryBulkRepository(bulkUsers()...)
+	service := services.NewUserBulkService(repo, newMemorySessionRepository(), services.NewAuthzService(engine, nil))
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	result, err := service.ApplyBulk(ctx, "9", &dtos.UserBulk{Operations: []dtos.UserBulkOperation{
+		{Op: services.BulkOpDelete, ID: 1},
+		{Op: services.BulkOpUpdate, ID: 2, Update: &dtos.UserBulkUpdate{Name: ptr("Robert")}},
+	}})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{services.BulkFailed, services.BulkSucceeded}, bulkStatuses(result))
+	assert.Contains(t, result.Results[0].Error, policy.ErrDenied.Error())
+	assert.Contains(t, repo.users, 1)
+	assert.Equal(t, "Robert", repo.users[2].Name)
+}
+
+func TestUserBulk_Deactivate(t *testing.T) {
+	users := append(bulkUsers(),
+		models.User{ID: 4, TenantID: "acme", Name: "Dan", Status: models.StatusDeactivated},
+		models.User{ID: 9, TenantID: "acme", Name: "Admin", Role: models.RoleAdmin},
+	)
+	repo := newMemoryBulkRepository(users...)
+	sessions := newMemorySessionRepository()
+	ann, bob := sessions.add(1, time.Now()), sessions.add(2, time.Now())
+	service := services.NewUserBulkService(repo, sessions, allowAll())
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	result, err := service.ApplyBulk(ctx, "9", &dtos.UserBulk{Operations: []dtos.UserBulkOperation{
+		{Op: services.BulkOpDeactivate, ID: 1, Reason: " Left the company "},
+		{Op: services.BulkOpDeactivate, ID: 2},
+		{Op: services.BulkOpDeactivate, ID: 4, Reason: "Again"},
+		{Op: services.BulkOpDeactivate, ID: 9, Reason: "Myself"},
+		{Op: services.BulkOpDeactivate, ID: 3, Reason: "Root"},
+		{Op: services.BulkOpUpdate, ID: 2, Update: &dtos.UserBulkUpdate{Name: ptr("Robert")}, Reason: "Renamed"},
+	}})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		services.BulkSucceeded, services.BulkFailed, services.BulkFailed,
+		services.BulkFailed, services.BulkFailed, services.BulkFailed,
+	}, bulkStatuses(result))
+	assert.Equal(t, services.ErrStatusReasonRequired.Error(), result.Results[1].Error)
+	assert.Contains(t, result.Results[2].Error, services.ErrInvalidStatusTransition.Error())
+	assert.Equal(t, services.ErrStatusChangeNotAllowed.Error(), result.Results[3].Error)
+	assert.Equal(t, "update takes no reason", result.Results[5].Error)
+
+	assert.Equal(t, models.StatusDeactivated, repo.users[1].Status)
+	require.Len(t, repo.users[1].StatusHistory, 1)
+	change := repo.users[1].StatusHistory[0]
+	assert.Equal(t, models.StatusActive, change.From)
+	assert.Equal(t, "Left the company", change.Reason)
+	assert.Equal(t, "9", change.ActorID)
+	assert.NotNil(t, sessions.find(ann.ID.Hex()).RevokedAt, "deactivated users are signed out")
+	assert.Nil(t, sessions.find(bob.ID.Hex()).RevokedAt)
+}
+
+func TestUserBulk_DeactivateChecksStatusPolicies(t *testing.T) {
+	engine := policy.NewEngine(false)
+	require.NoError(t, engine.Load(append(policy.DefaultPolicies(), policy.Policy{
+		ID: "no-status-changes", Effect: policy.EffectDeny, Actions: []string{services.ActionUsersUpdate}, Fields: []string{"status"},
+	})))
+	repo := newMemoryBulkRepository(bulkUsers()...)
+	service := services.NewUserBulkService(repo, newMemorySessionRepository(), services.NewAuthzService(engine, nil))
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	result, err := service.ApplyBulk(ctx, "9", &dtos.UserBulk{Operations: []dtos.UserBulkOperation{
+		{Op: services.BulkOpDeactivate, ID: 1, Reason: "Left"},
+	}})
+	require.NoError(t, err)
+
+	assert.Contains(t, result.Results[0].Error, policy.ErrDenied.Error())
+	assert.Empty(t, repo.users[1].Status)
+}
+
+func TestUserBulk_RolledBackDeactivationKeepsSessions(t *testing.T) {
+	repo := newMemoryBulkRepository(bulkUsers()...)
+	sessions := newMemorySessionRepository()
+	session := sessions.add(1, time.Now())
+	service := services.NewUserBulkService(repo, sessions, allowAll())
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	_, err := service.ApplyBulk(ctx, "9", &dtos.UserBulk{Atomic: true, Operations: []dtos.UserBulkOperation{
+		{Op: services.BulkOpDeactivate, ID: 1, Reason: "Left"},
+		{Op: services.BulkOpDelete, ID: 42},
+	}})
+	require.ErrorIs(t, err, services.ErrBulkRolledBack)
+
+	assert.Nil(t, sessions.find(session.ID.Hex()).RevokedAt)
+}
@@ -0,0 +1,233 @@
+package services_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/models"
+	"7-solutions/policy"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"7-solutions/tenant"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryExportRepository keeps users, export jobs and files in memory.
+type memoryExportRepository struct {
+	mu    sync.Mutex
+	users []models.User
+	jobs  map[primitive.ObjectID]models.ExportJob
+	files map[primitive.ObjectID][]byte
+}
+
+func newMemoryExportRepository(users ...models.User) *memoryExportRepository {
+	return &memoryExportRepository{
+		users: users,
+		jobs:  map[primitive.ObjectID]models.ExportJob{},
+		files: map[primitive.ObjectID][]byte{},
+	}
+}
+
+func (r *memoryExportRepository) StreamUsers(ctx context.Context, filter *dtos.UserFilter, fn func(*models.User) error) error {
+	for _, user := range r.users {
+		if filter.Role != "" && user.Role != filter.Role {
+			continue
+		}
+		if !matchAttributes(user.Attributes, filter.Attributes) {
+			continue
+		}
+		if err := fn(&user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryExportRepository) CreateExportJob(ctx context.Context, job *models.ExportJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job.ID = primitive.NewObjectID()
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *memoryExportRepository) UpdateExportJob(ctx context.Context, job *models.ExportJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *memoryExportRepository) GetExportJob(ctx context.Context, id string) (*models.ExportJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	objectID, _ := primitive.ObjectIDFromHex(id)
+	job, ok := r.jobs[objectID]
+	if !ok {
+		return nil, repositories.ErrExportJobNotFound
+	}
+	return &job, nil
+}
+
+func (r *memoryExportRepository) SaveExportFile(ctx context.Context, fileID primitive.ObjectID, name string, write func(io.Writer) error) (int64, error) {
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.files[fileID] = buf.Bytes()
+	return int64(buf.Len()), nil
+}
+
+func (r *memoryExportRepository) OpenExportFile(ctx context.Context, fileID primitive.ObjectID) (io.ReadCloser, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return io.NopCloser(bytes.NewReader(r.files[fileID])), nil
+}
+
+func (r *memoryExportRepository) DeleteExpiredExports(ctx context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deleted int64
+	for id, job := range r.jobs {
+		if !job.ExpiresAt.After(now) {
+			delete(r.files, *job.FileID)
+			delete(r.jobs, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func exportUsers() []models.User {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []models.User{
+		{ID: 1, Name: "Ann", Email: "ann@acme.example", Password: "hash-1", Role: models.RoleAdmin, TenantID: "acme", EmailVerified: true, CreatedAt: created},
+		{ID: 2, Name: "=HYPERLINK(\"x\")", Email: "bob@acme.example", Password: "hash-2", TenantID: "acme", CreatedAt: created},
+	}
+}
+
+func TestUserExport_CSV(t *testing.T) {
+	service := services.NewUserExportService(newMemoryExportRepository(exportUsers()...), newMemoryProfileRepository(), allowAll())
+
+	var buf bytes.Buffer
+	rows, err := service.ExportUsers(tenant.WithID(context.Background(), "acme"), &dtos.UserExport{Format: services.ExportFormatCSV}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rows)
+	assert.NotContains(t, buf.String(), "hash-")
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"id", "name", "email", "emailVerified", "role", "tenantId", "createdAt"},
+		{"1", "Ann", "ann@acme.example", "true", "admin", "acme", "2026-01-02T03:04:05Z"},
+		{"2", "'=HYPERLINK(\"x\")", "bob@acme.example", "false", "user", "acme", "2026-01-02T03:04:05Z"},
+	}, records)
+}
+
+func TestUserExport_NDJSONWithFilter(t *testing.T) {
+	service := services.NewUserExportService(newMemoryExportRepository(exportUsers()...), newMemoryProfileRepository(), allowAll())
+
+	var buf bytes.Buffer
+	input := &dtos.UserExport{Format: services.ExportFormatNDJSON, UserFilter: dtos.UserFilter{Role: models.RoleAdmin}}
+	_, err := service.ExportUsers(tenant.WithID(context.Background(), "acme"), input, &buf)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1)
+	var row map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &row))
+	assert.Equal(t, "ann@acme.example", row["email"])
+	assert.NotContains(t, row, "password")
+}
+
+func TestUserExport_XLSX(t *testing.T) {
+	service := services.NewUserExportService(newMemoryExportRepository(exportUsers()...), newMemoryProfileRepository(), allowAll())
+
+	var buf bytes.Buffer
+	_, err := service.ExportUsers(tenant.WithID(context.Background(), "acme"), &dtos.UserExport{Format: services.ExportFormatXLSX}, &buf)
+	require.NoError(t, err)
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	f, err := archive.Open("xl/worksheets/sheet1.xml")
+	require.NoError(t, err)
+	sheet, _ := io.ReadAll(f)
+	assert.Contains(t, string(sheet), "ann@acme.example")
+	assert.Equal(t, 3, strings.Count(string(sheet), "<row "))
+}
+
+func TestUserExport_Rejected(t *testing.T) {
+	ctx := tenant.WithID(context.Background(), "acme")
+	var buf bytes.Buffer
+
+	service := services.NewUserExportService(newMemoryExportRepository(exportUsers()...), newMemoryProfileRepository(), allowAll())
+	_, err := service.ExportUsers(ctx, &dtos.UserExport{Format: "pdf"}, &buf)
+	assert.ErrorIs(t, err, services.ErrInvalidExport)
+
+	engine := policy.NewEngine(false)
+	require.NoError(t, engine.Load(append(policy.DefaultPolicies(),
+		policy.Policy{ID: "no-exports", Effect: policy.EffectDeny, Actions: []string{services.ActionUsersExport}})))
+	service = services.NewUserExportService(newMemoryExportRepository(exportUsers()...), newMemoryProfileRepository(), services.NewAuthzService(engine, nil))
+	_, err = service.ExportUsers(ctx, &dtos.UserExport{Format: services.ExportFormatCSV}, &buf)
+	assert.ErrorIs(t, err, policy.ErrDenied)
+	assert.Zero(t, buf.Len())
+}
+
+func TestUserExport_BackgroundJob(t *testing.T) {
+	repo := newMemoryExportRepository(exportUsers()...)
+	service := services.NewUserExportService(repo, newMemoryProfileRepository(), allowAll())
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	job, err := service.StartExport(ctx, "1", &dtos.UserExport{Format: services.ExportFormatNDJSON})
+	require.NoError(t, err)
+	assert.Equal(t, models.ExportRunning, job.Status)
+
+	waitCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, service.Wait(waitCtx))
+
+	done, file, err := service.OpenExportFile(ctx, job.ID)
+	require.NoError(t, err)
+	defer file.Close()
+	assert.Equal(t, models.ExportCompleted, done.Status)
+	assert.Equal(t, 2, done.Rows)
+	data, _ := io.ReadAll(file)
+	assert.Equal(t, done.Size, int64(len(data)))
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+
+	deleted, err := service.PurgeExpiredExports(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestUserExport_NotReady(t *testing.T) {
+	repo := newMemoryExportRepository()
+	service := services.NewUserExportService(repo, newMemoryProfileRepository(), allowAll())
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	fileID := primitive.NewObjectID()
+	job := &models.ExportJob{Status: models.ExportRunning, FileID: &fileID, ExpiresAt: time.Now().Add(-time.Minute)}
+	require.NoError(t, repo.CreateExportJob(ctx, job))
+
+	_, _, err := service.OpenExportFile(ctx, job.ID.Hex())
+	assert.ErrorIs(t, err, services.ErrExportNotReady)
+
+	deleted, err := service.PurgeExpiredExports(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	_, err = service.GetExportJob(ctx, job.ID.Hex())
+	assert.ErrorIs(t, err, repositories.ErrExportJobNotFound)
+}
@@ -0,0 +1,265 @@
+package services_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"7-solutions/tenant"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryImportRepository keeps users and import jobs in memory. Emails in
+// taken fail on insert as if another request had just created them.
+type memoryImportRepository struct {
+	mu     sync.Mutex
+	users  []models.User
+	jobs   map[primitive.ObjectID]models.ImportJob
+	taken  map[string]bool
+	nextID int
+}
+
+func newMemoryImportRepository(users ...models.User) *memoryImportRepository {
+	return &memoryImportRepository{
+		users:  users,
+		jobs:   map[primitive.ObjectID]models.ImportJob{},
+		taken:  map[string]bool{},
+		nextID: 100,
+	}
+}
+
+func (r *memoryImportRepository) FindUsersByEmail(ctx context.Context, emails []string) ([]models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	users := []models.User{}
+	for _, user := range r.users {
+		for _, email := range emails {
+			if strings.EqualFold(user.Email, email) {
+				users = append(users, user)
+			}
+		}
+	}
+	return users, nil
+}
+
+func (r *memoryImportRepository) InsertUsers(ctx context.Context, users []models.User) (map[int]error, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tenantID, _ := tenant.FromContext(ctx)
+	failed := map[int]error{}
+	for i := range users {
+		r.nextID++
+		users[i].ID = r.nextID
+		users[i].TenantID = tenantID
+		if r.taken[users[i].Email] {
+			failed[i] = repositories.ErrEmailTaken
+			continue
+		}
+		r.users = append(r.users, users[i])
+	}
+	return failed, nil
+}
+
+func (r *memoryImportRepository) UpdateUsers(ctx context.Context, users []models.User) (map[int]error, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, update := range users {
+		for i := range r.users {
+			if r.users[i].ID == update.ID {
+				r.users[i].Name = update.Name
+				if update.Role != "" {
+					r.users[i].Role = update.Role
+				}
+			}
+		}
+	}
+	return map[int]error{}, nil
+}
+
+func (r *memoryImportRepository) CreateImportJob(ctx context.Context, job *models.ImportJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job.ID = primitive.NewObjectID()
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *memoryImportRepository) UpdateImportJob(ctx context.Context, job *models.ImportJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := *job
+	saved.Results = append([]models.ImportRowResult{}, job.Results...)
+	r.jobs[job.ID] = saved
+	return nil
+}
+
+func (r *memoryImportRepository) GetImportJob(ctx context.Context, id string) (*models.ImportJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	objectID, _ := primitive.ObjectIDFromHex(id)
+	job, ok := r.jobs[objectID]
+	if !ok {
+		return nil, repositories.ErrImportJobNotFound
+	}
+	return &job, nil
+}
+
+func (r *memoryImportRepository) user(email string) (models.User, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, true
+		}
+	}
+	return models.User{}, false
+}
+
+func importCSV(t *testing.T, service services.UserImportService, dryRun bool, csv string) *dtos.UserImportResponse {
+	t.Helper()
+	input := &dtos.UserImport{Format: services.ImportFormatCSV, DryRun: dryRun}
+	result, err := service.ImportUsers(tenant.WithID(context.Background(), "acme"), "1", input, strings.NewReader(csv))
+	require.NoError(t, err)
+	return result
+}
+
+func TestUserImport_CreatesUpdatesAndReportsRows(t *testing.T) {
+	repo := newMemoryImportRepository(
+		models.User{ID: 1, Name: "Old Name", Email: "ann@acme.example", Role: models.RoleUser, TenantID: "acme"},
+		models.User{ID: 2, Name: "Root", Email: "root@acme.example", Role: models.RoleSuperAdmin, TenantID: "acme"},
+	)
+	repo.taken["raced@acme.example"] = true
+	service := services.NewUserImportService(repo, allowAll())
+
+	result := importCSV(t, service, false, "\uFEFFEmail,Name,Password,Role\n"+
+		"bob@acme.example,Bob,secret123,\n"+
+		"ANN@acme.example,Ann,,admin\n"+
+		"not-an-email,Nobody,,\n"+
+		"bob@acme.example,Bob Again,,\n"+
+		"root@acme.example,Root,,\n"+
+		"raced@acme.example,Raced,,\n"+
+		"carl@acme.example,Carl,,owner\n")
+
+	assert.Empty(t, result.ID)
+	assert.Equal(t, models.ImportCompleted, result.Status)
+	assert.Equal(t, 7, result.Total)
+	assert.Equal(t, 7, result.Processed)
+	assert.Equal(t, dtos.UserImportSummary{Created: 1, Updated: 1, Invalid: 2, Conflicts: 2, Failed: 1}, result.Summary)
+
+	statuses := make([]string, len(result.Results))
+	for i, row := range result.Results {
+		assert.Equal(t, i+1, row.Row)
+		statuses[i] = row.Status
+	}
+	assert.Equal(t, []string{
+		models.ImportRowCreated, models.ImportRowUpdated, models.ImportRowInvalid, models.ImportRowConflict,
+		models.ImportRowFailed, models.ImportRowConflict, models.ImportRowInvalid,
+	}, statuses)
+	assert.Contains(t, result.Results[3].Error, "duplicate of row 1")
+
+	bob, ok := repo.user("bob@acme.example")
+	require.True(t, ok)
+	assert.Equal(t, "acme", bob.TenantID)
+	assert.Equal(t, models.RoleUser, bob.Role)
+	assert.NotEqual(t, "secret123", bob.Password)
+	assert.Equal(t, bob.ID, result.Results[0].UserID)
+
+	ann, _ := repo.user("ann@acme.example")
+	assert.Equal(t, "Ann", ann.Name)
+	assert.Equal(t, models.RoleAdmin, ann.Role)
+	assert.Equal(t, 1, result.Results[1].UserID)
+}
+
+func TestUserImport_DryRunWritesNothing(t *testing.T) {
+	repo := newMemoryImportRepository(models.User{ID: 1, Name: "Old Name", Email: "ann@acme.example", TenantID: "acme"})
+	service := services.NewUserImportService(repo, allowAll())
+
+	result := importCSV(t, service, true, "email,name\nbob@acme.example,Bob\nann@acme.example,Ann\n")
+
+	assert.True(t, result.DryRun)
+	assert.Equal(t, dtos.UserImportSummary{Created: 1, Updated: 1}, result.Summary)
+	_, created := repo.user("bob@acme.example")
+	assert.False(t, created)
+	ann, _ := repo.user("ann@acme.example")
+	assert.Equal(t, "Old Name", ann.Name)
+}
+
+func TestUserImport_NDJSON(t *testing.T) {
+	repo := newMemoryImportRepository()
+	service := services.NewUserImportService(repo, allowAll())
+
+	input := &dtos.UserImport{Format: services.ImportFormatNDJSON}
+	body := `{"email":"bob@acme.example","name":"Bob"}` + "\n\n" + `{"email":"x@acme.example","nickname":"X"}` + "\n"
+	result, err := service.ImportUsers(tenant.WithID(context.Background(), "acme"), "1", input, strings.NewReader(body))
+	require.NoError(t, err)
+
+	require.Len(t, result.Results, 2)
+	assert.Equal(t, models.ImportRowCreated, result.Results[0].Status)
+	assert.Equal(t, models.ImportRowInvalid, result.Results[1].Status)
+	assert.Contains(t, result.Results[1].Error, "invalid JSON")
+}
+
+func TestUserImport_InvalidFiles(t *testing.T) {
+	service := services.NewUserImportService(newMemoryImportRepository(), allowAll())
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	for name, body := range map[string]string{
+		"empty":          "",
+		"header only":    "email,name\n",
+		"unknown column": "email,nickname\nbob@acme.example,Bob\n",
+		"no email":       "name\nBob\n",
+	} {
+		_, err := service.ImportUsers(ctx, "1", &dtos.UserImport{Format: services.ImportFormatCSV}, strings.NewReader(body))
+		assert.ErrorIs(t, err, services.ErrInvalidImport, name)
+	}
+
+	_, err := service.ImportUsers(ctx, "1", &dtos.UserImport{Format: "xml"}, strings.NewReader("<users/>"))
+	assert.ErrorIs(t, err, services.ErrInvalidImport)
+
+	var large strings.Builder
+	large.WriteString("email,name\n")
+	for i := 0; i <= services.MaxImportRows; i++ {
+		fmt.Fprintf(&large, "user%d@acme.example,User\n", i)
+	}
+	_, err = service.ImportUsers(ctx, "1", &dtos.UserImport{Format: services.ImportFormatCSV}, strings.NewReader(large.String()))
+	assert.ErrorIs(t, err, services.ErrImportTooLarge)
+}
+
+func TestUserImport_LargeImportRunsAsJob(t *testing.T) {
+	repo := newMemoryImportRepository()
+	service := services.NewUserImportService(repo, allowAll())
+
+	var body strings.Builder
+	body.WriteString("email,name\n")
+	for i := 0; i < services.ImportSyncRows+1; i++ {
+		fmt.Fprintf(&body, "user%d@acme.example,User %d\n", i, i)
+	}
+	body.WriteString("broken,Broken\n")
+
+	result := importCSV(t, service, false, body.String())
+	require.NotEmpty(t, result.ID)
+	assert.Equal(t, models.ImportRunning, result.Status)
+	assert.Equal(t, services.ImportSyncRows+2, result.Total)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, service.Wait(ctx))
+
+	job, err := service.GetImportJob(tenant.WithID(context.Background(), "acme"), result.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ImportCompleted, job.Status)
+	assert.Equal(t, services.ImportSyncRows+2, job.Processed)
+	assert.Equal(t, dtos.UserImportSummary{Created: services.ImportSyncRows + 1, Invalid: 1}, job.Summary)
+	assert.NotEmpty(t, job.CompletedAt)
+	_, ok := repo.user("user1000@acme.example")
+	assert.True(t, ok)
+}
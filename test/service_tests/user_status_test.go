@@ -0,0 +1,121 @@
+package services_test
+
+import (
+	"7-solutions/dtos"
+	"7-solutions/models"
+	"7-solutions/repositories"
+	"7-solutions/services"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStatusRepository keeps users in memory.
+type memoryStatusRepository struct {
+	users map[int]*models.User
+}
+
+func newMemoryStatusRepository(users ...models.User) *memoryStatusRepository {
+	repo := &memoryStatusRepository{users: map[int]*models.User{}}
+	for i := range users {
+		repo.users[users[i].ID] = &users[i]
+	}
+	return repo
+}
+
+func (r *memoryStatusRepository) GetUser(ctx context.Context, id int) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repositories.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *memoryStatusRepository) GetUserStatus(ctx context.Context, id int) (string, error) {
+	user, err := r.GetUser(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return user.AccountStatus(), nil
+}
+
+func (r *memoryStatusRepository) ChangeUserStatus(ctx context.Context, id int, change *models.StatusChange) error {
+	user, ok := r.users[id]
+	if !ok || user.AccountStatus() != change.From {
+		return repositories.ErrStatusChanged
+	}
+	user.Status = change.To
+	user.StatusHistory = append(user.StatusHistory, *change)
+	return nil
+}
+
+func statusUsers() []models.User {
+	return []models.User{
+		{ID: 1, Name: "Ann", Role: models.RoleUser},
+		{ID: 2, Name: "Root", Role: models.RoleSuperAdmin},
+		{ID: 9, Name: "Admin", Role: models.RoleAdmin},
+	}
+}
+
+func TestUserStatusService_ChangeStatus(t *testing.T) {
+	repo := newMemoryStatusRepository(statusUsers()...)
+	sessions := newMemorySessionRepository()
+	session := sessions.add(1, time.Now())
+	service := services.NewUserStatusService(repo, sessions, allowAll())
+	ctx := context.Background()
+
+	status, err := service.ChangeStatus(ctx, "9", 1, &dtos.UserStatusUpdate{Status: models.StatusSuspended, Reason: " chargeback "})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSuspended, status.Status)
+	require.Len(t, status.History, 1)
+	assert.Equal(t, dtos.UserStatusChange{From: "active", To: "suspended", Reason: "chargeback", ActorID: "9", At: status.History[0].At}, status.History[0])
+	assert.NotNil(t, sessions.find(session.ID.Hex()).RevokedAt)
+
+	status, err = service.ChangeStatus(ctx, "9", 1, &dtos.UserStatusUpdate{Status: models.StatusActive})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, status.Status)
+
+	status, err = service.GetStatus(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, status.History, 2)
+}
+
+func TestUserStatusService_ChangeStatusRefused(t *testing.T) {
+	repo := newMemoryStatusRepository(statusUsers()...)
+	repo.users[1].Status = models.StatusDeactivated
+	service := services.NewUserStatusService(repo, newMemorySessionRepository(), allowAll())
+	ctx := context.Background()
+
+	_, err := service.ChangeStatus(ctx, "9", 1, &dtos.UserStatusUpdate{Status: models.StatusLocked, Reason: "brute force"})
+	assert.ErrorIs(t, err, services.ErrInvalidStatusTransition)
+	_, err = service.ChangeStatus(ctx, "9", 1, &dtos.UserStatusUpdate{Status: models.StatusDeactivated, Reason: "again"})
+	assert.ErrorIs(t, err, services.ErrInvalidStatusTransition)
+	_, err = service.ChangeStatus(ctx, "9", 9, &dtos.UserStatusUpdate{Status: models.StatusLocked, Reason: "oops"})
+	assert.ErrorIs(t, err, services.ErrStatusChangeNotAllowed)
+	_, err = service.ChangeStatus(ctx, "9", 2, &dtos.UserStatusUpdate{Status: models.StatusLocked, Reason: "takeover"})
+	assert.ErrorIs(t, err, services.ErrStatusChangeNotAllowed)
+	_, err = service.ChangeStatus(ctx, "2", 9, &dtos.UserStatusUpdate{Status: models.StatusLocked, Reason: "  "})
+	assert.ErrorIs(t, err, services.ErrStatusReasonRequired)
+	_, err = service.ChangeStatus(ctx, "9", 42, &dtos.UserStatusUpdate{Status: models.StatusLocked, Reason: "gone"})
+	assert.ErrorIs(t, err, repositories.ErrUserNotFound)
+
+	assert.Empty(t, repo.users[1].StatusHistory)
+	assert.Empty(t, repo.users[9].StatusHistory)
+}
+
+func TestAccountChecker(t *testing.T) {
+	repo := newMemoryStatusRepository(statusUsers()...)
+	repo.users[1].Status = models.StatusLocked
+	checker := services.NewAccountChecker(repo)
+	ctx := context.Background()
+
+	assert.NoError(t, checker.CheckAccount(ctx, "9"))
+	err := checker.CheckAccount(ctx, "1")
+	assert.ErrorIs(t, err, services.ErrAccountInactive)
+	assert.ErrorContains(t, err, "locked")
+	assert.ErrorIs(t, checker.CheckAccount(ctx, "42"), services.ErrAccountInactive)
+}
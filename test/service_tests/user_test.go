@@ -2,7 +2,10 @@ package services_test
 
 import (
 	"7-solutions/dtos"
+	"7-solutions/policy"
 	"7-solutions/services"
+	"7-solutions/utils"
+	"context"
 	"errors"
 	"testing"
 
@@ -14,39 +17,46 @@ type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) CreateUser(userDto *dtos.UserRegister) (*dtos.UserResponse, error) {
-	args := m.Called(userDto)
+func (m *MockUserRepository) CreateUser(ctx context.Context, userDto *dtos.UserRegister) (*dtos.UserResponse, error) {
+	args := m.Called(ctx, userDto)
 	return args.Get(0).(*dtos.UserResponse), args.Error(1)
 }
 
-func (m *MockUserRepository) GetUserByID(id int) (*dtos.UserResponse, error) {
-	args := m.Called(id)
+func (m *MockUserRepository) GetUserByID(ctx context.Context, id int) (*dtos.UserResponse, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(*dtos.UserResponse), args.Error(1)
 }
 
-func (m *MockUserRepository) GetAllUsers() ([]dtos.UserResponse, error) {
-	args := m.Called()
+func (m *MockUserRepository) GetAllUsers(ctx context.Context, filter *dtos.UserFilter) ([]dtos.UserResponse, error) {
+	args := m.Called(ctx, filter)
 	return args.Get(0).([]dtos.UserResponse), args.Error(1)
 }
 
-func (m *MockUserRepository) UpdateUser(id int, userDto *dtos.UserUpdate) (*dtos.UserResponse, error) {
-	args := m.Called(id, userDto)
+func (m *MockUserRepository) UpdateUser(ctx context.Context, id int, userDto *dtos.UserUpdate) (*dtos.UserResponse, error) {
+	args := m.Called(ctx, id, userDto)
 	return args.Get(0).(*dtos.UserResponse), args.Error(1)
 }
 
-func (m *MockUserRepository) DeleteUser(id int) error {
-	args := m.Called(id)
+func (m *MockUserRepository) DeleteUser(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) CountUsers() (int64, error) {
-	args := m.Called()
+func (m *MockUserRepository) CountUsers(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
 	return args.Get(0).(int64), args.Error(1)
 }
 
+// allowAll authorizes everything, like the default policies.
+func allowAll() services.Authorizer {
+	engine := policy.NewEngine(false)
+	_ = engine.Load(policy.DefaultPolicies())
+	return services.NewAuthzService(engine, nil)
+}
+
 func TestCreateUser_Success(t *testing.T) {
 	repo := new(MockUserRepository)
-	svc := services.NewUserService(repo)
+	svc := services.NewUserService(repo, newMemoryProfileRepository(), allowAll())
 
 	userInput := &dtos.UserRegister{
 		Name:     "Test User",
@@ -59,9 +69,9 @@ func TestCreateUser_Success(t *testing.T) {
 		Email: "test@example.com",
 	}
 
-	repo.On("CreateUser", mock.AnythingOfType("*dtos.UserRegister")).Return(userResponse, nil)
+	repo.On("CreateUser", mock.Anything, mock.AnythingOfType("*dtos.UserRegister")).Return(userResponse, nil)
 
-	user, err := svc.CreateUser(userInput)
+	user, err := svc.CreateUser(context.Background(), userInput)
 	assert.NoError(t, err)
 	assert.Equal(t, userResponse.Name, user.Name)
 	assert.Equal(t, userResponse.Email, user.Email)
@@ -73,7 +83,7 @@ func TestCreateUser_HashPasswordError(t *testing.T) {
 
 func TestGetUserByID_Success(t *testing.T) {
 	repo := new(MockUserRepository)
-	svc := services.NewUserService(repo)
+	svc := services.NewUserService(repo, newMemoryProfileRepository(), allowAll())
 
 	userID := 1
 	userResponse := &dtos.UserResponse{
@@ -82,9 +92,9 @@ func TestGetUserByID_Success(t *testing.T) {
 		Email: "test@example.com",
 	}
 
-	repo.On("GetUserByID", userID).Return(userResponse, nil)
+	repo.On("GetUserByID", mock.Anything, userID).Return(userResponse, nil)
 
-	user, err := svc.GetUserByID(userID)
+	user, err := svc.GetUserByID(context.Background(), userID)
 	assert.NoError(t, err)
 	assert.Equal(t, userID, user.ID)
 	repo.AssertExpectations(t)
@@ -92,12 +102,12 @@ func TestGetUserByID_Success(t *testing.T) {
 
 func TestGetUserByID_NotFound(t *testing.T) {
 	repo := new(MockUserRepository)
-	svc := services.NewUserService(repo)
+	svc := services.NewUserService(repo, newMemoryProfileRepository(), allowAll())
 
 	userID := 999
-	repo.On("GetUserByID", userID).Return((*dtos.UserResponse)(nil), errors.New("user not found"))
+	repo.On("GetUserByID", mock.Anything, userID).Return((*dtos.UserResponse)(nil), errors.New("user not found"))
 
-	user, err := svc.GetUserByID(userID)
+	user, err := svc.GetUserByID(context.Background(), userID)
 	assert.Error(t, err)
 	assert.Nil(t, user)
 	repo.AssertExpectations(t)
@@ -105,16 +115,16 @@ func TestGetUserByID_NotFound(t *testing.T) {
 
 func TestGetAllUsers_Success(t *testing.T) {
 	repo := new(MockUserRepository)
-	svc := services.NewUserService(repo)
+	svc := services.NewUserService(repo, newMemoryProfileRepository(), allowAll())
 
 	users := []dtos.UserResponse{
 		{ID: 1, Name: "User1", Email: "user1@example.com"},
 		{ID: 2, Name: "User2", Email: "user2@example.com"},
 	}
 
-	repo.On("GetAllUsers").Return(users, nil)
+	repo.On("GetAllUsers", mock.Anything, mock.Anything).Return(users, nil)
 
-	result, err := svc.GetAllUsers()
+	result, err := svc.GetAllUsers(context.Background(), &dtos.UserFilter{})
 	assert.NoError(t, err)
 	assert.Len(t, result, 2)
 	repo.AssertExpectations(t)
@@ -122,7 +132,7 @@ func TestGetAllUsers_Success(t *testing.T) {
 
 func TestUpdateUser_Success(t *testing.T) {
 	repo := new(MockUserRepository)
-	svc := services.NewUserService(repo)
+	svc := services.NewUserService(repo, newMemoryProfileRepository(), allowAll())
 
 	userID := 1
 	updateData := &dtos.UserUpdate{
@@ -135,9 +145,10 @@ func TestUpdateUser_Success(t *testing.T) {
 		Email: updateData.Email,
 	}
 
-	repo.On("UpdateUser", userID, updateData).Return(updatedUser, nil)
+	repo.On("GetUserByID", mock.Anything, userID).Return(&dtos.UserResponse{ID: userID, Name: "Name", Email: "user@example.com"}, nil)
+	repo.On("UpdateUser", mock.Anything, userID, updateData).Return(updatedUser, nil)
 
-	result, err := svc.UpdateUser(userID, updateData)
+	result, err := svc.UpdateUser(context.Background(), userID, updateData)
 	assert.NoError(t, err)
 	assert.Equal(t, updateData.Name, result.Name)
 	repo.AssertExpectations(t)
@@ -145,24 +156,40 @@ func TestUpdateUser_Success(t *testing.T) {
 
 func TestDeleteUser_Success(t *testing.T) {
 	repo := new(MockUserRepository)
-	svc := services.NewUserService(repo)
+	svc := services.NewUserService(repo, newMemoryProfileRepository(), allowAll())
 
 	userID := 1
-	repo.On("DeleteUser", userID).Return(nil)
+	repo.On("DeleteUser", mock.Anything, userID).Return(nil)
 
-	err := svc.DeleteUser(userID)
+	err := svc.DeleteUser(context.Background(), userID)
 	assert.NoError(t, err)
 	repo.AssertExpectations(t)
 }
 
 func TestDeleteUser_Failure(t *testing.T) {
 	repo := new(MockUserRepository)
-	svc := services.NewUserService(repo)
+	svc := services.NewUserService(repo, newMemoryProfileRepository(), allowAll())
 
 	userID := 2
-	repo.On("DeleteUser", userID).Return(errors.New("delete failed"))
+	repo.On("DeleteUser", mock.Anything, userID).Return(errors.New("delete failed"))
 
-	err := svc.DeleteUser(userID)
+	err := svc.DeleteUser(context.Background(), userID)
 	assert.Error(t, err)
 	repo.AssertExpectations(t)
 }
+
+func TestCreateUser_Canceled(t *testing.T) {
+	repo := new(MockUserRepository)
+	svc := services.NewUserService(repo, newMemoryProfileRepository(), allowAll())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := svc.CreateUser(ctx, &dtos.UserRegister{
+		Name:     "Test User",
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+	assert.ErrorIs(t, err, utils.ErrCanceled)
+	repo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+}
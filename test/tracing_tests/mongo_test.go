@@ -0,0 +1,49 @@
+package tracing_test
+
+import (
+	"7-solutions/repositories"
+	"7-solutions/tenant"
+	"7-solutions/tracing"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMongoMonitor(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tracing.NewProvider("test", 1, sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	clientOptions := options.Client().SetMonitor(tracing.NewMongoMonitor())
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock).ClientOptions(clientOptions))
+
+	mt.Run("TestCreatesClientSpanUnderParent", func(mt *mtest.T) {
+		exporter.Reset()
+		repo := repositories.NewUserRepository(mt.Client.Database("testdb"))
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "test.users", mtest.FirstBatch,
+			bson.D{{Key: "n", Value: int64(3)}},
+		))
+
+		ctx, parent := tracing.Start(tenant.WithID(context.Background(), tenant.DefaultID), "parent")
+		_, err := repo.CountUsers(ctx)
+		parent.End()
+		require.NoError(t, err)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 2)
+		assert.Equal(t, "aggregate users", spans[0].Name)
+		assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind)
+		assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent.SpanID())
+	})
+}
@@ -0,0 +1,84 @@
+package xlsx_test
+
+import (
+	"7-solutions/xlsx"
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string `xml:"r,attr"`
+			T      string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readPart(t *testing.T, data []byte, name string) []byte {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	f, err := archive.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+	part, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return part
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := xlsx.NewWriter(&buf, "Users & Co")
+	require.NoError(t, err)
+	require.NoError(t, w.WriteRow("id", "name", "verified"))
+	require.NoError(t, w.WriteRow(7, "<Ann> & \"Bob\"\x01", true))
+	require.NoError(t, w.Close())
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		readPart(t, buf.Bytes(), name)
+	}
+	assert.Contains(t, string(readPart(t, buf.Bytes(), "xl/workbook.xml")), `name="Users &amp; Co"`)
+
+	var s sheet
+	require.NoError(t, xml.Unmarshal(readPart(t, buf.Bytes(), "xl/worksheets/sheet1.xml"), &s))
+	require.Len(t, s.Rows, 2)
+	assert.Equal(t, "inlineStr", s.Rows[0].Cells[0].T)
+	assert.Equal(t, "id", s.Rows[0].Cells[0].Inline)
+
+	row := s.Rows[1]
+	assert.Equal(t, 2, row.R)
+	assert.Equal(t, "A2", row.Cells[0].R)
+	assert.Equal(t, "7", row.Cells[0].Value)
+	assert.Equal(t, "<Ann> & \"Bob\"�", row.Cells[1].Inline)
+	assert.Equal(t, "b", row.Cells[2].T)
+	assert.Equal(t, "1", row.Cells[2].Value)
+}
+
+func TestWriter_ColumnNames(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := xlsx.NewWriter(&buf, "Sheet1")
+	require.NoError(t, err)
+	values := make([]any, 28)
+	for i := range values {
+		values[i] = i
+	}
+	require.NoError(t, w.WriteRow(values...))
+	require.NoError(t, w.Close())
+
+	var s sheet
+	require.NoError(t, xml.Unmarshal(readPart(t, buf.Bytes(), "xl/worksheets/sheet1.xml"), &s))
+	cells := s.Rows[0].Cells
+	assert.Equal(t, "Z1", cells[25].R)
+	assert.Equal(t, "AA1", cells[26].R)
+	assert.Equal(t, "AB1", cells[27].R)
+}
@@ -0,0 +1,125 @@
+package tracing
+
+import (
+	"7-solutions/utils"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+
+	instrumentationName = "7-solutions"
+)
+
+type Config struct {
+	Exporter     string
+	ServiceName  string
+	OTLPEndpoint string
+	OTLPInsecure bool
+	SampleRatio  float64
+}
+
+// NewConfigFromEnv reads TRACING_EXPORTER (none, stdout or otlp) and the
+// standard OTEL_* variables. The OTLP exporter additionally honours every
+// OTEL_EXPORTER_OTLP_* variable supported by the SDK.
+func NewConfigFromEnv() (Config, error) {
+	config := Config{
+		Exporter:     utils.GetEnv("TRACING_EXPORTER", ExporterNone),
+		ServiceName:  utils.GetEnv("OTEL_SERVICE_NAME", "7-solutions-api"),
+		OTLPEndpoint: os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		SampleRatio:  1,
+	}
+
+	var err error
+	if config.OTLPInsecure, err = utils.GetEnvBool("OTEL_EXPORTER_OTLP_INSECURE", false); err != nil {
+		return config, err
+	}
+	if v := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); v != "" {
+		if config.SampleRatio, err = strconv.ParseFloat(v, 64); err != nil {
+			return config, fmt.Errorf("invalid OTEL_TRACES_SAMPLER_ARG: %w", err)
+		}
+	}
+
+	switch config.Exporter {
+	case ExporterNone, ExporterStdout, ExporterOTLP:
+	default:
+		return config, fmt.Errorf("unknown TRACING_EXPORTER %q", config.Exporter)
+	}
+	return config, nil
+}
+
+// Setup installs a global tracer provider and the W3C trace context
+// propagator. The returned function flushes and stops the provider.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if config.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Exporter {
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if config.OTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(config.OTLPEndpoint))
+		}
+		if config.OTLPInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s exporter: %w", config.Exporter, err)
+	}
+
+	provider := NewProvider(config.ServiceName, config.SampleRatio, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider builds a tracer provider for serviceName. Tests pass
+// sdktrace.WithSyncer(tracetest.NewInMemoryExporter()) to inspect spans.
+func NewProvider(serviceName string, sampleRatio float64, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	res := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	}, opts...)
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+// Start starts an internal span named name as a child of the span in ctx.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// RecordError marks span as failed with err, if err is not nil.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
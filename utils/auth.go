@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -9,12 +12,66 @@ import (
 
 var secretKey = []byte("secretpassword")
 
-func GenerateToken(name, email string) (string, error) {
+// LoginTokenTTL is how long login tokens, and the sessions they belong to,
+// stay valid.
+const LoginTokenTTL = time.Hour * 24
+
+// GenerateSessionToken issues a login token tied, by its sid claim, to the
+// session sessionID, so it stops working once the session is revoked.
+func GenerateSessionToken(id int, name, email, role, tenantID, sessionID string) (string, error) {
+	claims := loginClaims(id, name, email, role, tenantID)
+	claims["sid"] = sessionID
+	return signToken(claims, LoginTokenTTL)
+}
+
+// GenerateImpersonationToken issues a login token for the user that also
+// names, in the RFC 8693 act claim, the user actorID acting on their behalf.
+func GenerateImpersonationToken(id int, name, email, role, tenantID, actorID string, ttl time.Duration) (string, error) {
+	claims := loginClaims(id, name, email, role, tenantID)
+	claims["act"] = map[string]interface{}{"sub": actorID}
+	return signToken(claims, ttl)
+}
+
+func loginClaims(id int, name, email, role, tenantID string) jwt.MapClaims {
 	claims := jwt.MapClaims{}
+	claims["sub"] = strconv.Itoa(id)
 	claims["email"] = email
-	claims["exp"] = time.Now().Add(time.Hour * 24).Unix()
 	claims["name"] = name
-	claims["iat"] = time.Now().Unix()
+	claims["role"] = role
+	claims["tenantId"] = tenantID
+	return claims
+}
+
+// GenerateOAuthToken issues an access token to clientID limited to scope
+// and to the client's tenant. owner holds the claims of the resource owner's
+// login token, or is nil when the client acts on its own behalf (client
+// credentials grant). The owner's role is deliberately not carried over.
+func GenerateOAuthToken(clientID, tenantID, scope string, owner jwt.MapClaims, ttl time.Duration) (string, error) {
+	claims := jwt.MapClaims{}
+	claims["client_id"] = clientID
+	claims["scope"] = scope
+	claims["tenantId"] = tenantID
+	if owner != nil {
+		claims["sub"] = owner["sub"]
+		claims["email"] = owner["email"]
+		claims["name"] = owner["name"]
+	} else {
+		claims["sub"] = "client:" + clientID
+	}
+	return signToken(claims, ttl)
+}
+
+// signToken adds the registered claims, including a random jti so the token
+// can be revoked individually.
+func signToken(claims jwt.MapClaims, ttl time.Duration) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", fmt.Errorf("failed to generate token id: %w", err)
+	}
+	now := time.Now()
+	claims["jti"] = hex.EncodeToString(jti)
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(secretKey)
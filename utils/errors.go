@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrCanceled is returned when the caller gave up on the operation,
+	// typically because the client disconnected.
+	ErrCanceled = errors.New("operation canceled")
+	// ErrTimeout is returned when the operation ran past its deadline.
+	ErrTimeout = errors.New("operation timed out")
+)
+
+// ContextError tags err with ErrCanceled or ErrTimeout when it was caused by
+// context cancellation or a driver timeout, and returns it unchanged
+// otherwise.
+func ContextError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrCanceled), errors.Is(err, ErrTimeout):
+		return err
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("%w: %w", ErrCanceled, err)
+	case errors.Is(err, context.DeadlineExceeded), mongo.IsTimeout(err):
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -9,15 +10,308 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+const (
+	EmailUniqueIndexName         = "tenantId_1_email_1"
+	APIKeyPrefixUniqueIndexName  = "prefix_1"
+	OAuthClientIDUniqueIndexName = "clientId_1"
+	OAuthCodeHashUniqueIndexName = "codeHash_1"
+	IdentityUniqueIndexName      = "provider_1_subject_1"
+	GroupNameUniqueIndexName     = "tenantId_1_name_1"
+	GroupMemberUniqueIndexName   = "groupId_1_userId_1"
+	InvitationTokenIndexName     = "tokenHash_1"
+	AttributeKeyUniqueIndexName  = "tenantId_1_key_1"
+)
+
+// RequiredIndexes lists, per collection, the indexes that must exist before
+// the API reports itself ready.
+var RequiredIndexes = map[string][]string{
+	"users":                 {EmailUniqueIndexName},
+	"api_keys":              {APIKeyPrefixUniqueIndexName},
+	"oauth_clients":         {OAuthClientIDUniqueIndexName},
+	"oauth_codes":           {OAuthCodeHashUniqueIndexName},
+	"identities":            {IdentityUniqueIndexName},
+	"groups":                {GroupNameUniqueIndexName},
+	"group_members":         {GroupMemberUniqueIndexName},
+	"invitations":           {InvitationTokenIndexName},
+	"attribute_definitions": {AttributeKeyUniqueIndexName},
+}
+
+// legacyEmailUniqueIndexName made emails unique across all tenants.
+const legacyEmailUniqueIndexName = "email_1"
+
+// EmailCollation compares emails case-insensitively. Email lookups and the
+// email indexes use it so that what a lookup treats as the same address the
+// unique index does too.
+var EmailCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// EnsureEmailUniqueIndex makes emails unique within a tenant, ignoring case,
+// so the same address can be registered once per organization. A
+// case-sensitive index left by an earlier version is replaced.
 func EnsureEmailUniqueIndex(db *mongo.Database) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	indexModel := mongo.IndexModel{
-		Keys:    bson.M{"email": 1},
+		Keys:    bson.D{{Key: "tenantId", Value: 1}, {Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName(EmailUniqueIndexName).SetCollation(EmailCollation),
+	}
+
+	indexes := db.Collection("users").Indexes()
+	_, err := indexes.CreateOne(ctx, indexModel)
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && (commandErr.Name == "IndexOptionsConflict" || commandErr.Name == "IndexKeySpecsConflict") {
+		if _, err := indexes.DropOne(ctx, EmailUniqueIndexName); err != nil {
+			return err
+		}
+		_, err = indexes.CreateOne(ctx, indexModel)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = indexes.DropOne(ctx, legacyEmailUniqueIndexName)
+	if errors.As(err, &commandErr) && commandErr.Name == "IndexNotFound" {
+		return nil
+	}
+	return err
+}
+
+// EnsureDefaultOrganization creates the default organization and moves
+// users, API keys and OAuth clients created before organizations existed
+// into it.
+func EnsureDefaultOrganization(db *mongo.Database, defaultID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("organizations").UpdateOne(ctx,
+		bson.M{"_id": defaultID},
+		bson.M{"$setOnInsert": bson.M{"name": "Default", "createdAt": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	missing := bson.M{"$or": bson.A{bson.M{"tenantId": bson.M{"$exists": false}}, bson.M{"tenantId": ""}}}
+	for _, collection := range []string{"users", "api_keys", "oauth_clients"} {
+		_, err := db.Collection(collection).UpdateMany(ctx, missing, bson.M{"$set": bson.M{"tenantId": defaultID}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func EnsureAPIKeyPrefixUniqueIndex(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexModel := mongo.IndexModel{
+		Keys:    bson.M{"prefix": 1},
+		Options: options.Index().SetUnique(true).SetName(APIKeyPrefixUniqueIndexName),
+	}
+
+	_, err := db.Collection("api_keys").Indexes().CreateOne(ctx, indexModel)
+	return err
+}
+
+// EnsureOAuthIndexes creates the lookup indexes of the OAuth collections and
+// lets Mongo drop authorization codes and revoked token ids once they have
+// expired.
+func EnsureOAuthIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	expireAt := mongo.IndexModel{
+		Keys:    bson.M{"expiresAt": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+	indexes := map[string][]mongo.IndexModel{
+		"oauth_clients": {{
+			Keys:    bson.M{"clientId": 1},
+			Options: options.Index().SetUnique(true).SetName(OAuthClientIDUniqueIndexName),
+		}},
+		"oauth_codes": {{
+			Keys:    bson.M{"codeHash": 1},
+			Options: options.Index().SetUnique(true).SetName(OAuthCodeHashUniqueIndexName),
+		}, expireAt},
+		"revoked_tokens": {expireAt},
+	}
+	for collection, indexModels := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, indexModels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureFederationIndexes makes each external identity linkable to a single
+// user and lets Mongo drop abandoned logins once they have expired.
+func EnsureFederationIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexes := map[string][]mongo.IndexModel{
+		"identities": {{
+			Keys:    bson.D{{Key: "provider", Value: 1}, {Key: "subject", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName(IdentityUniqueIndexName),
+		}, {
+			Keys: bson.M{"userId": 1},
+		}},
+		"federation_states": {{
+			Keys:    bson.M{"expiresAt": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		}},
+	}
+	for collection, indexModels := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, indexModels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureGroupIndexes makes group names unique per tenant and users members
+// of a group at most once, and indexes the lookups of subgroups and of a
+// user's groups.
+func EnsureGroupIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexes := map[string][]mongo.IndexModel{
+		"groups": {{
+			Keys:    bson.D{{Key: "tenantId", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName(GroupNameUniqueIndexName),
+		}, {
+			Keys: bson.M{"parentId": 1},
+		}},
+		"group_members": {{
+			Keys:    bson.D{{Key: "groupId", Value: 1}, {Key: "userId", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName(GroupMemberUniqueIndexName),
+		}, {
+			Keys: bson.M{"userId": 1},
+		}},
+	}
+	for collection, indexModels := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, indexModels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureInvitationIndexes makes invitation tokens unique and indexes the
+// pending invitations of a tenant by email.
+func EnsureInvitationIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexModels := []mongo.IndexModel{{
+		Keys:    bson.M{"tokenHash": 1},
+		Options: options.Index().SetUnique(true).SetName(InvitationTokenIndexName),
+	}, {
+		Keys:    bson.D{{Key: "tenantId", Value: 1}, {Key: "email", Value: 1}},
+		Options: options.Index().SetCollation(EmailCollation),
+	}}
+
+	_, err := db.Collection("invitations").Indexes().CreateMany(ctx, indexModels)
+	return err
+}
+
+// EnsureSessionIndexes indexes the sessions of a user and lets Mongo drop
+// sessions once they have expired.
+func EnsureSessionIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexModels := []mongo.IndexModel{{
+		Keys: bson.M{"userId": 1},
+	}, {
+		Keys:    bson.M{"expiresAt": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}}
+
+	_, err := db.Collection("sessions").Indexes().CreateMany(ctx, indexModels)
+	return err
+}
+
+// EnsureImportIndexes lets Mongo drop user import jobs once they are no
+// longer kept.
+func EnsureImportIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexModel := mongo.IndexModel{
+		Keys:    bson.M{"expiresAt": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	_, err := db.Collection("import_jobs").Indexes().CreateOne(ctx, indexModel)
+	return err
+}
+
+// EnsureExportIndexes indexes user export jobs by expiry for the purge
+// job. It is not a TTL index: the purge job deletes each job's file too.
+func EnsureExportIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexModel := mongo.IndexModel{Keys: bson.M{"expiresAt": 1}}
+
+	_, err := db.Collection("export_jobs").Indexes().CreateOne(ctx, indexModel)
+	return err
+}
+
+// EnsurePrivacyIndexes indexes audit events by the users they concern, for
+// data exports and erasures, and erasures by subject and progress.
+func EnsurePrivacyIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	auditIndexModels := []mongo.IndexModel{
+		{Keys: bson.M{"userId": 1}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.M{"actorId": 1}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.M{"targetUserId": 1}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "tenantId", Value: 1}, {Key: "attributes.email", Value: 1}}},
+	}
+	if _, err := db.Collection("audit_events").Indexes().CreateMany(ctx, auditIndexModels); err != nil {
+		return err
+	}
+
+	erasureIndexModels := []mongo.IndexModel{{
+		Keys:    bson.M{"subject": 1},
 		Options: options.Index().SetUnique(true),
+	}, {
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "updatedAt", Value: 1}},
+	}}
+	_, err := db.Collection("erasures").Indexes().CreateMany(ctx, erasureIndexModels)
+	return err
+}
+
+// EnsureProfileIndexes makes the keys of custom attributes unique per
+// tenant.
+func EnsureProfileIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenantId", Value: 1}, {Key: "key", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName(AttributeKeyUniqueIndexName),
+	}
+	_, err := db.Collection("attribute_definitions").Indexes().CreateOne(ctx, indexModel)
+	return err
+}
+
+// EnsureRateLimitTTLIndex lets Mongo drop idle rate limit buckets once they
+// would have refilled completely.
+func EnsureRateLimitTTLIndex(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexModel := mongo.IndexModel{
+		Keys:    bson.M{"expiresAt": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
 	}
 
-	_, err := db.Collection("users").Indexes().CreateOne(ctx, indexModel)
+	_, err := db.Collection("rate_limits").Indexes().CreateOne(ctx, indexModel)
 	return err
 }
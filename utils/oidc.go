@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// ID tokens are signed with RS256 so relying parties can verify them with
+// the published JWKS instead of sharing secretKey.
+var idTokens struct {
+	sync.Mutex
+	issuer string
+	key    *rsa.PrivateKey
+	keyID  string
+}
+
+// SetupIDTokenSigning sets the issuer and loads the RSA signing key from the
+// PEM file at keyFile. Without a key file an ephemeral key is generated, so
+// ID tokens stop verifying whenever the process restarts.
+func SetupIDTokenSigning(issuer, keyFile string) error {
+	var key *rsa.PrivateKey
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read OIDC signing key: %w", err)
+		}
+		if key, err = parseRSAPrivateKey(data); err != nil {
+			return err
+		}
+	}
+
+	idTokens.Lock()
+	defer idTokens.Unlock()
+	idTokens.issuer = issuer
+	if key != nil {
+		setIDTokenKey(key)
+	}
+	return nil
+}
+
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("OIDC signing key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OIDC signing key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("OIDC signing key must be an RSA key")
+	}
+	return key, nil
+}
+
+// setIDTokenKey must be called with idTokens locked. The key id is derived
+// from the public key so it only changes when the key does.
+func setIDTokenKey(key *rsa.PrivateKey) {
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	sum := sha256.Sum256(der)
+	idTokens.key = key
+	idTokens.keyID = base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+func idTokenKey() (*rsa.PrivateKey, string, error) {
+	idTokens.Lock()
+	defer idTokens.Unlock()
+	if idTokens.key == nil {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to generate OIDC signing key: %w", err)
+		}
+		setIDTokenKey(key)
+	}
+	return idTokens.key, idTokens.keyID, nil
+}
+
+// Issuer returns the OIDC issuer identifier, the base URL of this server.
+func Issuer() string {
+	idTokens.Lock()
+	defer idTokens.Unlock()
+	return idTokens.issuer
+}
+
+// GenerateIDToken signs an OIDC ID token for audience (the client id) with
+// the given user claims. iss, aud, iat and exp are filled in here.
+func GenerateIDToken(audience string, claims jwt.MapClaims, ttl time.Duration) (string, error) {
+	key, keyID, err := idTokenKey()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims["iss"] = Issuer()
+	claims["aud"] = audience
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	return token.SignedString(key)
+}
+
+// VerifyIDToken checks the signature and expiry of an ID token issued by
+// this server.
+func VerifyIDToken(tokenString string) (jwt.MapClaims, error) {
+	key, _, err := idTokenKey()
+	if err != nil {
+		return nil, err
+	}
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return &key.PublicKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		return claims, nil
+	}
+	return nil, fmt.Errorf("invalid token")
+}
+
+// JWKS returns the JSON Web Key Set with the public ID token signing key.
+func JWKS() (map[string]interface{}, error) {
+	key, keyID, err := idTokenKey()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		}},
+	}, nil
+}
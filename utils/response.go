@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"7-solutions/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorBody builds the JSON body for an error response, tagged with the
+// request id so clients can quote it in bug reports.
+func ErrorBody(c *gin.Context, message string) gin.H {
+	body := gin.H{"error": message}
+	if requestID := logging.RequestID(c.Request.Context()); requestID != "" {
+		body["requestId"] = requestID
+	}
+	return body
+}